@@ -0,0 +1,182 @@
+// Package client is a Go client of the gateway REST API.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Errors of requests the gateway rejected, matched by errors.Is against errors returned by Client.
+var (
+	// ErrInvalidID is returned when the gateway rejects object ID, e.g. not matching its object ID pattern.
+	ErrInvalidID = errors.New("invalid object ID")
+	// ErrNotFound is returned when the object doesn't exist.
+	ErrNotFound = errors.New("object not found")
+	// ErrTooLarge is returned when object content exceeds maximum object size of the gateway.
+	ErrTooLarge = errors.New("object too large")
+	// ErrContentTypeNotAllowed is returned when the gateway doesn't accept objects of the content type.
+	ErrContentTypeNotAllowed = errors.New("content type not allowed")
+	// ErrRateLimited is returned when the client exceeded its request rate.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnavailable is returned when the gateway can't serve requests at the moment, e.g. while starting,
+	// shutting down or when not enough replicas are available.
+	ErrUnavailable = errors.New("service unavailable")
+	// ErrTimeout is returned when storage nodes didn't complete the operation in time.
+	ErrTimeout = errors.New("operation timed out")
+)
+
+// statusErrors maps HTTP statuses to errors they are reported as.
+var statusErrors = map[int]error{
+	http.StatusBadRequest:            ErrInvalidID,
+	http.StatusNotFound:              ErrNotFound,
+	http.StatusRequestEntityTooLarge: ErrTooLarge,
+	http.StatusUnsupportedMediaType:  ErrContentTypeNotAllowed,
+	http.StatusTooManyRequests:       ErrRateLimited,
+	http.StatusServiceUnavailable:    ErrUnavailable,
+	http.StatusGatewayTimeout:        ErrTimeout,
+}
+
+// Error is an error response of the gateway. It matches the error its status maps to, if any.
+type Error struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Message is the message of the response, or status text if the response has none.
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gateway responded %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return statusErrors[e.StatusCode]
+}
+
+// Config holds client settings.
+type Config struct {
+	// BaseURL is the URL of the gateway, e.g. "http://localhost:3000".
+	BaseURL string
+	// Timeout bounds whole requests, including reading of downloaded content. Zero disables it.
+	Timeout time.Duration
+	// HTTPClient sends the requests. Defaults to a client with default transport.
+	HTTPClient *http.Client
+}
+
+// Client stores, retrieves and deletes objects using the gateway.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+}
+
+// New creates client of the gateway at cfg.BaseURL.
+func New(cfg *Config) (*Client, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: expected scheme and host", cfg.BaseURL)
+	}
+
+	httpClient := &http.Client{}
+	if cfg.HTTPClient != nil {
+		// copied, so the timeout doesn't change the given client
+		copied := *cfg.HTTPClient
+		httpClient = &copied
+	}
+	if cfg.Timeout > 0 {
+		httpClient.Timeout = cfg.Timeout
+	}
+	return &Client{baseURL: baseURL, httpClient: httpClient}, nil
+}
+
+// Put stores content read from r as the object with given ID. Empty content type is detected by the gateway.
+func (c *Client) Put(ctx context.Context, id, contentType string, r io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.objectURL(id), r)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	return drain(res)
+}
+
+// Get returns content of the object with given ID, along with its content type. Content must be closed.
+func (c *Client) Get(ctx context.Context, id string) (io.ReadCloser, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.objectURL(id), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	res, err := c.do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	return res.Body, res.Header.Get("Content-Type"), nil
+}
+
+// Delete deletes the object with given ID.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.objectURL(id), nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	return drain(res)
+}
+
+// objectURL returns URL of the object with given ID. Segments of IDs containing "/" are escaped one by one,
+// as the gateway matches such IDs as they are.
+func (c *Client) objectURL(id string) string {
+	segments := strings.Split(id, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return c.baseURL.String() + "/object/" + strings.Join(segments, "/")
+}
+
+// do sends the request, returning error responses as *Error.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, err)
+	}
+	if res.StatusCode < http.StatusBadRequest {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	// responses not coming from the gateway itself, e.g. of a proxy, may not be JSON
+	apiErr := &Error{StatusCode: res.StatusCode}
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err == nil {
+		apiErr.Message = body.Message
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(res.StatusCode)
+	}
+	return nil, apiErr
+}
+
+// drain reads the rest of response body and closes it, so the connection can be reused.
+func drain(res *http.Response) error {
+	defer res.Body.Close()
+	_, err := io.Copy(io.Discard, res.Body)
+	return err
+}
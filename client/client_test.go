@@ -0,0 +1,291 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/gateway"
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockStorage keeps objects in memory, failing all operations with err if set
+type MockStorage struct {
+	mu      sync.Mutex
+	objects map[string]*storage.Object
+	err     error
+}
+
+func (ms *MockStorage) Init(ctx context.Context) error {
+	return nil
+}
+
+func (ms *MockStorage) Put(ctx context.Context, object *storage.Object) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.err != nil {
+		return ms.err
+	}
+	ms.objects[object.ID] = object
+	return nil
+}
+
+func (ms *MockStorage) Get(ctx context.Context, id string) (*storage.Object, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.err != nil {
+		return nil, ms.err
+	}
+	return ms.objects[id], nil
+}
+
+func (ms *MockStorage) Delete(ctx context.Context, id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.err != nil {
+		return ms.err
+	}
+	if _, ok := ms.objects[id]; !ok {
+		return storage.ErrObjectNotFound
+	}
+	delete(ms.objects, id)
+	return nil
+}
+
+func (ms *MockStorage) PutStream(ctx context.Context, object *storage.ObjectStream) error {
+	content, err := io.ReadAll(object.Content)
+	if err != nil {
+		return err
+	}
+	return ms.Put(ctx, &storage.Object{ID: object.ID, ContentType: object.ContentType, Content: content})
+}
+
+func (ms *MockStorage) GetStream(ctx context.Context, id string) (*storage.ObjectStream, error) {
+	object, err := ms.Get(ctx, id)
+	if object == nil || err != nil {
+		return nil, err
+	}
+	return &storage.ObjectStream{
+		ID:          object.ID,
+		ContentType: object.ContentType,
+		Size:        int64(len(object.Content)),
+		Content:     io.NopCloser(bytes.NewReader(object.Content)),
+	}, nil
+}
+
+func (ms *MockStorage) GetRange(ctx context.Context, id string, offset, length int64) (*storage.ObjectStream, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (ms *MockStorage) Stat(ctx context.Context, id string) (*storage.ObjectInfo, error) {
+	object, err := ms.Get(ctx, id)
+	if object == nil || err != nil {
+		return nil, err
+	}
+	return &storage.ObjectInfo{ID: object.ID, ContentType: object.ContentType, Size: int64(len(object.Content))}, nil
+}
+
+func (ms *MockStorage) Ping(ctx context.Context) error {
+	return ms.err
+}
+
+func (ms *MockStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var ids []string
+	for id := range ms.objects {
+		if strings.HasPrefix(id, prefix) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (ms *MockStorage) has(id string) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	_, ok := ms.objects[id]
+	return ok
+}
+
+func (ms *MockStorage) Copy(ctx context.Context, srcID, dstID string) error {
+	return errors.New("not implemented")
+}
+
+// newTestClient creates client of a gateway serving objects of the storage
+func newTestClient(t *testing.T, s storage.Storage, cfg *gateway.Config) *Client {
+	server := httptest.NewServer(gateway.NewServer(s, cfg))
+	t.Cleanup(server.Close)
+	c, err := New(&Config{BaseURL: server.URL})
+	assert.NoError(t, err)
+	return c
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&Config{BaseURL: "localhost:3000"})
+	assert.Error(t, err)
+	_, err = New(&Config{BaseURL: "://"})
+	assert.Error(t, err)
+
+	// given HTTP client is used, without changing it
+	httpClient := &http.Client{}
+	c, err := New(&Config{BaseURL: "http://localhost:3000/", Timeout: time.Second, HTTPClient: httpClient})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:3000/object/1", c.objectURL("1"))
+	assert.Equal(t, time.Second, c.httpClient.Timeout)
+	assert.Zero(t, httpClient.Timeout)
+}
+
+func TestClient(t *testing.T) {
+	s := &MockStorage{objects: map[string]*storage.Object{}}
+	// spaces are allowed, so IDs needing escaping are accepted
+	c := newTestClient(t, s, &gateway.Config{ObjectIDPattern: regexp.MustCompile(`^[a-zA-Z0-9._/ -]+$`)})
+	ctx := context.Background()
+
+	// objects are stored, read and deleted, including IDs with path segments and escaped characters
+	for _, id := range []string{"1", "reports/2024/jan report.csv"} {
+		assert.NoError(t, c.Put(ctx, id, "text/csv", strings.NewReader("a,b\n1,2\n")))
+		content, contentType, err := c.Get(ctx, id)
+		if assert.NoError(t, err, id) {
+			read, _ := io.ReadAll(content)
+			content.Close()
+			assert.Equal(t, "a,b\n1,2\n", string(read))
+			assert.Equal(t, "text/csv", contentType)
+		}
+		assert.True(t, s.has(id), id)
+		assert.NoError(t, c.Delete(ctx, id))
+		assert.False(t, s.has(id), id)
+	}
+
+	// content type is detected by the gateway when not given
+	assert.NoError(t, c.Put(ctx, "page", "", strings.NewReader("<html><body>hello</body></html>")))
+	_, contentType, err := c.Get(ctx, "page")
+	assert.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", contentType)
+}
+
+func TestClient_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		storage     *MockStorage
+		cfg         *gateway.Config
+		call        func(c *Client) error
+		expectedErr error
+		status      int
+	}{
+		{
+			name:        "missing object",
+			call:        func(c *Client) error { _, _, err := c.Get(ctx, "missing"); return err },
+			expectedErr: ErrNotFound,
+			status:      http.StatusNotFound,
+		},
+		{
+			name:        "delete missing object",
+			call:        func(c *Client) error { return c.Delete(ctx, "missing") },
+			expectedErr: ErrNotFound,
+			status:      http.StatusNotFound,
+		},
+		{
+			name:        "invalid ID",
+			call:        func(c *Client) error { return c.Put(ctx, "../etc/passwd", "", strings.NewReader("data")) },
+			expectedErr: ErrInvalidID,
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "too large",
+			cfg:         &gateway.Config{MaxObjectSize: 2},
+			call:        func(c *Client) error { return c.Put(ctx, "1", "", strings.NewReader("data")) },
+			expectedErr: ErrTooLarge,
+			status:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "content type not allowed",
+			cfg:         &gateway.Config{AllowedContentTypes: gateway.ContentTypes{"image/*"}},
+			call:        func(c *Client) error { return c.Put(ctx, "1", "text/plain", strings.NewReader("data")) },
+			expectedErr: ErrContentTypeNotAllowed,
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "storage not ready",
+			storage:     &MockStorage{err: storage.ErrNotReady},
+			call:        func(c *Client) error { _, _, err := c.Get(ctx, "1"); return err },
+			expectedErr: ErrUnavailable,
+			status:      http.StatusServiceUnavailable,
+		},
+		{
+			name:    "storage failure",
+			storage: &MockStorage{err: errors.New("node down")},
+			call:    func(c *Client) error { return c.Put(ctx, "1", "", strings.NewReader("data")) },
+			status:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.storage
+			if s == nil {
+				s = &MockStorage{objects: map[string]*storage.Object{}}
+			}
+			cfg := tt.cfg
+			if cfg == nil {
+				cfg = &gateway.Config{}
+			}
+			err := tt.call(newTestClient(t, s, cfg))
+
+			var apiErr *Error
+			if assert.ErrorAs(t, err, &apiErr) {
+				assert.Equal(t, tt.status, apiErr.StatusCode)
+				assert.NotEmpty(t, apiErr.Message)
+			}
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestClient_NonJSONError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	c, err := New(&Config{BaseURL: server.URL})
+	assert.NoError(t, err)
+
+	err = c.Delete(context.Background(), "1")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	var apiErr *Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusText(http.StatusServiceUnavailable), apiErr.Message)
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	c, err := New(&Config{BaseURL: server.URL, Timeout: 50 * time.Millisecond})
+	assert.NoError(t, err)
+
+	start := time.Now()
+	_, _, err = c.Get(context.Background(), "1")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
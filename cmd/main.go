@@ -2,37 +2,258 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/cavke/go-distributed-object-storage/internal/gateway"
+	"github.com/cavke/go-distributed-object-storage/internal/logging"
+	"github.com/cavke/go-distributed-object-storage/internal/metrics"
 	"github.com/cavke/go-distributed-object-storage/internal/storage"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	dockercli "github.com/docker/docker/client"
+	"github.com/labstack/echo/v4"
 )
 
-const EnvBucketName = "BUCKET_NAME"
+const (
+	EnvBucketName        = "BUCKET_NAME"
+	EnvBucketRegion      = "BUCKET_REGION"
+	EnvBucketRegionAdopt = "BUCKET_REGION_ADOPT"
+	EnvVerifyLength      = "VERIFY_CONTENT_LENGTH"
+	EnvStreamChecksums   = "STREAM_CHECKSUMS"
+	EnvRingFingerprint   = "EXPECTED_RING_FINGERPRINT"
+	EnvMetadataTimeout   = "NODE_METADATA_TIMEOUT"
+	EnvDataTimeout       = "NODE_DATA_TIMEOUT"
+	EnvNodeOpTimeout     = "NODE_OPERATION_TIMEOUT"
+	EnvContentType       = "DEFAULT_CONTENT_TYPE"
+	EnvWarmupConns       = "NODE_WARMUP_CONNECTIONS"
+	EnvMaxIdleConns      = "NODE_MAX_IDLE_CONNECTIONS"
+	EnvMaxConns          = "NODE_MAX_CONNECTIONS"
+	EnvRetryAttempts     = "NODE_RETRY_ATTEMPTS"
+	EnvRetryDelay        = "NODE_RETRY_BASE_DELAY"
+	EnvReplication       = "REPLICATION_FACTOR"
+	EnvWriteConsistency  = "WRITE_CONSISTENCY"
+	EnvReadConsistency   = "READ_CONSISTENCY"
+	EnvReadStrategy      = "READ_STRATEGY"
+	EnvReadinessTimeout  = "NODE_READINESS_TIMEOUT"
+	EnvInitConcurrency   = "NODE_INIT_CONCURRENCY"
+	EnvAbortOnInitFail   = "NODE_INIT_ABORT_ON_FAILURE"
+	EnvNodeChangeWindow  = "NODE_CHANGE_WINDOW"
+	EnvResumeStreams     = "RESUME_STREAMS"
+	EnvPublicEndpoints   = "PRESIGN_PUBLIC_ENDPOINTS"
+	EnvMaxPresignExpiry  = "MAX_PRESIGN_EXPIRY"
+	EnvRebalance         = "REBALANCE"
+	EnvRebalanceRate     = "REBALANCE_RATE"
+	EnvScanOnMiss        = "SCAN_ON_MISS"
+	EnvScanConcurrency   = "SCAN_CONCURRENCY"
+	EnvScanTimeout       = "SCAN_TIMEOUT"
+	EnvScanRelocate      = "SCAN_RELOCATE"
+	EnvWriteBuffer       = "WRITE_BUFFER_SIZE"
+	EnvWriteBufferWork   = "WRITE_BUFFER_WORKERS"
+	EnvWriteBufferMax    = "WRITE_BUFFER_MAX_OBJECT_SIZE"
+	EnvWriteBufferBlock  = "WRITE_BUFFER_BLOCK"
+	EnvSweepInterval     = "EXPIRY_SWEEP_INTERVAL"
+	EnvClockSkew         = "CLOCK_SKEW_TOLERANCE"
+	EnvBreakerThreshold  = "NODE_BREAKER_THRESHOLD"
+	EnvBreakerCooldown   = "NODE_BREAKER_COOLDOWN"
+	EnvMaxOpTimeout      = "MAX_OPERATION_TIMEOUT"
+	EnvRewriteRules      = "OBJECT_ID_REWRITE_RULES"
+	EnvObjectIDPattern   = "OBJECT_ID_PATTERN"
+	EnvMaxObjectIDLength = "MAX_OBJECT_ID_LENGTH"
+	EnvIdempotencyTTL    = "IDEMPOTENCY_TTL"
+	EnvIdempotencyKeys   = "IDEMPOTENCY_MAX_KEYS"
+	EnvStreamBuffer      = "STREAM_BUFFER_SIZE"
+	EnvMaxObjectSize     = "MAX_OBJECT_SIZE"
+	EnvContentTypes      = "ALLOWED_CONTENT_TYPES"
+	EnvReadyQuorum       = "READY_QUORUM"
+	EnvAccessStatsKeys   = "ACCESS_STATS_MAX_KEYS"
+	EnvMetadataPrefix    = "METADATA_HEADER_PREFIX"
+	EnvDebugEndpoints    = "DEBUG_ENDPOINTS"
+	EnvUsageCacheTTL     = "USAGE_CACHE_TTL"
+	EnvBatchParallelism  = "BATCH_PARALLELISM"
+	EnvRateLimit         = "RATE_LIMIT"
+	EnvRateLimitBurst    = "RATE_LIMIT_BURST"
+	EnvRateLimitClients  = "RATE_LIMIT_MAX_CLIENTS"
+	EnvRateLimitHeader   = "RATE_LIMIT_KEY_HEADER"
+	EnvNodeDiscovery     = "NODE_DISCOVERY"
+	EnvListenAddr        = "LISTEN_ADDR"
+	EnvShutdownTimeout   = "SHUTDOWN_TIMEOUT"
+	EnvStaticNodes       = "STATIC_NODES"
+	EnvNodeSecure        = "NODE_SECURE"
+	EnvNodeCACert        = "NODE_CA_CERT"
+	EnvCompression       = "COMPRESSION"
+	EnvCompressTypes     = "COMPRESSION_CONTENT_TYPES"
+	EnvCompressPassThru  = "COMPRESSION_PASS_THROUGH"
+	EnvDedup             = "DEDUP"
+	EnvDedupBucket       = "DEDUP_BUCKET"
+	EnvDedupRetention    = "DEDUP_BLOB_RETENTION"
+	EnvKeyPrefix         = "OBJECT_KEY_PREFIX"
+	EnvLogLevel          = "LOG_LEVEL"
+	EnvJSONAccessLog     = "JSON_ACCESS_LOG"
+)
 
 func main() {
-	log.Println("Starting storage system")
+	logger, err := newLogger(os.Getenv(EnvLogLevel))
+	slog.SetDefault(logger)
+	if err != nil {
+		slog.Warn("invalid log level, using info", "env", EnvLogLevel, "error", err)
+	}
+
+	slog.Info("starting storage system")
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	cli, err := dockercli.NewClientWithOpts(dockercli.FromEnv)
-	checkError(err)
+	discoverer, err := newNodeDiscoverer(getEnvWithFallback(EnvNodeDiscovery, "docker"))
+	if err != nil {
+		fatal("invalid node discovery", err)
+	}
+	writeConsistency, err := storage.ParseConsistencyLevel(getEnvWithFallback(EnvWriteConsistency, ""))
+	if err != nil {
+		fatal("invalid "+EnvWriteConsistency, err)
+	}
+	readConsistency, err := storage.ParseConsistencyLevel(getEnvWithFallback(EnvReadConsistency, ""))
+	if err != nil {
+		fatal("invalid "+EnvReadConsistency, err)
+	}
+	readStrategy, err := storage.ParseReadStrategy(getEnvWithFallback(EnvReadStrategy, ""))
+	if err != nil {
+		fatal("invalid "+EnvReadStrategy, err)
+	}
+	publicEndpoints, err := storage.ParsePublicEndpoints(getEnvWithFallback(EnvPublicEndpoints, ""))
+	if err != nil {
+		fatal("invalid "+EnvPublicEndpoints, err)
+	}
+	clockSkew := getEnvDurationWithFallback(EnvClockSkew, storage.DefaultClockSkew)
 
-	bucketName := getEnvWithFallback(EnvBucketName, "default")
-	storage := storage.NewDistributedStorage(cli, bucketName)
-	storage.Init(ctx)
+	m := metrics.New()
+	storage := storage.NewDistributedStorage(discoverer, &storage.DistributedConfig{
+		Node: storage.MinioConfig{
+			BucketName:          getEnvWithFallback(EnvBucketName, "default"),
+			Region:              getEnvWithFallback(EnvBucketRegion, ""),
+			AdoptBucketRegion:   getEnvBoolWithFallback(EnvBucketRegionAdopt, false),
+			VerifyContentLength: getEnvBoolWithFallback(EnvVerifyLength, true),
+			StreamChecksums:     getEnvBoolWithFallback(EnvStreamChecksums, false),
+			MetadataTimeout:     getEnvDurationWithFallback(EnvMetadataTimeout, storage.DefaultMetadataTimeout),
+			DataTimeout:         getEnvDurationWithFallback(EnvDataTimeout, 0),
+			OperationTimeout:    getEnvDurationWithFallback(EnvNodeOpTimeout, 0),
+			DefaultContentType:  getEnvWithFallback(EnvContentType, storage.DefaultContentType),
+			WarmupConnections:   getEnvIntWithFallback(EnvWarmupConns, storage.DefaultWarmupConnections),
+			MaxIdleConnsPerHost: getEnvIntWithFallback(EnvMaxIdleConns, storage.DefaultMaxIdleConnsPerHost),
+			MaxConnsPerHost:     getEnvIntWithFallback(EnvMaxConns, 0),
+			Retry: storage.RetryConfig{
+				MaxAttempts: getEnvIntWithFallback(EnvRetryAttempts, 3),
+				BaseDelay:   getEnvDurationWithFallback(EnvRetryDelay, 100*time.Millisecond),
+			},
+			Secure:     getEnvBoolWithFallback(EnvNodeSecure, false),
+			CACertPath: getEnvWithFallback(EnvNodeCACert, ""),
+			Compression: storage.CompressionConfig{
+				Enabled:      getEnvBoolWithFallback(EnvCompression, false),
+				ContentTypes: getEnvListWithFallback(EnvCompressTypes, storage.DefaultCompressibleTypes),
+				PassThrough:  getEnvBoolWithFallback(EnvCompressPassThru, false),
+			},
+			Dedup: storage.DedupConfig{
+				Enabled:    getEnvBoolWithFallback(EnvDedup, false),
+				BucketName: getEnvWithFallback(EnvDedupBucket, ""),
+				Retention:  getEnvDurationWithFallback(EnvDedupRetention, storage.DefaultBlobRetention),
+			},
+			KeyPrefix: getEnvWithFallback(EnvKeyPrefix, ""),
+			ClockSkew: clockSkew,
+		},
+		ReplicationFactor:       getEnvIntWithFallback(EnvReplication, 1),
+		WriteConsistency:        writeConsistency,
+		ReadConsistency:         readConsistency,
+		ReadStrategy:            readStrategy,
+		ExpectedRingFingerprint: getEnvWithFallback(EnvRingFingerprint, ""),
+		NodeReadinessTimeout:    getEnvDurationWithFallback(EnvReadinessTimeout, 0),
+		NodeInitConcurrency:     getEnvIntWithFallback(EnvInitConcurrency, storage.DefaultNodeInitConcurrency),
+		AbortOnNodeInitFailure:  getEnvBoolWithFallback(EnvAbortOnInitFail, false),
+		NodeChangeWindow:        getEnvDurationWithFallback(EnvNodeChangeWindow, 0),
+		ResumeStreams:           getEnvBoolWithFallback(EnvResumeStreams, false),
+		PublicEndpoints:         publicEndpoints,
+		Rebalance: storage.RebalanceConfig{
+			Enabled: getEnvBoolWithFallback(EnvRebalance, false),
+			Rate:    getEnvIntWithFallback(EnvRebalanceRate, storage.DefaultRebalanceRate),
+		},
+		ScanOnMiss: storage.ScanConfig{
+			Enabled:     getEnvBoolWithFallback(EnvScanOnMiss, false),
+			Concurrency: getEnvIntWithFallback(EnvScanConcurrency, storage.DefaultScanConcurrency),
+			Timeout:     getEnvDurationWithFallback(EnvScanTimeout, storage.DefaultScanTimeout),
+			Relocate:    getEnvBoolWithFallback(EnvScanRelocate, false),
+		},
+		WriteBuffer: storage.WriteBufferConfig{
+			Size:          getEnvIntWithFallback(EnvWriteBuffer, 0),
+			Workers:       getEnvIntWithFallback(EnvWriteBufferWork, storage.DefaultWriteBufferWorkers),
+			MaxObjectSize: int64(getEnvIntWithFallback(EnvWriteBufferMax, storage.DefaultWriteBufferMaxObjectSize)),
+			BlockWhenFull: getEnvBoolWithFallback(EnvWriteBufferBlock, false),
+		},
+		ExpirySweepInterval: getEnvDurationWithFallback(EnvSweepInterval, storage.DefaultExpirySweepInterval),
+		Breaker: storage.BreakerConfig{
+			Threshold: getEnvIntWithFallback(EnvBreakerThreshold, 0),
+			Cooldown:  getEnvDurationWithFallback(EnvBreakerCooldown, storage.DefaultBreakerCooldown),
+		},
+		Metrics: m,
+		Logger:  logger,
+	})
+	allowedContentTypes, err := gateway.ParseContentTypes(getEnvWithFallback(EnvContentTypes, ""))
+	if err != nil {
+		fatal("invalid "+EnvContentTypes, err)
+	}
+	rewriteRules, err := gateway.ParseRewriteRules(getEnvWithFallback(EnvRewriteRules, ""))
+	if err != nil {
+		fatal("invalid "+EnvRewriteRules, err)
+	}
+	objectIDPattern, err := regexp.Compile(getEnvWithFallback(EnvObjectIDPattern, gateway.DefaultObjectIDPattern))
+	if err != nil {
+		fatal("invalid "+EnvObjectIDPattern, err)
+	}
 
-	server := gateway.NewServer(storage)
+	server := gateway.NewServer(storage, &gateway.Config{
+		MaxOperationTimeout:  getEnvDurationWithFallback(EnvMaxOpTimeout, 30*time.Second),
+		RewriteRules:         rewriteRules,
+		ObjectIDPattern:      objectIDPattern,
+		MaxObjectIDLength:    getEnvIntWithFallback(EnvMaxObjectIDLength, gateway.DefaultMaxObjectIDLength),
+		IdempotencyTTL:       getEnvDurationWithFallback(EnvIdempotencyTTL, 0),
+		IdempotencyMaxKeys:   getEnvIntWithFallback(EnvIdempotencyKeys, 10000),
+		StreamBufferSize:     getEnvIntWithFallback(EnvStreamBuffer, gateway.DefaultStreamBufferSize),
+		MaxObjectSize:        int64(getEnvIntWithFallback(EnvMaxObjectSize, 0)),
+		AllowedContentTypes:  allowedContentTypes,
+		Metrics:              m,
+		ReadyQuorum:          getEnvIntWithFallback(EnvReadyQuorum, 0),
+		AccessStatsMaxKeys:   getEnvIntWithFallback(EnvAccessStatsKeys, 0),
+		MaxPresignExpiry:     getEnvDurationWithFallback(EnvMaxPresignExpiry, gateway.DefaultMaxPresignExpiry),
+		BatchParallelism:     getEnvIntWithFallback(EnvBatchParallelism, gateway.DefaultBatchParallelism),
+		UsageCacheTTL:        getEnvDurationWithFallback(EnvUsageCacheTTL, gateway.DefaultUsageCacheTTL),
+		JSONAccessLog:        getEnvBoolWithFallback(EnvJSONAccessLog, false),
+		DebugEndpoints:       getEnvBoolWithFallback(EnvDebugEndpoints, false),
+		Logger:               logger,
+		MetadataHeaderPrefix: getEnvWithFallback(EnvMetadataPrefix, gateway.DefaultMetadataHeaderPrefix),
+		ClockSkew:            clockSkew,
+		RateLimit: gateway.RateLimitConfig{
+			RequestsPerSecond: getEnvFloatWithFallback(EnvRateLimit, 0),
+			Burst:             getEnvIntWithFallback(EnvRateLimitBurst, 0),
+			MaxClients:        getEnvIntWithFallback(EnvRateLimitClients, gateway.DefaultRateLimitMaxClients),
+			KeyHeader:         getEnvWithFallback(EnvRateLimitHeader, ""),
+		},
+	})
 
-	log.Println("Starting gateway server")
+	listenAddr := getEnvWithFallback(EnvListenAddr, ":3000")
+	shutdownTimeout := getEnvDurationWithFallback(EnvShutdownTimeout, 5*time.Second)
+
+	slog.Info("starting gateway server", "addr", listenAddr)
 	go func() {
-		if err := server.Start(":3000"); err != nil {
-			log.Printf("Server error: %s\n", err)
+		if err := server.Start(listenAddr); err != nil {
+			slog.Error("server stopped", "error", err)
+		}
+	}()
+	// storage is initialized once the server runs, so requests arriving meanwhile are rejected as unavailable
+	go func() {
+		if err := storage.Init(ctx); err != nil && ctx.Err() == nil {
+			fatal("cannot initialize storage", err)
 		}
 	}()
 
@@ -43,28 +264,173 @@ func main() {
 		syscall.SIGTERM,
 		syscall.SIGQUIT)
 
+	// SIGHUP reloads node topology, other signals shut down
 	sig := <-sigc
-	log.Printf("Received signal: '%s', initiating server shutdown\n", sig.String())
-	cancel()
+	for sig == syscall.SIGHUP {
+		reload(ctx, storage)
+		sig = <-sigc
+	}
+	slog.Info("received signal, initiating server shutdown", "signal", sig.String())
+	shutdown(server, storage, cancel, shutdownTimeout)
+
+	slog.Info("storage system shutdown completed successfully")
+}
 
-	closeCtx, cancelClose := context.WithTimeout(ctx, time.Second*5)
+// newLogger creates logger of the storage system, logging records of given level ("debug", "info", "warn"
+// or "error") and above. Empty level defaults to info; so does invalid one, which is reported.
+func newLogger(level string) (*slog.Logger, error) {
+	if level == "" {
+		return logging.New(os.Stderr, slog.LevelInfo), nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return logging.New(os.Stderr, slog.LevelInfo), err
+	}
+	return logging.New(os.Stderr, l), nil
+}
+
+// fatal logs error preventing the storage system from starting and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// newNodeDiscoverer creates discoverer of storage nodes: minio docker containers, or nodes listed in STATIC_NODES.
+func newNodeDiscoverer(kind string) (storage.NodeDiscoverer, error) {
+	switch kind {
+	case "docker":
+		cli, err := dockercli.NewClientWithOpts(dockercli.FromEnv)
+		checkError(err)
+		return storage.NewDockerDiscoverer(cli), nil
+	case "static":
+		// not logged by getEnvWithFallback, as the value holds node credentials
+		nodes, err := storage.ParseStaticNodes(os.Getenv(EnvStaticNodes))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvStaticNodes, err)
+		}
+		return storage.NewStaticDiscoverer(nodes), nil
+	default:
+		return nil, fmt.Errorf("%s must be docker or static, got %q", EnvNodeDiscovery, kind)
+	}
+}
+
+// reloader is implemented by storages able to rediscover their nodes.
+type reloader interface {
+	Reload(ctx context.Context) error
+}
+
+// reload rediscovers storage nodes, keeping current ones if it fails. Requests in flight aren't affected.
+func reload(ctx context.Context, s storage.Storage) {
+	r, ok := s.(reloader)
+	if !ok {
+		slog.Warn("storage doesn't support reloading nodes, ignoring SIGHUP")
+		return
+	}
+	slog.Info("received SIGHUP, reloading storage nodes")
+	if err := r.Reload(ctx); err != nil {
+		slog.Error("cannot reload storage nodes", "error", err)
+	}
+}
+
+// writeFlusher is implemented by storages buffering writes, flushing them on shutdown.
+type writeFlusher interface {
+	FlushWrites(ctx context.Context) error
+}
+
+// stopper is implemented by storages running background workers, stopping them on shutdown.
+type stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// shutdown stops the storage system in order:
+//  1. stop accepting new connections and drain in-flight requests,
+//  2. flush writes the storage buffered,
+//  3. stop storage background workers and await acknowledged replica writes,
+//  4. cancel the root context to stop remaining goroutines bound to it.
+//
+// The root context must not be cancelled first, as that would abort in-flight requests being drained
+// and stop the storage before buffered writes are flushed.
+func shutdown(server *echo.Echo, s storage.Storage, cancel context.CancelFunc, timeout time.Duration) {
+	closeCtx, cancelClose := context.WithTimeout(context.Background(), timeout)
 	defer cancelClose()
+
+	slog.Info("draining gateway server")
 	checkError(server.Shutdown(closeCtx))
 
-	log.Println("Storage system shutdown completed successfully")
+	if flusher, ok := s.(writeFlusher); ok {
+		slog.Info("flushing buffered writes")
+		checkError(flusher.FlushWrites(closeCtx))
+	}
+
+	if stopper, ok := s.(stopper); ok {
+		slog.Info("stopping storage background workers")
+		checkError(stopper.Stop(closeCtx))
+	}
+	cancel()
 }
 
 func checkError(err error) {
 	if err != nil {
-		log.Println(err.Error())
+		slog.Error(err.Error())
 	}
 }
 
 func getEnvWithFallback(key, fallback string) string {
 	value, exists := os.LookupEnv(key)
 	if !exists {
-		log.Printf("Environment variable %s not set, using default value: %s", key, fallback)
+		slog.Info("environment variable not set, using default value", "env", key, "default", fallback)
 		return fallback
 	}
 	return value
 }
+
+// getEnvListWithFallback returns comma separated values of the environment variable, ignoring empty ones.
+func getEnvListWithFallback(key string, fallback []string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnvWithFallback(key, strings.Join(fallback, ",")), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvBoolWithFallback(key string, fallback bool) bool {
+	value := getEnvWithFallback(key, strconv.FormatBool(fallback))
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("environment variable has invalid boolean value, using default value", "env", key, "value", value, "default", fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func getEnvDurationWithFallback(key string, fallback time.Duration) time.Duration {
+	value := getEnvWithFallback(key, fallback.String())
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("environment variable has invalid duration value, using default value", "env", key, "value", value, "default", fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func getEnvIntWithFallback(key string, fallback int) int {
+	value := getEnvWithFallback(key, strconv.Itoa(fallback))
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("environment variable has invalid integer value, using default value", "env", key, "value", value, "default", fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func getEnvFloatWithFallback(key string, fallback float64) float64 {
+	value := getEnvWithFallback(key, strconv.FormatFloat(fallback, 'g', -1, 64))
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("environment variable has invalid number value, using default value", "env", key, "value", value, "default", fallback)
+		return fallback
+	}
+	return parsed
+}
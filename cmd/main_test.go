@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEnvDurationWithFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    *string
+		expected time.Duration
+	}{
+		{name: "unset", expected: 5 * time.Second},
+		{name: "valid", value: ptr("30s"), expected: 30 * time.Second},
+		{name: "invalid", value: ptr("soon"), expected: 5 * time.Second},
+		{name: "missing unit", value: ptr("10"), expected: 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.value != nil {
+				t.Setenv(EnvShutdownTimeout, *tt.value)
+			}
+			assert.Equal(t, tt.expected, getEnvDurationWithFallback(EnvShutdownTimeout, 5*time.Second))
+		})
+	}
+}
+
+func TestGetEnvWithFallback(t *testing.T) {
+	assert.Equal(t, ":3000", getEnvWithFallback(EnvListenAddr, ":3000"))
+
+	t.Setenv(EnvListenAddr, "127.0.0.1:8080")
+	assert.Equal(t, "127.0.0.1:8080", getEnvWithFallback(EnvListenAddr, ":3000"))
+}
+
+func TestGetEnvListWithFallback(t *testing.T) {
+	assert.Equal(t, []string{"text/", "application/json"}, getEnvListWithFallback(EnvCompressTypes, []string{"text/", "application/json"}))
+
+	t.Setenv(EnvCompressTypes, " text/csv,, application/xml ")
+	assert.Equal(t, []string{"text/csv", "application/xml"}, getEnvListWithFallback(EnvCompressTypes, []string{"text/"}))
+}
+
+func TestGetEnvFloatWithFallback(t *testing.T) {
+	assert.Equal(t, 0.0, getEnvFloatWithFallback(EnvRateLimit, 0))
+
+	t.Setenv(EnvRateLimit, "2.5")
+	assert.Equal(t, 2.5, getEnvFloatWithFallback(EnvRateLimit, 0))
+
+	t.Setenv(EnvRateLimit, "fast")
+	assert.Equal(t, 1.0, getEnvFloatWithFallback(EnvRateLimit, 1))
+}
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		level       string
+		expected    slog.Level
+		expectedErr bool
+	}{
+		{level: "", expected: slog.LevelInfo},
+		{level: "debug", expected: slog.LevelDebug},
+		{level: "WARN", expected: slog.LevelWarn},
+		{level: "verbose", expected: slog.LevelInfo, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			logger, err := newLogger(tt.level)
+			assert.Equal(t, tt.expectedErr, err != nil)
+			assert.True(t, logger.Enabled(context.Background(), tt.expected))
+			assert.False(t, logger.Enabled(context.Background(), tt.expected-1))
+		})
+	}
+}
+
+// recordingStorage records shutdown steps performed on it, and whether the gateway server was drained before
+type recordingStorage struct {
+	storage.Storage
+	addr  string
+	calls []string
+}
+
+func (rs *recordingStorage) record(step string) {
+	if conn, err := net.Dial("tcp", rs.addr); err == nil {
+		conn.Close()
+		step += " before drain"
+	}
+	rs.calls = append(rs.calls, step)
+}
+
+func (rs *recordingStorage) FlushWrites(ctx context.Context) error {
+	rs.record("flush")
+	return nil
+}
+
+func (rs *recordingStorage) Stop(ctx context.Context) error {
+	rs.record("stop")
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := echo.New()
+	server.Listener = listener
+	go func() { _ = server.Start("") }()
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return true
+	}, time.Second, 5*time.Millisecond)
+
+	s := &recordingStorage{addr: listener.Addr().String()}
+	cancel := func() { s.calls = append(s.calls, "cancel") }
+	shutdown(server, s, cancel, time.Second)
+	assert.Equal(t, []string{"flush", "stop", "cancel"}, s.calls)
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,148 @@
+package gateway
+
+import (
+	"container/list"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultAccessStatsTop is the default number of objects listed by /admin/access.
+const DefaultAccessStatsTop = 10
+
+// AccessStat holds read statistics of an object.
+type AccessStat struct {
+	ID         string    `json:"id"`
+	Reads      int64     `json:"reads"`
+	LastAccess time.Time `json:"lastAccess"`
+}
+
+type AccessStatsResponse struct {
+	Objects []AccessStat `json:"objects"`
+}
+
+// accessStats counts object reads served by this gateway instance in memory, so tracking doesn't add
+// a storage write per read. It's bounded; when full, the least recently read object is forgotten first,
+// so cold objects make room for hot ones.
+type accessStats struct {
+	mu         sync.Mutex
+	maxEntries int
+	clock      storage.Clock
+	entries    map[string]*list.Element
+	// recency holds *AccessStat ordered from the most recently read
+	recency *list.List
+}
+
+func newAccessStats(maxEntries int, clock storage.Clock) *accessStats {
+	return &accessStats{
+		maxEntries: maxEntries,
+		clock:      clock,
+		entries:    make(map[string]*list.Element),
+		recency:    list.New(),
+	}
+}
+
+// record counts a read of object ID.
+func (as *accessStats) record(id string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	now := as.clock.Now()
+	if elem, ok := as.entries[id]; ok {
+		stat := elem.Value.(*AccessStat)
+		stat.Reads++
+		stat.LastAccess = now
+		as.recency.MoveToFront(elem)
+		return
+	}
+
+	for as.recency.Len() >= as.maxEntries && as.recency.Len() > 0 {
+		oldest := as.recency.Back()
+		delete(as.entries, oldest.Value.(*AccessStat).ID)
+		as.recency.Remove(oldest)
+	}
+	as.entries[id] = as.recency.PushFront(&AccessStat{ID: id, Reads: 1, LastAccess: now})
+}
+
+// get returns read statistics of object ID, if it's tracked.
+func (as *accessStats) get(id string) (AccessStat, bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	elem, ok := as.entries[id]
+	if !ok {
+		return AccessStat{}, false
+	}
+	return *elem.Value.(*AccessStat), true
+}
+
+// top returns up to n most read objects, the most read first.
+func (as *accessStats) top(n int) []AccessStat {
+	as.mu.Lock()
+	stats := make([]AccessStat, 0, len(as.entries))
+	for _, elem := range as.entries {
+		stats = append(stats, *elem.Value.(*AccessStat))
+	}
+	as.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Reads != stats[j].Reads {
+			return stats[i].Reads > stats[j].Reads
+		}
+		return stats[i].ID < stats[j].ID
+	})
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// trackAccess records successful object reads. Reads are recorded once the response is written,
+// so tracking doesn't delay it.
+func trackAccess(stats *accessStats) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			if err == nil && c.Response().Status == http.StatusOK {
+				stats.record(c.Param("id"))
+			}
+			return err
+		}
+	}
+}
+
+// registerAccessRoutes registers endpoints exposing object read statistics.
+func registerAccessRoutes(e *echo.Echo, stats *accessStats, objectMiddlewares []echo.MiddlewareFunc) {
+	e.GET("/admin/access", func(c echo.Context) error { return getTopAccessed(stats, c) })
+	e.GET("/admin/access/*", func(c echo.Context) error { return getObjectAccess(stats, c) }, objectMiddlewares...)
+}
+
+// getTopAccessed returns the most read objects, their number set by the top query param.
+func getTopAccessed(stats *accessStats, c echo.Context) error {
+	top := DefaultAccessStatsTop
+	if param := c.QueryParam("top"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n <= 0 {
+			return c.JSON(http.StatusBadRequest, Response{Message: fmt.Sprintf("Invalid top: %s", param)})
+		}
+		top = n
+	}
+	return c.JSON(http.StatusOK, AccessStatsResponse{Objects: stats.top(top)})
+}
+
+// getObjectAccess returns read statistics of an object.
+func getObjectAccess(stats *accessStats, c echo.Context) error {
+	objectID := c.Param("id")
+
+	stat, ok := stats.get(objectID)
+	if !ok {
+		return c.JSON(http.StatusNotFound, Response{Message: fmt.Sprintf("No reads recorded for object: %s", objectID)})
+	}
+	return c.JSON(http.StatusOK, stat)
+}
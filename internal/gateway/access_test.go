@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessStats(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := storage.ClockFunc(func() time.Time { return now })
+	stats := newAccessStats(2, clock)
+
+	stats.record("a")
+	now = now.Add(time.Second)
+	stats.record("b")
+	stats.record("a")
+
+	stat, ok := stats.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, AccessStat{ID: "a", Reads: 2, LastAccess: now}, stat)
+	assert.Equal(t, []AccessStat{
+		{ID: "a", Reads: 2, LastAccess: now},
+		{ID: "b", Reads: 1, LastAccess: now},
+	}, stats.top(10))
+	assert.Len(t, stats.top(1), 1)
+
+	// when full, the least recently read object is forgotten
+	stats.record("c")
+	_, ok = stats.get("b")
+	assert.False(t, ok)
+	_, ok = stats.get("a")
+	assert.True(t, ok)
+}
+
+func TestAccessStatsEndpoints(t *testing.T) {
+	ms := &MockStorage{objects: map[string]*storage.Object{
+		"hot":  {ID: "hot", Content: []byte("hot content")},
+		"cold": {ID: "cold", Content: []byte("cold content")},
+	}}
+	e := NewServer(ms, &Config{AccessStatsMaxKeys: 10})
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader("")))
+		return rec
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, request(http.MethodGet, "/object/hot").Code)
+	}
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/object/cold").Code)
+	// only successful reads are counted
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/object/missing").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodHead, "/object/cold").Code)
+
+	rec := request(http.MethodGet, "/admin/access?top=1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var top AccessStatsResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &top))
+	if assert.Len(t, top.Objects, 1) {
+		assert.Equal(t, "hot", top.Objects[0].ID)
+		assert.Equal(t, int64(3), top.Objects[0].Reads)
+	}
+
+	rec = request(http.MethodGet, "/admin/access/cold")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var stat AccessStat
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stat))
+	assert.Equal(t, int64(1), stat.Reads)
+
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/admin/access/missing").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/admin/access?top=0").Code)
+}
+
+func TestAccessStatsEndpoints_Disabled(t *testing.T) {
+	e := NewServer(&MockStorage{objects: map[string]*storage.Object{}}, &Config{})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/access", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
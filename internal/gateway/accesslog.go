@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/logging"
+	"github.com/labstack/echo/v4"
+)
+
+// accessLogEntry is a JSON access log line.
+type accessLogEntry struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	ObjectID  string    `json:"object_id,omitempty"`
+	Status    int       `json:"status"`
+	BytesIn   int64     `json:"bytes_in"`
+	BytesOut  int64     `json:"bytes_out"`
+	// Node is the primary node of the object, if the storage resolves object placement.
+	Node      string  `json:"node,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// bodyCounter counts bytes read from the request body, which may be streamed without known length.
+type bodyCounter struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *bodyCounter) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// jsonAccessLog writes an access log line per request to out, as a JSON object. Lines are written whole,
+// so concurrent requests don't interleave them. Object requests are logged with the object node if pl
+// isn't nil.
+func jsonAccessLog(out io.Writer, pl placementLocator) echo.MiddlewareFunc {
+	var mu sync.Mutex
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+			body := &bodyCounter{ReadCloser: req.Body}
+			req.Body = body
+
+			err := next(c)
+			if err != nil {
+				// error is rendered now, so its status and size are logged
+				c.Error(err)
+			}
+
+			entry := accessLogEntry{
+				Time:      start,
+				RequestID: logging.RequestID(req.Context()),
+				Method:    req.Method,
+				Path:      req.URL.Path,
+				ObjectID:  c.Param("id"),
+				Status:    c.Response().Status,
+				BytesIn:   body.n,
+				BytesOut:  c.Response().Size,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if entry.ObjectID != "" && pl != nil {
+				if placements, locateErr := pl.Locate(entry.ObjectID); locateErr == nil && len(placements) > 0 {
+					entry.Node = placements[0].Node.String()
+				}
+			}
+			line, marshalErr := json.Marshal(entry)
+			if marshalErr == nil {
+				mu.Lock()
+				_, _ = out.Write(append(line, '\n'))
+				mu.Unlock()
+			}
+			return err
+		}
+	}
+}
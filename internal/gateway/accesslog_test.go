@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+	s := &locatorStorage{
+		MockStorage: MockStorage{objects: map[string]*storage.Object{}},
+		placements:  []storage.Placement{{Node: storage.Node{ID: "node1", Name: "1"}, Primary: true, Available: true}},
+	}
+	e := NewServer(s, &Config{JSONAccessLog: true, AccessLogOutput: &logs})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/object/validID", strings.NewReader("test content")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/object/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// one JSON object per request
+	lines := strings.Split(strings.TrimSuffix(logs.String(), "\n"), "\n")
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+	var put, get map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &put))
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &get))
+
+	assert.Equal(t, http.MethodPut, put["method"])
+	assert.Equal(t, "/object/validID", put["path"])
+	assert.Equal(t, "validID", put["object_id"])
+	assert.Equal(t, float64(http.StatusOK), put["status"])
+	assert.Equal(t, float64(len("test content")), put["bytes_in"])
+	assert.Equal(t, float64(rec.Body.Len()), get["bytes_out"])
+	assert.Equal(t, "node1#1", put["node"])
+	assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), get["request_id"])
+	assert.Contains(t, put, "latency_ms")
+	assert.Contains(t, put, "time")
+
+	// failed requests are logged with the status of the rendered error
+	assert.Equal(t, "missing", get["object_id"])
+	assert.Equal(t, float64(http.StatusNotFound), get["status"])
+	assert.Equal(t, float64(0), get["bytes_in"])
+}
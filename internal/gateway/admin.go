@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// ringInspector is implemented by storages exposing details of their hash ring.
+type ringInspector interface {
+	RingFingerprint() string
+	RingMembers() []string
+}
+
+// placementLocator is implemented by storages able to resolve object placement without contacting nodes.
+type placementLocator interface {
+	Locate(id string) ([]storage.Placement, error)
+}
+
+type NodePlacement struct {
+	Node      string `json:"node"`
+	Endpoint  string `json:"endpoint"`
+	Primary   bool   `json:"primary"`
+	Available bool   `json:"available"`
+}
+
+type LocateResponse struct {
+	ID    string          `json:"id"`
+	Nodes []NodePlacement `json:"nodes"`
+}
+
+type RingResponse struct {
+	Fingerprint string   `json:"fingerprint"`
+	Nodes       []string `json:"nodes"`
+}
+
+// registerAdminRoutes registers operator endpoints supported by the storage. Object middlewares resolve
+// object IDs of object specific endpoints the same way as object routes do.
+func registerAdminRoutes(e *echo.Echo, s storage.Storage, objectMiddlewares []echo.MiddlewareFunc) {
+	if ri, ok := s.(ringInspector); ok {
+		e.GET("/admin/ring", func(c echo.Context) error { return getRing(ri, c) })
+	}
+	if pl, ok := s.(placementLocator); ok {
+		e.GET("/admin/locate/*", func(c echo.Context) error { return locateObject(pl, c) }, objectMiddlewares...)
+	}
+}
+
+// getRing returns ring fingerprint, so operators can confirm all gateway instances agree on placement.
+func getRing(ri ringInspector, c echo.Context) error {
+	return c.JSON(http.StatusOK, RingResponse{
+		Fingerprint: ri.RingFingerprint(),
+		Nodes:       ri.RingMembers(),
+	})
+}
+
+// locateObject returns nodes object would be placed on, without touching the nodes.
+func locateObject(pl placementLocator, c echo.Context) error {
+	objectID := c.Param("id")
+
+	placements, err := pl.Locate(objectID)
+	if err != nil {
+		requestLogger(c).ErrorContext(c.Request().Context(), "cannot locate object", "operation", "locate", "object_id", objectID, "error", err)
+		return c.JSON(http.StatusInternalServerError, Response{Message: fmt.Sprintf("Cannot locate object: %s", objectID)})
+	}
+
+	resp := LocateResponse{ID: objectID, Nodes: make([]NodePlacement, 0, len(placements))}
+	for _, p := range placements {
+		resp.Nodes = append(resp.Nodes, NodePlacement{
+			Node:      p.Node.String(),
+			Endpoint:  p.Node.Endpoint,
+			Primary:   p.Primary,
+			Available: p.Available,
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
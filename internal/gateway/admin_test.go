@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// ringStorage is MockStorage exposing hash ring details
+type ringStorage struct {
+	MockStorage
+	fingerprint string
+	members     []string
+}
+
+func (rs *ringStorage) RingFingerprint() string {
+	return rs.fingerprint
+}
+
+func (rs *ringStorage) RingMembers() []string {
+	return rs.members
+}
+
+func TestGetRing(t *testing.T) {
+	rs := &ringStorage{fingerprint: "abc123", members: []string{"node1#1", "node2#2"}}
+	e := NewServer(rs, &Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/ring", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp RingResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, RingResponse{Fingerprint: "abc123", Nodes: []string{"node1#1", "node2#2"}}, resp)
+}
+
+func TestGetRing_NotSupported(t *testing.T) {
+	e := NewServer(&MockStorage{}, &Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/ring", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// locatorStorage is MockStorage resolving object placement
+type locatorStorage struct {
+	MockStorage
+	placements []storage.Placement
+	err        error
+}
+
+func (ls *locatorStorage) Locate(id string) ([]storage.Placement, error) {
+	return ls.placements, ls.err
+}
+
+func TestLocateObject(t *testing.T) {
+	placements := []storage.Placement{
+		{Node: storage.Node{ID: "node1", Name: "1", Endpoint: "1.1.1.1:9000", SecretKey: "secret"}, Primary: true, Available: true},
+		{Node: storage.Node{ID: "node2", Name: "2", Endpoint: "2.2.2.2:9000", SecretKey: "secret"}, Available: false},
+	}
+
+	tests := []struct {
+		name           string
+		objectID       string
+		storage        *locatorStorage
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "invalid object ID",
+			objectID:       "invalid@ID",
+			storage:        &locatorStorage{},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Invalid objectID: must match ^[a-zA-Z0-9._/-]+$"}`,
+		},
+		{
+			name:           "locate error",
+			objectID:       "validID",
+			storage:        &locatorStorage{err: errors.New("test error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"message":"Cannot locate object: validID"}`,
+		},
+		{
+			name:           "success",
+			objectID:       "validID",
+			storage:        &locatorStorage{placements: placements},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"validID","nodes":[` +
+				`{"node":"node1#1","endpoint":"1.1.1.1:9000","primary":true,"available":true},` +
+				`{"node":"node2#2","endpoint":"2.2.2.2:9000","primary":false,"available":false}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewServer(tt.storage, &Config{})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/locate/"+tt.objectID, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package gateway
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sync"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultBatchParallelism is the default number of objects of a batch stored at once.
+const DefaultBatchParallelism = 8
+
+// BatchResult is the outcome of storing a single object of a batch.
+type BatchResult struct {
+	ID      string `json:"id"`
+	Status  int    `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// BatchResponse lists outcomes of storing objects of a batch, in the order they were sent.
+type BatchResponse struct {
+	Stored  int           `json:"stored"`
+	Failed  int           `json:"failed"`
+	Results []BatchResult `json:"results"`
+}
+
+// batchEntry is an object read from a batch archive, along with the ID it was sent under.
+type batchEntry struct {
+	name   string
+	object *storage.Object
+	err    error
+}
+
+// batchReader returns the next object of a batch, or io.EOF once there are none.
+type batchReader func() (*batchEntry, error)
+
+// newBatchReader reads objects of a multipart form, each part named by object ID, or of a tar stream,
+// each regular file named by object ID.
+func newBatchReader(req *http.Request, maxSize int64, metadataPrefix string) (batchReader, error) {
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	if err != nil {
+		return nil, err
+	}
+	switch mediaType {
+	case echo.MIMEMultipartForm:
+		mr := multipart.NewReader(req.Body, params["boundary"])
+		return func() (*batchEntry, error) {
+			part, err := mr.NextPart()
+			if err != nil {
+				return nil, err
+			}
+			defer part.Close()
+			entry := &batchEntry{name: part.FormName(), object: &storage.Object{
+				ContentType: part.Header.Get(echo.HeaderContentType),
+				Metadata:    requestMetadata(http.Header(part.Header), metadataPrefix),
+			}}
+			entry.object.Content, entry.err = readBatchContent(part, maxSize)
+			return entry, nil
+		}, nil
+	case "application/x-tar", "application/tar":
+		tr := tar.NewReader(req.Body)
+		return func() (*batchEntry, error) {
+			for {
+				header, err := tr.Next()
+				if err != nil {
+					return nil, err
+				}
+				// directories and links hold no object content
+				if header.Typeflag != tar.TypeReg {
+					continue
+				}
+				entry := &batchEntry{name: header.Name, object: &storage.Object{}}
+				entry.object.Content, entry.err = readBatchContent(tr, maxSize)
+				return entry, nil
+			}
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported content type %s", mediaType)
+	}
+}
+
+// readBatchContent reads content of a batch entry. Content exceeding the maximum object size, if set,
+// fails with errObjectTooLarge, after being skipped.
+func readBatchContent(r io.Reader, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		return io.ReadAll(r)
+	}
+	content, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > maxSize {
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return nil, err
+		}
+		return nil, errObjectTooLarge
+	}
+	return content, nil
+}
+
+// putBatch stores objects of a batch concurrently, at most parallelism of them at once. Each object is reported
+// on its own, so failures of some don't abort the rest; the response is 207 Multi-Status unless all were stored.
+func putBatch(s storage.Storage, c echo.Context, cfg *Config, policy ObjectIDPolicy, parallelism int) error {
+	ctx := c.Request().Context()
+	next, err := newBatchReader(c.Request(), cfg.MaxObjectSize, metadataHeaderPrefix(cfg))
+	if err != nil {
+		return c.JSON(http.StatusUnsupportedMediaType, Response{Message: fmt.Sprintf("Invalid batch: %v, expected multipart/form-data or application/x-tar", err)})
+	}
+
+	var (
+		mu      sync.Mutex
+		results []BatchResult
+		g       errgroup.Group
+	)
+	g.SetLimit(parallelism)
+	report := func(i int, status int, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[i].Status, results[i].Message = status, message
+	}
+	for {
+		entry, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// objects read so far are still stored, as they would be by separate requests
+			_ = g.Wait()
+			requestLogger(c).WarnContext(ctx, "cannot read batch", "operation", "batch", "error", err)
+			return c.JSON(http.StatusBadRequest, Response{Message: "Cannot read batch body"})
+		}
+
+		id := cfg.RewriteRules.Apply(entry.name)
+		mu.Lock()
+		i := len(results)
+		results = append(results, BatchResult{ID: id})
+		mu.Unlock()
+		if err := policy.Validate(id); err != nil {
+			report(i, http.StatusBadRequest, fmt.Sprintf("Invalid objectID: %v", err))
+			continue
+		}
+		if errors.Is(entry.err, errObjectTooLarge) {
+			report(i, http.StatusRequestEntityTooLarge, fmt.Sprintf("Object exceeds maximum size of %d bytes", cfg.MaxObjectSize))
+			continue
+		}
+		if entry.err != nil {
+			report(i, http.StatusBadRequest, "Cannot read object content")
+			continue
+		}
+
+		entry.object.ID = id
+		if sniffable(entry.object.ContentType) && len(entry.object.Content) > 0 {
+			entry.object.ContentType = http.DetectContentType(entry.object.Content)
+		}
+		if !cfg.AllowedContentTypes.Allows(entry.object.ContentType) {
+			report(i, http.StatusUnsupportedMediaType, contentTypeNotAllowed(entry.object.ContentType))
+			continue
+		}
+		g.Go(func() error {
+			putCtx, buffered := storage.TrackBufferedWrites(ctx)
+			if err := s.Put(putCtx, entry.object); err != nil {
+				requestLogger(c).ErrorContext(ctx, "cannot store object", "operation", "batch", "object_id", id, "error", err)
+				report(i, storageErrorStatus(ctx, err), fmt.Sprintf("Cannot store object: %s", id))
+				return nil
+			}
+			if buffered.Load() {
+				report(i, http.StatusAccepted, "")
+				return nil
+			}
+			report(i, http.StatusOK, "")
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	response := BatchResponse{Results: results}
+	if response.Results == nil {
+		response.Results = []BatchResult{}
+	}
+	for _, result := range results {
+		if result.Status == http.StatusOK || result.Status == http.StatusAccepted {
+			response.Stored++
+		} else {
+			response.Failed++
+		}
+	}
+	status := http.StatusOK
+	if response.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	return c.JSON(status, response)
+}
@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"sync"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// batchStorage stores objects concurrently, failing to store objects with the failing ID
+type batchStorage struct {
+	MockStorage
+	mu      sync.Mutex
+	failing string
+}
+
+func (bs *batchStorage) Put(ctx context.Context, object *storage.Object) error {
+	if object.ID == bs.failing {
+		return errors.New("node down")
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return bs.MockStorage.Put(ctx, object)
+}
+
+func TestPutBatch_Multipart(t *testing.T) {
+	bs := &batchStorage{MockStorage: MockStorage{objects: map[string]*storage.Object{}}, failing: "failing"}
+	e := NewServer(bs, &Config{MaxObjectSize: 10, BatchParallelism: 2})
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct{ id, content string }{
+		{"photos/1", "photo"},
+		{"../escape", "invalid"},
+		{"notes", "text"},
+		{"", "unnamed"},
+		{"large", "content larger than allowed"},
+		{"failing", "lost"},
+	}
+	for _, part := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="`+part.id+`"`)
+		header.Set("Content-Type", "text/plain")
+		header.Set(DefaultMetadataHeaderPrefix+"Author", "jane")
+		w, err := mw.CreatePart(header)
+		assert.NoError(t, err)
+		_, _ = w.Write([]byte(part.content))
+	}
+	assert.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/objects/batch", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+
+	var response BatchResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Stored)
+	assert.Equal(t, 4, response.Failed)
+	statuses := map[string]int{}
+	for _, result := range response.Results {
+		statuses[result.ID] = result.Status
+	}
+	assert.Equal(t, map[string]int{
+		"photos/1":  http.StatusOK,
+		"../escape": http.StatusBadRequest,
+		"notes":     http.StatusOK,
+		"":          http.StatusBadRequest,
+		"large":     http.StatusRequestEntityTooLarge,
+		"failing":   http.StatusInternalServerError,
+	}, statuses)
+
+	// only valid objects are stored, with content type and metadata of their part
+	assert.Len(t, bs.objects, 2)
+	if photo := bs.objects["photos/1"]; assert.NotNil(t, photo) {
+		assert.Equal(t, "photo", string(photo.Content))
+		assert.Equal(t, "text/plain", photo.ContentType)
+		assert.Equal(t, map[string]string{"Author": "jane"}, photo.Metadata)
+	}
+}
+
+func TestPutBatch_Tar(t *testing.T) {
+	bs := &batchStorage{MockStorage: MockStorage{objects: map[string]*storage.Object{}}}
+	e := NewServer(bs, &Config{})
+
+	var body bytes.Buffer
+	tw := tar.NewWriter(&body)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "docs/", Typeflag: tar.TypeDir, Mode: 0o755}))
+	for _, file := range []struct{ id, content string }{{"docs/a", "first"}, {"docs/b", "second"}} {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: file.id, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(file.content))}))
+		_, _ = tw.Write([]byte(file.content))
+	}
+	assert.NoError(t, tw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/bucket/archive/objects/batch", &body)
+	req.Header.Set("Content-Type", "application/x-tar")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"stored": 2, "failed": 0, "results": [{"id": "docs/a", "status": 200}, {"id": "docs/b", "status": 200}]}`, rec.Body.String())
+	assert.Equal(t, "second", string(bs.objects["docs/b"].Content))
+	// tar entries carry no content type, so it's detected
+	assert.Equal(t, "text/plain; charset=utf-8", bs.objects["docs/b"].ContentType)
+}
+
+func TestPutBatch_UnsupportedContentType(t *testing.T) {
+	e := NewServer(&MockStorage{objects: map[string]*storage.Object{}}, &Config{})
+
+	req := httptest.NewRequest(http.MethodPost, "/objects/batch", bytes.NewBufferString("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
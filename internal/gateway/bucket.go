@@ -0,0 +1,32 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// objectRouter registers object routes, either at the root or within a group.
+type objectRouter interface {
+	GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	HEAD(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	PUT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+}
+
+// bucketParam makes storage operations of bucket routes use the bucket named by the bucket param,
+// rejecting names not following S3 bucket naming rules.
+func bucketParam(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		bucket := c.Param("bucket")
+		if err := storage.ValidateBucketName(bucket); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid bucket: %v", err))
+		}
+		req := c.Request()
+		c.SetRequest(req.WithContext(storage.WithBucket(req.Context(), bucket)))
+		return next(c)
+	}
+}
@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// bucketsStorage keeps objects of each bucket in its own MockStorage, created by the first write into the bucket
+type bucketsStorage struct {
+	MockStorage
+	buckets map[string]*MockStorage
+}
+
+func (bs *bucketsStorage) bucket(ctx context.Context, create bool) *MockStorage {
+	name := storage.BucketFromContext(ctx)
+	if bs.buckets[name] == nil && create {
+		bs.buckets[name] = &MockStorage{objects: map[string]*storage.Object{}}
+	}
+	if bs.buckets[name] == nil {
+		return &MockStorage{}
+	}
+	return bs.buckets[name]
+}
+
+func (bs *bucketsStorage) PutStream(ctx context.Context, object *storage.ObjectStream) error {
+	return bs.bucket(ctx, true).PutStream(ctx, object)
+}
+
+func (bs *bucketsStorage) GetStream(ctx context.Context, id string) (*storage.ObjectStream, error) {
+	return bs.bucket(ctx, false).GetStream(ctx, id)
+}
+
+func (bs *bucketsStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	return bs.bucket(ctx, false).List(ctx, prefix)
+}
+
+func TestBucketRoutes(t *testing.T) {
+	bs := &bucketsStorage{buckets: map[string]*MockStorage{}}
+	e := NewServer(bs, &Config{})
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	// same object ID in the configured bucket and a bucket named in the path
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/object/1", "default content").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/bucket/photos/object/1", "photo").Code)
+	assert.Contains(t, bs.buckets, "")
+	assert.Contains(t, bs.buckets, "photos")
+
+	rec := serve(http.MethodGet, "/bucket/photos/object/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "photo", rec.Body.String())
+	rec = serve(http.MethodGet, "/object/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "default content", rec.Body.String())
+	rec = serve(http.MethodGet, "/bucket/photos/objects", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `["1"]`, rec.Body.String())
+
+	// unknown bucket holds no objects
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/bucket/videos/object/1", "").Code)
+
+	// invalid bucket names are rejected before reaching storage
+	for _, bucket := range []string{"Photos", "ph", "photos..2024", "photos_2024"} {
+		rec := serve(http.MethodPut, "/bucket/"+bucket+"/object/1", "photo")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, bucket)
+		assert.Contains(t, rec.Body.String(), "Invalid bucket", bucket)
+	}
+	assert.Len(t, bs.buckets, 2)
+}
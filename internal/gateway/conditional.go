@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// HeaderIfNoneMatch lists ETags of object content the client already has. On upload, "*" stores the object
+	// only if it doesn't exist yet.
+	HeaderIfNoneMatch = "If-None-Match"
+	// HeaderIfMatch lists ETags of the object the upload may replace, or "*" for any existing object.
+	HeaderIfMatch = "If-Match"
+	// HeaderIfModifiedSince is the Last-Modified of object content the client already has.
+	HeaderIfModifiedSince = "If-Modified-Since"
+	// HeaderIfUnmodifiedSince is the date the object the upload may replace must not be modified after.
+	HeaderIfUnmodifiedSince = "If-Unmodified-Since"
+)
+
+// etagListed reports whether ETag header value (comma separated quoted ETags or "*") lists the object ETag.
+// Weak ETags are compared by value, as both weak and strong ETags identify content for If-None-Match.
+func etagListed(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		candidate = strings.TrimPrefix(candidate, "W/")
+		if etag != "" && candidate == `"`+etag+`"` {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionalRead reports whether the request has If-None-Match or If-Modified-Since header.
+func conditionalRead(header http.Header) bool {
+	return header.Get(HeaderIfNoneMatch) != "" || header.Get(HeaderIfModifiedSince) != ""
+}
+
+// notModified reports whether the client already has the object content: If-None-Match lists its ETag or,
+// without If-None-Match, the object wasn't modified since If-Modified-Since.
+func notModified(header http.Header, info *storage.ObjectInfo, clockSkew time.Duration) bool {
+	if ifNoneMatch := header.Get(HeaderIfNoneMatch); ifNoneMatch != "" {
+		return etagListed(ifNoneMatch, info.ETag)
+	}
+	since, err := http.ParseTime(header.Get(HeaderIfModifiedSince))
+	return err == nil && !info.LastModified.IsZero() && !modifiedSince(info.LastModified, since, clockSkew)
+}
+
+// modifiedSince reports whether object modified at lastModified may have been modified after the HTTP date,
+// compared at its second precision. Dates within the tolerated clock skew, e.g. taken from the client's clock,
+// aren't ordered against the modification, so the object is considered modified unless the date is its
+// Last-Modified.
+func modifiedSince(lastModified, date time.Time, clockSkew time.Duration) bool {
+	lastModified = lastModified.Truncate(time.Second)
+	return !lastModified.Equal(date) && !storage.BeforeWithSkew(lastModified, date, clockSkew)
+}
+
+// getObjectIfNotModified responds 304 Not Modified when the client already has the object content. Only object
+// metadata is retrieved, so content the client already has isn't downloaded from the node. It reports whether
+// the response was sent; otherwise the object should be sent as usual.
+func getObjectIfNotModified(s storage.Storage, c echo.Context, clockSkew time.Duration) (bool, error) {
+	ctx := c.Request().Context()
+	objectID := c.Param("id")
+
+	info, err := s.Stat(ctx, objectID)
+	if err != nil {
+		requestLogger(c).ErrorContext(ctx, "cannot retrieve object metadata", "operation", "stat", "object_id", objectID, "error", err)
+		return true, c.JSON(storageErrorStatus(ctx, err), Response{Message: fmt.Sprintf("Error retrieving object: %s", objectID)})
+	}
+	if info == nil {
+		return true, c.JSON(http.StatusNotFound, Response{Message: fmt.Sprintf("Object doesn't exist: %s", objectID)})
+	}
+	if !notModified(c.Request().Header, info, clockSkew) {
+		return false, nil
+	}
+
+	// validators are sent, so the client can refresh its cached copy
+	setObjectHeaders(c, -1, info.LastModified, info.ETag)
+	return true, c.NoContent(http.StatusNotModified)
+}
+
+// writePrecondition returns precondition of the upload given by If-Match, If-None-Match and If-Unmodified-Since
+// headers, or nil if it's unconditional. ETags are compared as for If-None-Match of reads. If-Unmodified-Since
+// is ignored along with If-Match, and fails the upload only if the object was modified after it by more than
+// the tolerated clock skew, so skewed clocks don't cause spurious conflicts.
+func writePrecondition(header http.Header, clockSkew time.Duration) storage.Precondition {
+	ifMatch, ifNoneMatch := header.Get(HeaderIfMatch), header.Get(HeaderIfNoneMatch)
+	unmodifiedSince, err := http.ParseTime(header.Get(HeaderIfUnmodifiedSince))
+	checkModified := ifMatch == "" && err == nil
+	if ifMatch == "" && ifNoneMatch == "" && !checkModified {
+		return nil
+	}
+	return func(current *storage.ObjectInfo) bool {
+		if ifMatch != "" && (current == nil || !etagListed(ifMatch, current.ETag)) {
+			return false
+		}
+		if checkModified && (current == nil || storage.AfterWithSkew(current.LastModified.Truncate(time.Second), unmodifiedSince, clockSkew)) {
+			return false
+		}
+		return ifNoneMatch == "" || current == nil || !etagListed(ifNoneMatch, current.ETag)
+	}
+}
@@ -0,0 +1,302 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEtagListed(t *testing.T) {
+	tests := []struct {
+		header   string
+		etag     string
+		expected bool
+	}{
+		{header: `"abc"`, etag: "abc", expected: true},
+		{header: `W/"abc"`, etag: "abc", expected: true},
+		{header: `"xyz", "abc"`, etag: "abc", expected: true},
+		{header: `*`, etag: "abc", expected: true},
+		{header: `*`, etag: "", expected: true},
+		{header: `"xyz"`, etag: "abc", expected: false},
+		{header: `abc`, etag: "abc", expected: false},
+		{header: `""`, etag: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.expected, etagListed(tt.header, tt.etag))
+		})
+	}
+}
+
+func TestGetObject_IfNoneMatch(t *testing.T) {
+	ms := &MockStorage{
+		objects: map[string]*storage.Object{
+			"validID": {ID: "validID", ContentType: "text/plain", Content: []byte("test content"), ETag: "abc"},
+		},
+		lastModified: time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC),
+	}
+	e := NewServer(ms, &Config{})
+
+	tests := []struct {
+		name           string
+		objectID       string
+		ifNoneMatch    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "matching ETag",
+			objectID:       "validID",
+			ifNoneMatch:    `"abc"`,
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:           "non-matching ETag",
+			objectID:       "validID",
+			ifNoneMatch:    `"xyz"`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "test content",
+		},
+		{
+			name:           "no header",
+			objectID:       "validID",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "test content",
+		},
+		{
+			name:           "missing object",
+			objectID:       "missingID",
+			ifNoneMatch:    `"abc"`,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/object/"+tt.objectID, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set(HeaderIfNoneMatch, tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			switch tt.expectedStatus {
+			case http.StatusNotModified:
+				assert.Empty(t, rec.Body.String())
+				assert.Equal(t, `"abc"`, rec.Header().Get(HeaderETag))
+				assert.Equal(t, "Sun, 01 Oct 2023 12:00:00 GMT", rec.Header().Get(echo.HeaderLastModified))
+			case http.StatusOK:
+				assert.Equal(t, tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetObject_IfNoneMatchStorageError(t *testing.T) {
+	e := NewServer(&MockStorage{err: errors.New("test error")}, &Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/object/validID", nil)
+	req.Header.Set(HeaderIfNoneMatch, `"abc"`)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestPutObject_Preconditions(t *testing.T) {
+	tests := []struct {
+		name           string
+		objectID       string
+		ifMatch        string
+		ifNoneMatch    string
+		expectedStatus int
+	}{
+		{
+			name:           "matching If-Match",
+			objectID:       "validID",
+			ifMatch:        `"abc"`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "one of If-Match ETags matching",
+			objectID:       "validID",
+			ifMatch:        `"xyz", "abc"`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "stale If-Match",
+			objectID:       "validID",
+			ifMatch:        `"xyz"`,
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:           "If-Match of missing object",
+			objectID:       "missingID",
+			ifMatch:        `*`,
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:           "If-Match any existing object",
+			objectID:       "validID",
+			ifMatch:        `*`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "If-None-Match of existing object",
+			objectID:       "validID",
+			ifNoneMatch:    `*`,
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:           "If-None-Match of missing object",
+			objectID:       "missingID",
+			ifNoneMatch:    `*`,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &MockStorage{objects: map[string]*storage.Object{
+				"validID": {ID: "validID", ContentType: "text/plain", Content: []byte("old content"), ETag: "abc"},
+			}}
+			e := NewServer(ms, &Config{})
+
+			req := httptest.NewRequest(http.MethodPut, "/object/"+tt.objectID, strings.NewReader("new content"))
+			req.Header.Set(echo.HeaderContentType, "text/plain")
+			if tt.ifMatch != "" {
+				req.Header.Set(HeaderIfMatch, tt.ifMatch)
+			}
+			if tt.ifNoneMatch != "" {
+				req.Header.Set(HeaderIfNoneMatch, tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			object := ms.objects[tt.objectID]
+			if tt.expectedStatus == http.StatusOK {
+				if assert.NotNil(t, object) {
+					assert.Equal(t, "new content", string(object.Content))
+				}
+				return
+			}
+			assert.Contains(t, rec.Body.String(), "Object doesn't match the precondition: "+tt.objectID)
+			if object != nil {
+				assert.Equal(t, "old content", string(object.Content))
+			}
+		})
+	}
+}
+
+func TestModifiedSince(t *testing.T) {
+	lastModified := time.Date(2023, 10, 1, 12, 0, 0, 500, time.UTC)
+	tests := []struct {
+		name     string
+		date     time.Time
+		expected bool
+	}{
+		{name: "Last-Modified", date: lastModified.Truncate(time.Second), expected: false},
+		{name: "before skew", date: lastModified.Add(2 * time.Second), expected: false},
+		{name: "within skew", date: lastModified.Truncate(time.Second).Add(time.Second), expected: true},
+		{name: "earlier", date: lastModified.Add(-time.Hour), expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, modifiedSince(lastModified, tt.date, time.Second))
+		})
+	}
+}
+
+func TestGetObject_IfModifiedSince(t *testing.T) {
+	ms := &MockStorage{
+		objects: map[string]*storage.Object{
+			"validID": {ID: "validID", ContentType: "text/plain", Content: []byte("test content"), ETag: "abc"},
+		},
+		lastModified: time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC),
+	}
+	e := NewServer(ms, &Config{ClockSkew: time.Minute})
+
+	tests := []struct {
+		name            string
+		method          string
+		ifModifiedSince string
+		ifNoneMatch     string
+		expectedStatus  int
+	}{
+		{name: "Last-Modified", method: http.MethodGet, ifModifiedSince: "Sun, 01 Oct 2023 12:00:00 GMT", expectedStatus: http.StatusNotModified},
+		{name: "later than skew", method: http.MethodGet, ifModifiedSince: "Sun, 01 Oct 2023 12:05:00 GMT", expectedStatus: http.StatusNotModified},
+		{name: "within skew", method: http.MethodGet, ifModifiedSince: "Sun, 01 Oct 2023 12:00:30 GMT", expectedStatus: http.StatusOK},
+		{name: "modified", method: http.MethodGet, ifModifiedSince: "Sat, 30 Sep 2023 12:00:00 GMT", expectedStatus: http.StatusOK},
+		{name: "invalid date", method: http.MethodGet, ifModifiedSince: "yesterday", expectedStatus: http.StatusOK},
+		{name: "If-None-Match takes precedence", method: http.MethodGet, ifModifiedSince: "Sun, 01 Oct 2023 12:00:00 GMT", ifNoneMatch: `"xyz"`, expectedStatus: http.StatusOK},
+		{name: "head", method: http.MethodHead, ifModifiedSince: "Sun, 01 Oct 2023 12:00:00 GMT", expectedStatus: http.StatusNotModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/object/validID", nil)
+			req.Header.Set(HeaderIfModifiedSince, tt.ifModifiedSince)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set(HeaderIfNoneMatch, tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusNotModified {
+				assert.Empty(t, rec.Body.String())
+				assert.Equal(t, "Sun, 01 Oct 2023 12:00:00 GMT", rec.Header().Get(echo.HeaderLastModified))
+			}
+		})
+	}
+}
+
+func TestPutObject_IfUnmodifiedSince(t *testing.T) {
+	tests := []struct {
+		name              string
+		objectID          string
+		ifMatch           string
+		ifUnmodifiedSince string
+		expectedStatus    int
+	}{
+		{name: "unmodified", objectID: "validID", ifUnmodifiedSince: "Sun, 01 Oct 2023 12:00:00 GMT", expectedStatus: http.StatusOK},
+		{name: "modified within skew", objectID: "validID", ifUnmodifiedSince: "Sun, 01 Oct 2023 11:59:30 GMT", expectedStatus: http.StatusOK},
+		{name: "modified", objectID: "validID", ifUnmodifiedSince: "Sun, 01 Oct 2023 11:00:00 GMT", expectedStatus: http.StatusPreconditionFailed},
+		{name: "missing object", objectID: "missingID", ifUnmodifiedSince: "Sun, 01 Oct 2023 12:00:00 GMT", expectedStatus: http.StatusPreconditionFailed},
+		{name: "ignored with If-Match", objectID: "validID", ifMatch: `"abc"`, ifUnmodifiedSince: "Sun, 01 Oct 2023 11:00:00 GMT", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &MockStorage{
+				objects: map[string]*storage.Object{
+					"validID": {ID: "validID", ContentType: "text/plain", Content: []byte("old content"), ETag: "abc"},
+				},
+				lastModified: time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC),
+			}
+			e := NewServer(ms, &Config{ClockSkew: time.Minute})
+
+			req := httptest.NewRequest(http.MethodPut, "/object/"+tt.objectID, strings.NewReader("new content"))
+			req.Header.Set(echo.HeaderContentType, "text/plain")
+			req.Header.Set(HeaderIfUnmodifiedSince, tt.ifUnmodifiedSince)
+			if tt.ifMatch != "" {
+				req.Header.Set(HeaderIfMatch, tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
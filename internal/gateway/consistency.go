@@ -0,0 +1,31 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderConsistencyLevel lets clients set the consistency level (one, quorum or all) of their request's
+// storage operations, instead of the configured read or write consistency.
+const HeaderConsistencyLevel = "X-Consistency-Level"
+
+// consistencyLevel makes storage operations use the consistency level set by the X-Consistency-Level header.
+func consistencyLevel(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Request().Header.Get(HeaderConsistencyLevel)
+		if header == "" {
+			return next(c)
+		}
+
+		level, err := storage.ParseConsistencyLevel(header)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Response{Message: fmt.Sprintf("Invalid %s header: %s", HeaderConsistencyLevel, header)})
+		}
+		req := c.Request()
+		c.SetRequest(req.WithContext(storage.WithConsistency(req.Context(), level)))
+		return next(c)
+	}
+}
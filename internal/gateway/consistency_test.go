@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// consistencyStorage records consistency level of the last storage operation, failing it if the level is unreachable
+type consistencyStorage struct {
+	MockStorage
+	level       storage.ConsistencyLevel
+	unreachable storage.ConsistencyLevel
+}
+
+func (cs *consistencyStorage) check(ctx context.Context) error {
+	cs.level = storage.ConsistencyFromContext(ctx)
+	if cs.level != "" && cs.level == cs.unreachable {
+		return fmt.Errorf("failed to put data: %w", storage.ErrConsistencyNotReached)
+	}
+	return nil
+}
+
+func (cs *consistencyStorage) PutStream(ctx context.Context, object *storage.ObjectStream) error {
+	if err := cs.check(ctx); err != nil {
+		return err
+	}
+	return cs.MockStorage.PutStream(ctx, object)
+}
+
+func (cs *consistencyStorage) GetStream(ctx context.Context, id string) (*storage.ObjectStream, error) {
+	if err := cs.check(ctx); err != nil {
+		return nil, err
+	}
+	return cs.MockStorage.GetStream(ctx, id)
+}
+
+func TestConsistencyLevel(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		header         string
+		expectedLevel  storage.ConsistencyLevel
+		expectedStatus int
+	}{
+		{name: "configured level", method: http.MethodPut, expectedStatus: http.StatusOK},
+		{name: "write level", method: http.MethodPut, header: "quorum", expectedLevel: storage.ConsistencyQuorum, expectedStatus: http.StatusOK},
+		{name: "read level", method: http.MethodGet, header: "ONE", expectedLevel: storage.ConsistencyOne, expectedStatus: http.StatusOK},
+		{name: "level not reached", method: http.MethodPut, header: "all", expectedLevel: storage.ConsistencyAll, expectedStatus: http.StatusServiceUnavailable},
+		{name: "invalid level", method: http.MethodPut, header: "most", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &consistencyStorage{
+				MockStorage: MockStorage{objects: map[string]*storage.Object{"object-1": {ID: "object-1", Content: []byte("data")}}},
+				unreachable: storage.ConsistencyAll,
+			}
+			e := NewServer(cs, &Config{})
+
+			req := httptest.NewRequest(tt.method, "/object/object-1", strings.NewReader("data"))
+			if tt.header != "" {
+				req.Header.Set(HeaderConsistencyLevel, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedLevel, cs.level)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ContentTypes is an allowlist of content types of uploaded objects, matched by media type, ignoring parameters
+// (e.g. "; charset=utf-8") and case. Entry with "*" subtype (e.g. "image/*") allows all subtypes of its type.
+// Empty allowlist allows all content types.
+type ContentTypes []string
+
+// Allows checks if the content type is allowed.
+func (ct ContentTypes) Allows(contentType string) bool {
+	if len(ct) == 0 {
+		return true
+	}
+	mediaType := baseMediaType(contentType)
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	return slices.Contains(ct, mediaType) || slices.Contains(ct, mainType+"/*")
+}
+
+// ParseContentTypes parses comma separated content types, e.g. "image/png,image/jpeg" or "image/*".
+// Empty spec results in allowlist allowing all content types.
+func ParseContentTypes(spec string) (ContentTypes, error) {
+	var types ContentTypes
+	for _, raw := range strings.Split(spec, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content type %q: %w", raw, err)
+		}
+		if len(params) > 0 || !strings.Contains(mediaType, "/") {
+			return nil, fmt.Errorf("invalid content type %q: expected type/subtype", raw)
+		}
+		types = append(types, mediaType)
+	}
+	return types, nil
+}
+
+// baseMediaType returns lowercase media type of the content type, without parameters.
+func baseMediaType(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+func unsupportedContentType(c echo.Context, contentType string) error {
+	return c.JSON(http.StatusUnsupportedMediaType, Response{Message: contentTypeNotAllowed(contentType)})
+}
+
+// contentTypeNotAllowed returns message rejecting upload of the content type.
+func contentTypeNotAllowed(contentType string) string {
+	if contentType == "" {
+		return "Content type is required"
+	}
+	return fmt.Sprintf("Content type %s is not allowed", baseMediaType(contentType))
+}
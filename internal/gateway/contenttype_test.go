@@ -0,0 +1,142 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseContentTypes(t *testing.T) {
+	types, err := ParseContentTypes(" image/PNG, image/*,,text/plain ")
+	assert.NoError(t, err)
+	assert.Equal(t, ContentTypes{"image/png", "image/*", "text/plain"}, types)
+
+	types, err = ParseContentTypes("")
+	assert.NoError(t, err)
+	assert.Empty(t, types)
+
+	for _, spec := range []string{"image", "text/plain; charset=utf-8", "image/png,/"} {
+		_, err := ParseContentTypes(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestContentTypes_Allows(t *testing.T) {
+	types := ContentTypes{"image/*", "text/plain"}
+	assert.True(t, types.Allows("image/png"))
+	assert.True(t, types.Allows("text/plain; charset=utf-8"))
+	assert.True(t, types.Allows("Text/Plain"))
+	assert.False(t, types.Allows("text/html; charset=utf-8"))
+	assert.False(t, types.Allows("application/octet-stream"))
+	assert.False(t, types.Allows(""))
+
+	// empty allowlist allows everything
+	assert.True(t, ContentTypes(nil).Allows("application/x-anything"))
+	assert.True(t, ContentTypes(nil).Allows(""))
+}
+
+func TestPutObject_AllowedContentTypes(t *testing.T) {
+	png := "\x89PNG\x0d\x0a\x1a\x0a" + strings.Repeat("\x00", 16)
+	tests := []struct {
+		name           string
+		allowed        ContentTypes
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "allowed type",
+			allowed:        ContentTypes{"image/png", "text/plain"},
+			contentType:    "text/plain; charset=utf-8",
+			body:           "hello",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "allowed detected type",
+			allowed:        ContentTypes{"image/*"},
+			body:           png,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "disallowed type",
+			allowed:        ContentTypes{"image/*"},
+			contentType:    "text/html; charset=utf-8",
+			body:           "<html></html>",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedBody:   "Content type text/html is not allowed",
+		},
+		{
+			name:           "disallowed detected type",
+			allowed:        ContentTypes{"image/*"},
+			body:           "plain text",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedBody:   "Content type text/plain is not allowed",
+		},
+		{
+			name:           "empty allowlist",
+			contentType:    "application/x-custom",
+			body:           "anything",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &MockStorage{objects: make(map[string]*storage.Object)}
+			e := NewServer(ms, &Config{AllowedContentTypes: tt.allowed})
+
+			req := httptest.NewRequest(http.MethodPut, "/object/validID", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, ms.objects, "validID")
+				return
+			}
+			var resp Response
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.expectedBody, resp.Message)
+			assert.NotContains(t, ms.objects, "validID")
+		})
+	}
+}
+
+func TestPutBatch_AllowedContentTypes(t *testing.T) {
+	bs := &batchStorage{MockStorage: MockStorage{objects: map[string]*storage.Object{}}}
+	e := NewServer(bs, &Config{AllowedContentTypes: ContentTypes{"text/plain"}, BatchParallelism: 1})
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ id, contentType string }{{"notes", "text/plain"}, {"page", "text/html"}} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="`+part.id+`"`)
+		header.Set("Content-Type", part.contentType)
+		w, err := mw.CreatePart(header)
+		assert.NoError(t, err)
+		_, _ = w.Write([]byte("content"))
+	}
+	assert.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/objects/batch", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+	assert.JSONEq(t, `{"stored": 1, "failed": 1, "results": [`+
+		`{"id": "notes", "status": 200}, `+
+		`{"id": "page", "status": 415, "message": "Content type text/html is not allowed"}]}`, rec.Body.String())
+	assert.NotContains(t, bs.objects, "page")
+}
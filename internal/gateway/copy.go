@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderCopySource makes object PUT copy the named object, of the same bucket, instead of storing the request body.
+const HeaderCopySource = "X-Copy-Source"
+
+// copySourceID returns ID of the object named by X-Copy-Source header, which may be URL encoded and start with "/".
+// It's rewritten and validated as object IDs of routes are.
+func copySourceID(header string, rules RewriteRules, policy ObjectIDPolicy) (string, error) {
+	id, err := url.PathUnescape(header)
+	if err != nil {
+		return "", err
+	}
+	id = rules.Apply(strings.TrimPrefix(id, "/"))
+	if err := policy.Validate(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func copyObject(s storage.Storage, c echo.Context, rules RewriteRules, policy ObjectIDPolicy) error {
+	ctx := c.Request().Context()
+	objectID := c.Param("id")
+
+	header := c.Request().Header.Get(HeaderCopySource)
+	sourceID, err := copySourceID(header, rules, policy)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Response{Message: fmt.Sprintf("Invalid %s header: %v", HeaderCopySource, err)})
+	}
+	if sourceID == objectID {
+		return c.JSON(http.StatusBadRequest, Response{Message: fmt.Sprintf("Object can't be copied to itself: %s", objectID)})
+	}
+
+	// copy object within storage
+	err = s.Copy(ctx, sourceID, objectID)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return c.JSON(http.StatusBadRequest, Response{Message: fmt.Sprintf("Copy source doesn't exist: %s", sourceID)})
+	}
+	if err != nil {
+		requestLogger(c).ErrorContext(ctx, "cannot copy object", "operation", "copy", "object_id", objectID, "source_id", sourceID, "error", err)
+		return c.JSON(storageErrorStatus(ctx, err), Response{Message: fmt.Sprintf("Cannot copy object: %s", sourceID)})
+	}
+
+	return c.JSON(http.StatusOK, Response{Message: fmt.Sprintf("Object %s was successfully copied to ID: %s", sourceID, objectID)})
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyObject(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		source         string
+		expectedStatus int
+		expectedID     string
+	}{
+		{name: "copy", target: "/object/copy", source: "source", expectedStatus: http.StatusOK, expectedID: "copy"},
+		{name: "URL encoded source with leading slash", target: "/object/copy", source: "/dir%2Fsource", expectedStatus: http.StatusOK, expectedID: "copy"},
+		{name: "rewritten source", target: "/object/copy", source: "legacy/source", expectedStatus: http.StatusOK, expectedID: "copy"},
+		{name: "copy within bucket", target: "/bucket/photos/object/copy", source: "source", expectedStatus: http.StatusOK, expectedID: "copy"},
+		{name: "missing source", target: "/object/copy", source: "missing", expectedStatus: http.StatusBadRequest},
+		{name: "invalid source", target: "/object/copy", source: "../source", expectedStatus: http.StatusBadRequest},
+		{name: "copy to itself", target: "/object/source", source: "source", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &storage.Object{ID: "source", ContentType: "text/plain", Content: []byte("data"), Metadata: map[string]string{"Author": "jane"}}
+			ms := &MockStorage{objects: map[string]*storage.Object{"source": source, "dir/source": source}}
+			e := NewServer(ms, &Config{RewriteRules: RewriteRules{{Pattern: regexp.MustCompile(`^legacy/`), Replacement: ""}}})
+
+			req := httptest.NewRequest(http.MethodPut, tt.target, nil)
+			req.Header.Set(HeaderCopySource, tt.source)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+			if tt.expectedID == "" {
+				assert.Len(t, ms.objects, 2)
+				return
+			}
+			if copied := ms.objects[tt.expectedID]; assert.NotNil(t, copied) {
+				assert.Equal(t, "data", string(copied.Content))
+				assert.Equal(t, "text/plain", copied.ContentType)
+				assert.Equal(t, map[string]string{"Author": "jane"}, copied.Metadata)
+			}
+		})
+	}
+}
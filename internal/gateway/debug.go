@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// ringDebugger is implemented by storages able to tell which nodes own keys of their hash ring.
+type ringDebugger interface {
+	LocateNode(id string) (storage.Node, error)
+	RingNodes() ([]storage.RingNode, error)
+}
+
+type RingNodeResponse struct {
+	Node       string `json:"node"`
+	Endpoint   string `json:"endpoint"`
+	Partitions int    `json:"partitions"`
+}
+
+type RingDebugResponse struct {
+	Key      string             `json:"key,omitempty"`
+	Node     string             `json:"node,omitempty"`
+	Endpoint string             `json:"endpoint,omitempty"`
+	Nodes    []RingNodeResponse `json:"nodes"`
+}
+
+// registerDebugRoutes registers endpoints exposing storage internals for debugging, supported by the storage.
+func registerDebugRoutes(e *echo.Echo, s storage.Storage, rules RewriteRules) {
+	if rd, ok := s.(ringDebugger); ok {
+		e.GET("/debug/ring", func(c echo.Context) error { return debugRing(rd, c, rules) })
+	}
+}
+
+// debugRing returns nodes on the hash ring with partitions they own, and the node owning key query param, if set.
+// The key is rewritten the same way object IDs are, so it resolves to the node the object is stored on.
+func debugRing(rd ringDebugger, c echo.Context, rules RewriteRules) error {
+	ctx := c.Request().Context()
+	ringNodes, err := rd.RingNodes()
+	if err != nil {
+		requestLogger(c).ErrorContext(ctx, "cannot inspect ring", "operation", "debug_ring", "error", err)
+		return c.JSON(storageErrorStatus(ctx, err), Response{Message: "Cannot inspect ring"})
+	}
+	resp := RingDebugResponse{Nodes: make([]RingNodeResponse, 0, len(ringNodes))}
+	for _, ringNode := range ringNodes {
+		resp.Nodes = append(resp.Nodes, RingNodeResponse{
+			Node:       ringNode.Node.String(),
+			Endpoint:   ringNode.Node.Endpoint,
+			Partitions: ringNode.Partitions,
+		})
+	}
+
+	if key := c.QueryParam("key"); key != "" {
+		resp.Key = rules.Apply(key)
+		node, err := rd.LocateNode(resp.Key)
+		if err != nil {
+			requestLogger(c).ErrorContext(ctx, "cannot locate key", "operation", "debug_ring", "object_id", resp.Key, "error", err)
+			return c.JSON(storageErrorStatus(ctx, err), Response{Message: fmt.Sprintf("Cannot locate key: %s", resp.Key)})
+		}
+		resp.Node, resp.Endpoint = node.String(), node.Endpoint
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// ringDebugStorage is MockStorage locating keys on its ring, recording the located ones
+type ringDebugStorage struct {
+	MockStorage
+	nodes   []storage.RingNode
+	located []string
+}
+
+func (rs *ringDebugStorage) LocateNode(id string) (storage.Node, error) {
+	rs.located = append(rs.located, id)
+	return rs.nodes[len(id)%len(rs.nodes)].Node, nil
+}
+
+func (rs *ringDebugStorage) RingNodes() ([]storage.RingNode, error) {
+	return rs.nodes, nil
+}
+
+func TestDebugRing(t *testing.T) {
+	rs := &ringDebugStorage{nodes: []storage.RingNode{
+		{Node: storage.Node{ID: "node1", Name: "1", Endpoint: "1.1.1.1:9000", SecretKey: "secret"}, Partitions: 130},
+		{Node: storage.Node{ID: "node2", Name: "2", Endpoint: "2.2.2.2:9000", SecretKey: "secret"}, Partitions: 141},
+	}}
+	rules, err := ParseRewriteRules("^legacy/=>")
+	assert.NoError(t, err)
+	serve := func(cfg *Config, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		NewServer(rs, cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// not exposed unless enabled
+	assert.Equal(t, http.StatusNotFound, serve(&Config{}, "/debug/ring").Code)
+
+	rec := serve(&Config{DebugEndpoints: true}, "/debug/ring")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"nodes":[`+
+		`{"node":"node1#1","endpoint":"1.1.1.1:9000","partitions":130},`+
+		`{"node":"node2#2","endpoint":"2.2.2.2:9000","partitions":141}]}`, strings.TrimSpace(rec.Body.String()))
+
+	// key is located as the object ID it's stored under
+	rec = serve(&Config{DebugEndpoints: true, RewriteRules: rules}, "/debug/ring?key=legacy/foo")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `{"key":"foo","node":"node2#2","endpoint":"2.2.2.2:9000","nodes":[`)
+	assert.Equal(t, []string{"foo"}, rs.located)
+}
+
+func TestDebugRing_NotReady(t *testing.T) {
+	s := storage.NewDistributedStorage(storage.NewStaticDiscoverer(nil), &storage.DistributedConfig{})
+	e := NewServer(s, &Config{DebugEndpoints: true})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/ring?key=foo", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,26 @@
+package gateway
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+)
+
+// HeaderExpireAfter lets clients upload temporary objects, treated as absent once the duration (e.g. "1h") elapses.
+const HeaderExpireAfter = "X-Expire-After"
+
+// objectExpiry returns when object uploaded with X-Expire-After header value expires, zero if the header is empty.
+func objectExpiry(header string, clock storage.Clock) (time.Time, error) {
+	if header == "" {
+		return time.Time{}, nil
+	}
+	expireAfter, err := time.ParseDuration(header)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if expireAfter <= 0 {
+		return time.Time{}, errors.New("duration must be positive")
+	}
+	return clock.Now().Add(expireAfter), nil
+}
@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := storage.ClockFunc(func() time.Time { return now })
+
+	expiresAt, err := objectExpiry("", clock)
+	assert.NoError(t, err)
+	assert.True(t, expiresAt.IsZero())
+	expiresAt, err = objectExpiry("90m", clock)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(90*time.Minute), expiresAt)
+	for _, header := range []string{"tomorrow", "0s", "-1h"} {
+		_, err = objectExpiry(header, clock)
+		assert.Error(t, err, header)
+	}
+}
+
+func TestPutObject_ExpireAfter(t *testing.T) {
+	ms := &MockStorage{objects: map[string]*storage.Object{}}
+	e := NewServer(ms, &Config{})
+	put := func(id, expireAfter string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/object/"+id, strings.NewReader("scratch data"))
+		req.Header.Set(HeaderExpireAfter, expireAfter)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, put("scratch", "1h").Code)
+	if obj := ms.objects["scratch"]; assert.NotNil(t, obj) {
+		assert.WithinDuration(t, time.Now().Add(time.Hour), obj.ExpiresAt, time.Minute)
+	}
+
+	rec := put("invalid", "soon")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), HeaderExpireAfter)
+	assert.NotContains(t, ms.objects, "invalid")
+}
@@ -0,0 +1,177 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// errNodeUnavailable mimics error of distributed storage whose node holding the object is down
+var errNodeUnavailable = fmt.Errorf("failed to get data using node (node1#1): %w (node1#1)", storage.ErrNodeUnreachable)
+
+// faultyStorage decorates storage with injected faults, failing operations on specific object IDs,
+// or all operations while simulating an outage of all nodes
+type faultyStorage struct {
+	storage.Storage
+	faults map[string]error
+	outage error
+}
+
+func newFaultyStorage() *faultyStorage {
+	return &faultyStorage{
+		Storage: &MockStorage{objects: make(map[string]*storage.Object)},
+		faults:  make(map[string]error),
+	}
+}
+
+// failID makes operations on object ID fail with err
+func (fs *faultyStorage) failID(id string, err error) *faultyStorage {
+	fs.faults[id] = err
+	return fs
+}
+
+func (fs *faultyStorage) fault(id string) error {
+	if fs.outage != nil {
+		return fs.outage
+	}
+	return fs.faults[id]
+}
+
+func (fs *faultyStorage) Put(ctx context.Context, object *storage.Object) error {
+	if err := fs.fault(object.ID); err != nil {
+		return err
+	}
+	return fs.Storage.Put(ctx, object)
+}
+
+func (fs *faultyStorage) Get(ctx context.Context, id string) (*storage.Object, error) {
+	if err := fs.fault(id); err != nil {
+		return nil, err
+	}
+	return fs.Storage.Get(ctx, id)
+}
+
+func (fs *faultyStorage) Delete(ctx context.Context, id string) error {
+	if err := fs.fault(id); err != nil {
+		return err
+	}
+	return fs.Storage.Delete(ctx, id)
+}
+
+func (fs *faultyStorage) PutStream(ctx context.Context, object *storage.ObjectStream) error {
+	if err := fs.fault(object.ID); err != nil {
+		// storage fails after consuming the body
+		_, _ = io.Copy(io.Discard, object.Content)
+		return err
+	}
+	return fs.Storage.PutStream(ctx, object)
+}
+
+func (fs *faultyStorage) GetStream(ctx context.Context, id string) (*storage.ObjectStream, error) {
+	if err := fs.fault(id); err != nil {
+		return nil, err
+	}
+	return fs.Storage.GetStream(ctx, id)
+}
+
+func (fs *faultyStorage) GetRange(ctx context.Context, id string, offset, length int64) (*storage.ObjectStream, error) {
+	if err := fs.fault(id); err != nil {
+		return nil, err
+	}
+	return fs.Storage.GetRange(ctx, id, offset, length)
+}
+
+func (fs *faultyStorage) Stat(ctx context.Context, id string) (*storage.ObjectInfo, error) {
+	if err := fs.fault(id); err != nil {
+		return nil, err
+	}
+	return fs.Storage.Stat(ctx, id)
+}
+
+func (fs *faultyStorage) Ping(ctx context.Context) error {
+	if fs.outage != nil {
+		return fs.outage
+	}
+	return fs.Storage.Ping(ctx)
+}
+
+func TestStorageErrorMapping(t *testing.T) {
+	faults := map[string]error{
+		"truncated":   fmt.Errorf("failed to get data using node (node1#1): %w", storage.ErrContentLengthMismatch),
+		"corrupted":   fmt.Errorf("failed to get data using node (node1#1): %w", storage.ErrChecksumMismatch),
+		"slow":        fmt.Errorf("failed to get data using node (node1#1): %w", context.DeadlineExceeded),
+		"unavailable": errNodeUnavailable,
+	}
+
+	tests := []struct {
+		method         string
+		id             string
+		expectedStatus int
+	}{
+		{method: http.MethodGet, id: "truncated", expectedStatus: http.StatusBadGateway},
+		{method: http.MethodHead, id: "truncated", expectedStatus: http.StatusBadGateway},
+		{method: http.MethodGet, id: "corrupted", expectedStatus: http.StatusBadGateway},
+		{method: http.MethodGet, id: "slow", expectedStatus: http.StatusGatewayTimeout},
+		{method: http.MethodHead, id: "slow", expectedStatus: http.StatusGatewayTimeout},
+		{method: http.MethodPut, id: "slow", expectedStatus: http.StatusGatewayTimeout},
+		{method: http.MethodDelete, id: "slow", expectedStatus: http.StatusGatewayTimeout},
+		{method: http.MethodGet, id: "unavailable", expectedStatus: http.StatusInternalServerError},
+		{method: http.MethodPut, id: "unavailable", expectedStatus: http.StatusInternalServerError},
+		{method: http.MethodDelete, id: "unavailable", expectedStatus: http.StatusInternalServerError},
+		// other objects are unaffected by faults of failing ones
+		{method: http.MethodGet, id: "healthy", expectedStatus: http.StatusNotFound},
+		{method: http.MethodPut, id: "healthy", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.id, func(t *testing.T) {
+			fs := newFaultyStorage()
+			for id, err := range faults {
+				fs.failID(id, err)
+			}
+			e := NewServer(fs, &Config{})
+
+			req := httptest.NewRequest(tt.method, "/object/"+tt.id, strings.NewReader("test content"))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestNodeOutage(t *testing.T) {
+	fs := newFaultyStorage()
+	e := NewServer(fs, &Config{})
+
+	request := func(method, path string) int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader("test content")))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request(http.MethodPut, "/object/validID"))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/object/validID"))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/ready"))
+
+	// all nodes down
+	fs.outage = errNodeUnavailable
+	assert.Equal(t, http.StatusInternalServerError, request(http.MethodGet, "/object/validID"))
+	assert.Equal(t, http.StatusInternalServerError, request(http.MethodPut, "/object/validID"))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/health"))
+
+	// readiness is checked on a fresh server, as node statuses are cached
+	e = NewServer(fs, &Config{})
+	assert.Equal(t, http.StatusServiceUnavailable, request(http.MethodGet, "/ready"))
+
+	// nodes recovered
+	fs.outage = nil
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/object/validID"))
+}
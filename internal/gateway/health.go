@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultReadinessCacheTTL is the default time node statuses are reused by readiness probes.
+const DefaultReadinessCacheTTL = 5 * time.Second
+
+// storageNodeKey is the node name reported for storages not exposing their nodes.
+const storageNodeKey = "storage"
+
+// nodePinger is implemented by storages able to ping each of their nodes.
+type nodePinger interface {
+	PingNodes(ctx context.Context) map[string]error
+}
+
+// breakerInspector is implemented by storages guarding their nodes with circuit breakers.
+type breakerInspector interface {
+	BreakerStates() map[string]storage.BreakerState
+}
+
+type HealthResponse struct {
+	Status   string                          `json:"status"`
+	Breakers map[string]storage.BreakerState `json:"breakers,omitempty"`
+}
+
+type NodeStatus struct {
+	Node  string `json:"node"`
+	Ready bool   `json:"ready"`
+	Error string `json:"error,omitempty"`
+}
+
+type ReadyResponse struct {
+	Ready bool         `json:"ready"`
+	Nodes []NodeStatus `json:"nodes"`
+}
+
+// readinessChecker pings storage nodes, reusing the result for a TTL so frequent probes don't load the nodes.
+type readinessChecker struct {
+	storage storage.Storage
+	quorum  int
+	ttl     time.Duration
+	clock   storage.Clock
+
+	mu        sync.Mutex
+	nodes     []NodeStatus
+	expiresAt time.Time
+}
+
+func newReadinessChecker(s storage.Storage, quorum int, ttl time.Duration, clock storage.Clock) *readinessChecker {
+	return &readinessChecker{storage: s, quorum: quorum, ttl: ttl, clock: clock}
+}
+
+// check returns node statuses and whether enough nodes are ready: quorum of them, or all if quorum isn't set.
+func (rc *readinessChecker) check(ctx context.Context) ([]NodeStatus, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if now := rc.clock.Now(); rc.nodes == nil || !now.Before(rc.expiresAt) {
+		rc.nodes = rc.ping(ctx)
+		rc.expiresAt = now.Add(rc.ttl)
+	}
+
+	ready := 0
+	for _, node := range rc.nodes {
+		if node.Ready {
+			ready++
+		}
+	}
+	required := rc.quorum
+	if required <= 0 || required > len(rc.nodes) {
+		required = len(rc.nodes)
+	}
+	return rc.nodes, ready > 0 && ready >= required
+}
+
+// ping pings storage nodes, or the storage as a whole if it doesn't expose its nodes.
+func (rc *readinessChecker) ping(ctx context.Context) []NodeStatus {
+	results := map[string]error{}
+	if np, ok := rc.storage.(nodePinger); ok {
+		results = np.PingNodes(ctx)
+	} else {
+		results[storageNodeKey] = rc.storage.Ping(ctx)
+	}
+
+	nodes := make([]NodeStatus, 0, len(results))
+	for key, err := range results {
+		status := NodeStatus{Node: key, Ready: err == nil}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		nodes = append(nodes, status)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Node < nodes[j].Node })
+	return nodes
+}
+
+// getHealth reports the gateway process is up, without checking storage. Circuit breaker states of storage nodes
+// are included, as tracked by the gateway from recent operations; open breakers don't make the gateway unhealthy.
+func getHealth(s storage.Storage, c echo.Context) error {
+	response := HealthResponse{Status: "ok"}
+	if bi, ok := s.(breakerInspector); ok {
+		response.Breakers = bi.BreakerStates()
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// getReady reports whether enough storage nodes serve requests for the gateway to receive traffic.
+func getReady(rc *readinessChecker, c echo.Context) error {
+	nodes, ready := rc.check(c.Request().Context())
+
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	return c.JSON(status, ReadyResponse{Ready: ready, Nodes: nodes})
+}
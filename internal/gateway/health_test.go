@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// nodesStorage is MockStorage pinging nodes with predefined results, counting pings
+type nodesStorage struct {
+	MockStorage
+	nodes map[string]error
+	pings int
+}
+
+func (ns *nodesStorage) PingNodes(ctx context.Context) map[string]error {
+	ns.pings++
+	return ns.nodes
+}
+
+// breakersStorage is MockStorage reporting predefined circuit breaker states
+type breakersStorage struct {
+	MockStorage
+	breakers map[string]storage.BreakerState
+}
+
+func (bs *breakersStorage) BreakerStates() map[string]storage.BreakerState {
+	return bs.breakers
+}
+
+func TestGetHealth(t *testing.T) {
+	tests := []struct {
+		name         string
+		storage      storage.Storage
+		expectedBody string
+	}{
+		{
+			name:         "storage error",
+			storage:      &MockStorage{err: errors.New("test error")},
+			expectedBody: `{"status":"ok"}`,
+		},
+		{
+			name: "breaker states",
+			storage: &breakersStorage{breakers: map[string]storage.BreakerState{
+				"node1#1": storage.BreakerClosed,
+				"node2#2": storage.BreakerOpen,
+			}},
+			expectedBody: `{"status":"ok","breakers":{"node1#1":"closed","node2#2":"open"}}`,
+		},
+		{
+			name:         "breakers disabled",
+			storage:      &breakersStorage{breakers: map[string]storage.BreakerState{}},
+			expectedBody: `{"status":"ok"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewServer(tt.storage, &Config{})
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestGetReady(t *testing.T) {
+	nodes := map[string]error{
+		"node1#1": nil,
+		"node2#2": errors.New("connection refused"),
+		"node3#3": nil,
+	}
+
+	tests := []struct {
+		name           string
+		storage        storage.Storage
+		quorum         int
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "all nodes required",
+			storage:        &nodesStorage{nodes: nodes},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody: `{"ready":false,"nodes":[` +
+				`{"node":"node1#1","ready":true},` +
+				`{"node":"node2#2","ready":false,"error":"connection refused"},` +
+				`{"node":"node3#3","ready":true}]}`,
+		},
+		{
+			name:           "quorum reached",
+			storage:        &nodesStorage{nodes: nodes},
+			quorum:         2,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"ready":true,"nodes":[` +
+				`{"node":"node1#1","ready":true},` +
+				`{"node":"node2#2","ready":false,"error":"connection refused"},` +
+				`{"node":"node3#3","ready":true}]}`,
+		},
+		{
+			name:           "no nodes",
+			storage:        &nodesStorage{nodes: map[string]error{}},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"ready":false,"nodes":[]}`,
+		},
+		{
+			name:           "storage without nodes",
+			storage:        &MockStorage{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"ready":true,"nodes":[{"node":"storage","ready":true}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewServer(tt.storage, &Config{ReadyQuorum: tt.quorum})
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestGetReady_Cached(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := storage.ClockFunc(func() time.Time { return now })
+
+	ns := &nodesStorage{nodes: map[string]error{"node1#1": errors.New("connection refused")}}
+	readiness := newReadinessChecker(ns, 0, 5*time.Second, clock)
+	e := echo.New()
+	e.GET("/ready", func(c echo.Context) error { return getReady(readiness, c) })
+
+	ready := func() int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, ready())
+
+	// node recovered, but cached status is reused
+	ns.nodes = map[string]error{"node1#1": nil}
+	now = now.Add(4 * time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, ready())
+	assert.Equal(t, 1, ns.pings)
+
+	// status refreshed after TTL
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, ready())
+	assert.Equal(t, 2, ns.pings)
+}
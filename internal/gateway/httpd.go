@@ -1,92 +1,544 @@
 package gateway
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"github.com/cavke/go-distributed-object-storage/internal/metrics"
 	"github.com/cavke/go-distributed-object-storage/internal/storage"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"regexp"
+	"strconv"
+	"sync/atomic"
+	"time"
 )
 
-var alphanumericRegex = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+// HeaderOperationTimeout lets clients set the deadline of their request's storage operations.
+const HeaderOperationTimeout = "X-Operation-Timeout"
 
-func NewServer(s storage.Storage) *echo.Echo {
+// HeaderETag identifies object content, so clients can cache it.
+const HeaderETag = "ETag"
+
+// DefaultStreamBufferSize is the default size of the buffer object content is streamed to clients through.
+const DefaultStreamBufferSize = 32 * 1024
+
+// Config holds gateway settings.
+type Config struct {
+	// MaxOperationTimeout bounds the deadline clients can request using the X-Operation-Timeout header.
+	// Zero disables the header.
+	MaxOperationTimeout time.Duration
+	// RewriteRules are applied to incoming object IDs before validation and storage.
+	RewriteRules RewriteRules
+	// ObjectIDPattern is the regexp object IDs must match. Defaults to DefaultObjectIDPattern.
+	ObjectIDPattern *regexp.Regexp
+	// MaxObjectIDLength is the maximum object ID length in bytes. Defaults to DefaultMaxObjectIDLength.
+	MaxObjectIDLength int
+	// IdempotencyTTL is how long responses of write requests with Idempotency-Key header are replayed.
+	// Zero disables idempotency keys.
+	IdempotencyTTL time.Duration
+	// IdempotencyMaxKeys bounds the number of remembered idempotency keys.
+	IdempotencyMaxKeys int
+	// StreamBufferSize is the size of the buffer object content is streamed to clients through, bounding
+	// memory used by each download. Defaults to DefaultStreamBufferSize.
+	StreamBufferSize int
+	// Metrics records object requests and is exposed on /metrics. Nil disables metrics.
+	Metrics *metrics.Metrics
+	// ReadyQuorum is the number of storage nodes that must serve requests for /ready to succeed.
+	// Zero requires all nodes.
+	ReadyQuorum int
+	// ReadinessCacheTTL is how long node statuses are reused by /ready. Defaults to DefaultReadinessCacheTTL.
+	ReadinessCacheTTL time.Duration
+	// MaxObjectSize is the maximum size of uploaded object content in bytes. Zero disables the limit.
+	MaxObjectSize int64
+	// AllowedContentTypes are content types objects may be uploaded with, checked after content type missing
+	// from the request is detected. Objects of other types are rejected with 415. Empty allows all types.
+	AllowedContentTypes ContentTypes
+	// AccessStatsMaxKeys is the number of objects whose reads are counted and exposed on /admin/access.
+	// Zero disables access statistics.
+	AccessStatsMaxKeys int
+	// JSONAccessLog logs requests as JSON lines, with object ID, node and bytes transferred, instead of the echo
+	// text format.
+	JSONAccessLog bool
+	// AccessLogOutput is where JSON access log is written to. Defaults to os.Stdout.
+	AccessLogOutput io.Writer
+	// BatchParallelism is the number of objects of a batch upload stored at once. Defaults to DefaultBatchParallelism.
+	BatchParallelism int
+	// MaxPresignExpiry bounds validity of presigned URLs. Defaults to DefaultMaxPresignExpiry.
+	MaxPresignExpiry time.Duration
+	// RateLimit limits request rate of each client. Disabled unless RateLimit.RequestsPerSecond is set.
+	RateLimit RateLimitConfig
+	// UsageCacheTTL is how long node usage is reused by /stats. Defaults to DefaultUsageCacheTTL.
+	UsageCacheTTL time.Duration
+	// Logger logs request failures, with request ID of the request. Defaults to slog.Default().
+	Logger *slog.Logger
+	// DebugEndpoints exposes storage internals on /debug routes, e.g. which node owns a key of the hash ring.
+	// Disabled by default, as they aren't meant for production.
+	DebugEndpoints bool
+	// MetadataHeaderPrefix is the prefix of request headers stored as object metadata on upload, and of response
+	// headers metadata is returned in. Defaults to DefaultMetadataHeaderPrefix.
+	MetadataHeaderPrefix string
+	// ClockSkew is the tolerated difference between the clocks of clients and the gateway when comparing
+	// If-Modified-Since and If-Unmodified-Since dates with Last-Modified of objects. Zero tolerates no skew.
+	ClockSkew time.Duration
+}
+
+func NewServer(s storage.Storage, cfg *Config) *echo.Echo {
 	// echo instance
 	e := echo.New()
+	e.HTTPErrorHandler = errorHandler
 
 	// middlewares
-	e.Use(middleware.Logger())
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	e.Use(requestID(logger))
+	if cfg.JSONAccessLog {
+		out := cfg.AccessLogOutput
+		if out == nil {
+			out = os.Stdout
+		}
+		pl, _ := s.(placementLocator)
+		e.Use(jsonAccessLog(out, pl))
+	} else {
+		e.Use(middleware.Logger())
+	}
 	e.Use(middleware.Recover())
+	// requests arriving during shutdown are rejected, while echo drains the in-flight ones
+	draining := new(atomic.Bool)
+	e.Server.RegisterOnShutdown(func() { draining.Store(true) })
+	e.Use(retryUnavailable, rejectWhileDraining(draining))
+	if cfg.RateLimit.RequestsPerSecond > 0 {
+		e.Use(rateLimit(newRateLimiter(cfg.RateLimit, storage.SystemClock), cfg.RateLimit.KeyHeader))
+	}
+	if cfg.MaxOperationTimeout > 0 {
+		e.Use(operationTimeout(cfg.MaxOperationTimeout))
+	}
+
+	// object route middlewares
+	var objectMiddlewares []echo.MiddlewareFunc
+	if cfg.Metrics != nil {
+		objectMiddlewares = append(objectMiddlewares, instrument(cfg.Metrics))
+	}
+	objectMiddlewares = append(objectMiddlewares, objectIDParam)
+	if len(cfg.RewriteRules) > 0 {
+		objectMiddlewares = append(objectMiddlewares, rewriteObjectID(cfg.RewriteRules))
+	}
+	policy := objectIDPolicy(cfg)
+	objectMiddlewares = append(objectMiddlewares, validateObjectID(policy), consistencyLevel)
+
+	// write route middlewares; batches name their objects in the body, so only options of the request apply to them
+	writeMiddlewares := append([]echo.MiddlewareFunc{}, objectMiddlewares...)
+	var batchMiddlewares []echo.MiddlewareFunc
+	if cfg.Metrics != nil {
+		batchMiddlewares = append(batchMiddlewares, instrument(cfg.Metrics))
+	}
+	batchMiddlewares = append(batchMiddlewares, consistencyLevel)
+	if cfg.IdempotencyTTL > 0 && cfg.IdempotencyMaxKeys > 0 {
+		cache := newIdempotencyCache(cfg.IdempotencyTTL, cfg.IdempotencyMaxKeys, storage.SystemClock)
+		writeMiddlewares = append(writeMiddlewares, idempotency(cache))
+		batchMiddlewares = append(batchMiddlewares, idempotency(cache))
+	}
+
+	// read route middlewares
+	readMiddlewares := objectMiddlewares
+	var stats *accessStats
+	if cfg.AccessStatsMaxKeys > 0 {
+		stats = newAccessStats(cfg.AccessStatsMaxKeys, storage.SystemClock)
+		readMiddlewares = append(append([]echo.MiddlewareFunc{}, objectMiddlewares...), trackAccess(stats))
+	}
 
 	// routes
-	e.GET("/object/:id", func(c echo.Context) error { return getObject(s, c) })
-	e.PUT("/object/:id", func(c echo.Context) error { return putObject(s, c) })
+	streamBufferSize := cfg.StreamBufferSize
+	if streamBufferSize <= 0 {
+		streamBufferSize = DefaultStreamBufferSize
+	}
+	metadataPrefix := metadataHeaderPrefix(cfg)
+	batchParallelism := cfg.BatchParallelism
+	if batchParallelism <= 0 {
+		batchParallelism = DefaultBatchParallelism
+	}
+	maxPresignExpiry := cfg.MaxPresignExpiry
+	if maxPresignExpiry <= 0 {
+		maxPresignExpiry = DefaultMaxPresignExpiry
+	}
+	objectRoutes := func(r objectRouter) {
+		r.GET("/object/*", func(c echo.Context) error { return getObject(s, c, streamBufferSize, metadataPrefix, cfg.ClockSkew) }, readMiddlewares...)
+		r.HEAD("/object/*", func(c echo.Context) error { return headObject(s, c, metadataPrefix, cfg.ClockSkew) }, objectMiddlewares...)
+		r.PUT("/object/*", func(c echo.Context) error {
+			if c.Request().Header.Get(HeaderCopySource) != "" {
+				return copyObject(s, c, cfg.RewriteRules, policy)
+			}
+			return putObject(s, c, cfg.MaxObjectSize, cfg.AllowedContentTypes, metadataPrefix, storage.SystemClock, cfg.ClockSkew)
+		}, writeMiddlewares...)
+		r.DELETE("/object/*", func(c echo.Context) error { return deleteObject(s, c) }, writeMiddlewares...)
+		r.GET("/objects", func(c echo.Context) error { return listObjects(s, c) })
+		r.POST("/objects/batch", func(c echo.Context) error { return putBatch(s, c, cfg, policy, batchParallelism) }, batchMiddlewares...)
+		if p, ok := s.(presigner); ok {
+			r.GET("/presign/object/*", func(c echo.Context) error { return presignObject(p, c, maxPresignExpiry, storage.SystemClock) }, objectMiddlewares...)
+		}
+	}
+	// objects of the configured bucket, and of buckets named in the path
+	objectRoutes(e)
+	objectRoutes(e.Group("/bucket/:bucket", bucketParam))
+	registerAdminRoutes(e, s, objectMiddlewares)
+	if cfg.DebugEndpoints {
+		registerDebugRoutes(e, s, cfg.RewriteRules)
+	}
+	if stats != nil {
+		registerAccessRoutes(e, stats, objectMiddlewares)
+	}
+	if ur, ok := s.(usageReporter); ok {
+		usageCacheTTL := cfg.UsageCacheTTL
+		if usageCacheTTL <= 0 {
+			usageCacheTTL = DefaultUsageCacheTTL
+		}
+		usage := newUsageCache(ur, usageCacheTTL, storage.SystemClock)
+		e.GET("/stats", func(c echo.Context) error { return getStats(usage, c) })
+	}
+
+	// probes
+	readinessCacheTTL := cfg.ReadinessCacheTTL
+	if readinessCacheTTL <= 0 {
+		readinessCacheTTL = DefaultReadinessCacheTTL
+	}
+	readiness := newReadinessChecker(s, cfg.ReadyQuorum, readinessCacheTTL, storage.SystemClock)
+	e.GET("/health", func(c echo.Context) error { return getHealth(s, c) })
+	e.GET("/ready", func(c echo.Context) error { return getReady(readiness, c) })
+	if cfg.Metrics != nil {
+		e.GET("/metrics", echo.WrapHandler(cfg.Metrics.Handler()))
+	}
 
 	return e
 }
 
+// objectIDPolicy returns object ID policy configured by cfg, falling back to defaults.
+func objectIDPolicy(cfg *Config) ObjectIDPolicy {
+	policy := DefaultObjectIDPolicy()
+	if cfg.ObjectIDPattern != nil {
+		policy.Pattern = cfg.ObjectIDPattern
+	}
+	if cfg.MaxObjectIDLength > 0 {
+		policy.MaxLength = cfg.MaxObjectIDLength
+	}
+	return policy
+}
+
 type Response struct {
 	Message string `json:"message"`
 }
 
-func validateObjectID(id string) bool {
-	if len(id) == 0 || len(id) > 32 {
-		return false
+// errorHandler renders errors not handled by route handlers (unknown routes, recovered panics,
+// body limit rejections, ...) in the same Response format the handlers use.
+func errorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	code := http.StatusInternalServerError
+	message := http.StatusText(code)
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		code = he.Code
+		message = http.StatusText(code)
+		if m, ok := he.Message.(string); ok {
+			message = m
+		}
+	} else {
+		requestLogger(c).ErrorContext(c.Request().Context(), "unhandled error", "error", err)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(code)
+	} else {
+		err = c.JSON(code, Response{Message: message})
+	}
+	if err != nil {
+		requestLogger(c).WarnContext(c.Request().Context(), "cannot write error response", "error", err)
 	}
-	return alphanumericRegex.MatchString(id)
 }
 
-func getObject(s storage.Storage, c echo.Context) error {
-	ctx := c.Request().Context()
-	objectID := c.Param("id")
+// operationTimeout derives the request context deadline from the X-Operation-Timeout header,
+// clamped to the given maximum.
+func operationTimeout(max time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Request().Header.Get(HeaderOperationTimeout)
+			if header == "" {
+				return next(c)
+			}
+
+			timeout, err := parseOperationTimeout(header, max)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, Response{Message: fmt.Sprintf("Invalid %s header: %s", HeaderOperationTimeout, header)})
+			}
 
-	if !validateObjectID(objectID) {
-		return c.JSON(http.StatusBadRequest, Response{Message: "Invalid objectID. Must be alfa-numeric string between 1 and 32 characters."})
+			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
 	}
+}
+
+// parseOperationTimeout parses duration (e.g. "500ms", "2s") and clamps it to max.
+func parseOperationTimeout(value string, max time.Duration) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if timeout <= 0 {
+		return 0, errors.New("timeout must be positive")
+	}
+	if timeout > max {
+		return max, nil
+	}
+	return timeout, nil
+}
+
+// storageErrorStatus maps storage error to HTTP status code.
+func storageErrorStatus(ctx context.Context, err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, storage.ErrContentLengthMismatch) || errors.Is(err, storage.ErrChecksumMismatch):
+		return http.StatusBadGateway
+	case errors.Is(err, storage.ErrConsistencyNotReached) || errors.Is(err, storage.ErrNotReady) ||
+		errors.Is(err, storage.ErrNoNodeAvailable) || errors.Is(err, storage.ErrWriteBufferFull):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func getObject(s storage.Storage, c echo.Context, bufferSize int, metadataPrefix string, clockSkew time.Duration) error {
+	if conditionalRead(c.Request().Header) {
+		if sent, err := getObjectIfNotModified(s, c, clockSkew); sent {
+			return err
+		}
+	}
+	if rangeHeader := c.Request().Header.Get(HeaderRange); rangeHeader != "" {
+		return getObjectRange(s, c, rangeHeader, bufferSize, metadataPrefix)
+	}
+
+	ctx := c.Request().Context()
+	objectID := c.Param("id")
 
-	// retrieve object from storage
-	object, err := s.Get(ctx, objectID)
+	// retrieve object stream from storage
+	object, err := s.GetStream(ctx, objectID)
 	if err != nil {
-		log.Printf("Cannot retrieve object: %v", err)
-		return c.JSON(http.StatusInternalServerError, Response{Message: fmt.Sprintf("Error retrieving object: %s", objectID)})
+		requestLogger(c).ErrorContext(ctx, "cannot retrieve object", "operation", "get", "object_id", objectID, "error", err)
+		return c.JSON(storageErrorStatus(ctx, err), Response{Message: fmt.Sprintf("Error retrieving object: %s", objectID)})
 	}
 	if object == nil {
 		return c.JSON(http.StatusNotFound, Response{Message: fmt.Sprintf("Object doesn't exist: %s", objectID)})
 	}
+	defer object.Content.Close()
 
-	return c.Blob(http.StatusOK, object.ContentType, object.Content)
+	// content verified at the end of stream, e.g. against its checksum, fails before the status is sent
+	// if it fits the buffer
+	if err := readAhead(object, bufferSize); err != nil {
+		requestLogger(c).ErrorContext(ctx, "cannot retrieve object", "operation", "get", "object_id", objectID, "error", err)
+		return c.JSON(storageErrorStatus(ctx, err), Response{Message: fmt.Sprintf("Error retrieving object: %s", objectID)})
+	}
+
+	// stream object content to the client; status is already sent when streaming fails midway
+	setObjectHeaders(c, object.Size, object.LastModified, object.ETag)
+	setMetadataHeaders(c, metadataPrefix, object.Metadata)
+	if object.ContentEncoding != "" {
+		c.Response().Header().Set(echo.HeaderContentEncoding, object.ContentEncoding)
+	}
+	if err := streamObject(c, object, http.StatusOK, bufferSize); err != nil {
+		requestLogger(c).WarnContext(ctx, "cannot stream object", "operation", "get", "object_id", objectID, "error", err)
+	}
+	return nil
+}
+
+// readAhead reads up to size bytes of object content in advance, so failures of reading them surface before
+// any response is sent. Content read is streamed first.
+func readAhead(object *storage.ObjectStream, size int) error {
+	head := make([]byte, size)
+	n, err := io.ReadFull(object.Content, head)
+	var rest io.Reader
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		// whole content was read, so the ended stream isn't read again
+		rest = bytes.NewReader(head[:n])
+	case err != nil:
+		return err
+	default:
+		rest = io.MultiReader(bytes.NewReader(head), object.Content)
+	}
+	object.Content = struct {
+		io.Reader
+		io.Closer
+	}{rest, object.Content}
+	return nil
 }
 
-func putObject(s storage.Storage, c echo.Context) error {
+// streamObject writes object content to the client with given status through a buffer of given size. Content
+// is read from the node only as fast as the client consumes it, so a slow client holds at most one buffer.
+func streamObject(c echo.Context, object *storage.ObjectStream, status int, bufferSize int) error {
+	c.Response().Header().Set(echo.HeaderContentType, object.ContentType)
+	c.Response().Header().Set(HeaderAcceptRanges, "bytes")
+	c.Response().WriteHeader(status)
+	// hide WriterTo of the content, which would bypass the buffer
+	_, err := io.CopyBuffer(c.Response(), struct{ io.Reader }{object.Content}, make([]byte, bufferSize))
+	return err
+}
+
+func headObject(s storage.Storage, c echo.Context, metadataPrefix string, clockSkew time.Duration) error {
 	ctx := c.Request().Context()
-	contentType := c.Request().Header.Get(echo.HeaderContentType)
 	objectID := c.Param("id")
 
-	if !validateObjectID(objectID) {
-		return c.JSON(http.StatusBadRequest, Response{Message: "Invalid objectID. Must be alfa-numeric string between 1 and 32 characters."})
+	// retrieve object metadata from storage
+	info, err := s.Stat(ctx, objectID)
+	if err != nil {
+		requestLogger(c).ErrorContext(ctx, "cannot retrieve object metadata", "operation", "stat", "object_id", objectID, "error", err)
+		return c.NoContent(storageErrorStatus(ctx, err))
+	}
+	if info == nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if conditionalRead(c.Request().Header) && notModified(c.Request().Header, info, clockSkew) {
+		setObjectHeaders(c, -1, info.LastModified, info.ETag)
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, info.ContentType)
+	c.Response().Header().Set(HeaderAcceptRanges, "bytes")
+	setObjectHeaders(c, info.Size, info.LastModified, info.ETag)
+	setMetadataHeaders(c, metadataPrefix, info.Metadata)
+	return c.NoContent(http.StatusOK)
+}
+
+// setObjectHeaders sets headers describing object content, used by clients for caching and progress reporting.
+// Unknown values are omitted.
+func setObjectHeaders(c echo.Context, size int64, lastModified time.Time, etag string) {
+	header := c.Response().Header()
+	if size >= 0 {
+		header.Set(echo.HeaderContentLength, strconv.FormatInt(size, 10))
+	}
+	if !lastModified.IsZero() {
+		header.Set(echo.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+	if etag != "" {
+		header.Set(HeaderETag, `"`+etag+`"`)
+	}
+}
+
+// errObjectTooLarge is recorded by request body when its content exceeds the maximum object size.
+var errObjectTooLarge = errors.New("object too large")
+
+func putObject(s storage.Storage, c echo.Context, maxSize int64, allowed ContentTypes, metadataPrefix string, clock storage.Clock, clockSkew time.Duration) error {
+	ctx, buffered := storage.TrackBufferedWrites(c.Request().Context())
+	if precondition := writePrecondition(c.Request().Header, clockSkew); precondition != nil {
+		ctx = storage.WithPrecondition(ctx, precondition)
 	}
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	objectID := c.Param("id")
 
-	// read object bytes from request body
-	body, err := io.ReadAll(c.Request().Body)
+	expiresAt, err := objectExpiry(c.Request().Header.Get(HeaderExpireAfter), clock)
 	if err != nil {
-		log.Printf("Cannot read request body: %v", err)
-		return c.JSON(http.StatusBadRequest, Response{Message: "Cannot read request body"})
+		return c.JSON(http.StatusBadRequest, Response{Message: fmt.Sprintf("Invalid %s header: %s", HeaderExpireAfter, c.Request().Header.Get(HeaderExpireAfter))})
+	}
+
+	// fail fast on declared size, undeclared (chunked) one is checked while reading
+	if maxSize > 0 && c.Request().ContentLength > maxSize {
+		return objectTooLarge(c, maxSize)
 	}
 
-	// put object to storage
-	object := storage.Object{
-		ID:          objectID,
-		ContentType: contentType,
-		Content:     body,
+	// stream request body to storage, detecting content type missing from the request from its leading bytes
+	body := &requestBody{r: c.Request().Body, limit: maxSize}
+	var content io.ReadCloser = body
+	if sniffable(contentType) {
+		contentType, content, err = sniffContentType(contentType, body)
+	}
+	if err == nil && !allowed.Allows(contentType) {
+		return unsupportedContentType(c, contentType)
+	}
+	if err == nil {
+		err = s.PutStream(ctx, &storage.ObjectStream{
+			ID:          objectID,
+			ContentType: contentType,
+			Size:        c.Request().ContentLength,
+			Content:     content,
+			Metadata:    requestMetadata(c.Request().Header, metadataPrefix),
+			ExpiresAt:   expiresAt,
+		})
+	}
+	if errors.Is(body.err, errObjectTooLarge) {
+		return objectTooLarge(c, maxSize)
+	}
+	if body.err != nil {
+		requestLogger(c).WarnContext(ctx, "cannot read request body", "operation", "put", "object_id", objectID, "error", body.err)
+		return c.JSON(http.StatusBadRequest, Response{Message: "Cannot read request body"})
+	}
+	if errors.Is(err, storage.ErrPreconditionFailed) {
+		return c.JSON(http.StatusPreconditionFailed, Response{Message: fmt.Sprintf("Object doesn't match the precondition: %s", objectID)})
 	}
-	err = s.Put(ctx, &object)
 	if err != nil {
-		log.Printf("Cannot store object: %v", err)
-		return c.JSON(http.StatusInternalServerError, Response{Message: fmt.Sprintf("Cannot store object: %s", objectID)})
+		requestLogger(c).ErrorContext(ctx, "cannot store object", "operation", "put", "object_id", objectID, "error", err)
+		return c.JSON(storageErrorStatus(ctx, err), Response{Message: fmt.Sprintf("Cannot store object: %s", objectID)})
 	}
 
+	// buffered object isn't stored yet, so it's acknowledged as accepted
+	if buffered.Load() {
+		return c.JSON(http.StatusAccepted, Response{Message: fmt.Sprintf("Object was accepted for storage with ID: %s", objectID)})
+	}
 	return c.JSON(http.StatusOK, Response{Message: fmt.Sprintf("Object was successfully stored with ID: %s", objectID)})
 }
+
+// objectTooLarge responds 413 with the maximum object size.
+func objectTooLarge(c echo.Context, maxSize int64) error {
+	return c.JSON(http.StatusRequestEntityTooLarge, Response{Message: fmt.Sprintf("Object exceeds maximum size of %d bytes", maxSize)})
+}
+
+// requestBody records request body read error, so client errors can be told apart from storage errors.
+// Reading past the limit, if set, fails with errObjectTooLarge.
+type requestBody struct {
+	r     io.ReadCloser
+	limit int64
+	read  int64
+	err   error
+}
+
+func (rb *requestBody) Read(p []byte) (int, error) {
+	n, err := rb.r.Read(p)
+	rb.read += int64(n)
+	if rb.limit > 0 && rb.read > rb.limit {
+		rb.err = errObjectTooLarge
+		return n, rb.err
+	}
+	if err != nil && err != io.EOF {
+		rb.err = err
+	}
+	return n, err
+}
+
+func (rb *requestBody) Close() error {
+	return rb.r.Close()
+}
+
+func deleteObject(s storage.Storage, c echo.Context) error {
+	ctx := c.Request().Context()
+	objectID := c.Param("id")
+
+	// delete object from storage
+	err := s.Delete(ctx, objectID)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return c.JSON(http.StatusNotFound, Response{Message: fmt.Sprintf("Object doesn't exist: %s", objectID)})
+	}
+	if err != nil {
+		requestLogger(c).ErrorContext(ctx, "cannot delete object", "operation", "delete", "object_id", objectID, "error", err)
+		return c.JSON(storageErrorStatus(ctx, err), Response{Message: fmt.Sprintf("Cannot delete object: %s", objectID)})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
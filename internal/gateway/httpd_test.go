@@ -1,22 +1,34 @@
 package gateway
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/cavke/go-distributed-object-storage/internal/storage"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type MockStorage struct {
-	objects map[string]*storage.Object
-	err     error
+	objects      map[string]*storage.Object
+	err          error
+	delay        time.Duration
+	lastModified time.Time
 }
 
 func (ms *MockStorage) Init(ctx context.Context) error {
@@ -24,6 +36,9 @@ func (ms *MockStorage) Init(ctx context.Context) error {
 }
 
 func (ms *MockStorage) Get(ctx context.Context, id string) (*storage.Object, error) {
+	if err := ms.wait(ctx); err != nil {
+		return nil, err
+	}
 	if ms.err != nil {
 		return nil, ms.err
 	}
@@ -31,27 +46,182 @@ func (ms *MockStorage) Get(ctx context.Context, id string) (*storage.Object, err
 }
 
 func (ms *MockStorage) Put(ctx context.Context, object *storage.Object) error {
+	if err := ms.wait(ctx); err != nil {
+		return err
+	}
 	if ms.err != nil {
 		return ms.err
 	}
+	if err := ms.checkPrecondition(ctx, object.ID); err != nil {
+		return err
+	}
 	ms.objects[object.ID] = object
 	return nil
 }
 
+func (ms *MockStorage) PutStream(ctx context.Context, object *storage.ObjectStream) error {
+	if err := ms.wait(ctx); err != nil {
+		return err
+	}
+	content, err := io.ReadAll(object.Content)
+	if err != nil {
+		return err
+	}
+	if ms.err != nil {
+		return ms.err
+	}
+	if err := ms.checkPrecondition(ctx, object.ID); err != nil {
+		return err
+	}
+	ms.objects[object.ID] = &storage.Object{ID: object.ID, ContentType: object.ContentType, Content: content, Metadata: object.Metadata, ExpiresAt: object.ExpiresAt}
+	return nil
+}
+
+// checkPrecondition checks precondition of conditional write against the stored object
+func (ms *MockStorage) checkPrecondition(ctx context.Context, id string) error {
+	var current *storage.ObjectInfo
+	if object := ms.objects[id]; object != nil {
+		current = &storage.ObjectInfo{ID: id, ETag: object.ETag, LastModified: ms.lastModified}
+	}
+	return storage.CheckPrecondition(ctx, current)
+}
+
+func (ms *MockStorage) GetStream(ctx context.Context, id string) (*storage.ObjectStream, error) {
+	object, err := ms.Get(ctx, id)
+	if object == nil || err != nil {
+		return nil, err
+	}
+	return &storage.ObjectStream{
+		ID:              object.ID,
+		ContentType:     object.ContentType,
+		Size:            int64(len(object.Content)),
+		Content:         io.NopCloser(bytes.NewReader(object.Content)),
+		Metadata:        object.Metadata,
+		LastModified:    ms.lastModified,
+		ETag:            object.ETag,
+		ContentEncoding: object.ContentEncoding,
+	}, nil
+}
+
+func (ms *MockStorage) GetRange(ctx context.Context, id string, offset, length int64) (*storage.ObjectStream, error) {
+	object, err := ms.Get(ctx, id)
+	if object == nil || err != nil {
+		return nil, err
+	}
+	if offset+length > int64(len(object.Content)) {
+		return nil, storage.ErrInvalidRange
+	}
+	return &storage.ObjectStream{
+		ID:           object.ID,
+		ContentType:  object.ContentType,
+		Size:         length,
+		Content:      io.NopCloser(bytes.NewReader(object.Content[offset : offset+length])),
+		Metadata:     object.Metadata,
+		LastModified: ms.lastModified,
+		ETag:         object.ETag,
+	}, nil
+}
+
+func (ms *MockStorage) Stat(ctx context.Context, id string) (*storage.ObjectInfo, error) {
+	object, err := ms.Get(ctx, id)
+	if object == nil || err != nil {
+		return nil, err
+	}
+	return &storage.ObjectInfo{
+		ID:           object.ID,
+		ContentType:  object.ContentType,
+		Size:         int64(len(object.Content)),
+		Metadata:     object.Metadata,
+		LastModified: ms.lastModified,
+		ETag:         object.ETag,
+	}, nil
+}
+
+func (ms *MockStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := ms.wait(ctx); err != nil {
+		return nil, err
+	}
+	if ms.err != nil {
+		return nil, ms.err
+	}
+	var ids []string
+	for id := range ms.objects {
+		if strings.HasPrefix(id, prefix) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (ms *MockStorage) Ping(ctx context.Context) error {
+	return ms.err
+}
+
+func (ms *MockStorage) Delete(ctx context.Context, id string) error {
+	if err := ms.wait(ctx); err != nil {
+		return err
+	}
+	if ms.err != nil {
+		return ms.err
+	}
+	if _, ok := ms.objects[id]; !ok {
+		return storage.ErrObjectNotFound
+	}
+	delete(ms.objects, id)
+	return nil
+}
+
+func (ms *MockStorage) Copy(ctx context.Context, srcID, dstID string) error {
+	if err := ms.wait(ctx); err != nil {
+		return err
+	}
+	if ms.err != nil {
+		return ms.err
+	}
+	object, ok := ms.objects[srcID]
+	if !ok {
+		return storage.ErrObjectNotFound
+	}
+	copied := *object
+	copied.ID = dstID
+	ms.objects[dstID] = &copied
+	return nil
+}
+
+// wait simulates slow storage node honoring context cancellation
+func (ms *MockStorage) wait(ctx context.Context) error {
+	if ms.delay == 0 {
+		return nil
+	}
+	select {
+	case <-time.After(ms.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// testObjectMiddlewares resolve and validate object ID the same way as NewServer does
+var testObjectMiddlewares = []echo.MiddlewareFunc{objectIDParam, validateObjectID(DefaultObjectIDPolicy())}
+
 func TestGetObject(t *testing.T) {
+	lastModified := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
 	tests := []struct {
-		name           string
-		objectID       string
-		mockStorage    *MockStorage
-		expectedStatus int
-		expectedBody   string
+		name            string
+		objectID        string
+		mockStorage     *MockStorage
+		expectedStatus  int
+		expectedBody    string
+		expectedHeaders map[string]string
 	}{
 		{
 			name:           "invalid object ID",
 			objectID:       "invalid@ID",
 			mockStorage:    &MockStorage{},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Invalid objectID. Must be alfa-numeric string between 1 and 32 characters.",
+			expectedBody:   "Invalid objectID: must match " + DefaultObjectIDPattern,
 		},
 		{
 			name:     "internal server error",
@@ -62,6 +232,15 @@ func TestGetObject(t *testing.T) {
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   "Error retrieving object: validID",
 		},
+		{
+			name:     "truncated object",
+			objectID: "validID",
+			mockStorage: &MockStorage{
+				err: fmt.Errorf("node error: %w", storage.ErrContentLengthMismatch),
+			},
+			expectedStatus: http.StatusBadGateway,
+			expectedBody:   "Error retrieving object: validID",
+		},
 		{
 			name:     "object not found",
 			objectID: "missingID",
@@ -79,6 +258,35 @@ func TestGetObject(t *testing.T) {
 					"validID": {
 						Content:     []byte("test content"),
 						ContentType: "text/plain",
+						ETag:        "6ae8a75555209fd6c44157c0aed8016e763ff435a19cf186f76863140143ff72",
+					},
+				},
+				lastModified: lastModified,
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "test content",
+			expectedHeaders: map[string]string{
+				echo.HeaderContentType:   "text/plain",
+				echo.HeaderContentLength: "12",
+				echo.HeaderLastModified:  "Sun, 01 Oct 2023 12:00:00 GMT",
+				HeaderETag:               `"6ae8a75555209fd6c44157c0aed8016e763ff435a19cf186f76863140143ff72"`,
+			},
+		},
+		{
+			name:           "path traversal",
+			objectID:       "images/../secret",
+			mockStorage:    &MockStorage{},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `Invalid objectID: must not contain empty, "." or ".." path segments`,
+		},
+		{
+			name:     "nested key",
+			objectID: "images/2024/pic-1_a.jpg",
+			mockStorage: &MockStorage{
+				objects: map[string]*storage.Object{
+					"images/2024/pic-1_a.jpg": {
+						Content:     []byte("test content"),
+						ContentType: "image/jpeg",
 					},
 				},
 			},
@@ -92,10 +300,10 @@ func TestGetObject(t *testing.T) {
 			// Create a new Echo instance
 			e := echo.New()
 
-			// Register the route to allow Echo to understand the :id parameter
-			e.GET("/object/:id", func(c echo.Context) error {
-				return getObject(tt.mockStorage, c)
-			})
+			// Register the route resolving object ID the same way as NewServer
+			e.GET("/object/*", func(c echo.Context) error {
+				return getObject(tt.mockStorage, c, DefaultStreamBufferSize, DefaultMetadataHeaderPrefix, 0)
+			}, testObjectMiddlewares...)
 
 			// Set up the request and response recorder
 			req := httptest.NewRequest(http.MethodGet, "/object/"+tt.objectID, nil)
@@ -115,11 +323,36 @@ func TestGetObject(t *testing.T) {
 					t.Fatalf("Failed to unmarshal response: %v", err)
 				}
 				assert.Equal(t, tt.expectedBody, resp.Message)
+			} else {
+				assert.Equal(t, tt.expectedBody, rec.Body.String())
+			}
+			for header, value := range tt.expectedHeaders {
+				assert.Equal(t, value, rec.Header().Get(header), header)
 			}
 		})
 	}
 }
 
+func TestGetObject_ContentEncoding(t *testing.T) {
+	// content passed through compressed by storage is sent with its encoding, and metadata can't override it
+	ms := &MockStorage{objects: map[string]*storage.Object{
+		"object-1": {ID: "object-1", ContentType: "text/plain", Content: []byte("gzipped"), ContentEncoding: storage.ContentEncodingGzip, Metadata: map[string]string{"Encoding": "br"}},
+		"object-2": {ID: "object-2", ContentType: "text/plain", Content: []byte("plain")},
+	}}
+	e := NewServer(ms, &Config{MetadataHeaderPrefix: "Content-"})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/object/object-1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, "7", rec.Header().Get(echo.HeaderContentLength))
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/object/object-2", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+}
+
 func TestPutObject(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -137,7 +370,7 @@ func TestPutObject(t *testing.T) {
 			contentType:    "text/plain",
 			mockStorage:    &MockStorage{},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Invalid objectID. Must be alfa-numeric string between 1 and 32 characters.",
+			expectedBody:   "Invalid objectID: must match " + DefaultObjectIDPattern,
 		},
 		{
 			name:           "error reading request body",
@@ -176,10 +409,10 @@ func TestPutObject(t *testing.T) {
 			// Create a new Echo instance
 			e := echo.New()
 
-			// Register the route to allow Echo to understand the :id parameter
-			e.PUT("/object/:id", func(c echo.Context) error {
-				return putObject(tt.mockStorage, c)
-			})
+			// Register the route resolving object ID the same way as NewServer
+			e.PUT("/object/*", func(c echo.Context) error {
+				return putObject(tt.mockStorage, c, 0, nil, DefaultMetadataHeaderPrefix, storage.SystemClock, 0)
+			}, testObjectMiddlewares...)
 
 			// Setup the request and response recorder
 			req := httptest.NewRequest(http.MethodPut, "/object/"+tt.objectID, tt.body)
@@ -203,9 +436,570 @@ func TestPutObject(t *testing.T) {
 	}
 }
 
+// checksumNode is a fake minio node keeping content and headers of objects uploaded or copied onto themselves
+type checksumNode struct {
+	mu      sync.Mutex
+	content map[string][]byte
+	headers map[string]http.Header
+}
+
+func (n *checksumNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	key := r.URL.Path
+	if r.Method == http.MethodPut {
+		stored := http.Header{}
+		for name, values := range r.Header {
+			if strings.HasPrefix(name, "X-Amz-Meta-") || name == "Content-Type" {
+				stored[name] = values
+			}
+		}
+		n.headers[key] = stored
+		if r.Header.Get("X-Amz-Copy-Source") != "" {
+			_, _ = io.WriteString(w, `<CopyObjectResult><ETag>"etag"</ETag><LastModified>2023-10-01T12:00:00.000Z</LastModified></CopyObjectResult>`)
+			return
+		}
+		n.content[key] = signedPayload(r)
+		w.Header().Set("ETag", `"etag"`)
+		return
+	}
+	content, ok := n.content[key]
+	if !ok {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+		return
+	}
+	for name, values := range n.headers[key] {
+		w.Header()[name] = values
+	}
+	w.Header().Set("ETag", `"etag"`)
+	http.ServeContent(w, r, key, time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC), bytes.NewReader(content))
+}
+
+// signedPayload returns payload of request body, which is signed in chunks over plain HTTP
+func signedPayload(r *http.Request) []byte {
+	body, _ := io.ReadAll(r.Body)
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return body
+	}
+	var payload []byte
+	for len(body) > 0 {
+		header, rest, _ := bytes.Cut(body, []byte("\r\n"))
+		hexSize, _, _ := strings.Cut(string(header), ";")
+		size, err := strconv.ParseInt(hexSize, 16, 64)
+		if err != nil || size == 0 || int64(len(rest)) < size+2 {
+			break
+		}
+		payload = append(payload, rest[:size]...)
+		body = rest[size+2:]
+	}
+	return payload
+}
+
+func TestPutObject_Checksum(t *testing.T) {
+	node := &checksumNode{content: map[string][]byte{}, headers: map[string]http.Header{}}
+	server := httptest.NewServer(node)
+	defer server.Close()
+	s, err := storage.NewMinioStorage(&storage.MinioConfig{
+		Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:       "key",
+		SecretKey:       "secret",
+		BucketName:      "default",
+		Region:          "us-east-1",
+		StreamChecksums: true,
+	})
+	assert.NoError(t, err)
+	e := NewServer(s, &Config{})
+
+	// checksum of streamed upload is stored
+	req := httptest.NewRequest(http.MethodPut, "/object/validID", strings.NewReader("test content"))
+	req.Header.Set(echo.HeaderContentType, "text/plain")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	sum := sha256.Sum256([]byte("test content"))
+	checksum := hex.EncodeToString(sum[:])
+	node.mu.Lock()
+	assert.Equal(t, checksum, node.headers["/default/validID"].Get("X-Amz-Meta-Sha256"))
+	node.mu.Unlock()
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/object/validID", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "test content", rec.Body.String())
+	assert.Equal(t, `"`+checksum+`"`, rec.Header().Get(HeaderETag))
+
+	// corrupted content is detected before it's sent
+	node.mu.Lock()
+	node.content["/default/validID"][0] ^= 0xff
+	node.mu.Unlock()
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/object/validID", nil))
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.JSONEq(t, `{"message": "Error retrieving object: validID"}`, rec.Body.String())
+}
+
+func TestPutObject_MaxObjectSize(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           io.Reader
+		expectedStatus int
+	}{
+		{name: "under limit", body: strings.NewReader("12345"), expectedStatus: http.StatusOK},
+		{name: "at limit", body: strings.NewReader("1234567890"), expectedStatus: http.StatusOK},
+		{name: "over declared limit", body: strings.NewReader("12345678901"), expectedStatus: http.StatusRequestEntityTooLarge},
+		// body without declared length is checked while it's read
+		{name: "over undeclared limit", body: struct{ io.Reader }{strings.NewReader("12345678901")}, expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "at undeclared limit", body: struct{ io.Reader }{strings.NewReader("1234567890")}, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &MockStorage{objects: make(map[string]*storage.Object)}
+			e := NewServer(ms, &Config{MaxObjectSize: 10})
+
+			req := httptest.NewRequest(http.MethodPut, "/object/validID", tt.body)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusRequestEntityTooLarge {
+				var resp Response
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, "Object exceeds maximum size of 10 bytes", resp.Message)
+				assert.NotContains(t, ms.objects, "validID")
+			} else {
+				assert.Contains(t, ms.objects, "validID")
+			}
+		})
+	}
+}
+
+// bufferingStorage is MockStorage buffering writes of objects with the buffered ID prefix
+type bufferingStorage struct {
+	MockStorage
+	buffered string
+}
+
+func (bs *bufferingStorage) Put(ctx context.Context, object *storage.Object) error {
+	if strings.HasPrefix(object.ID, bs.buffered) {
+		storage.MarkBuffered(ctx)
+	}
+	return bs.MockStorage.Put(ctx, object)
+}
+
+func (bs *bufferingStorage) PutStream(ctx context.Context, object *storage.ObjectStream) error {
+	if strings.HasPrefix(object.ID, bs.buffered) {
+		storage.MarkBuffered(ctx)
+	}
+	return bs.MockStorage.PutStream(ctx, object)
+}
+
+func TestPutObject_Buffered(t *testing.T) {
+	bs := &bufferingStorage{MockStorage: MockStorage{objects: make(map[string]*storage.Object)}, buffered: "buffered"}
+	// batch objects are stored one by one, as MockStorage isn't safe for concurrent use
+	e := NewServer(bs, &Config{BatchParallelism: 1})
+	put := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/object/"+id, strings.NewReader("content")))
+		return rec
+	}
+
+	// buffered object is acknowledged as accepted, not stored
+	rec := put("buffered1")
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.JSONEq(t, `{"message": "Object was accepted for storage with ID: buffered1"}`, rec.Body.String())
+	assert.Equal(t, http.StatusOK, put("stored1").Code)
+
+	// so are buffered objects of a batch
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, id := range []string{"buffered2", "stored2"} {
+		w, err := mw.CreateFormFile(id, id)
+		assert.NoError(t, err)
+		_, _ = w.Write([]byte("content"))
+	}
+	assert.NoError(t, mw.Close())
+	req := httptest.NewRequest(http.MethodPost, "/objects/batch", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"stored": 2, "failed": 0, "results": [{"id": "buffered2", "status": 202}, {"id": "stored2", "status": 200}]}`, rec.Body.String())
+
+	// full buffer asks the client to retry
+	bs.err = fmt.Errorf("failed to push data: %w", storage.ErrWriteBufferFull)
+	rec = put("buffered3")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+}
+
+func TestHeadObject(t *testing.T) {
+	lastModified := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		objectID        string
+		mockStorage     *MockStorage
+		expectedStatus  int
+		expectedHeaders map[string]string
+	}{
+		{
+			name:           "invalid object ID",
+			objectID:       "invalid@ID",
+			mockStorage:    &MockStorage{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "internal server error",
+			objectID:       "validID",
+			mockStorage:    &MockStorage{err: errors.New("test error")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "object not found",
+			objectID:       "missingID",
+			mockStorage:    &MockStorage{objects: make(map[string]*storage.Object)},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:     "success",
+			objectID: "validID",
+			mockStorage: &MockStorage{
+				objects: map[string]*storage.Object{
+					"validID": {Content: []byte("test content"), ContentType: "text/plain"},
+				},
+				lastModified: lastModified,
+			},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				echo.HeaderContentType:   "text/plain",
+				echo.HeaderContentLength: "12",
+				echo.HeaderLastModified:  "Sun, 01 Oct 2023 12:00:00 GMT",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HEAD("/object/*", func(c echo.Context) error {
+				return headObject(tt.mockStorage, c, DefaultMetadataHeaderPrefix, 0)
+			}, testObjectMiddlewares...)
+
+			req := httptest.NewRequest(http.MethodHead, "/object/"+tt.objectID, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Empty(t, rec.Body.String())
+			for header, value := range tt.expectedHeaders {
+				assert.Equal(t, value, rec.Header().Get(header))
+			}
+		})
+	}
+}
+
+func TestDeleteObject(t *testing.T) {
+	tests := []struct {
+		name           string
+		objectID       string
+		mockStorage    *MockStorage
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "invalid object ID",
+			objectID:       "invalid@ID",
+			mockStorage:    &MockStorage{},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid objectID: must match " + DefaultObjectIDPattern,
+		},
+		{
+			name:     "internal server error",
+			objectID: "validID",
+			mockStorage: &MockStorage{
+				err: errors.New("test error"),
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Cannot delete object: validID",
+		},
+		{
+			name:     "object not found",
+			objectID: "missingID",
+			mockStorage: &MockStorage{
+				objects: make(map[string]*storage.Object),
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Object doesn't exist: missingID",
+		},
+		{
+			name:     "success",
+			objectID: "validID",
+			mockStorage: &MockStorage{
+				objects: map[string]*storage.Object{
+					"validID": {Content: []byte("test content"), ContentType: "text/plain"},
+				},
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create a new Echo instance
+			e := echo.New()
+
+			// Register the route resolving object ID the same way as NewServer
+			e.DELETE("/object/*", func(c echo.Context) error {
+				return deleteObject(tt.mockStorage, c)
+			}, testObjectMiddlewares...)
+
+			// Set up the request and response recorder
+			req := httptest.NewRequest(http.MethodDelete, "/object/"+tt.objectID, nil)
+			rec := httptest.NewRecorder()
+
+			// Start the Echo router
+			e.ServeHTTP(rec, req)
+
+			// Assert the outcomes
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			if rec.Code == http.StatusNoContent {
+				assert.Empty(t, rec.Body.String())
+				assert.NotContains(t, tt.mockStorage.objects, tt.objectID)
+				return
+			}
+
+			// Parse the error message
+			var resp Response
+			err := json.Unmarshal(rec.Body.Bytes(), &resp)
+			if err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			assert.Equal(t, tt.expectedBody, resp.Message)
+		})
+	}
+}
+
+func TestParseOperationTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    time.Duration
+		expectedErr bool
+	}{
+		{name: "valid", value: "500ms", expected: 500 * time.Millisecond},
+		{name: "clamped to max", value: "1h", expected: 10 * time.Second},
+		{name: "not a duration", value: "soon", expectedErr: true},
+		{name: "negative", value: "-1s", expectedErr: true},
+		{name: "zero", value: "0s", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeout, err := parseOperationTimeout(tt.value, 10*time.Second)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, timeout)
+		})
+	}
+}
+
+func TestOperationTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		header         string
+		delay          time.Duration
+		expectedStatus int
+	}{
+		{name: "no header", delay: 20 * time.Millisecond, expectedStatus: http.StatusOK},
+		{name: "within deadline", header: "1s", delay: 20 * time.Millisecond, expectedStatus: http.StatusOK},
+		{name: "deadline exceeded", header: "10ms", delay: time.Second, expectedStatus: http.StatusGatewayTimeout},
+		{name: "invalid header", header: "later", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &MockStorage{
+				objects: map[string]*storage.Object{
+					"validID": {Content: []byte("test content"), ContentType: "text/plain"},
+				},
+				delay: tt.delay,
+			}
+			e := NewServer(ms, &Config{MaxOperationTimeout: 5 * time.Second})
+
+			req := httptest.NewRequest(http.MethodGet, "/object/validID", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderOperationTimeout, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           io.Reader
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "unknown route",
+			method:         http.MethodGet,
+			path:           "/unknown",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Not Found",
+		},
+		{
+			name:           "method not allowed",
+			method:         http.MethodPost,
+			path:           "/object/validID",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   "Method Not Allowed",
+		},
+		{
+			name:           "body limit exceeded",
+			method:         http.MethodPut,
+			path:           "/object/validID",
+			body:           strings.NewReader(strings.Repeat("x", 2048)),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   "Request Entity Too Large",
+		},
+		{
+			name:           "recovered panic",
+			method:         http.MethodGet,
+			path:           "/panic",
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Internal Server Error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewServer(&MockStorage{objects: make(map[string]*storage.Object)}, &Config{})
+			e.Use(middleware.BodyLimit("1K"))
+			e.GET("/panic", func(c echo.Context) error { panic("test panic") })
+
+			req := httptest.NewRequest(tt.method, tt.path, tt.body)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			var resp Response
+			err := json.Unmarshal(rec.Body.Bytes(), &resp)
+			if err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedBody, resp.Message)
+		})
+	}
+}
+
+func TestStorageErrorStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{
+			name:           "no node available",
+			err:            fmt.Errorf("failed to get data: %w", storage.ErrNoNodeAvailable),
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "node unreachable",
+			err:            fmt.Errorf("failed to get data using node (node1#1): %w (node1#1)", storage.ErrNodeUnreachable),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "storage not ready",
+			err:            fmt.Errorf("failed to get data: %w", storage.ErrNotReady),
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "write buffer full",
+			err:            storage.ErrWriteBufferFull,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "internal error",
+			err:            errors.New("unexpected failure"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewServer(&MockStorage{err: tt.err}, &Config{})
+			for _, req := range []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/object/validID", nil),
+				httptest.NewRequest(http.MethodPut, "/object/validID", strings.NewReader("content")),
+			} {
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				assert.Equal(t, tt.expectedStatus, rec.Code, req.Method)
+			}
+		})
+	}
+}
+
 // Custom error reader to simulate error when reading request body
 type errorReader struct{}
 
 func (er *errorReader) Read(p []byte) (n int, err error) {
 	return 0, errors.New("read error")
 }
+
+// slowResponseWriter consumes response body slowly, tracking how far ahead of it content was read
+type slowResponseWriter struct {
+	*httptest.ResponseRecorder
+	content  *countingReader
+	written  int
+	maxAhead int
+}
+
+func (w *slowResponseWriter) Write(p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	w.maxAhead = max(w.maxAhead, w.content.read-w.written)
+	w.written += len(p)
+	return w.ResponseRecorder.Write(p)
+}
+
+type countingReader struct {
+	r    io.Reader
+	read int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.read += n
+	return n, err
+}
+
+func TestStreamObject(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 64*1024)
+	bufferSize := 1024
+
+	reader := &countingReader{r: bytes.NewReader(content)}
+	rec := &slowResponseWriter{ResponseRecorder: httptest.NewRecorder(), content: reader}
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/object/validID", nil), rec)
+
+	err := streamObject(c, &storage.ObjectStream{ID: "validID", ContentType: "text/plain", Size: -1, Content: io.NopCloser(reader)}, http.StatusOK, bufferSize)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, content, rec.Body.Bytes())
+	// content wasn't read from the node further ahead of the client than one buffer
+	assert.LessOrEqual(t, rec.maxAhead, bufferSize)
+}
@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// HeaderIdempotencyKey lets clients safely retry write requests.
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed marks responses replayed from the idempotency cache.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// idempotentResponse is a recorded response of completed request.
+type idempotentResponse struct {
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+	inProgress  bool
+}
+
+// idempotencyCache holds responses of write requests keyed by idempotency key for a TTL.
+// The cache is bounded; when full, the oldest entries are evicted first.
+type idempotencyCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	clock      storage.Clock
+	entries    map[string]*idempotentResponse
+	order      []string
+}
+
+func newIdempotencyCache(ttl time.Duration, maxEntries int, clock storage.Clock) *idempotencyCache {
+	return &idempotencyCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		clock:      clock,
+		entries:    make(map[string]*idempotentResponse),
+	}
+}
+
+// reserve returns recorded response for key and true, or marks the key in progress if it's not known, returning
+// the reservation to be completed and false.
+func (ic *idempotencyCache) reserve(key string) (*idempotentResponse, bool) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	now := ic.clock.Now()
+	if entry, ok := ic.entries[key]; ok && (entry.inProgress || now.Before(entry.expiresAt)) {
+		return entry, true
+	}
+
+	ic.evict(now)
+	reservation := &idempotentResponse{inProgress: true}
+	ic.entries[key] = reservation
+	ic.order = append(ic.order, key)
+	return reservation, false
+}
+
+// complete records response for the reserved key, or forgets the key if response shouldn't be replayed.
+// Reservation evicted meanwhile isn't recorded, as the key isn't tracked for eviction anymore and may have
+// been reserved again.
+func (ic *idempotencyCache) complete(key string, reservation, response *idempotentResponse) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	if ic.entries[key] != reservation {
+		return
+	}
+	if response == nil {
+		delete(ic.entries, key)
+		return
+	}
+	response.expiresAt = ic.clock.Now().Add(ic.ttl)
+	ic.entries[key] = response
+}
+
+// evict removes expired entries and the oldest ones exceeding the size limit. Must be called with lock held.
+func (ic *idempotencyCache) evict(now time.Time) {
+	kept := ic.order[:0]
+	for _, key := range ic.order {
+		entry, ok := ic.entries[key]
+		if !ok {
+			continue
+		}
+		if !entry.inProgress && !now.Before(entry.expiresAt) {
+			delete(ic.entries, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	ic.order = kept
+
+	for len(ic.order) >= ic.maxEntries && len(ic.order) > 0 {
+		delete(ic.entries, ic.order[0])
+		ic.order = ic.order[1:]
+	}
+}
+
+// responseRecorder captures response body while writing it to the client.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
+
+// idempotency replays the recorded response of a request with an already seen Idempotency-Key
+// instead of executing it again. Server errors are not recorded, so such requests can be retried.
+func idempotency(cache *idempotencyCache) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			idempotencyKey := c.Request().Header.Get(HeaderIdempotencyKey)
+			if idempotencyKey == "" {
+				return next(c)
+			}
+
+			// scope key to the request target, so the same key can't replay a response of other object
+			key := c.Request().Method + " " + c.Request().URL.Path + " " + idempotencyKey
+			entry, known := cache.reserve(key)
+			if known {
+				if entry.inProgress {
+					return c.JSON(http.StatusConflict, Response{Message: "Request with the same idempotency key is in progress"})
+				}
+				c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+				return c.Blob(entry.status, entry.contentType, entry.body)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			err := next(c)
+			if err != nil || c.Response().Status >= http.StatusInternalServerError {
+				cache.complete(key, entry, nil)
+				return err
+			}
+
+			cache.complete(key, entry, &idempotentResponse{
+				status:      c.Response().Status,
+				contentType: c.Response().Header().Get(echo.HeaderContentType),
+				body:        recorder.body.Bytes(),
+			})
+			return nil
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingStorage counts PutStream calls of wrapped MockStorage
+type countingStorage struct {
+	*MockStorage
+	puts int
+}
+
+func (cs *countingStorage) PutStream(ctx context.Context, object *storage.ObjectStream) error {
+	cs.puts++
+	return cs.MockStorage.PutStream(ctx, object)
+}
+
+func TestIdempotency(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := storage.ClockFunc(func() time.Time { return now })
+
+	ms := &countingStorage{MockStorage: &MockStorage{objects: make(map[string]*storage.Object)}}
+	e := echo.New()
+	e.PUT("/object/:id", func(c echo.Context) error {
+		return putObject(ms, c, 0, nil, DefaultMetadataHeaderPrefix, storage.SystemClock, 0)
+	}, idempotency(newIdempotencyCache(time.Minute, 10, clock)))
+
+	put := func(id, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/object/"+id, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// first request is executed
+	rec := put("validID", "key-1", "first")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, 1, ms.puts)
+
+	// retry returns cached result without re-executing the write
+	rec = put("validID", "key-1", "second")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(HeaderIdempotentReplayed))
+	var resp Response
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Object was successfully stored with ID: validID", resp.Message)
+	assert.Equal(t, 1, ms.puts)
+	assert.Equal(t, []byte("first"), ms.objects["validID"].Content)
+
+	// same key for different object is executed
+	put("otherID", "key-1", "other")
+	assert.Equal(t, 2, ms.puts)
+
+	// requests without key are always executed
+	put("validID", "", "third")
+	assert.Equal(t, 3, ms.puts)
+
+	// expired key is executed again
+	now = now.Add(2 * time.Minute)
+	rec = put("validID", "key-1", "fourth")
+	assert.Empty(t, rec.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, 4, ms.puts)
+}
+
+func TestIdempotency_ServerErrorNotCached(t *testing.T) {
+	ms := &countingStorage{MockStorage: &MockStorage{objects: make(map[string]*storage.Object), err: errors.New("test error")}}
+	e := echo.New()
+	e.PUT("/object/:id", func(c echo.Context) error {
+		return putObject(ms, c, 0, nil, DefaultMetadataHeaderPrefix, storage.SystemClock, 0)
+	}, idempotency(newIdempotencyCache(time.Minute, 10, storage.SystemClock)))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPut, "/object/validID", strings.NewReader("content"))
+		req.Header.Set(HeaderIdempotencyKey, "key-1")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	}
+	assert.Equal(t, 2, ms.puts)
+}
+
+func TestIdempotencyCache_Bounded(t *testing.T) {
+	cache := newIdempotencyCache(time.Minute, 2, storage.SystemClock)
+	for _, key := range []string{"a", "b", "c"} {
+		reservation, found := cache.reserve(key)
+		assert.False(t, found)
+		cache.complete(key, reservation, &idempotentResponse{status: http.StatusOK})
+	}
+
+	assert.Len(t, cache.entries, 2)
+	assert.NotContains(t, cache.entries, "a")
+}
+
+func TestIdempotencyCache_EvictedInProgress(t *testing.T) {
+	cache := newIdempotencyCache(time.Minute, 1, storage.SystemClock)
+
+	// second request evicts the first one while in progress
+	first, found := cache.reserve("a")
+	assert.False(t, found)
+	second, found := cache.reserve("b")
+	assert.False(t, found)
+	cache.complete("b", second, &idempotentResponse{status: http.StatusOK})
+	cache.complete("a", first, &idempotentResponse{status: http.StatusOK})
+
+	// evicted request isn't recorded, so the size bound holds and its key isn't replayed
+	assert.Len(t, cache.entries, 1)
+	assert.Equal(t, []string{"b"}, cache.order)
+	_, found = cache.reserve("a")
+	assert.False(t, found)
+	assert.Len(t, cache.entries, 1)
+	assert.NotContains(t, cache.entries, "b")
+}
@@ -0,0 +1,41 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultListMax is the default maximum number of object IDs returned by /objects.
+const DefaultListMax = 1000
+
+// listObjects returns sorted IDs of objects starting with the prefix query param, at most max of them.
+func listObjects(s storage.Storage, c echo.Context) error {
+	ctx := c.Request().Context()
+	prefix := c.QueryParam("prefix")
+
+	max := DefaultListMax
+	if param := c.QueryParam("max"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n <= 0 {
+			return c.JSON(http.StatusBadRequest, Response{Message: fmt.Sprintf("Invalid max: %s", param)})
+		}
+		max = n
+	}
+
+	ids, err := s.List(ctx, prefix)
+	if err != nil {
+		requestLogger(c).ErrorContext(ctx, "cannot list objects", "operation", "list", "prefix", prefix, "error", err)
+		return c.JSON(storageErrorStatus(ctx, err), Response{Message: fmt.Sprintf("Cannot list objects with prefix: %s", prefix)})
+	}
+	if len(ids) > max {
+		ids = ids[:max]
+	}
+	if ids == nil {
+		ids = []string{}
+	}
+	return c.JSON(http.StatusOK, ids)
+}
@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListObjects(t *testing.T) {
+	objects := map[string]*storage.Object{
+		"photos/1": {ID: "photos/1"},
+		"photos/2": {ID: "photos/2"},
+		"photos/3": {ID: "photos/3"},
+		"video/1":  {ID: "video/1"},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		mockStorage    *MockStorage
+		expectedStatus int
+		expectedIDs    []string
+	}{
+		{
+			name:           "all objects",
+			mockStorage:    &MockStorage{objects: objects},
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []string{"photos/1", "photos/2", "photos/3", "video/1"},
+		},
+		{
+			name:           "prefix",
+			query:          "?prefix=photos/",
+			mockStorage:    &MockStorage{objects: objects},
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []string{"photos/1", "photos/2", "photos/3"},
+		},
+		{
+			name:           "bounded",
+			query:          "?prefix=photos/&max=2",
+			mockStorage:    &MockStorage{objects: objects},
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []string{"photos/1", "photos/2"},
+		},
+		{
+			name:           "no match",
+			query:          "?prefix=music/",
+			mockStorage:    &MockStorage{objects: objects},
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []string{},
+		},
+		{
+			name:           "invalid max",
+			query:          "?max=0",
+			mockStorage:    &MockStorage{objects: objects},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "storage error",
+			mockStorage:    &MockStorage{err: errors.New("node down")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewServer(tt.mockStorage, &Config{})
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/objects"+tt.query, nil))
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedIDs != nil {
+				var ids []string
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ids))
+				assert.Equal(t, tt.expectedIDs, ids)
+			}
+		})
+	}
+}
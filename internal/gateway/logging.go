@@ -0,0 +1,32 @@
+package gateway
+
+import (
+	"log/slog"
+
+	"github.com/cavke/go-distributed-object-storage/internal/logging"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// loggerContextKey is the echo context key of the request logger.
+const loggerContextKey = "logger"
+
+// requestID assigns every request a correlation ID, reusing X-Request-ID sent by the client (e.g. a proxy),
+// and returns it in X-Request-ID response header. The ID is carried by the request context, so records logged
+// on behalf of the request, including storage ones, can be correlated.
+func requestID(logger *slog.Logger) echo.MiddlewareFunc {
+	return middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, id string) {
+			c.SetRequest(c.Request().WithContext(logging.WithRequestID(c.Request().Context(), id)))
+			c.Set(loggerContextKey, logger)
+		},
+	})
+}
+
+// requestLogger returns logger of the request, the default one if the request didn't pass requestID middleware.
+func requestLogger(c echo.Context) *slog.Logger {
+	if logger, ok := c.Get(loggerContextKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/logging"
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// requestIDStorage records request IDs carried by contexts of GetStream calls
+type requestIDStorage struct {
+	*MockStorage
+	requestIDs []string
+}
+
+func (s *requestIDStorage) GetStream(ctx context.Context, id string) (*storage.ObjectStream, error) {
+	s.requestIDs = append(s.requestIDs, logging.RequestID(ctx))
+	return s.MockStorage.GetStream(ctx, id)
+}
+
+func TestRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	s := &requestIDStorage{MockStorage: &MockStorage{err: errors.New("node unreachable")}}
+	e := NewServer(s, &Config{Logger: slog.New(logging.NewHandler(slog.NewJSONHandler(&logs, nil)))})
+
+	// generated ID is returned to the client and passed to storage
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/object/validID", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	id := rec.Header().Get(echo.HeaderXRequestID)
+	assert.NotEmpty(t, id)
+	assert.Equal(t, []string{id}, s.requestIDs)
+	// failure is logged with the request ID and structured fields
+	assert.Contains(t, logs.String(), `"request_id":"`+id+`"`)
+	assert.Contains(t, logs.String(), `"object_id":"validID"`)
+	assert.Contains(t, logs.String(), `"error":"node unreachable"`)
+
+	// ID sent by the client is kept
+	req := httptest.NewRequest(http.MethodGet, "/object/validID", nil)
+	req.Header.Set(echo.HeaderXRequestID, "client-id")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, "client-id", rec.Header().Get(echo.HeaderXRequestID))
+	assert.Equal(t, "client-id", s.requestIDs[1])
+}
@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultMetadataHeaderPrefix is the default prefix of headers carrying object metadata.
+const DefaultMetadataHeaderPrefix = "X-Amz-Meta-"
+
+// reservedHeaders describe object content or the response itself, so they're neither stored as object metadata
+// nor overwritten by it, whatever the metadata header prefix.
+var reservedHeaders = map[string]bool{
+	echo.HeaderContentType:     true,
+	echo.HeaderContentLength:   true,
+	echo.HeaderContentEncoding: true,
+	HeaderContentRange:         true,
+	HeaderAcceptRanges:         true,
+	HeaderETag:                 true,
+	echo.HeaderLastModified:    true,
+	echo.HeaderXRequestID:      true,
+}
+
+// metadataHeaderPrefix returns canonical metadata header prefix configured by cfg, falling back to the default.
+func metadataHeaderPrefix(cfg *Config) string {
+	if cfg.MetadataHeaderPrefix == "" {
+		return DefaultMetadataHeaderPrefix
+	}
+	return http.CanonicalHeaderKey(cfg.MetadataHeaderPrefix)
+}
+
+// requestMetadata collects object metadata from request headers with the prefix, keyed by header name without it.
+// Repeated headers are joined by commas. It returns nil if there's no metadata.
+func requestMetadata(header http.Header, prefix string) map[string]string {
+	var metadata map[string]string
+	for name, values := range header {
+		key, ok := strings.CutPrefix(name, prefix)
+		if !ok || key == "" || reservedHeaders[name] {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[key] = strings.Join(values, ",")
+	}
+	return metadata
+}
+
+// setMetadataHeaders sets response headers carrying object metadata. Reserved headers are left as they are.
+func setMetadataHeaders(c echo.Context, prefix string, metadata map[string]string) {
+	header := c.Response().Header()
+	for key, value := range metadata {
+		if name := http.CanonicalHeaderKey(prefix + key); !reservedHeaders[name] {
+			header.Set(name, value)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestMetadata(t *testing.T) {
+	header := http.Header{}
+	header.Set(echo.HeaderContentType, "text/plain")
+	header.Set("X-Amz-Meta-Author", "jane")
+	header.Add("X-Amz-Meta-Reviewed-By", "john")
+	header.Add("X-Amz-Meta-Reviewed-By", "joan")
+	header.Set("X-Amz-Meta-", "no key")
+
+	assert.Equal(t, map[string]string{"Author": "jane", "Reviewed-By": "john,joan"}, requestMetadata(header, DefaultMetadataHeaderPrefix))
+	assert.Nil(t, requestMetadata(http.Header{echo.HeaderContentType: {"text/plain"}}, DefaultMetadataHeaderPrefix))
+	// reserved headers aren't metadata, even if they have the prefix
+	assert.Equal(t, map[string]string{"Language": "en"}, requestMetadata(http.Header{
+		echo.HeaderContentType: {"text/plain"},
+		"Content-Language":     {"en"},
+	}, "Content-"))
+}
+
+func TestObjectMetadata(t *testing.T) {
+	tests := []struct {
+		name             string
+		prefix           string
+		requestHeaders   map[string]string
+		expectedMetadata map[string]string
+		expectedHeaders  map[string]string
+	}{
+		{
+			name:             "default prefix",
+			requestHeaders:   map[string]string{"X-Amz-Meta-Author": "jane", "x-amz-meta-reviewed-by": "john", "X-Other": "ignored"},
+			expectedMetadata: map[string]string{"Author": "jane", "Reviewed-By": "john"},
+			expectedHeaders:  map[string]string{"X-Amz-Meta-Author": "jane", "X-Amz-Meta-Reviewed-By": "john"},
+		},
+		{
+			name:             "custom prefix",
+			prefix:           "x-object-",
+			requestHeaders:   map[string]string{"X-Object-Author": "jane", "X-Amz-Meta-Author": "ignored"},
+			expectedMetadata: map[string]string{"Author": "jane"},
+			expectedHeaders:  map[string]string{"X-Object-Author": "jane"},
+		},
+		{
+			name:             "prefix of reserved headers",
+			prefix:           "Content-",
+			requestHeaders:   map[string]string{"Content-Language": "en"},
+			expectedMetadata: map[string]string{"Language": "en"},
+			expectedHeaders:  map[string]string{"Content-Language": "en", "Content-Type": "text/plain", "Content-Length": "4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &MockStorage{objects: map[string]*storage.Object{}}
+			e := NewServer(ms, &Config{MetadataHeaderPrefix: tt.prefix})
+
+			req := httptest.NewRequest(http.MethodPut, "/object/object-1", strings.NewReader("data"))
+			req.Header.Set(echo.HeaderContentType, "text/plain")
+			for name, value := range tt.requestHeaders {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.expectedMetadata, ms.objects["object-1"].Metadata)
+
+			// metadata is returned by get, partial get and head
+			partial := httptest.NewRequest(http.MethodGet, "/object/object-1", nil)
+			partial.Header.Set(HeaderRange, "bytes=0-3")
+			for _, req := range []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/object/object-1", nil),
+				partial,
+				httptest.NewRequest(http.MethodHead, "/object/object-1", nil),
+			} {
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				for name, value := range tt.expectedHeaders {
+					assert.Equal(t, value, rec.Header().Get(name), name)
+				}
+			}
+		})
+	}
+}
+
+func TestSetMetadataHeaders(t *testing.T) {
+	// metadata stored under reserved names, e.g. before the prefix changed, doesn't overwrite them
+	ms := &MockStorage{objects: map[string]*storage.Object{
+		"object-1": {ID: "object-1", ContentType: "text/plain", Content: []byte("data"), Metadata: map[string]string{"Type": "image/png", "Length": "1000"}},
+	}}
+	e := NewServer(ms, &Config{MetadataHeaderPrefix: "Content-"})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/object/object-1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "4", rec.Header().Get("Content-Length"))
+	assert.Equal(t, "data", rec.Body.String())
+}
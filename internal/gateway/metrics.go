@@ -0,0 +1,33 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// instrument records count and duration of object requests, labeled by operation (lowercase HTTP method).
+func instrument(m *metrics.Metrics) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			// errors are rendered by error handler later, so response status isn't set yet
+			status := c.Response().Status
+			if err != nil {
+				status = http.StatusInternalServerError
+				var he *echo.HTTPError
+				if errors.As(err, &he) {
+					status = he.Code
+				}
+			}
+			m.ObserveRequest(strings.ToLower(c.Request().Method), status, time.Since(start))
+			return err
+		}
+	}
+}
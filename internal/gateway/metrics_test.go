@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/metrics"
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	ms := &MockStorage{objects: make(map[string]*storage.Object)}
+	e := NewServer(ms, &Config{Metrics: metrics.New()})
+
+	requests := []struct {
+		method         string
+		path           string
+		body           io.Reader
+		expectedStatus int
+	}{
+		{method: http.MethodPut, path: "/object/validID", body: strings.NewReader("test content"), expectedStatus: http.StatusOK},
+		{method: http.MethodGet, path: "/object/validID", expectedStatus: http.StatusOK},
+		{method: http.MethodGet, path: "/object/missingID", expectedStatus: http.StatusNotFound},
+		{method: http.MethodGet, path: "/object/invalid@ID", expectedStatus: http.StatusBadRequest},
+	}
+	for _, r := range requests {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(r.method, r.path, r.body))
+		assert.Equal(t, r.expectedStatus, rec.Code)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `gateway_requests_total{operation="get"} 3`)
+	assert.Contains(t, body, `gateway_requests_total{operation="put"} 1`)
+	assert.Contains(t, body, `gateway_request_duration_seconds_count{operation="get",status="200"} 1`)
+	assert.Contains(t, body, `gateway_request_duration_seconds_count{operation="get",status="400"} 1`)
+	assert.Contains(t, body, `gateway_request_duration_seconds_count{operation="get",status="404"} 1`)
+}
+
+func TestMetricsEndpoint_Disabled(t *testing.T) {
+	e := NewServer(&MockStorage{}, &Config{})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// DefaultObjectIDPattern accepts letters, digits, "-", "_", "." and "/".
+	DefaultObjectIDPattern = `^[a-zA-Z0-9._/-]+$`
+	// DefaultMaxObjectIDLength is the default maximum object ID length in bytes.
+	DefaultMaxObjectIDLength = 1024
+)
+
+// ObjectIDPolicy defines object IDs accepted by the gateway.
+type ObjectIDPolicy struct {
+	// Pattern is the regexp object IDs must match.
+	Pattern *regexp.Regexp
+	// MaxLength is the maximum object ID length in bytes.
+	MaxLength int
+}
+
+// DefaultObjectIDPolicy returns policy accepting IDs matching DefaultObjectIDPattern up to DefaultMaxObjectIDLength.
+func DefaultObjectIDPolicy() ObjectIDPolicy {
+	return ObjectIDPolicy{
+		Pattern:   regexp.MustCompile(DefaultObjectIDPattern),
+		MaxLength: DefaultMaxObjectIDLength,
+	}
+}
+
+// Validate checks object ID length and characters. IDs are used as node object keys, which are paths,
+// so IDs with empty, "." or ".." path segments (including leading or trailing "/") are rejected as well.
+func (p ObjectIDPolicy) Validate(id string) error {
+	if id == "" {
+		return errors.New("must not be empty")
+	}
+	if len(id) > p.MaxLength {
+		return fmt.Errorf("must not be longer than %d characters", p.MaxLength)
+	}
+	if !p.Pattern.MatchString(id) {
+		return fmt.Errorf("must match %s", p.Pattern)
+	}
+	for _, segment := range strings.Split(id, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return errors.New(`must not contain empty, "." or ".." path segments`)
+		}
+	}
+	return nil
+}
+
+// objectIDParam exposes object ID matched by the wildcard of object routes as the id param.
+// Object routes use wildcard, as unlike named params it matches IDs containing "/".
+func objectIDParam(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		names := append([]string(nil), c.ParamNames()...)
+		for i, name := range names {
+			if name == "*" {
+				names[i] = "id"
+			}
+		}
+		c.SetParamNames(names...)
+		return next(c)
+	}
+}
+
+// validateObjectID rejects requests with object ID not accepted by the policy.
+func validateObjectID(policy ObjectIDPolicy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if err := policy.Validate(c.Param("id")); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid objectID: %v", err))
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectIDPolicy_Validate(t *testing.T) {
+	policy := DefaultObjectIDPolicy()
+
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{id: "validID", valid: true},
+		{id: "543b8e0e-f093-4668-9eb3-3adbbbee452a", valid: true},
+		{id: "images/2024/pic.jpg", valid: true},
+		{id: "snake_case.tar.gz", valid: true},
+		{id: "a..b", valid: true},
+		{id: strings.Repeat("a", DefaultMaxObjectIDLength), valid: true},
+		{id: ""},
+		{id: strings.Repeat("a", DefaultMaxObjectIDLength+1)},
+		{id: "invalid@ID"},
+		{id: "with space"},
+		{id: "/absolute"},
+		{id: "trailing/"},
+		{id: "double//slash"},
+		{id: "../escape"},
+		{id: "images/../secret"},
+		{id: "./current"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			err := policy.Validate(tt.id)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestObjectIDPolicy_Configured(t *testing.T) {
+	policy := objectIDPolicy(&Config{ObjectIDPattern: regexp.MustCompile(`^[a-z]+$`), MaxObjectIDLength: 4})
+
+	assert.NoError(t, policy.Validate("abcd"))
+	assert.Error(t, policy.Validate("abcde"))
+	assert.Error(t, policy.Validate("ABC"))
+}
+
+func TestNewServer_NestedObjectID(t *testing.T) {
+	ms := &MockStorage{objects: make(map[string]*storage.Object)}
+	e := NewServer(ms, &Config{})
+
+	req := httptest.NewRequest(http.MethodPut, "/object/images/2024/pic.jpg", strings.NewReader("test content"))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, ms.objects, "images/2024/pic.jpg")
+
+	req = httptest.NewRequest(http.MethodGet, "/object/images/2024/pic.jpg", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "test content", rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/object/", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// DefaultPresignExpiry is the validity of presigned URLs requested without expiry.
+	DefaultPresignExpiry = 5 * time.Minute
+	// DefaultMaxPresignExpiry is the default longest validity of presigned URLs.
+	DefaultMaxPresignExpiry = time.Hour
+)
+
+// presigner is implemented by storages able to presign URLs giving clients direct access to objects on nodes.
+type presigner interface {
+	PresignedURL(ctx context.Context, id string, op storage.PresignOperation, expiry time.Duration) (*url.URL, error)
+}
+
+type PresignResponse struct {
+	URL       string    `json:"url"`
+	Method    string    `json:"method"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// presignExpiry parses expiry query param, a duration ("300s", "5m") or number of seconds, clamped to max.
+func presignExpiry(value string, max time.Duration) (time.Duration, error) {
+	if value == "" {
+		return min(DefaultPresignExpiry, max), nil
+	}
+	expiry, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, atoiErr := strconv.Atoi(value)
+		if atoiErr != nil {
+			return 0, err
+		}
+		expiry = time.Duration(seconds) * time.Second
+	}
+	if expiry < time.Second {
+		return 0, fmt.Errorf("expiry must be at least 1s")
+	}
+	return min(expiry, max), nil
+}
+
+// presignObject returns URL for the client to get or put the object directly on its node, offloading
+// the transfer from the gateway.
+func presignObject(p presigner, c echo.Context, maxExpiry time.Duration, clock storage.Clock) error {
+	objectID := c.Param("id")
+	op, err := storage.ParsePresignOperation(c.QueryParam("op"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Response{Message: fmt.Sprintf("Invalid op: %v", err)})
+	}
+	expiry, err := presignExpiry(c.QueryParam("expiry"), maxExpiry)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Response{Message: fmt.Sprintf("Invalid expiry: %v", err)})
+	}
+
+	ctx := c.Request().Context()
+	u, err := p.PresignedURL(ctx, objectID, op, expiry)
+	if err != nil {
+		requestLogger(c).ErrorContext(ctx, "cannot presign object", "operation", "presign", "object_id", objectID, "error", err)
+		return c.JSON(storageErrorStatus(ctx, err), Response{Message: fmt.Sprintf("Cannot presign object: %s", objectID)})
+	}
+
+	method := http.MethodGet
+	if op == storage.PresignPut {
+		method = http.MethodPut
+	}
+	return c.JSON(http.StatusOK, PresignResponse{URL: u.String(), Method: method, ExpiresAt: clock.Now().Add(expiry).UTC()})
+}
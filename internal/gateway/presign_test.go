@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// presignerStorage is MockStorage presigning URLs of a fake node, recording the requested expiry
+type presignerStorage struct {
+	MockStorage
+	expiry time.Duration
+	bucket string
+	err    error
+}
+
+func (ps *presignerStorage) PresignedURL(ctx context.Context, id string, op storage.PresignOperation, expiry time.Duration) (*url.URL, error) {
+	ps.expiry = expiry
+	ps.bucket = storage.BucketFromContext(ctx)
+	if ps.err != nil {
+		return nil, ps.err
+	}
+	return &url.URL{Scheme: "http", Host: "10.0.0.1:9000", Path: "/default/" + id, RawQuery: "op=" + string(op)}, nil
+}
+
+func TestPresignObject(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		storageErr     error
+		expectedStatus int
+		expectedURL    string
+		expectedMethod string
+		expectedExpiry time.Duration
+		expectedBucket string
+	}{
+		{name: "get", target: "/presign/object/validID?op=get", expectedStatus: http.StatusOK, expectedURL: "http://10.0.0.1:9000/default/validID?op=get", expectedMethod: http.MethodGet, expectedExpiry: DefaultPresignExpiry},
+		{name: "put", target: "/presign/object/validID?op=put&expiry=300s", expectedStatus: http.StatusOK, expectedURL: "http://10.0.0.1:9000/default/validID?op=put", expectedMethod: http.MethodPut, expectedExpiry: 300 * time.Second},
+		{name: "expiry in seconds", target: "/presign/object/validID?op=get&expiry=60", expectedStatus: http.StatusOK, expectedMethod: http.MethodGet, expectedExpiry: time.Minute},
+		{name: "expiry clamped", target: "/presign/object/validID?op=get&expiry=48h", expectedStatus: http.StatusOK, expectedMethod: http.MethodGet, expectedExpiry: DefaultMaxPresignExpiry},
+		{name: "bucket", target: "/bucket/photos/presign/object/validID?op=get", expectedStatus: http.StatusOK, expectedMethod: http.MethodGet, expectedExpiry: DefaultPresignExpiry, expectedBucket: "photos"},
+		{name: "missing op", target: "/presign/object/validID", expectedStatus: http.StatusBadRequest},
+		{name: "invalid op", target: "/presign/object/validID?op=delete", expectedStatus: http.StatusBadRequest},
+		{name: "invalid expiry", target: "/presign/object/validID?op=get&expiry=soon", expectedStatus: http.StatusBadRequest},
+		{name: "negative expiry", target: "/presign/object/validID?op=get&expiry=-5m", expectedStatus: http.StatusBadRequest},
+		{name: "invalid ID", target: "/presign/object/..?op=get", expectedStatus: http.StatusBadRequest},
+		{name: "storage failure", target: "/presign/object/validID?op=get", storageErr: errors.New("node down"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &presignerStorage{err: tt.storageErr}
+			e := NewServer(s, &Config{})
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			assert.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var response PresignResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			if tt.expectedURL != "" {
+				assert.Equal(t, tt.expectedURL, response.URL)
+			}
+			assert.Equal(t, tt.expectedMethod, response.Method)
+			assert.Equal(t, tt.expectedExpiry, s.expiry)
+			assert.Equal(t, tt.expectedBucket, s.bucket)
+			assert.WithinDuration(t, time.Now().Add(tt.expectedExpiry), response.ExpiresAt, time.Minute)
+		})
+	}
+
+	// storage not presigning URLs has no presign endpoint
+	rec := httptest.NewRecorder()
+	NewServer(&MockStorage{}, &Config{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/presign/object/validID?op=get", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
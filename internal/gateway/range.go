@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// HeaderRange requests part of object content.
+	HeaderRange = "Range"
+	// HeaderContentRange describes part of object content sent in response to a Range request.
+	HeaderContentRange = "Content-Range"
+	// HeaderAcceptRanges advertises support of Range requests.
+	HeaderAcceptRanges = "Accept-Ranges"
+)
+
+// parseRange resolves single byte range of Range header ("bytes=0-1023", "bytes=1024-" or "bytes=-1024")
+// against content of given size, returning its offset and length. Ranges reaching past the end of content
+// are truncated to it; ranges starting past it are not satisfiable.
+func parseRange(header string, size int64) (offset, length int64, err error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return 0, 0, errors.New("only bytes ranges are supported")
+	}
+	if strings.Contains(spec, ",") {
+		return 0, 0, errors.New("multiple ranges are not supported")
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("malformed range %q", spec)
+	}
+
+	// suffix range of the last bytes
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, fmt.Errorf("malformed range %q", spec)
+		}
+		if size == 0 {
+			return 0, 0, errors.New("range not satisfiable for empty object")
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, fmt.Errorf("malformed range %q", spec)
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, fmt.Errorf("malformed range %q", spec)
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return 0, 0, fmt.Errorf("range start %d past object size %d", start, size)
+	}
+	return start, end - start + 1, nil
+}
+
+// getObjectRange streams byte range of object requested by the Range header with 206 Partial Content.
+// Object size is needed to resolve the range, so object metadata is retrieved first.
+func getObjectRange(s storage.Storage, c echo.Context, rangeHeader string, bufferSize int, metadataPrefix string) error {
+	ctx := c.Request().Context()
+	objectID := c.Param("id")
+
+	info, err := s.Stat(ctx, objectID)
+	if err != nil {
+		requestLogger(c).ErrorContext(ctx, "cannot retrieve object metadata", "operation", "stat", "object_id", objectID, "error", err)
+		return c.JSON(storageErrorStatus(ctx, err), Response{Message: fmt.Sprintf("Error retrieving object: %s", objectID)})
+	}
+	if info == nil {
+		return c.JSON(http.StatusNotFound, Response{Message: fmt.Sprintf("Object doesn't exist: %s", objectID)})
+	}
+
+	offset, length, err := parseRange(rangeHeader, info.Size)
+	if err != nil {
+		return rangeNotSatisfiable(c, info.Size, err)
+	}
+
+	object, err := s.GetRange(ctx, objectID, offset, length)
+	if errors.Is(err, storage.ErrInvalidRange) {
+		// object shrank since its metadata was retrieved
+		return rangeNotSatisfiable(c, info.Size, err)
+	}
+	if err != nil {
+		requestLogger(c).ErrorContext(ctx, "cannot retrieve object range", "operation", "get_range", "object_id", objectID, "error", err)
+		return c.JSON(storageErrorStatus(ctx, err), Response{Message: fmt.Sprintf("Error retrieving object: %s", objectID)})
+	}
+	if object == nil {
+		return c.JSON(http.StatusNotFound, Response{Message: fmt.Sprintf("Object doesn't exist: %s", objectID)})
+	}
+	defer object.Content.Close()
+
+	c.Response().Header().Set(HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", offset, offset+object.Size-1, info.Size))
+	setObjectHeaders(c, object.Size, info.LastModified, info.ETag)
+	setMetadataHeaders(c, metadataPrefix, info.Metadata)
+
+	// stream object content to the client; status is already sent when streaming fails midway
+	if err := streamObject(c, object, http.StatusPartialContent, bufferSize); err != nil {
+		requestLogger(c).WarnContext(ctx, "cannot stream object range", "operation", "get_range", "object_id", objectID, "error", err)
+	}
+	return nil
+}
+
+// rangeNotSatisfiable responds 416 with the object size, so the client can correct the range.
+func rangeNotSatisfiable(c echo.Context, size int64, err error) error {
+	c.Response().Header().Set(HeaderContentRange, fmt.Sprintf("bytes */%d", size))
+	return c.JSON(http.StatusRequestedRangeNotSatisfiable, Response{Message: fmt.Sprintf("Invalid range: %v", err)})
+}
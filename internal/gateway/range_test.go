@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header         string
+		size           int64
+		expectedOffset int64
+		expectedLength int64
+		expectedErr    bool
+	}{
+		{header: "bytes=0-1023", size: 4096, expectedOffset: 0, expectedLength: 1024},
+		{header: "bytes=1024-", size: 4096, expectedOffset: 1024, expectedLength: 3072},
+		{header: "bytes=-1024", size: 4096, expectedOffset: 3072, expectedLength: 1024},
+		{header: "bytes=0-0", size: 1, expectedOffset: 0, expectedLength: 1},
+		// ranges past the end are truncated
+		{header: "bytes=4000-8000", size: 4096, expectedOffset: 4000, expectedLength: 96},
+		{header: "bytes=-8000", size: 4096, expectedOffset: 0, expectedLength: 4096},
+		// not satisfiable
+		{header: "bytes=4096-", size: 4096, expectedErr: true},
+		{header: "bytes=-1", size: 0, expectedErr: true},
+		{header: "bytes=-0", size: 4096, expectedErr: true},
+		// malformed or unsupported
+		{header: "bytes=10-5", size: 4096, expectedErr: true},
+		{header: "bytes=a-b", size: 4096, expectedErr: true},
+		{header: "bytes=0-1,5-6", size: 4096, expectedErr: true},
+		{header: "items=0-1", size: 4096, expectedErr: true},
+		{header: "bytes=5", size: 4096, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			offset, length, err := parseRange(tt.header, tt.size)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedOffset, offset)
+			assert.Equal(t, tt.expectedLength, length)
+		})
+	}
+}
+
+func TestGetObjectRange(t *testing.T) {
+	ms := &MockStorage{objects: map[string]*storage.Object{
+		"validID": {ID: "validID", ContentType: "text/plain", Content: []byte("0123456789")},
+	}}
+	e := NewServer(ms, &Config{})
+
+	tests := []struct {
+		name                 string
+		objectID             string
+		rangeHeader          string
+		expectedStatus       int
+		expectedContentRange string
+		expectedBody         string
+	}{
+		{
+			name:                 "valid range",
+			objectID:             "validID",
+			rangeHeader:          "bytes=2-5",
+			expectedStatus:       http.StatusPartialContent,
+			expectedContentRange: "bytes 2-5/10",
+			expectedBody:         "2345",
+		},
+		{
+			name:                 "suffix range",
+			objectID:             "validID",
+			rangeHeader:          "bytes=-3",
+			expectedStatus:       http.StatusPartialContent,
+			expectedContentRange: "bytes 7-9/10",
+			expectedBody:         "789",
+		},
+		{
+			name:                 "out of bounds range",
+			objectID:             "validID",
+			rangeHeader:          "bytes=10-20",
+			expectedStatus:       http.StatusRequestedRangeNotSatisfiable,
+			expectedContentRange: "bytes */10",
+		},
+		{
+			name:           "no range",
+			objectID:       "validID",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "0123456789",
+		},
+		{
+			name:           "missing object",
+			objectID:       "missingID",
+			rangeHeader:    "bytes=0-1",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/object/"+tt.objectID, nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set(HeaderRange, tt.rangeHeader)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedContentRange, rec.Header().Get(HeaderContentRange))
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rec.Body.String())
+				assert.Equal(t, "bytes", rec.Header().Get(HeaderAcceptRanges))
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"container/list"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultRateLimitMaxClients is the default number of clients whose request rate is tracked.
+const DefaultRateLimitMaxClients = 10000
+
+// RateLimitConfig configures limiting of request rate per client.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained request rate allowed to each client. Zero disables rate limiting.
+	RequestsPerSecond float64
+	// Burst is the number of requests a client may send at once, after being idle. Defaults to
+	// RequestsPerSecond rounded up.
+	Burst int
+	// MaxClients bounds the number of tracked clients. When full, the least recently seen client is forgotten,
+	// which lets it start with a full burst again. Defaults to DefaultRateLimitMaxClients.
+	MaxClients int
+	// KeyHeader is the request header identifying clients, e.g. API key header set by an authenticating proxy.
+	// Clients are identified by their IP address if empty or if the request doesn't have the header.
+	KeyHeader string
+}
+
+// tokenBucket holds tokens of a client, each allowing a request. Tokens are refilled at the allowed rate.
+type tokenBucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter limits request rate of clients with a token bucket per client, kept in memory.
+type rateLimiter struct {
+	mu         sync.Mutex
+	rate       float64
+	burst      float64
+	maxClients int
+	clock      storage.Clock
+	buckets    map[string]*list.Element
+	// recency holds *tokenBucket ordered from the most recently seen client
+	recency *list.List
+}
+
+func newRateLimiter(cfg RateLimitConfig, clock storage.Clock) *rateLimiter {
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(cfg.RequestsPerSecond))
+	}
+	maxClients := cfg.MaxClients
+	if maxClients <= 0 {
+		maxClients = DefaultRateLimitMaxClients
+	}
+	return &rateLimiter{
+		rate:       cfg.RequestsPerSecond,
+		burst:      float64(burst),
+		maxClients: maxClients,
+		clock:      clock,
+		buckets:    make(map[string]*list.Element),
+		recency:    list.New(),
+	}
+}
+
+// allow takes a token of the client, if it has one. Otherwise it returns how long until the client has one.
+func (rl *rateLimiter) allow(key string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.clock.Now()
+	var bucket *tokenBucket
+	if elem, ok := rl.buckets[key]; ok {
+		bucket = elem.Value.(*tokenBucket)
+		bucket.tokens = math.Min(rl.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*rl.rate)
+		bucket.updated = now
+		rl.recency.MoveToFront(elem)
+	} else {
+		for rl.recency.Len() >= rl.maxClients && rl.recency.Len() > 0 {
+			oldest := rl.recency.Back()
+			delete(rl.buckets, oldest.Value.(*tokenBucket).key)
+			rl.recency.Remove(oldest)
+		}
+		bucket = &tokenBucket{key: key, tokens: rl.burst, updated: now}
+		rl.buckets[key] = rl.recency.PushFront(bucket)
+	}
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / rl.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// rateLimit rejects requests of clients exceeding their request rate with 429 Too Many Requests, telling them
+// when to retry in Retry-After header. Probes and metrics aren't limited, as they don't load the nodes.
+func rateLimit(rl *rateLimiter, keyHeader string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.Path() {
+			case "/health", "/ready", "/metrics":
+				return next(c)
+			}
+
+			// keys are prefixed, so a header value can't pose as a client IP
+			key := "ip:" + c.RealIP()
+			if keyHeader != "" {
+				if value := c.Request().Header.Get(keyHeader); value != "" {
+					key = "key:" + value
+				}
+			}
+			if ok, retryAfter := rl.allow(key); !ok {
+				c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				return echo.NewHTTPError(http.StatusTooManyRequests, "Too many requests")
+			}
+			return next(c)
+		}
+	}
+}
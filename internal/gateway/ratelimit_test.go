@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := storage.ClockFunc(func() time.Time { return now })
+	rl := newRateLimiter(RateLimitConfig{RequestsPerSecond: 2, Burst: 3, MaxClients: 2}, clock)
+
+	// burst is allowed at once, then requests wait for tokens refilled at the rate
+	for i := 0; i < 3; i++ {
+		ok, _ := rl.allow("client-1")
+		assert.True(t, ok, i)
+	}
+	ok, retryAfter := rl.allow("client-1")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = rl.allow("client-1")
+	assert.True(t, ok)
+
+	// clients are limited independently
+	ok, _ = rl.allow("client-2")
+	assert.True(t, ok)
+
+	// least recently seen client is forgotten when full, starting with a full burst again
+	ok, _ = rl.allow("client-3")
+	assert.True(t, ok)
+	assert.Len(t, rl.buckets, 2)
+	assert.NotContains(t, rl.buckets, "client-1")
+	for i := 0; i < 3; i++ {
+		ok, _ = rl.allow("client-1")
+		assert.True(t, ok, i)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	e := NewServer(&MockStorage{}, &Config{RateLimit: RateLimitConfig{RequestsPerSecond: 1, Burst: 5, KeyHeader: "X-API-Key"}})
+	serve := func(target, ip, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = ip + ":12345"
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// burst beyond the limit gets some requests rejected
+	limited := 0
+	for i := 0; i < 20; i++ {
+		rec := serve(fmt.Sprintf("/object/%d", i), "10.0.0.1", "")
+		if rec.Code == http.StatusTooManyRequests {
+			limited++
+			assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+			continue
+		}
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+	assert.GreaterOrEqual(t, limited, 14)
+
+	// other clients, identified by IP or API key, aren't affected
+	assert.Equal(t, http.StatusNotFound, serve("/object/1", "10.0.0.2", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("/object/1", "10.0.0.1", "secret-key").Code)
+	// probes aren't limited
+	assert.Equal(t, http.StatusOK, serve("/health", "10.0.0.1", "").Code)
+}
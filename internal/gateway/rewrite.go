@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	rewriteRuleSeparator       = ";"
+	rewriteReplacementOperator = "=>"
+)
+
+// RewriteRule rewrites object IDs matching Pattern using Replacement (regexp.ReplaceAllString semantics).
+type RewriteRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// RewriteRules is an ordered chain of rewrite rules, each applied to the output of the previous one.
+type RewriteRules []RewriteRule
+
+// Apply rewrites object ID using all rules in order.
+func (rr RewriteRules) Apply(id string) string {
+	for _, rule := range rr {
+		id = rule.Pattern.ReplaceAllString(id, rule.Replacement)
+	}
+	return id
+}
+
+// ParseRewriteRules parses rules in format "pattern=>replacement;pattern=>replacement".
+// Empty spec results in no rules.
+func ParseRewriteRules(spec string) (RewriteRules, error) {
+	var rules RewriteRules
+	for _, raw := range strings.Split(spec, rewriteRuleSeparator) {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		pattern, replacement, found := strings.Cut(raw, rewriteReplacementOperator)
+		if !found {
+			return nil, fmt.Errorf("invalid rewrite rule %q: missing %q", raw, rewriteReplacementOperator)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rewrite rule %q: %w", raw, err)
+		}
+		rules = append(rules, RewriteRule{Pattern: re, Replacement: replacement})
+	}
+	return rules, nil
+}
+
+// rewriteObjectID rewrites the object ID route parameter before it's validated and passed to storage.
+func rewriteObjectID(rules RewriteRules) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			names := c.ParamNames()
+			values := append([]string(nil), c.ParamValues()...)
+			for i, name := range names {
+				if name == "id" && i < len(values) {
+					values[i] = rules.Apply(values[i])
+				}
+			}
+			c.SetParamValues(values...)
+			return next(c)
+		}
+	}
+}
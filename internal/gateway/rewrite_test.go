@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRewriteRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          string
+		expectedCount int
+		expectedErr   bool
+	}{
+		{name: "empty", spec: "", expectedCount: 0},
+		{name: "single rule", spec: "^legacy=>", expectedCount: 1},
+		{name: "multiple rules with whitespace", spec: " ^legacy=> ; ^v1([0-9]+)$=>v2$1 ;", expectedCount: 2},
+		{name: "missing operator", spec: "^legacy", expectedErr: true},
+		{name: "invalid regexp", spec: "([a-z=>x", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRewriteRules(tt.spec)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, rules, tt.expectedCount)
+		})
+	}
+}
+
+func TestRewriteRules_Apply(t *testing.T) {
+	rules, err := ParseRewriteRules("^legacy=>;^v1([0-9]+)$=>v2$1;^v2=>obj")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		id       string
+		expected string
+	}{
+		{id: "legacyv1123", expected: "obj123"},
+		{id: "v1123", expected: "obj123"},
+		{id: "v2123", expected: "obj123"},
+		{id: "other", expected: "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			assert.Equal(t, tt.expected, rules.Apply(tt.id))
+		})
+	}
+}
+
+func TestRewriteObjectID(t *testing.T) {
+	rules, err := ParseRewriteRules("^legacy=>")
+	assert.NoError(t, err)
+
+	ms := &MockStorage{objects: make(map[string]*storage.Object)}
+	e := NewServer(ms, &Config{RewriteRules: rules})
+
+	// store object using legacy ID
+	req := httptest.NewRequest(http.MethodPut, "/object/legacy42", strings.NewReader("test content"))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, ms.objects, "42")
+
+	// old and new IDs resolve to same object
+	for _, id := range []string{"legacy42", "42"} {
+		req = httptest.NewRequest(http.MethodGet, "/object/"+id, nil)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "test content", rec.Body.String())
+	}
+}
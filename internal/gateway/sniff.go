@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// sniffLen is the number of leading content bytes content type is detected from, as by http.DetectContentType.
+const sniffLen = 512
+
+// sniffable checks if content type sent by the client tells nothing, so it's detected from the content instead.
+func sniffable(contentType string) bool {
+	mediaType := baseMediaType(contentType)
+	return mediaType == "" || mediaType == echo.MIMEOctetStream
+}
+
+// sniffContentType detects content type from leading bytes of content, returning reader of the whole content.
+// Empty content keeps the given content type.
+func sniffContentType(contentType string, content io.ReadCloser) (string, io.ReadCloser, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	head = head[:n]
+	if n > 0 {
+		contentType = http.DetectContentType(head)
+	}
+	return contentType, struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), content), content}, nil
+}
@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutObject_SniffContentType(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 1000)...)
+	tests := []struct {
+		name        string
+		contentType string
+		content     []byte
+		expected    string
+	}{
+		{name: "missing type is detected", content: png, expected: "image/png"},
+		{name: "generic type is detected", contentType: "application/octet-stream", content: png, expected: "image/png"},
+		{name: "explicit type is kept", contentType: "application/x-custom", content: png, expected: "application/x-custom"},
+		{name: "short content is detected", content: []byte("<html><body>hi</body></html>"), expected: "text/html; charset=utf-8"},
+		{name: "empty content keeps missing type", content: []byte{}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &MockStorage{objects: map[string]*storage.Object{}}
+			e := NewServer(ms, &Config{})
+
+			req := httptest.NewRequest(http.MethodPut, "/object/image", bytes.NewReader(tt.content))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			if stored := ms.objects["image"]; assert.NotNil(t, stored) {
+				assert.Equal(t, tt.expected, stored.ContentType)
+				// sniffed bytes are stored along with the rest of the content
+				assert.Equal(t, tt.content, stored.Content)
+			}
+		})
+	}
+}
+
+func TestPutObject_SniffTooLarge(t *testing.T) {
+	ms := &MockStorage{objects: map[string]*storage.Object{}}
+	e := NewServer(ms, &Config{MaxObjectSize: 10})
+
+	// chunked body exceeding the limit within the sniffed bytes
+	req := httptest.NewRequest(http.MethodPut, "/object/large", bytes.NewReader(bytes.Repeat([]byte("a"), 100)))
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Empty(t, ms.objects)
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultUsageCacheTTL is the default time node usage is reused by /stats.
+const DefaultUsageCacheTTL = time.Minute
+
+// usageReporter is implemented by storages able to report usage of each of their nodes.
+type usageReporter interface {
+	NodesUsage(ctx context.Context) (map[string]storage.NodeUsage, error)
+}
+
+type StatsResponse struct {
+	Nodes map[string]storage.NodeUsage `json:"nodes"`
+	Total storage.NodeUsage            `json:"total"`
+}
+
+// usageCache reuses node usage for a TTL, as computing it lists all objects on the nodes.
+// Failures aren't cached.
+type usageCache struct {
+	reporter usageReporter
+	ttl      time.Duration
+	clock    storage.Clock
+
+	mu        sync.Mutex
+	response  *StatsResponse
+	expiresAt time.Time
+}
+
+func newUsageCache(reporter usageReporter, ttl time.Duration, clock storage.Clock) *usageCache {
+	return &usageCache{reporter: reporter, ttl: ttl, clock: clock}
+}
+
+// get returns usage of storage nodes and their total, computing it if the cached one expired.
+func (uc *usageCache) get(ctx context.Context) (*StatsResponse, error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	now := uc.clock.Now()
+	if uc.response != nil && now.Before(uc.expiresAt) {
+		return uc.response, nil
+	}
+	nodes, err := uc.reporter.NodesUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response := &StatsResponse{Nodes: nodes}
+	for _, usage := range nodes {
+		response.Total.Objects += usage.Objects
+		response.Total.Bytes += usage.Bytes
+	}
+	uc.response, uc.expiresAt = response, now.Add(uc.ttl)
+	return response, nil
+}
+
+// getStats returns object count and bytes stored on each storage node and in the whole cluster.
+func getStats(uc *usageCache, c echo.Context) error {
+	response, err := uc.get(c.Request().Context())
+	if err != nil {
+		requestLogger(c).ErrorContext(c.Request().Context(), "cannot retrieve storage usage", "operation", "usage", "error", err)
+		return c.JSON(storageErrorStatus(c.Request().Context(), err), Response{Message: "Cannot retrieve storage usage"})
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// usageStorage is MockStorage reporting usage of its nodes, counting the reports
+type usageStorage struct {
+	MockStorage
+	nodes   map[string]storage.NodeUsage
+	err     error
+	reports int
+}
+
+func (us *usageStorage) NodesUsage(ctx context.Context) (map[string]storage.NodeUsage, error) {
+	us.reports++
+	return us.nodes, us.err
+}
+
+func TestGetStats(t *testing.T) {
+	s := &usageStorage{nodes: map[string]storage.NodeUsage{
+		"node1#1": {Objects: 3, Bytes: 300},
+		"node2#2": {Objects: 2, Bytes: 1024},
+	}}
+	e := NewServer(s, &Config{})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"nodes":{"node1#1":{"objects":3,"bytes":300},"node2#2":{"objects":2,"bytes":1024}},"total":{"objects":5,"bytes":1324}}`, rec.Body.String())
+
+	// storage not reporting usage has no stats
+	rec = httptest.NewRecorder()
+	NewServer(&MockStorage{}, &Config{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestUsageCache(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	s := &usageStorage{err: errors.New("node down")}
+	uc := newUsageCache(s, time.Minute, storage.ClockFunc(func() time.Time { return now }))
+
+	// failures aren't cached
+	_, err := uc.get(context.TODO())
+	assert.Error(t, err)
+	s.err = nil
+	s.nodes = map[string]storage.NodeUsage{"node1#1": {Objects: 1, Bytes: 10}}
+	response, err := uc.get(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, storage.NodeUsage{Objects: 1, Bytes: 10}, response.Total)
+	assert.Equal(t, 2, s.reports)
+
+	// usage is reused until TTL elapses
+	s.nodes = map[string]storage.NodeUsage{"node1#1": {Objects: 2, Bytes: 20}}
+	now = now.Add(59 * time.Second)
+	response, _ = uc.get(context.TODO())
+	assert.Equal(t, int64(1), response.Total.Objects)
+	now = now.Add(time.Second)
+	response, _ = uc.get(context.TODO())
+	assert.Equal(t, int64(2), response.Total.Objects)
+	assert.Equal(t, 3, s.reports)
+}
@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unavailableRetryAfter is how long clients are told to wait before retrying requests the gateway can't serve
+// at the moment, e.g. while storage initializes or the gateway shuts down.
+const unavailableRetryAfter = time.Second
+
+// retryUnavailable tells clients when to retry requests failed with 503 Service Unavailable in Retry-After
+// header, unless the handler did already.
+func retryUnavailable(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res := c.Response()
+		res.Before(func() {
+			if res.Status == http.StatusServiceUnavailable && res.Header().Get(echo.HeaderRetryAfter) == "" {
+				res.Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(unavailableRetryAfter.Seconds())))
+			}
+		})
+		return next(c)
+	}
+}
+
+// rejectWhileDraining rejects requests arriving once the server started shutting down with 503 Service
+// Unavailable, while in-flight ones are drained. Readiness probe fails too, so load balancers stop routing
+// to the gateway; health probe and metrics are still served.
+func rejectWhileDraining(draining *atomic.Bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.Path() {
+			case "/health", "/metrics":
+				return next(c)
+			}
+			if draining.Load() {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "Server is shutting down")
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageNotReady(t *testing.T) {
+	// storage not initialized yet has no ring to locate objects on
+	s := storage.NewDistributedStorage(storage.NewStaticDiscoverer(nil), &storage.DistributedConfig{})
+	e := NewServer(s, &Config{})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/object/1", nil),
+		httptest.NewRequest(http.MethodPut, "/object/1", strings.NewReader("content")),
+		httptest.NewRequest(http.MethodGet, "/objects", nil),
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code, req.Method+" "+req.URL.Path)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestRejectWhileDraining(t *testing.T) {
+	e := NewServer(&MockStorage{objects: map[string]*storage.Object{"1": {ID: "1", Content: []byte("content")}}}, &Config{})
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	assert.Equal(t, http.StatusOK, serve("/object/1").Code)
+
+	// once shutdown starts, new requests are rejected, except health probe
+	assert.NoError(t, e.Shutdown(context.Background()))
+	assert.Eventually(t, func() bool { return serve("/object/1").Code == http.StatusServiceUnavailable }, time.Second, 5*time.Millisecond)
+	rec := serve("/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, serve("/health").Code)
+}
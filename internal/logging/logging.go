@@ -0,0 +1,55 @@
+// Package logging provides structured loggers correlating records of a request by its ID.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// RequestIDKey is the key request ID is logged under.
+const RequestIDKey = "request_id"
+
+type requestIDKey struct{}
+
+// WithRequestID returns context carrying request ID, which is added to records logged with it.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns request ID carried by ctx, or empty string if there's none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds request ID carried by record context to the record.
+type contextHandler struct {
+	slog.Handler
+}
+
+// NewHandler wraps handler to add request ID of record context to records, so records logged by storage
+// on behalf of a request can be correlated with it.
+func NewHandler(handler slog.Handler) slog.Handler {
+	return contextHandler{Handler: handler}
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// New returns logger writing human readable key=value records of given minimum level to w.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(NewHandler(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})))
+}
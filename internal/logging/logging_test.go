@@ -0,0 +1,34 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, RequestID(ctx))
+	assert.Equal(t, "abc", RequestID(WithRequestID(ctx, "abc")))
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo).With("component", "test")
+
+	logger.InfoContext(WithRequestID(context.Background(), "abc"), "object stored", "object_id", "object-1")
+	assert.Contains(t, buf.String(), `msg="object stored" component=test object_id=object-1 request_id=abc`)
+
+	// records logged without request are left as they are
+	buf.Reset()
+	logger.InfoContext(context.Background(), "node added")
+	assert.NotContains(t, buf.String(), RequestIDKey)
+
+	// records below minimum level are dropped
+	buf.Reset()
+	logger.Debug("object located")
+	assert.Empty(t, buf.String())
+}
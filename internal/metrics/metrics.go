@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds Prometheus collectors of the gateway and its storage.
+// Methods are no-ops on nil Metrics, so instrumentation can be disabled by passing nil.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	nodeErrors      *prometheus.CounterVec
+	nodeReauth      *prometheus.CounterVec
+}
+
+// New creates Metrics with collectors registered in their own registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_requests_total",
+			Help: "Total number of object requests by operation.",
+		}, []string{"operation"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gateway_request_duration_seconds",
+			Help:    "Duration of object requests by operation and HTTP status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation", "status"}),
+		nodeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "storage_node_errors_total",
+			Help: "Total number of failed storage node operations by node and operation.",
+		}, []string{"node", "operation"}),
+		nodeReauth: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "node_reauth_total",
+			Help: "Total number of storage node re-authentications with rotated credentials by node and result.",
+		}, []string{"node", "result"}),
+	}
+	m.registry.MustRegister(m.requests, m.requestDuration, m.nodeErrors, m.nodeReauth)
+	return m
+}
+
+// Registry returns the registry collectors are registered in.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler returns HTTP handler exposing metrics in Prometheus format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a handled object request.
+func (m *Metrics) ObserveRequest(operation string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(operation).Inc()
+	m.requestDuration.WithLabelValues(operation, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// NodeError records a failed operation of the storage node with given key.
+func (m *Metrics) NodeError(node, operation string) {
+	if m == nil {
+		return
+	}
+	m.nodeErrors.WithLabelValues(node, operation).Inc()
+}
+
+// NodeReauth records re-authentication of the storage node with given key, with result "success" or "failure".
+func (m *Metrics) NodeReauth(node, result string) {
+	if m == nil {
+		return
+	}
+	m.nodeReauth.WithLabelValues(node, result).Inc()
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	m.ObserveRequest("get", http.StatusOK, 10*time.Millisecond)
+	m.ObserveRequest("get", http.StatusNotFound, 10*time.Millisecond)
+	m.ObserveRequest("put", http.StatusOK, 10*time.Millisecond)
+	m.NodeError("node1#1", "put")
+	m.NodeReauth("node1#1", "success")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("get")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("put")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.requestDuration))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.nodeErrors.WithLabelValues("node1#1", "put")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.nodeReauth.WithLabelValues("node1#1", "success")))
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `gateway_requests_total{operation="get"} 2`)
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *Metrics
+	assert.NotPanics(t, func() {
+		m.ObserveRequest("get", http.StatusOK, time.Millisecond)
+		m.NodeError("node1#1", "get")
+		m.NodeReauth("node1#1", "failure")
+	})
+}
@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultBreakerCooldown is the default time an open circuit rejects node operations.
+const DefaultBreakerCooldown = 10 * time.Second
+
+// ErrCircuitOpen is returned without contacting the node while its circuit breaker is open.
+var ErrCircuitOpen = errors.New("node circuit breaker open")
+
+// BreakerConfig configures circuit breakers of storage nodes.
+type BreakerConfig struct {
+	// Threshold is the number of consecutive node failures opening the circuit. Zero disables breakers.
+	Threshold int
+	// Cooldown is how long an open circuit rejects operations before letting one through to probe
+	// the node. Defaults to DefaultBreakerCooldown.
+	Cooldown time.Duration
+}
+
+// BreakerState is the state of a node circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed lets operations through to the node.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects operations without contacting the node.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single operation through to probe whether the node recovered.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// breakerStorage guards node storage with a circuit breaker, so operations on a dead node fail fast
+// instead of each waiting for connection timeout, and replicas are tried right away. Only node failures
+// (network and server errors) count; missing objects, rejected requests and cancelled contexts don't.
+type breakerStorage struct {
+	Storage
+	threshold int
+	cooldown  time.Duration
+	clock     Clock
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+}
+
+func newBreakerStorage(storage Storage, cfg BreakerConfig, clock Clock) *breakerStorage {
+	cooldown := cfg.Cooldown
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return &breakerStorage{Storage: storage, threshold: cfg.Threshold, cooldown: cooldown, clock: clock, state: BreakerClosed}
+}
+
+// State returns the current breaker state. Open circuit whose cooldown elapsed is reported half-open,
+// as the next operation probes the node.
+func (b *breakerStorage) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && !b.clock.Now().Before(b.openedAt.Add(b.cooldown)) {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether operation may contact the node. Once cooldown of open circuit elapses, a single
+// operation is let through as a probe; others keep failing fast until it completes.
+func (b *breakerStorage) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.clock.Now().Before(b.openedAt.Add(b.cooldown)) {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		return nil
+	case BreakerHalfOpen:
+		// probe in flight
+		return ErrCircuitOpen
+	default:
+		return nil
+	}
+}
+
+// record updates breaker with outcome of operation let through by allow.
+func (b *breakerStorage) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case retryable(err):
+		b.failures++
+		if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+			b.state = BreakerOpen
+			b.openedAt = b.clock.Now()
+		}
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		// operation abandoned by the caller tells nothing about the node; if it was the probe,
+		// the next operation probes the node instead
+		if b.state == BreakerHalfOpen {
+			b.state = BreakerOpen
+		}
+	default:
+		// node responded
+		b.state = BreakerClosed
+		b.failures = 0
+	}
+}
+
+// do runs operation on the node if the breaker allows it.
+func (b *breakerStorage) do(op func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := op()
+	b.record(err)
+	return err
+}
+
+func (b *breakerStorage) Put(ctx context.Context, object *Object) error {
+	return b.do(func() error { return b.Storage.Put(ctx, object) })
+}
+
+func (b *breakerStorage) Get(ctx context.Context, id string) (object *Object, err error) {
+	err = b.do(func() error {
+		object, err = b.Storage.Get(ctx, id)
+		return err
+	})
+	return object, err
+}
+
+func (b *breakerStorage) Delete(ctx context.Context, id string) error {
+	return b.do(func() error { return b.Storage.Delete(ctx, id) })
+}
+
+func (b *breakerStorage) Copy(ctx context.Context, srcID, dstID string) error {
+	return b.do(func() error { return b.Storage.Copy(ctx, srcID, dstID) })
+}
+
+func (b *breakerStorage) PutStream(ctx context.Context, object *ObjectStream) error {
+	return b.do(func() error { return b.Storage.PutStream(ctx, object) })
+}
+
+func (b *breakerStorage) GetStream(ctx context.Context, id string) (object *ObjectStream, err error) {
+	err = b.do(func() error {
+		object, err = b.Storage.GetStream(ctx, id)
+		return err
+	})
+	return object, err
+}
+
+func (b *breakerStorage) GetRange(ctx context.Context, id string, offset, length int64) (object *ObjectStream, err error) {
+	err = b.do(func() error {
+		object, err = b.Storage.GetRange(ctx, id, offset, length)
+		return err
+	})
+	return object, err
+}
+
+func (b *breakerStorage) Stat(ctx context.Context, id string) (info *ObjectInfo, err error) {
+	err = b.do(func() error {
+		info, err = b.Storage.Stat(ctx, id)
+		return err
+	})
+	return info, err
+}
+
+func (b *breakerStorage) Ping(ctx context.Context) error {
+	return b.do(func() error { return b.Storage.Ping(ctx) })
+}
+
+func (b *breakerStorage) List(ctx context.Context, prefix string) (ids []string, err error) {
+	err = b.do(func() error {
+		ids, err = b.Storage.List(ctx, prefix)
+		return err
+	})
+	return ids, err
+}
+
+func (b *breakerStorage) Usage(ctx context.Context) (usage NodeUsage, err error) {
+	ur, ok := b.Storage.(usageReporter)
+	if !ok {
+		return NodeUsage{}, errors.New("storage doesn't report usage")
+	}
+	err = b.do(func() error {
+		usage, err = ur.Usage(ctx)
+		return err
+	})
+	return usage, err
+}
+
+func (b *breakerStorage) SweepExpired(ctx context.Context) (deleted int, err error) {
+	sweeper, ok := b.Storage.(expirySweeper)
+	if !ok {
+		return 0, errors.New("storage doesn't sweep expired objects")
+	}
+	err = b.do(func() error {
+		deleted, err = sweeper.SweepExpired(ctx)
+		return err
+	})
+	return deleted, err
+}
+
+func (b *breakerStorage) SweepBlobs(ctx context.Context) (deleted int, err error) {
+	sweeper, ok := b.Storage.(blobSweeper)
+	if !ok {
+		return 0, errors.New("storage doesn't sweep blobs")
+	}
+	err = b.do(func() error {
+		deleted, err = sweeper.SweepBlobs(ctx)
+		return err
+	})
+	return deleted, err
+}
+
+func (b *breakerStorage) PresignedURL(ctx context.Context, id string, op PresignOperation, expiry time.Duration) (u *url.URL, err error) {
+	p, ok := b.Storage.(presigner)
+	if !ok {
+		return nil, errors.New("storage doesn't presign URLs")
+	}
+	err = b.do(func() error {
+		u, err = p.PresignedURL(ctx, id, op, expiry)
+		return err
+	})
+	return u, err
+}
+
+// CloseIdleConnections closes idle connections of the guarded storage, if it holds any.
+func (b *breakerStorage) CloseIdleConnections() {
+	if closer, ok := b.Storage.(idleConnectionCloser); ok {
+		closer.CloseIdleConnections()
+	}
+}
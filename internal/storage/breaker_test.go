@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBreakerStorage_Transitions(t *testing.T) {
+	unreachable := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	object := &Object{ID: "object-1", Content: []byte("data1")}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	node := new(MockStorage)
+	breaker := newBreakerStorage(node, BreakerConfig{Threshold: 2, Cooldown: time.Minute}, ClockFunc(func() time.Time { return now }))
+	assert.Equal(t, BreakerClosed, breaker.State())
+
+	// failures below threshold keep the circuit closed
+	node.On("Get", mock.Anything, "object-1").Return((*Object)(nil), unreachable).Twice()
+	_, err := breaker.Get(context.TODO(), "object-1")
+	assert.ErrorIs(t, err, unreachable)
+	assert.Equal(t, BreakerClosed, breaker.State())
+
+	// threshold reached
+	_, err = breaker.Get(context.TODO(), "object-1")
+	assert.ErrorIs(t, err, unreachable)
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	// open circuit fails fast without contacting the node
+	_, err = breaker.Get(context.TODO(), "object-1")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	node.AssertNumberOfCalls(t, "Get", 2)
+
+	// failed probe opens the circuit for another cooldown
+	now = now.Add(time.Minute)
+	assert.Equal(t, BreakerHalfOpen, breaker.State())
+	node.On("Get", mock.Anything, "object-1").Return((*Object)(nil), unreachable).Once()
+	_, err = breaker.Get(context.TODO(), "object-1")
+	assert.ErrorIs(t, err, unreachable)
+	assert.Equal(t, BreakerOpen, breaker.State())
+	now = now.Add(time.Minute - time.Second)
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	// successful probe closes the circuit
+	now = now.Add(time.Second)
+	assert.Equal(t, BreakerHalfOpen, breaker.State())
+	node.On("Get", mock.Anything, "object-1").Return(object, nil)
+	obj, err := breaker.Get(context.TODO(), "object-1")
+	assert.NoError(t, err)
+	assert.Equal(t, object, obj)
+	assert.Equal(t, BreakerClosed, breaker.State())
+
+	// failures are counted again from zero
+	node.On("Ping", mock.Anything).Return(unreachable)
+	assert.ErrorIs(t, breaker.Ping(context.TODO()), unreachable)
+	assert.Equal(t, BreakerClosed, breaker.State())
+	node.AssertNumberOfCalls(t, "Get", 4)
+}
+
+func TestBreakerStorage_SingleProbe(t *testing.T) {
+	unreachable := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	node := new(MockStorage)
+	breaker := newBreakerStorage(node, BreakerConfig{Threshold: 1}, ClockFunc(func() time.Time { return now }))
+	node.On("Stat", mock.Anything, "object-1").Return((*ObjectInfo)(nil), unreachable).Once()
+	_, err := breaker.Stat(context.TODO(), "object-1")
+	assert.ErrorIs(t, err, unreachable)
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	// default cooldown applies; operations arriving while the probe is in flight fail fast
+	now = now.Add(DefaultBreakerCooldown)
+	probing := make(chan struct{})
+	release := make(chan struct{})
+	node.On("Stat", mock.Anything, "object-1").Return(&ObjectInfo{ID: "object-1"}, nil).Run(func(mock.Arguments) {
+		close(probing)
+		<-release
+	}).Once()
+	done := make(chan error)
+	go func() {
+		_, err := breaker.Stat(context.TODO(), "object-1")
+		done <- err
+	}()
+
+	<-probing
+	_, err = breaker.Stat(context.TODO(), "object-1")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestBreakerStorage_IgnoresNonNodeFailures(t *testing.T) {
+	unreachable := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "missing key", err: minio.ErrorResponse{Code: "NoSuchKey", StatusCode: 404}},
+		{name: "access denied", err: minio.ErrorResponse{Code: "AccessDenied", StatusCode: 403}},
+		{name: "cancelled", err: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := new(MockStorage)
+			breaker := newBreakerStorage(node, BreakerConfig{Threshold: 2}, SystemClock)
+			node.On("Delete", mock.Anything, "object-1").Return(unreachable).Once()
+			node.On("Delete", mock.Anything, "object-1").Return(tt.err).Once()
+			node.On("Delete", mock.Anything, "object-1").Return(unreachable).Once()
+
+			for i := 0; i < 3; i++ {
+				assert.Error(t, breaker.Delete(context.TODO(), "object-1"))
+			}
+			if errors.Is(tt.err, context.Canceled) {
+				// cancelled operation doesn't reset failures either
+				assert.Equal(t, BreakerOpen, breaker.State())
+				return
+			}
+			assert.Equal(t, BreakerClosed, breaker.State())
+		})
+	}
+}
+
+func TestDistributedStorage_BreakerFailover(t *testing.T) {
+	unreachable := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	found := &Object{ID: "object-1", Content: []byte("data1")}
+
+	ds, storages := createReplicatedStorage(2)
+	nodes := mustReplicas(t, ds, "object-1")
+	primary := storages[ringKey(nodes[0])]
+	primary.On("Init", mock.Anything).Return(nil)
+	primary.On("Get", mock.Anything, "object-1").Return((*Object)(nil), unreachable)
+	storages[ringKey(nodes[1])].On("Get", mock.Anything, "object-1").Return(found, nil)
+
+	ds.breakerConfig = BreakerConfig{Threshold: 1}
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) { return primary, nil }
+	storage, err := ds.initStorageNode(context.TODO(), nodes[0])
+	assert.NoError(t, err)
+	ds.availableStorages[ringKey(nodes[0])] = storage
+	assert.Equal(t, map[string]BreakerState{ringKey(nodes[0]): BreakerClosed}, ds.BreakerStates())
+
+	for i := 0; i < 3; i++ {
+		obj, err := ds.Get(context.TODO(), "object-1")
+		assert.NoError(t, err)
+		assert.Equal(t, found, obj)
+	}
+	// node is contacted only until its circuit opens
+	primary.AssertNumberOfCalls(t, "Get", 1)
+	assert.Equal(t, map[string]BreakerState{ringKey(nodes[0]): BreakerOpen}, ds.BreakerStates())
+}
+
+func TestBreakerStorage_CloseIdleConnections(t *testing.T) {
+	// idle connections of the guarded storage are closed, such as of storage replaced by a credential refresh
+	node := &closingStorage{MockStorage: new(MockStorage)}
+	breaker := newBreakerStorage(node, BreakerConfig{Threshold: 2, Cooldown: time.Minute}, ClockFunc(time.Now))
+	breaker.CloseIdleConnections()
+	assert.True(t, node.closed)
+
+	// storage without connections is left alone
+	breaker = newBreakerStorage(new(MockStorage), BreakerConfig{Threshold: 2, Cooldown: time.Minute}, ClockFunc(time.Now))
+	assert.NotPanics(t, breaker.CloseIdleConnections)
+}
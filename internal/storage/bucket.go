@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+)
+
+// ErrInvalidBucketName is returned for bucket names not following S3 bucket naming rules.
+var ErrInvalidBucketName = errors.New("invalid bucket name")
+
+type bucketKey struct{}
+
+// WithBucket returns context making storage operations performed with it use the bucket instead of the configured
+// one. Buckets are created on the nodes by the first write into them.
+func WithBucket(ctx context.Context, bucket string) context.Context {
+	return context.WithValue(ctx, bucketKey{}, bucket)
+}
+
+// BucketFromContext returns bucket carried by ctx, or empty string if operations use the configured bucket.
+func BucketFromContext(ctx context.Context) string {
+	bucket, _ := ctx.Value(bucketKey{}).(string)
+	return bucket
+}
+
+// ValidateBucketName checks that name follows S3 bucket naming rules: 3 to 63 lowercase letters, digits, dots
+// and hyphens, starting and ending with a letter or digit, without adjacent dots and not formatted as IP address.
+func ValidateBucketName(name string) error {
+	if err := s3utils.CheckValidBucketNameStrict(name); err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidBucketName, name, err)
+	}
+	return nil
+}
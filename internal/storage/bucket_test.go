@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, BucketFromContext(ctx))
+	assert.Equal(t, "photos", BucketFromContext(WithBucket(ctx, "photos")))
+}
+
+func TestValidateBucketName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "photos", valid: true},
+		{name: "tenant-1.photos", valid: true},
+		{name: "abc", valid: true},
+		{name: strings.Repeat("a", 63), valid: true},
+		{name: ""},
+		{name: "ab"},
+		{name: strings.Repeat("a", 64)},
+		{name: "Photos"},
+		{name: "photos_2024"},
+		{name: "tenant..photos"},
+		{name: "tenant.-photos"},
+		{name: "-photos"},
+		{name: "photos."},
+		{name: "192.168.1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBucketName(tt.name)
+			if tt.valid {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidBucketName)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package storage
+
+import "time"
+
+// DefaultClockSkew is the tolerance applied when comparing timestamps that may
+// have been produced by different hosts (the gateway and the minio nodes).
+// Clocks are expected to be NTP-synchronized, but small drift between them must
+// not cause spurious conflicts in conditional or expiry logic.
+const DefaultClockSkew = time.Second
+
+// Clock abstracts time retrieval so that time-dependent features (expiry,
+// conditional requests, last-writer-wins) can be driven deterministically in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a plain function to the Clock interface.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock is the Clock backed by the local wall clock.
+var SystemClock Clock = ClockFunc(time.Now)
+
+// AfterWithSkew reports whether t is after u by more than the given skew tolerance.
+// Timestamps within the tolerance window are treated as concurrent, not ordered.
+func AfterWithSkew(t, u time.Time, skew time.Duration) bool {
+	return t.Sub(u) > skew
+}
+
+// BeforeWithSkew reports whether t is before u by more than the given skew tolerance.
+func BeforeWithSkew(t, u time.Time, skew time.Duration) bool {
+	return u.Sub(t) > skew
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClockFunc(t *testing.T) {
+	fixed := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := ClockFunc(func() time.Time { return fixed })
+
+	assert.Equal(t, fixed, clock.Now())
+}
+
+func TestSkewComparisons(t *testing.T) {
+	base := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		t      time.Time
+		after  bool
+		before bool
+	}{
+		{name: "identical", t: base},
+		{name: "ahead within skew", t: base.Add(500 * time.Millisecond)},
+		{name: "behind within skew", t: base.Add(-500 * time.Millisecond)},
+		{name: "ahead exactly at skew", t: base.Add(DefaultClockSkew)},
+		{name: "ahead beyond skew", t: base.Add(2 * time.Second), after: true},
+		{name: "behind beyond skew", t: base.Add(-2 * time.Second), before: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.after, AfterWithSkew(tt.t, base, DefaultClockSkew))
+			assert.Equal(t, tt.before, BeforeWithSkew(tt.t, base, DefaultClockSkew))
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// ContentEncodingGzip is the content encoding of objects stored gzipped.
+	ContentEncodingGzip = "gzip"
+	// uncompressedSizeMetadataKey is the user metadata key original size of compressed objects is stored under.
+	uncompressedSizeMetadataKey = "Uncompressed-Size"
+	// compressedPartSize is the part size of compressed stream uploads. Their compressed size isn't known upfront,
+	// and minio would otherwise buffer parts sized for the largest possible object.
+	compressedPartSize = 16 << 20
+)
+
+// DefaultCompressibleTypes are content types compressed by default. Types ending with "/" match any subtype.
+var DefaultCompressibleTypes = []string{"text/", "application/json", "application/xml", "application/javascript", "image/svg+xml"}
+
+// incompressibleTypes hold already compressed content, which is stored as it is, even if allowed for compression.
+var incompressibleTypes = map[string]bool{
+	"image/jpeg":                   true,
+	"image/png":                    true,
+	"image/gif":                    true,
+	"image/webp":                   true,
+	"video/mp4":                    true,
+	"audio/mpeg":                   true,
+	"application/zip":              true,
+	"application/gzip":             true,
+	"application/x-gzip":           true,
+	"application/zstd":             true,
+	"application/x-7z-compressed":  true,
+	"application/x-rar-compressed": true,
+	"application/pdf":              true,
+}
+
+// CompressionConfig configures gzip compression of object content stored on nodes.
+type CompressionConfig struct {
+	// Enabled gzips content of objects with compressible content type before storing it. Checksum and size
+	// reported for compressed objects are the ones of the original content.
+	Enabled bool
+	// ContentTypes are the compressible content types. Types ending with "/" match any subtype (e.g. "text/").
+	// Defaults to DefaultCompressibleTypes.
+	ContentTypes []string
+	// PassThrough makes reads of whole objects return content still compressed, with ContentEncoding set,
+	// so clients decompress it. Ranges are always decompressed.
+	PassThrough bool
+}
+
+// compressible checks if content of the content type is gzipped before storing it.
+func (c CompressionConfig) compressible(contentType string) bool {
+	if !c.Enabled {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || incompressibleTypes[mediaType] {
+		return false
+	}
+	types := c.ContentTypes
+	if len(types) == 0 {
+		types = DefaultCompressibleTypes
+	}
+	for _, allowed := range types {
+		allowed = strings.ToLower(allowed)
+		if mediaType == allowed || strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipContent returns gzipped content.
+func gzipContent(content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(content); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gunzipContent returns content of gzipped data.
+func gunzipContent(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(zr)
+}
+
+// gzipStream returns reader of gzipped content, compressed as it's read. Closing it stops the compression.
+func gzipStream(content io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, content)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// gunzipReader decompresses gzipped content, closing it on Close.
+type gunzipReader struct {
+	*gzip.Reader
+	content io.Closer
+}
+
+func newGunzipReader(content io.ReadCloser) (io.ReadCloser, error) {
+	zr, err := gzip.NewReader(content)
+	if err != nil {
+		content.Close()
+		return nil, err
+	}
+	return &gunzipReader{Reader: zr, content: content}, nil
+}
+
+func (r *gunzipReader) Close() error {
+	r.Reader.Close()
+	return r.content.Close()
+}
+
+// decompressStream makes object content passed through compressed stream decompressed, so it can be stored
+// again. Its original size isn't known, so the size becomes unknown.
+func decompressStream(object *ObjectStream) error {
+	if object.ContentEncoding == "" {
+		return nil
+	}
+	content, err := newGunzipReader(object.Content)
+	if err != nil {
+		return fmt.Errorf("unable to decompress content: %w", err)
+	}
+	object.Content, object.Size, object.ContentEncoding = content, -1, ""
+	return nil
+}
+
+// setCompressed sets upload options of gzipped content with the original size.
+func setCompressed(opts *minio.PutObjectOptions, size int64) {
+	opts.ContentEncoding = ContentEncodingGzip
+	opts.UserMetadata[uncompressedSizeMetadataKey] = strconv.FormatInt(size, 10)
+	opts.PartSize = compressedPartSize
+}
+
+// compressed checks if object content is stored gzipped.
+func compressed(info minio.ObjectInfo) bool {
+	return info.Metadata.Get("Content-Encoding") == ContentEncodingGzip
+}
+
+// uncompressedSize returns original size of object content: the stored size, unless it's compressed.
+func uncompressedSize(info minio.ObjectInfo) (int64, error) {
+	if !compressed(info) {
+		return info.Size, nil
+	}
+	size, err := strconv.ParseInt(info.UserMetadata[uncompressedSizeMetadataKey], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid uncompressed size of compressed object: %w", err)
+	}
+	return size, nil
+}
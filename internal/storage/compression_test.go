@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressionConfig_Compressible(t *testing.T) {
+	tests := []struct {
+		cfg         CompressionConfig
+		contentType string
+		expected    bool
+	}{
+		{cfg: CompressionConfig{}, contentType: "text/plain", expected: false},
+		{cfg: CompressionConfig{Enabled: true}, contentType: "text/plain", expected: true},
+		{cfg: CompressionConfig{Enabled: true}, contentType: "text/csv; charset=utf-8", expected: true},
+		{cfg: CompressionConfig{Enabled: true}, contentType: "application/json", expected: true},
+		{cfg: CompressionConfig{Enabled: true}, contentType: "application/octet-stream", expected: false},
+		{cfg: CompressionConfig{Enabled: true}, contentType: "invalid;", expected: false},
+		{cfg: CompressionConfig{Enabled: true, ContentTypes: []string{"Application/Wasm"}}, contentType: "application/wasm", expected: true},
+		{cfg: CompressionConfig{Enabled: true, ContentTypes: []string{"Application/Wasm"}}, contentType: "text/plain", expected: false},
+		// already compressed types are skipped even if allowed
+		{cfg: CompressionConfig{Enabled: true, ContentTypes: []string{"image/", "application/zip"}}, contentType: "image/jpeg", expected: false},
+		{cfg: CompressionConfig{Enabled: true, ContentTypes: []string{"image/", "application/zip"}}, contentType: "application/zip", expected: false},
+		{cfg: CompressionConfig{Enabled: true, ContentTypes: []string{"image/", "application/zip"}}, contentType: "image/bmp", expected: true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.cfg.compressible(tt.contentType), "%+v %s", tt.cfg, tt.contentType)
+	}
+}
+
+// readPayload returns payload of request body, which is signed in chunks over plain HTTP
+// ("<hex size>;chunk-signature=<signature>\r\n<data>\r\n", ending with a zero sized chunk).
+func readPayload(r *http.Request) []byte {
+	body, _ := io.ReadAll(r.Body)
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return body
+	}
+	var payload []byte
+	for len(body) > 0 {
+		header, rest, _ := bytes.Cut(body, []byte("\r\n"))
+		hexSize, _, _ := strings.Cut(string(header), ";")
+		size, err := strconv.ParseInt(hexSize, 16, 64)
+		if err != nil || size == 0 || int64(len(rest)) < size+2 {
+			break
+		}
+		payload = append(payload, rest[:size]...)
+		body = rest[size+2:]
+	}
+	return payload
+}
+
+// copyObjectResult is the response of fake minio nodes to server-side copies.
+const copyObjectResult = `<CopyObjectResult><ETag>"etag"</ETag><LastModified>2023-10-01T12:00:00.000Z</LastModified></CopyObjectResult>`
+
+// compressionNode is a fake minio node keeping stored bytes and headers of uploaded objects,
+// uploaded either at once or in multiple parts.
+type compressionNode struct {
+	mu      sync.Mutex
+	stored  map[string][]byte
+	headers map[string]http.Header
+}
+
+func (n *compressionNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	query := r.URL.Query()
+
+	storeHeaders := func() {
+		stored := http.Header{}
+		for key, values := range r.Header {
+			if strings.HasPrefix(key, "X-Amz-Meta-") {
+				stored[key] = values
+			}
+		}
+		stored.Set("Content-Type", r.Header.Get("Content-Type"))
+		// chunked signing encoding isn't stored, as by minio
+		var encodings []string
+		for _, encoding := range strings.Split(r.Header.Get("Content-Encoding"), ",") {
+			if encoding = strings.TrimSpace(encoding); encoding != "" && encoding != "aws-chunked" {
+				encodings = append(encodings, encoding)
+			}
+		}
+		if len(encodings) > 0 {
+			stored.Set("Content-Encoding", strings.Join(encodings, ","))
+		}
+		n.headers[id] = stored
+	}
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		storeHeaders()
+		n.stored[id] = nil
+		_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><Bucket>default</Bucket><Key>` + id + `</Key><UploadId>upload</UploadId></InitiateMultipartUploadResult>`))
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		n.stored[id] = append(n.stored[id], readPayload(r)...)
+		w.Header().Set("ETag", `"part"`)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write([]byte(`<CompleteMultipartUploadResult><Bucket>default</Bucket><Key>` + id + `</Key><ETag>"etag"</ETag></CompleteMultipartUploadResult>`))
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		// objects are copied onto themselves only, replacing their metadata
+		storeHeaders()
+		_, _ = io.WriteString(w, copyObjectResult)
+	case r.Method == http.MethodPut:
+		storeHeaders()
+		n.stored[id] = readPayload(r)
+		w.Header().Set("ETag", `"etag"`)
+	default:
+		for key, values := range n.headers[id] {
+			w.Header()[key] = values
+		}
+		w.Header().Set("ETag", `"etag"`)
+		// content length of encoded content isn't set by ServeContent
+		if r.Header.Get("Range") == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(n.stored[id])))
+		}
+		http.ServeContent(w, r, id, time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC), bytes.NewReader(n.stored[id]))
+	}
+}
+
+func (n *compressionNode) object(id string) ([]byte, string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.stored[id], n.headers[id].Get("Content-Encoding")
+}
+
+func TestMinioStorage_Compression(t *testing.T) {
+	node := &compressionNode{stored: map[string][]byte{}, headers: map[string]http.Header{}}
+	server := httptest.NewServer(node)
+	defer server.Close()
+	newStorage := func(cfg CompressionConfig) Storage {
+		s, err := NewMinioStorage(&MinioConfig{
+			Endpoint:            strings.TrimPrefix(server.URL, "http://"),
+			AccessKey:           "key",
+			SecretKey:           "secret",
+			BucketName:          "default",
+			Region:              "us-east-1",
+			VerifyContentLength: true,
+			Compression:         cfg,
+		})
+		assert.NoError(t, err)
+		return s
+	}
+	s := newStorage(CompressionConfig{Enabled: true})
+	content := []byte(strings.Repeat("compressible content ", 1000))
+	readStream := func(object *ObjectStream) []byte {
+		defer object.Content.Close()
+		read, err := io.ReadAll(object.Content)
+		assert.NoError(t, err)
+		return read
+	}
+
+	// compressible content is stored gzipped, and read back as it was
+	err := s.Put(context.TODO(), &Object{ID: "text", ContentType: "text/plain", Content: content})
+	assert.NoError(t, err)
+	err = s.PutStream(context.TODO(), &ObjectStream{ID: "stream", ContentType: "application/json", Size: int64(len(content)), Content: io.NopCloser(bytes.NewReader(content))})
+	assert.NoError(t, err)
+	for _, id := range []string{"text", "stream"} {
+		stored, encoding := node.object(id)
+		assert.Less(t, len(stored), len(content), id)
+		assert.Equal(t, ContentEncodingGzip, encoding, id)
+	}
+
+	obj, err := s.Get(context.TODO(), "text")
+	if assert.NoError(t, err) {
+		assert.Equal(t, content, obj.Content)
+		assert.Empty(t, obj.ContentEncoding)
+		// checksum is of the original content
+		assert.Equal(t, contentChecksum(content), obj.Checksum)
+		assert.Nil(t, obj.Metadata)
+	}
+	stream, err := s.GetStream(context.TODO(), "stream")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(len(content)), stream.Size)
+		assert.Equal(t, content, readStream(stream))
+	}
+	info, err := s.Stat(context.TODO(), "text")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(len(content)), info.Size)
+	}
+
+	// ranges are of the original content, even beyond the compressed size
+	for _, offset := range []int64{100, int64(len(content)) - 10} {
+		stream, err = s.GetRange(context.TODO(), "text", offset, 50)
+		if assert.NoError(t, err) {
+			end := min(offset+50, int64(len(content)))
+			assert.Equal(t, end-offset, stream.Size)
+			assert.Equal(t, content[offset:end], readStream(stream))
+		}
+	}
+	_, err = s.GetRange(context.TODO(), "text", int64(len(content)), 1)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+
+	// already compressed types and streams of unknown size are stored as they are
+	err = s.Put(context.TODO(), &Object{ID: "photo", ContentType: "image/jpeg", Content: content})
+	assert.NoError(t, err)
+	err = s.PutStream(context.TODO(), &ObjectStream{ID: "chunked", ContentType: "text/plain", Size: -1, Content: io.NopCloser(bytes.NewReader(content))})
+	assert.NoError(t, err)
+	for _, id := range []string{"photo", "chunked"} {
+		stored, encoding := node.object(id)
+		assert.Equal(t, content, stored, id)
+		assert.Empty(t, encoding, id)
+	}
+
+	// passed through content is returned compressed, for clients to decompress
+	passThrough := newStorage(CompressionConfig{Enabled: true, PassThrough: true})
+	stored, _ := node.object("text")
+	obj, err = passThrough.Get(context.TODO(), "text")
+	if assert.NoError(t, err) {
+		assert.Equal(t, stored, obj.Content)
+		assert.Equal(t, ContentEncodingGzip, obj.ContentEncoding)
+	}
+	stream, err = passThrough.GetStream(context.TODO(), "text")
+	if assert.NoError(t, err) {
+		assert.Equal(t, ContentEncodingGzip, stream.ContentEncoding)
+		assert.Equal(t, int64(len(stored)), stream.Size)
+		decompressed, err := gunzipContent(readStream(stream))
+		assert.NoError(t, err)
+		assert.Equal(t, content, decompressed)
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConsistencyLevel is the number of replicas an operation must succeed on: one, a majority or all of them.
+type ConsistencyLevel string
+
+const (
+	// ConsistencyOne requires a single replica. Writes are acknowledged once the first replica is written.
+	ConsistencyOne ConsistencyLevel = "one"
+	// ConsistencyQuorum requires a majority of replicas.
+	ConsistencyQuorum ConsistencyLevel = "quorum"
+	// ConsistencyAll requires all replicas.
+	ConsistencyAll ConsistencyLevel = "all"
+)
+
+// ErrConsistencyNotReached is returned when fewer replicas succeeded than the consistency level requires.
+var ErrConsistencyNotReached = errors.New("consistency level not reached")
+
+// ParseConsistencyLevel parses consistency level name, case insensitively. Empty name is ConsistencyOne.
+func ParseConsistencyLevel(name string) (ConsistencyLevel, error) {
+	switch level := ConsistencyLevel(strings.ToLower(name)); level {
+	case "":
+		return ConsistencyOne, nil
+	case ConsistencyOne, ConsistencyQuorum, ConsistencyAll:
+		return level, nil
+	default:
+		return "", fmt.Errorf("unknown consistency level %q, expected one, quorum or all", name)
+	}
+}
+
+// required returns the number of replicas out of given ones the level requires.
+func (l ConsistencyLevel) required(replicas int) int {
+	switch l {
+	case ConsistencyAll:
+		return replicas
+	case ConsistencyQuorum:
+		return replicas/2 + 1
+	default:
+		return min(1, replicas)
+	}
+}
+
+type consistencyKey struct{}
+
+// WithConsistency returns context making storage operations performed with it require the consistency level,
+// instead of the configured read or write consistency.
+func WithConsistency(ctx context.Context, level ConsistencyLevel) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, level)
+}
+
+// ConsistencyFromContext returns consistency level carried by ctx, or empty level if operations use the configured one.
+func ConsistencyFromContext(ctx context.Context) ConsistencyLevel {
+	level, _ := ctx.Value(consistencyKey{}).(ConsistencyLevel)
+	return level
+}
+
+// consistency returns consistency level of operation performed with ctx: the one carried by ctx, or the given default.
+func consistency(ctx context.Context, defaultLevel ConsistencyLevel) ConsistencyLevel {
+	if level := ConsistencyFromContext(ctx); level != "" {
+		return level
+	}
+	return defaultLevel
+}
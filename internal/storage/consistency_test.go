@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConsistencyLevel(t *testing.T) {
+	for name, expected := range map[string]ConsistencyLevel{
+		"":       ConsistencyOne,
+		"one":    ConsistencyOne,
+		"QUORUM": ConsistencyQuorum,
+		"All":    ConsistencyAll,
+	} {
+		level, err := ParseConsistencyLevel(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, level, name)
+	}
+
+	_, err := ParseConsistencyLevel("two")
+	assert.Error(t, err)
+}
+
+func TestConsistencyLevel_Required(t *testing.T) {
+	tests := []struct {
+		level    ConsistencyLevel
+		replicas int
+		expected int
+	}{
+		{level: ConsistencyOne, replicas: 3, expected: 1},
+		{level: ConsistencyQuorum, replicas: 1, expected: 1},
+		{level: ConsistencyQuorum, replicas: 2, expected: 2},
+		{level: ConsistencyQuorum, replicas: 3, expected: 2},
+		{level: ConsistencyQuorum, replicas: 4, expected: 3},
+		{level: ConsistencyAll, replicas: 3, expected: 3},
+		{level: "", replicas: 3, expected: 1},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.level.required(tt.replicas), "%s of %d", tt.level, tt.replicas)
+	}
+}
+
+func TestConsistency(t *testing.T) {
+	assert.Equal(t, ConsistencyQuorum, consistency(context.Background(), ConsistencyQuorum))
+	assert.Equal(t, ConsistencyAll, consistency(WithConsistency(context.Background(), ConsistencyAll), ConsistencyQuorum))
+}
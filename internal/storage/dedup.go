@@ -0,0 +1,364 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// blobMetadataKey is the user metadata key references to deduplicated content hold its checksum under.
+	blobMetadataKey = "Blob"
+	// blobSizeMetadataKey is the user metadata key references hold size of the deduplicated content under.
+	blobSizeMetadataKey = "Blob-Size"
+	// blobUploadPrefix prefixes keys streamed content is uploaded under, until its checksum is known.
+	blobUploadPrefix = "uploads/"
+	// blobBucketSuffix is appended to the node bucket name to get the default blob bucket name.
+	blobBucketSuffix = "-blobs"
+	// DefaultBlobRetention is the default age deduplicated content no object refers to is collected at.
+	DefaultBlobRetention = time.Hour
+)
+
+// ErrBrokenReference is returned when deduplicated content an object refers to can't be read.
+var ErrBrokenReference = errors.New("deduplicated content not found")
+
+// DedupConfig configures content-addressable deduplication of object content stored on nodes.
+type DedupConfig struct {
+	// Enabled stores content of objects once per node, in the blob bucket keyed by its checksum. Objects themselves
+	// become references to it, which reads follow transparently. Writes pay an extra lookup, and streamed uploads
+	// an extra server-side copy of content not stored yet.
+	Enabled bool
+	// BucketName is the bucket content is stored in. Defaults to the node bucket name with "-blobs" suffix.
+	BucketName string
+	// Retention is how long content no object refers to is kept before it's collected, so content whose reference
+	// is still being written isn't. Defaults to DefaultBlobRetention.
+	Retention time.Duration
+}
+
+// blobSweeper is implemented by node storages able to delete deduplicated content no object refers to.
+type blobSweeper interface {
+	SweepBlobs(ctx context.Context) (int, error)
+}
+
+// blobBucket returns name of the bucket deduplicated content is stored in.
+func (s *MinioStorage) blobBucket() string {
+	if s.cfg.Dedup.BucketName != "" {
+		return s.cfg.Dedup.BucketName
+	}
+	return s.bucketName + blobBucketSuffix
+}
+
+// blobRetention returns how long deduplicated content no object refers to is kept.
+func (s *MinioStorage) blobRetention() time.Duration {
+	if s.cfg.Dedup.Retention > 0 {
+		return s.cfg.Dedup.Retention
+	}
+	return DefaultBlobRetention
+}
+
+// blobContext returns context of operations on deduplicated content referred to by objects of ctx bucket.
+// Blobs are never references, so a reference within the blob bucket, which could form a cycle, is broken.
+func (s *MinioStorage) blobContext(ctx context.Context) (context.Context, error) {
+	if s.bucket(ctx) == s.blobBucket() {
+		return nil, fmt.Errorf("%w: reference within blob bucket %s", ErrBrokenReference, s.blobBucket())
+	}
+	return WithBucket(ctx, s.blobBucket()), nil
+}
+
+// blobReference returns checksum of deduplicated content the object refers to, or empty string if it's
+// stored as it is.
+func blobReference(info minio.ObjectInfo) string {
+	return info.UserMetadata[blobMetadataKey]
+}
+
+// objectSize returns original size of object content, which is the size of content it refers to for references.
+func objectSize(info minio.ObjectInfo) (int64, error) {
+	if blobReference(info) == "" {
+		return uncompressedSize(info)
+	}
+	size, err := strconv.ParseInt(info.UserMetadata[blobSizeMetadataKey], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size of deduplicated content: %w", err)
+	}
+	return size, nil
+}
+
+// putDeduplicated stores object content in the blob bucket unless identical content is stored already,
+// and the object as a reference to it.
+func (s *MinioStorage) putDeduplicated(ctx context.Context, object *Object) error {
+	blobCtx, err := s.blobContext(ctx)
+	if err != nil {
+		return fmt.Errorf("error put object (%s | %s): %w", s.endpoint, object.ID, err)
+	}
+	checksum := contentChecksum(object.Content)
+	exists, err := s.blobExists(blobCtx, checksum)
+	if err != nil {
+		return fmt.Errorf("error put object (%s | %s): %w", s.endpoint, object.ID, err)
+	}
+	if !exists {
+		if err := s.put(blobCtx, &Object{ID: checksum, ContentType: object.ContentType, Content: object.Content}); err != nil {
+			return err
+		}
+	}
+	return s.putReference(ctx, &ObjectInfo{
+		ID:          object.ID,
+		ContentType: object.ContentType,
+		Size:        int64(len(object.Content)),
+		Metadata:    object.Metadata,
+		ExpiresAt:   object.ExpiresAt,
+	}, checksum)
+}
+
+// putStreamDeduplicated uploads streamed content to the blob bucket under a temporary key, as its checksum
+// is known only once it's read. Content is then kept under its checksum unless identical content is stored
+// already, and the object is stored as a reference to it.
+func (s *MinioStorage) putStreamDeduplicated(ctx context.Context, object *ObjectStream) error {
+	blobCtx, err := s.blobContext(ctx)
+	if err != nil {
+		return fmt.Errorf("error put object stream (%s | %s): %w", s.endpoint, object.ID, err)
+	}
+	upload, err := blobUploadKey()
+	if err != nil {
+		return fmt.Errorf("error put object stream (%s | %s): %w", s.endpoint, object.ID, err)
+	}
+	content := &hashingReader{ReadCloser: object.Content, hash: sha256.New()}
+	_, _, err = s.putStream(blobCtx, &ObjectStream{ID: upload, ContentType: object.ContentType, Size: object.Size, Content: content})
+	if err != nil {
+		return err
+	}
+	// temporary upload is removed even if the request is cancelled meanwhile
+	defer func() {
+		cleanupCtx := context.WithoutCancel(blobCtx)
+		if err := s.client.RemoveObject(cleanupCtx, s.blobBucket(), upload, minio.RemoveObjectOptions{}); err != nil {
+			s.logger.WarnContext(cleanupCtx, "cannot remove uploaded blob", "object_id", object.ID, "upload", upload, "error", err)
+		}
+	}()
+
+	checksum := hex.EncodeToString(content.hash.Sum(nil))
+	exists, err := s.blobExists(blobCtx, checksum)
+	if err != nil {
+		return fmt.Errorf("error put object stream (%s | %s): %w", s.endpoint, object.ID, err)
+	}
+	if !exists {
+		_, err := s.client.CopyObject(blobCtx,
+			minio.CopyDestOptions{Bucket: s.blobBucket(), Object: checksum},
+			minio.CopySrcOptions{Bucket: s.blobBucket(), Object: upload})
+		if err != nil {
+			return fmt.Errorf("error put object stream (%s | %s): unable to store uploaded blob: %w", s.endpoint, object.ID, err)
+		}
+	}
+	return s.putReference(ctx, &ObjectInfo{
+		ID:          object.ID,
+		ContentType: object.ContentType,
+		Size:        content.read,
+		Metadata:    object.Metadata,
+		ExpiresAt:   object.ExpiresAt,
+	}, checksum)
+}
+
+// putReference stores object without content, referring to deduplicated content with the checksum.
+// Content type, metadata and expiry are of the object, not of the content, which may be shared by other objects.
+func (s *MinioStorage) putReference(ctx context.Context, object *ObjectInfo, checksum string) error {
+	if err := s.ensureBucket(ctx); err != nil {
+		return fmt.Errorf("error put object (%s | %s): %w", s.endpoint, object.ID, err)
+	}
+	opts := minio.PutObjectOptions{
+		ContentType:  s.contentType(object.ContentType),
+		UserMetadata: userMetadata(object.Metadata, checksum),
+	}
+	opts.UserMetadata[blobMetadataKey] = checksum
+	opts.UserMetadata[blobSizeMetadataKey] = strconv.FormatInt(object.Size, 10)
+	setExpiry(opts.UserMetadata, object.ExpiresAt)
+	err := retry(ctx, s.logger, s.cfg.Retry, func() error {
+		_, err := s.client.PutObject(ctx, s.bucket(ctx), s.objectKey(ctx, object.ID), bytes.NewReader(nil), 0, opts)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error put object (%s | %s): unable to store reference: %w", s.endpoint, object.ID, err)
+	}
+	return nil
+}
+
+// blobExists checks if deduplicated content with the checksum is stored already. Content of a blob bucket
+// not created yet is missing.
+func (s *MinioStorage) blobExists(blobCtx context.Context, checksum string) (bool, error) {
+	_, err := s.client.StatObject(blobCtx, s.blobBucket(), checksum, minio.StatObjectOptions{})
+	if err != nil {
+		if keyDoesNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to check blob %s: %w", checksum, err)
+	}
+	return true, nil
+}
+
+// getReferenced returns object referring to deduplicated content, with the content read from the blob bucket.
+func (s *MinioStorage) getReferenced(ctx context.Context, id string, info minio.ObjectInfo) (*Object, error) {
+	blobCtx, err := s.blobContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get object (%s | %s): %w", s.endpoint, id, err)
+	}
+	blob, err := s.get(blobCtx, blobReference(info))
+	if err != nil {
+		return nil, err
+	}
+	if blob == nil {
+		return nil, fmt.Errorf("error get object (%s | %s): %w: %s", s.endpoint, id, ErrBrokenReference, blobReference(info))
+	}
+	blob.ID, blob.ContentType, blob.Metadata, blob.LastModified = id, info.ContentType, objectMetadata(info), info.LastModified
+	blob.Checksum, blob.ETag, blob.ExpiresAt = info.UserMetadata[checksumMetadataKey], objectETag(info), objectExpiry(info)
+	return blob, nil
+}
+
+// getReferencedStream returns stream of object referring to deduplicated content, streamed from the blob bucket.
+func (s *MinioStorage) getReferencedStream(ctx context.Context, id string, info minio.ObjectInfo, passThrough bool) (*ObjectStream, error) {
+	blobCtx, err := s.blobContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get object stream (%s | %s): %w", s.endpoint, id, err)
+	}
+	blob, err := s.getStream(blobCtx, blobReference(info), passThrough)
+	if err != nil {
+		return nil, err
+	}
+	if blob == nil {
+		return nil, fmt.Errorf("error get object stream (%s | %s): %w: %s", s.endpoint, id, ErrBrokenReference, blobReference(info))
+	}
+	setReferenceInfo(blob, id, info)
+	return blob, nil
+}
+
+// getReferencedRange returns range of object referring to deduplicated content, read from the blob bucket.
+func (s *MinioStorage) getReferencedRange(ctx context.Context, id string, info minio.ObjectInfo, offset, length int64) (*ObjectStream, error) {
+	blobCtx, err := s.blobContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get object range (%s | %s): %w", s.endpoint, id, err)
+	}
+	blob, err := s.getRange(blobCtx, blobReference(info), offset, length)
+	if err != nil {
+		return nil, err
+	}
+	if blob == nil {
+		return nil, fmt.Errorf("error get object range (%s | %s): %w: %s", s.endpoint, id, ErrBrokenReference, blobReference(info))
+	}
+	setReferenceInfo(blob, id, info)
+	return blob, nil
+}
+
+// setReferenceInfo sets properties of the object referring to the streamed deduplicated content.
+func setReferenceInfo(blob *ObjectStream, id string, info minio.ObjectInfo) {
+	blob.ID, blob.ContentType, blob.Metadata = id, info.ContentType, objectMetadata(info)
+	blob.LastModified, blob.ETag, blob.ExpiresAt = info.LastModified, objectETag(info), objectExpiry(info)
+}
+
+// SweepBlobs deletes deduplicated content no object refers to anymore, as deleting, overwriting or sweeping
+// objects removes only their references, returning the number of deleted blobs. Content, and abandoned uploads,
+// younger than the retention are kept, as their references may be still being written. References are looked up
+// in all buckets of the node, as other namespaces may share the content, and every object is checked on its own,
+// so the sweep is linear in the number of objects. Nothing is deleted unless all objects were checked.
+func (s *MinioStorage) SweepBlobs(ctx context.Context) (int, error) {
+	if !s.cfg.Dedup.Enabled {
+		return 0, nil
+	}
+	// listing stops only when its context is done, so it's cancelled when returning early on error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// content is listed before references, so content stored meanwhile isn't deleted along with its references
+	var candidates []string
+	for listed := range s.client.ListObjects(ctx, s.blobBucket(), minio.ListObjectsOptions{Recursive: true}) {
+		if listed.Err != nil {
+			if bucketDoesNotExist(listed.Err) {
+				return 0, nil
+			}
+			return 0, fmt.Errorf("error list blobs (%s | %s): %w", s.endpoint, s.blobBucket(), listed.Err)
+		}
+		if AfterWithSkew(s.clock.Now(), listed.LastModified.Add(s.blobRetention()), s.cfg.ClockSkew) {
+			candidates = append(candidates, listed.Key)
+		}
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+	referenced, err := s.blobReferences(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	var errs []error
+	for _, key := range candidates {
+		if referenced[key] {
+			continue
+		}
+		if err := s.client.RemoveObject(ctx, s.blobBucket(), key, minio.RemoveObjectOptions{}); err != nil {
+			errs = append(errs, fmt.Errorf("error delete blob (%s | %s): %w", s.endpoint, key, err))
+			continue
+		}
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// blobReferences returns checksums of deduplicated content objects of all buckets on the node refer to.
+func (s *MinioStorage) blobReferences(ctx context.Context) (map[string]bool, error) {
+	buckets, err := s.client.ListBuckets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error list buckets (%s): %w", s.endpoint, err)
+	}
+	referenced := map[string]bool{}
+	for _, bucket := range buckets {
+		if bucket.Name == s.blobBucket() {
+			continue
+		}
+		for listed := range s.client.ListObjects(ctx, bucket.Name, minio.ListObjectsOptions{Recursive: true}) {
+			if listed.Err != nil {
+				return nil, fmt.Errorf("error list objects (%s | %s): %w", s.endpoint, bucket.Name, listed.Err)
+			}
+			info, err := s.client.StatObject(ctx, bucket.Name, listed.Key, minio.StatObjectOptions{})
+			if err != nil {
+				// object deleted meanwhile refers to nothing
+				if keyDoesNotExist(err) {
+					continue
+				}
+				return nil, fmt.Errorf("error stat object (%s | %s): %w", s.endpoint, listed.Key, err)
+			}
+			if checksum := blobReference(info); checksum != "" {
+				referenced[checksum] = true
+			}
+		}
+	}
+	return referenced, nil
+}
+
+// blobUploadKey returns random key streamed content is uploaded under until its checksum is known.
+func blobUploadKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate upload key: %w", err)
+	}
+	return blobUploadPrefix + hex.EncodeToString(b), nil
+}
+
+// hashingReader computes SHA-256 and size of the content read through it.
+type hashingReader struct {
+	io.ReadCloser
+	hash hash.Hash
+	read int64
+}
+
+func (hr *hashingReader) Read(p []byte) (int, error) {
+	n, err := hr.ReadCloser.Read(p)
+	hr.hash.Write(p[:n])
+	hr.read += int64(n)
+	return n, err
+}
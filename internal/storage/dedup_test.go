@@ -0,0 +1,310 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bucketNode is a fake minio node keeping content and headers of objects of each bucket, copying objects
+// server-side, listing them by prefix and creating buckets on request.
+type bucketNode struct {
+	mu      sync.Mutex
+	buckets map[string]map[string][]byte
+	headers map[string]http.Header
+}
+
+func newBucketNode() *bucketNode {
+	return &bucketNode{buckets: map[string]map[string][]byte{}, headers: map[string]http.Header{}}
+}
+
+func (n *bucketNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	objects, exists := n.buckets[bucket]
+	notFound := func(code, message string) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `<Error><Code>`+code+`</Code><Message>`+message+`</Message></Error>`)
+	}
+
+	switch {
+	case bucket == "" && r.Method == http.MethodGet:
+		listing := `<ListAllMyBucketsResult><Buckets>`
+		for name := range n.buckets {
+			listing += `<Bucket><Name>` + name + `</Name><CreationDate>2023-10-01T12:00:00.000Z</CreationDate></Bucket>`
+		}
+		_, _ = io.WriteString(w, listing+`</Buckets></ListAllMyBucketsResult>`)
+	case key == "" && r.Method == http.MethodPut:
+		n.buckets[bucket] = map[string][]byte{}
+	case key == "" && r.Method == http.MethodHead:
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case !exists:
+		notFound("NoSuchBucket", "The specified bucket does not exist")
+	case key == "" && r.Method == http.MethodGet:
+		listing := `<ListBucketResult><Name>` + bucket + `</Name><IsTruncated>false</IsTruncated>`
+		for key, content := range objects {
+			if !strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+				continue
+			}
+			listing += `<Contents><Key>` + key + `</Key><Size>` + strconv.Itoa(len(content)) + `</Size>` +
+				`<LastModified>2023-10-01T12:00:00.000Z</LastModified></Contents>`
+		}
+		_, _ = io.WriteString(w, listing+`</ListBucketResult>`)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, _ := url.PathUnescape(strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/"))
+		srcBucket, srcKey, _ := strings.Cut(source, "/")
+		content, ok := n.buckets[srcBucket][srcKey]
+		if !ok {
+			notFound("NoSuchKey", MinioKeyNotExistErrString)
+			return
+		}
+		objects[key] = content
+		n.headers[bucket+"/"+key] = n.headers[source]
+		_, _ = io.WriteString(w, `<CopyObjectResult><ETag>"etag"</ETag><LastModified>2023-10-01T12:00:00.000Z</LastModified></CopyObjectResult>`)
+	case r.Method == http.MethodPut:
+		stored := http.Header{}
+		for name, values := range r.Header {
+			if strings.HasPrefix(name, "X-Amz-Meta-") || name == "Content-Type" {
+				stored[name] = values
+			}
+		}
+		objects[key] = readPayload(r)
+		n.headers[bucket+"/"+key] = stored
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		content, ok := objects[key]
+		if !ok {
+			notFound("NoSuchKey", MinioKeyNotExistErrString)
+			return
+		}
+		for name, values := range n.headers[bucket+"/"+key] {
+			w.Header()[name] = values
+		}
+		w.Header().Set("ETag", `"etag"`)
+		http.ServeContent(w, r, key, time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC), bytes.NewReader(content))
+	}
+}
+
+// keys returns keys of objects stored in the bucket.
+func (n *bucketNode) keys(bucket string) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var keys []string
+	for key := range n.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (n *bucketNode) object(bucket, key string) []byte {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.buckets[bucket][key]
+}
+
+func (n *bucketNode) remove(bucket, key string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.buckets[bucket], key)
+}
+
+func TestMinioStorage_Dedup(t *testing.T) {
+	node := newBucketNode()
+	node.buckets["default"] = map[string][]byte{}
+	server := httptest.NewServer(node)
+	defer server.Close()
+	s, err := NewMinioStorage(&MinioConfig{
+		Endpoint:            strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:           "key",
+		SecretKey:           "secret",
+		BucketName:          "default",
+		Region:              "us-east-1",
+		VerifyContentLength: true,
+		Dedup:               DedupConfig{Enabled: true},
+	})
+	assert.NoError(t, err)
+	ctx := context.TODO()
+	content := []byte("identical content")
+
+	// identical content stored under many IDs, at once or streamed, is stored once
+	assert.NoError(t, s.Put(ctx, &Object{ID: "a", ContentType: "text/plain", Content: content}))
+	assert.NoError(t, s.Put(ctx, &Object{ID: "b", ContentType: "text/csv", Content: content, Metadata: map[string]string{"Author": "jane"}}))
+	assert.NoError(t, s.PutStream(ctx, &ObjectStream{ID: "c", Size: int64(len(content)), Content: io.NopCloser(bytes.NewReader(content))}))
+	assert.NoError(t, s.Put(ctx, &Object{ID: "d", Content: []byte("other content")}))
+
+	checksum := contentChecksum(content)
+	assert.ElementsMatch(t, []string{checksum, contentChecksum([]byte("other content"))}, node.keys("default-blobs"))
+	assert.Equal(t, content, node.object("default-blobs", checksum))
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d"}, node.keys("default"))
+	assert.Empty(t, node.object("default", "b"))
+
+	// reads follow references, with properties of the referring object
+	obj, err := s.Get(ctx, "b")
+	if assert.NoError(t, err) && assert.NotNil(t, obj) {
+		assert.Equal(t, "b", obj.ID)
+		assert.Equal(t, content, obj.Content)
+		assert.Equal(t, "text/csv", obj.ContentType)
+		assert.Equal(t, map[string]string{"Author": "jane"}, obj.Metadata)
+		assert.Equal(t, checksum, obj.ETag)
+	}
+	stream, err := s.GetStream(ctx, "c")
+	if assert.NoError(t, err) && assert.NotNil(t, stream) {
+		read, err := io.ReadAll(stream.Content)
+		stream.Content.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, content, read)
+		assert.Equal(t, DefaultContentType, stream.ContentType)
+	}
+	stream, err = s.GetRange(ctx, "a", 10, 7)
+	if assert.NoError(t, err) && assert.NotNil(t, stream) {
+		read, _ := io.ReadAll(stream.Content)
+		stream.Content.Close()
+		assert.Equal(t, "content", string(read))
+		assert.Equal(t, "text/plain", stream.ContentType)
+	}
+	info, err := s.Stat(ctx, "a")
+	if assert.NoError(t, err) && assert.NotNil(t, info) {
+		assert.Equal(t, int64(len(content)), info.Size)
+		assert.Nil(t, info.Metadata)
+	}
+
+	// missing objects are still missing, while references to missing content are broken
+	obj, err = s.Get(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, obj)
+	node.remove("default-blobs", contentChecksum([]byte("other content")))
+	_, err = s.Get(ctx, "d")
+	assert.ErrorIs(t, err, ErrBrokenReference)
+	_, err = s.GetStream(ctx, "d")
+	assert.ErrorIs(t, err, ErrBrokenReference)
+
+	// references within the blob bucket aren't stored nor followed, so they can't form a cycle
+	assert.ErrorIs(t, s.Put(WithBucket(ctx, "default-blobs"), &Object{ID: "cycle", Content: content}), ErrBrokenReference)
+	node.mu.Lock()
+	node.buckets["default-blobs"]["cycle"] = nil
+	node.headers["default-blobs/cycle"] = http.Header{"X-Amz-Meta-Blob": {"cycle"}, "X-Amz-Meta-Blob-Size": {"0"}}
+	node.mu.Unlock()
+	_, err = s.Get(WithBucket(ctx, "default-blobs"), "cycle")
+	assert.ErrorIs(t, err, ErrBrokenReference)
+}
+
+func TestMinioStorage_SweepBlobs(t *testing.T) {
+	node := newBucketNode()
+	node.buckets["default"] = map[string][]byte{}
+	// bucket listing objects of which is denied
+	var denied atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if denied.Load() && r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/archive") {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>Access Denied.</Message></Error>`)
+			return
+		}
+		node.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	// objects are stored by the fake node at 2023-10-01T12:00:00Z
+	var mu sync.Mutex
+	now := time.Date(2023, 10, 1, 12, 30, 0, 0, time.UTC)
+	s, err := NewMinioStorage(&MinioConfig{
+		Endpoint:   strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:  "key",
+		SecretKey:  "secret",
+		BucketName: "default",
+		Region:     "us-east-1",
+		Dedup:      DedupConfig{Enabled: true, Retention: time.Hour},
+		Clock: ClockFunc(func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		}),
+	})
+	assert.NoError(t, err)
+	ctx := context.TODO()
+	shared, deleted, overwritten := []byte("shared content"), []byte("deleted content"), []byte("overwritten content")
+
+	// content shared by objects of other buckets too, content of deleted objects and of overwritten ones
+	assert.NoError(t, s.Put(ctx, &Object{ID: "a", Content: shared}))
+	assert.NoError(t, s.Put(WithBucket(ctx, "archive"), &Object{ID: "b", Content: shared}))
+	assert.NoError(t, s.Put(ctx, &Object{ID: "c", Content: deleted}))
+	assert.NoError(t, s.Delete(ctx, "c"))
+	assert.NoError(t, s.Put(ctx, &Object{ID: "d", Content: overwritten}))
+	assert.NoError(t, s.Put(ctx, &Object{ID: "d", Content: []byte("current content")}))
+	// abandoned upload of streamed content
+	node.mu.Lock()
+	node.buckets["default-blobs"][blobUploadPrefix+"abandoned"] = []byte("abandoned content")
+	node.mu.Unlock()
+
+	// unreferenced content younger than the retention is kept
+	sweeper := s.(blobSweeper)
+	swept, err := sweeper.SweepBlobs(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, swept)
+	assert.Len(t, node.keys("default-blobs"), 5)
+
+	// older unreferenced content is deleted, while referenced content is kept
+	mu.Lock()
+	now = now.Add(time.Hour)
+	mu.Unlock()
+	swept, err = sweeper.SweepBlobs(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, swept)
+	assert.ElementsMatch(t, []string{contentChecksum(shared), contentChecksum([]byte("current content"))}, node.keys("default-blobs"))
+	obj, err := s.Get(WithBucket(ctx, "archive"), "b")
+	if assert.NoError(t, err) && assert.NotNil(t, obj) {
+		assert.Equal(t, shared, obj.Content)
+	}
+
+	// content of the last referring object is deleted along with it
+	assert.NoError(t, s.Delete(ctx, "a"))
+	swept, err = sweeper.SweepBlobs(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, swept)
+	assert.NoError(t, s.Delete(WithBucket(ctx, "archive"), "b"))
+	swept, err = sweeper.SweepBlobs(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, swept)
+	assert.Equal(t, []string{contentChecksum([]byte("current content"))}, node.keys("default-blobs"))
+
+	// nothing is deleted when references can't all be checked
+	node.mu.Lock()
+	node.buckets["default-blobs"]["unreferenced"] = []byte("unreferenced content")
+	node.mu.Unlock()
+	denied.Store(true)
+	swept, err = sweeper.SweepBlobs(ctx)
+	assert.Error(t, err)
+	assert.Zero(t, swept)
+	assert.Len(t, node.keys("default-blobs"), 2)
+
+	// storage without deduplication has no content to sweep
+	plain, err := NewMinioStorage(&MinioConfig{
+		Endpoint:   strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:  "key",
+		SecretKey:  "secret",
+		BucketName: "default",
+		Region:     "us-east-1",
+	})
+	assert.NoError(t, err)
+	swept, err = plain.(blobSweeper).SweepBlobs(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, swept)
+	assert.Len(t, node.keys("default-blobs"), 2)
+}
@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// StaticNodeName is the name of nodes configured statically, whose ring key is their endpoint.
+const StaticNodeName = "static"
+
+// NodeWeightLabel is the label of node containers setting node weight, e.g. "2" for a node with twice the capacity.
+const NodeWeightLabel = "storage.weight"
+
+// staticWeightSuffix separates static node endpoint from its weight.
+const staticWeightSuffix = ";weight="
+
+// NodeDiscoverer finds storage nodes the distributed storage places objects on.
+type NodeDiscoverer interface {
+	Discover(ctx context.Context) ([]Node, error)
+}
+
+// nodeWatcher is implemented by discoverers able to notice nodes coming and going.
+// Watch calls rediscover on every change until ctx is cancelled.
+type nodeWatcher interface {
+	Watch(ctx context.Context, rediscover func(ctx context.Context))
+}
+
+// credentialResolver is implemented by discoverers able to resolve rotated node credentials.
+type credentialResolver interface {
+	Credentials(ctx context.Context, node Node) (accessKey, secretKey string, err error)
+}
+
+// DockerClient is the subset of docker client API used for storage node discovery.
+type DockerClient interface {
+	ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error)
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error)
+}
+
+// DockerDiscoverer discovers storage nodes as running minio docker containers.
+// Their credentials are read from container environment. It logs using slog.Default().
+type DockerDiscoverer struct {
+	client DockerClient
+}
+
+func NewDockerDiscoverer(cli DockerClient) *DockerDiscoverer {
+	return &DockerDiscoverer{client: cli}
+}
+
+// Discover returns Nodes that correspond to minio docker containers in running status
+func (d *DockerDiscoverer) Discover(ctx context.Context) ([]Node, error) {
+	containers, err := d.client.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filters.NewArgs(filters.KeyValuePair{
+			Key: "status", Value: "running",
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list containers: %w", err)
+	}
+
+	var storageNodes []Node
+	for _, container := range containers {
+		if container.ID == "" || container.NetworkSettings == nil {
+			continue
+		}
+		node := Node{ID: container.ID}
+
+		// parse name
+		if len(container.Names) > 0 {
+			node.Name = container.Names[0]
+		}
+		// check if it's relevant storage node
+		if !strings.Contains(node.Name, ContainerNamePattern) {
+			continue
+		}
+
+		// parse weight, an invalid one falls back to weight 1 rather than leaving the node out
+		if label, ok := container.Labels[NodeWeightLabel]; ok {
+			weight, err := parseNodeWeight(label)
+			if err != nil {
+				slog.WarnContext(ctx, "ignoring invalid node weight", "node", node, "error", err)
+			}
+			node.Weight = weight
+		}
+
+		// resolve IPAddress of storage node
+		var addr string
+		if container.NetworkSettings != nil && container.NetworkSettings.Networks != nil {
+			for _, n := range container.NetworkSettings.Networks {
+				if n.IPAddress != "" {
+					addr = n.IPAddress
+					break
+				}
+			}
+		}
+		if addr == "" {
+			slog.WarnContext(ctx, "skipping node, unable to resolve its ip address", "node", node)
+			continue
+		}
+		node.Endpoint = fmt.Sprintf("%s:%d", addr, MinioApiPort)
+
+		// resolve access credentials
+		node.AccessKey, node.SecretKey, err = d.Credentials(ctx, node)
+		if err != nil {
+			return nil, err
+		}
+
+		// add storage node
+		storageNodes = append(storageNodes, node)
+		slog.InfoContext(ctx, "node discovered", "node", node)
+	}
+
+	return storageNodes, nil
+}
+
+// Credentials reads minio access credentials from node container environment.
+func (d *DockerDiscoverer) Credentials(ctx context.Context, node Node) (string, string, error) {
+	inspectData, err := d.client.ContainerInspect(ctx, node.ID)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to inspect container %s: %w", node.ID, err)
+	}
+
+	containerEnv := make(map[string]string)
+	if inspectData.Config != nil {
+		for _, env := range inspectData.Config.Env {
+			splitted := strings.Split(env, "=")
+			if len(splitted) > 1 {
+				containerEnv[splitted[0]] = splitted[1]
+			}
+		}
+	}
+
+	return containerEnv[MinioAccessKeyEnv], containerEnv[MinioSecretKeyEnv], nil
+}
+
+// Watch rediscovers storage nodes whenever a node container starts or dies, until ctx is cancelled.
+// Failed events subscription is renewed, rediscovering nodes as events might have been missed meanwhile.
+func (d *DockerDiscoverer) Watch(ctx context.Context, rediscover func(ctx context.Context)) {
+	for {
+		err := d.watchEvents(ctx, rediscover)
+		if ctx.Err() != nil {
+			slog.Info("stopped watching storage nodes")
+			return
+		}
+		slog.Warn("watching storage nodes failed", "retry_delay", nodeEventsRetryDelay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			slog.Info("stopped watching storage nodes")
+			return
+		case <-time.After(nodeEventsRetryDelay):
+		}
+		rediscover(ctx)
+	}
+}
+
+// watchEvents subscribes to docker container events and rediscovers storage nodes on start or die
+// of a node container. It returns when subscription fails or ctx is cancelled.
+func (d *DockerDiscoverer) watchEvents(ctx context.Context, rediscover func(ctx context.Context)) error {
+	messages, errs := d.client.Events(ctx, types.EventsOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", string(events.ContainerEventType)),
+			filters.Arg("event", "start"),
+			filters.Arg("event", "die"),
+		),
+	})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case msg := <-messages:
+			if !strings.Contains(msg.Actor.Attributes["name"], ContainerNamePattern) {
+				continue
+			}
+			slog.InfoContext(ctx, "node container changed, rediscovering storage nodes", "container", msg.Actor.Attributes["name"], "action", msg.Action)
+			rediscover(ctx)
+		}
+	}
+}
+
+// StaticDiscoverer serves a fixed list of storage nodes, e.g. from configuration.
+// Nodes aren't watched and their credentials aren't refreshed.
+type StaticDiscoverer struct {
+	nodes []Node
+}
+
+func NewStaticDiscoverer(nodes []Node) *StaticDiscoverer {
+	return &StaticDiscoverer{nodes: nodes}
+}
+
+// Discover returns the configured nodes.
+func (d *StaticDiscoverer) Discover(ctx context.Context) ([]Node, error) {
+	return append([]Node(nil), d.nodes...), nil
+}
+
+// ParseStaticNodes parses comma separated list of nodes in format "accessKey:secretKey@host:port".
+// Endpoint prefixed with "https://" marks node served over TLS, and suffixed with ";weight=N" sets node weight.
+// Node ID is its endpoint, so placement doesn't depend on the order nodes are listed in.
+func ParseStaticNodes(value string) ([]Node, error) {
+	var nodes []Node
+	seen := make(map[string]bool)
+	for i, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// entries hold secrets, so errors refer to their position rather than content
+		at := strings.LastIndex(entry, "@")
+		if at < 0 {
+			return nil, fmt.Errorf("invalid node #%d: expected accessKey:secretKey@host:port", i+1)
+		}
+		accessKey, secretKey, ok := strings.Cut(entry[:at], ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid node #%d: expected accessKey:secretKey@host:port", i+1)
+		}
+		address, weightValue, weighted := strings.Cut(entry[at+1:], staticWeightSuffix)
+		endpoint, secure := strings.CutPrefix(address, "https://")
+		if endpoint == "" {
+			return nil, fmt.Errorf("invalid node #%d: missing endpoint", i+1)
+		}
+		weight := 0
+		if weighted {
+			var err error
+			if weight, err = parseNodeWeight(weightValue); err != nil {
+				return nil, fmt.Errorf("invalid node #%d: %w", i+1, err)
+			}
+		}
+		if seen[endpoint] {
+			return nil, fmt.Errorf("duplicate node endpoint %s", endpoint)
+		}
+		seen[endpoint] = true
+
+		nodes = append(nodes, Node{ID: endpoint, Name: StaticNodeName, Endpoint: endpoint, AccessKey: accessKey, SecretKey: secretKey, Secure: secure, Weight: weight})
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("no nodes configured")
+	}
+	return nodes, nil
+}
+
+// parseNodeWeight parses node weight, a positive integer. Invalid weight is returned as 0, i.e. weight 1.
+func parseNodeWeight(value string) (int, error) {
+	weight, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || weight < 1 {
+		return 0, fmt.Errorf("weight must be a positive integer, got %q", value)
+	}
+	return weight, nil
+}
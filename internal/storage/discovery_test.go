@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDockerDiscoverer_Discover(t *testing.T) {
+	client := &fakeDockerClient{
+		containers: []types.Container{
+			nodeContainer("node1", ContainerNamePattern+"1", "10.0.0.1"),
+			nodeContainer("node2", ContainerNamePattern+"2", ""),
+			weighted(nodeContainer("node3", ContainerNamePattern+"3", "10.0.0.3"), "2"),
+			weighted(nodeContainer("node4", ContainerNamePattern+"4", "10.0.0.4"), "-1"),
+			nodeContainer("other", "some-other-container", "10.0.0.3"),
+		},
+		env: map[string][]string{
+			"node1": {MinioAccessKeyEnv + "=key", MinioSecretKeyEnv + "=secret"},
+			"node2": {},
+			"node3": {},
+			"node4": {},
+		},
+	}
+
+	// node without network address is skipped
+	nodes, err := NewDockerDiscoverer(client).Discover(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, []Node{
+		{ID: "node1", Name: "/" + ContainerNamePattern + "1", Endpoint: "10.0.0.1:9000", AccessKey: "key", SecretKey: "secret"},
+		// invalid weight falls back to weight 1
+		{ID: "node3", Name: "/" + ContainerNamePattern + "3", Endpoint: "10.0.0.3:9000", Weight: 2},
+		{ID: "node4", Name: "/" + ContainerNamePattern + "4", Endpoint: "10.0.0.4:9000"},
+	}, nodes)
+}
+
+// weighted sets node weight label of the container
+func weighted(container types.Container, weight string) types.Container {
+	container.Labels = map[string]string{NodeWeightLabel: weight}
+	return container
+}
+
+func TestParseStaticNodes(t *testing.T) {
+	nodes, err := ParseStaticNodes("key1:secret1@10.0.0.1:9000, key2:p@ss:w0rd@minio-2.local:9000,key3:secret3@https://minio-3.local:9000;weight=2")
+	assert.NoError(t, err)
+	assert.Equal(t, []Node{
+		{ID: "10.0.0.1:9000", Name: StaticNodeName, Endpoint: "10.0.0.1:9000", AccessKey: "key1", SecretKey: "secret1"},
+		{ID: "minio-2.local:9000", Name: StaticNodeName, Endpoint: "minio-2.local:9000", AccessKey: "key2", SecretKey: "p@ss:w0rd"},
+		{ID: "minio-3.local:9000", Name: StaticNodeName, Endpoint: "minio-3.local:9000", AccessKey: "key3", SecretKey: "secret3", Secure: true, Weight: 2},
+	}, nodes)
+
+	tests := map[string]string{
+		"empty":              "",
+		"missing endpoint":   "key:hunter2@",
+		"missing host":       "key:hunter2@https://",
+		"missing secret":     "hunter2@10.0.0.1:9000",
+		"missing separator":  "hunter2",
+		"duplicate endpoint": "key1:hunter2@10.0.0.1:9000,key2:hunter2@10.0.0.1:9000",
+		"zero weight":        "key:hunter2@10.0.0.1:9000;weight=0",
+		"invalid weight":     "key:hunter2@10.0.0.1:9000;weight=heavy",
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseStaticNodes(value)
+			assert.Error(t, err)
+			// configuration errors are logged, so they must not leak credentials
+			if err != nil {
+				assert.NotContains(t, err.Error(), "hunter2")
+			}
+		})
+	}
+}
+
+func TestDistributedStorage_StaticDiscovery(t *testing.T) {
+	nodes, err := ParseStaticNodes("key:secret@10.0.0.1:9000,key:secret@10.0.0.2:9000")
+	assert.NoError(t, err)
+
+	var mu sync.Mutex
+	var endpoints []string
+	ds := NewDistributedStorage(NewStaticDiscoverer(nodes), &DistributedConfig{ReplicationFactor: 2}).(*DistributedStorage)
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) {
+		mu.Lock()
+		endpoints = append(endpoints, cfg.Endpoint)
+		mu.Unlock()
+		storage := new(MockStorage)
+		storage.On("Init", mock.Anything).Return(nil)
+		storage.On("Get", mock.Anything, "object-1").Return(&Object{ID: "object-1", Content: []byte("data1")}, nil)
+		storage.On("Get", mock.Anything, "object-2").Return((*Object)(nil), minio.ErrorResponse{Code: "InvalidAccessKeyId"})
+		return storage, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, ds.Init(ctx))
+	assert.ElementsMatch(t, []string{"10.0.0.1:9000", "10.0.0.2:9000"}, endpoints)
+	assert.Equal(t, []string{"10.0.0.1:9000#static", "10.0.0.2:9000#static"}, ds.RingMembers())
+
+	obj, err := ds.Get(ctx, "object-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "object-1", obj.ID)
+
+	// static nodes credentials can't be refreshed, so rejected credentials fail the operation
+	_, err = ds.Get(ctx, "object-2")
+	assert.Error(t, err)
+	assert.Len(t, endpoints, 2)
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// DefaultExpirySweepInterval is the default interval expired objects are deleted from the nodes in.
+	DefaultExpirySweepInterval = 10 * time.Minute
+	// expiresAtMetadataKey is the user metadata key expiry of temporary objects is stored under.
+	expiresAtMetadataKey = "Expires-At"
+)
+
+// expirySweeper is implemented by node storages able to delete their expired objects.
+type expirySweeper interface {
+	SweepExpired(ctx context.Context) (int, error)
+}
+
+// setExpiry stores expiry of the object in its user metadata, unless it never expires.
+func setExpiry(userMetadata map[string]string, expiresAt time.Time) {
+	if !expiresAt.IsZero() {
+		userMetadata[expiresAtMetadataKey] = expiresAt.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// objectExpiry returns when the object expires, zero if it never does. Invalid expiry never expires,
+// so objects aren't lost to corrupt metadata.
+func objectExpiry(info minio.ObjectInfo) time.Time {
+	value := info.UserMetadata[expiresAtMetadataKey]
+	if value == "" {
+		return time.Time{}
+	}
+	expiresAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return expiresAt
+}
+
+// expired checks if the object expired more than the tolerated clock skew ago, so it's treated as absent
+// until swept.
+func (s *MinioStorage) expired(info minio.ObjectInfo) bool {
+	expiresAt := objectExpiry(info)
+	return !expiresAt.IsZero() && AfterWithSkew(s.clock.Now(), expiresAt, s.cfg.ClockSkew)
+}
+
+// SweepExpired deletes expired objects of all buckets on the node, returning the number of deleted objects.
+// Listing doesn't return object metadata, so every object is checked on its own; the sweep is linear in the
+// number of objects. Deduplicated content never expires, so the blob bucket is skipped. Objects of other
+// namespaces are left to their deployments.
+func (s *MinioStorage) SweepExpired(ctx context.Context) (int, error) {
+	// listing stops only when its context is done, so it's cancelled when returning early on error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	buckets, err := s.client.ListBuckets(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error list buckets (%s): %w", s.endpoint, err)
+	}
+	deleted := 0
+	var errs []error
+	for _, bucket := range buckets {
+		if s.cfg.Dedup.Enabled && bucket.Name == s.blobBucket() {
+			continue
+		}
+		for listed := range s.client.ListObjects(ctx, bucket.Name, minio.ListObjectsOptions{Prefix: s.keyPrefix(bucket.Name), Recursive: true}) {
+			if listed.Err != nil {
+				return deleted, fmt.Errorf("error list objects (%s | %s): %w", s.endpoint, bucket.Name, listed.Err)
+			}
+			info, err := s.client.StatObject(ctx, bucket.Name, listed.Key, minio.StatObjectOptions{})
+			if err != nil {
+				if !keyDoesNotExist(err) {
+					errs = append(errs, fmt.Errorf("error stat object (%s | %s): %w", s.endpoint, listed.Key, err))
+				}
+				continue
+			}
+			if !s.expired(info) {
+				continue
+			}
+			if err := s.client.RemoveObject(ctx, bucket.Name, listed.Key, minio.RemoveObjectOptions{}); err != nil {
+				errs = append(errs, fmt.Errorf("error delete expired object (%s | %s): %w", s.endpoint, listed.Key, err))
+				continue
+			}
+			deleted++
+		}
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// sweepExpired deletes expired objects of all available nodes every interval, until ctx is cancelled. Deduplicated
+// content no object refers to anymore, such as content of the swept objects, is deleted right after.
+func (s *DistributedStorage) sweepExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.RLock()
+		storages := make(map[string]Storage, len(s.availableStorages))
+		for key, storage := range s.availableStorages {
+			storages[key] = storage
+		}
+		s.mu.RUnlock()
+
+		for key, storage := range storages {
+			sweeper, ok := storage.(expirySweeper)
+			if !ok {
+				continue
+			}
+			deleted, err := sweeper.SweepExpired(ctx)
+			if err != nil && ctx.Err() == nil {
+				s.logger.WarnContext(ctx, "node operation failed", "operation", "sweep_expired", "node", key, "error", err)
+				s.metrics.NodeError(key, "sweep_expired")
+			}
+			if deleted > 0 {
+				s.logger.InfoContext(ctx, "deleted expired objects", "node", key, "deleted", deleted)
+			}
+			s.sweepBlobs(ctx, key, storage)
+		}
+	}
+}
+
+// sweepBlobs deletes deduplicated content no object on the node refers to, if the node stores any.
+func (s *DistributedStorage) sweepBlobs(ctx context.Context, key string, storage Storage) {
+	sweeper, ok := storage.(blobSweeper)
+	if !ok {
+		return
+	}
+	deleted, err := sweeper.SweepBlobs(ctx)
+	if err != nil && ctx.Err() == nil {
+		s.logger.WarnContext(ctx, "node operation failed", "operation", "sweep_blobs", "node", key, "error", err)
+		s.metrics.NodeError(key, "sweep_blobs")
+	}
+	if deleted > 0 {
+		s.logger.InfoContext(ctx, "deleted unreferenced blobs", "node", key, "deleted", deleted)
+	}
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinioStorage_Expiry(t *testing.T) {
+	node := newBucketNode()
+	node.buckets["default"] = map[string][]byte{}
+	server := httptest.NewServer(node)
+	defer server.Close()
+	var mu sync.Mutex
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s, err := NewMinioStorage(&MinioConfig{
+		Endpoint:   strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:  "key",
+		SecretKey:  "secret",
+		BucketName: "default",
+		Region:     "us-east-1",
+		ClockSkew:  time.Second,
+		Clock: ClockFunc(func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		}),
+	})
+	assert.NoError(t, err)
+	ctx := context.TODO()
+	content := []byte("scratch data")
+
+	expiresAt := now.Add(time.Hour)
+	assert.NoError(t, s.Put(ctx, &Object{ID: "scratch", Content: content, ExpiresAt: expiresAt}))
+	assert.NoError(t, s.PutStream(WithBucket(ctx, "cache"), &ObjectStream{ID: "entry", Size: int64(len(content)), Content: io.NopCloser(bytes.NewReader(content)), ExpiresAt: expiresAt}))
+	assert.NoError(t, s.Put(ctx, &Object{ID: "permanent", Content: content}))
+
+	// objects are readable until they expire, along with their expiry
+	obj, err := s.Get(ctx, "scratch")
+	if assert.NoError(t, err) && assert.NotNil(t, obj) {
+		assert.True(t, expiresAt.Equal(obj.ExpiresAt))
+		assert.Nil(t, obj.Metadata)
+	}
+	info, err := s.Stat(WithBucket(ctx, "cache"), "entry")
+	if assert.NoError(t, err) && assert.NotNil(t, info) {
+		assert.True(t, expiresAt.Equal(info.ExpiresAt))
+	}
+
+	// objects expired within the tolerated clock skew are still readable
+	mu.Lock()
+	now = expiresAt.Add(time.Second)
+	mu.Unlock()
+	obj, err = s.Get(ctx, "scratch")
+	assert.NoError(t, err)
+	assert.NotNil(t, obj)
+	deleted, err := s.(expirySweeper).SweepExpired(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	// expired objects are absent, though still stored until swept
+	mu.Lock()
+	now = expiresAt.Add(time.Second + time.Nanosecond)
+	mu.Unlock()
+	obj, err = s.Get(ctx, "scratch")
+	assert.NoError(t, err)
+	assert.Nil(t, obj)
+	stream, err := s.GetStream(WithBucket(ctx, "cache"), "entry")
+	assert.NoError(t, err)
+	assert.Nil(t, stream)
+	stream, err = s.GetRange(ctx, "scratch", 0, 4)
+	assert.NoError(t, err)
+	assert.Nil(t, stream)
+	info, err = s.Stat(ctx, "scratch")
+	assert.NoError(t, err)
+	assert.Nil(t, info)
+	assert.ElementsMatch(t, []string{"scratch", "permanent"}, node.keys("default"))
+
+	// sweeper deletes expired objects of all buckets, keeping the others
+	deleted, err = s.(expirySweeper).SweepExpired(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Equal(t, []string{"permanent"}, node.keys("default"))
+	assert.Empty(t, node.keys("cache"))
+	obj, err = s.Get(ctx, "permanent")
+	assert.NoError(t, err)
+	assert.NotNil(t, obj)
+}
+
+// sweepingStorage counts sweeps of expired objects and unreferenced blobs
+type sweepingStorage struct {
+	MockStorage
+	sweeps     atomic.Int32
+	blobSweeps atomic.Int32
+}
+
+func (ss *sweepingStorage) SweepExpired(ctx context.Context) (int, error) {
+	ss.sweeps.Add(1)
+	return 1, nil
+}
+
+func (ss *sweepingStorage) SweepBlobs(ctx context.Context) (int, error) {
+	ss.blobSweeps.Add(1)
+	return 1, nil
+}
+
+func TestDistributedStorage_SweepExpired(t *testing.T) {
+	ds, _ := createReplicatedStorage(1)
+	nodes := []*sweepingStorage{{}, {}}
+	ds.availableStorages = map[string]Storage{"node1#1": nodes[0], "node2#2": nodes[1], "node3#3": new(MockStorage)}
+
+	// every node able to sweep is swept each interval, along with its unreferenced blobs, until the context
+	// is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ds.sweepExpired(ctx, 10*time.Millisecond)
+	}()
+	assert.Eventually(t, func() bool {
+		return nodes[0].sweeps.Load() >= 2 && nodes[1].sweeps.Load() >= 2 &&
+			nodes[0].blobSweeps.Load() >= 2 && nodes[1].blobSweeps.Load() >= 2
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sweeper didn't stop after cancellation")
+	}
+}
@@ -3,81 +3,453 @@ package storage
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"io"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-const MinioKeyNotExistErrString = "The specified key does not exist."
+const (
+	MinioKeyNotExistErrString = "The specified key does not exist."
+	// DefaultMetadataTimeout is the default response header timeout of metadata operations.
+	DefaultMetadataTimeout = 5 * time.Second
+	// DefaultContentType is the content type of objects stored without one.
+	DefaultContentType = "application/octet-stream"
+	// DefaultWarmupConnections is the default number of connections opened to each node during Init.
+	DefaultWarmupConnections = 2
+	// DefaultMaxIdleConnsPerHost is the default number of idle connections kept open to each node, per client.
+	DefaultMaxIdleConnsPerHost = 100
+	// checksumMetadataKey is the user metadata key object checksum is stored under (x-amz-meta-sha256).
+	checksumMetadataKey = "Sha256"
+	// userMetadataPrefix is the header prefix of minio user metadata.
+	userMetadataPrefix = "X-Amz-Meta-"
+	// maxCopyObjectSize is the size of the largest object copied by a single request, in bytes.
+	maxCopyObjectSize = 5 << 30
+)
+
+// ErrContentLengthMismatch is returned when node returns object body of different size than declared.
+var ErrContentLengthMismatch = errors.New("object content length mismatch")
+
+// ErrChecksumMismatch is returned when node returns object content not matching its stored checksum.
+var ErrChecksumMismatch = errors.New("object checksum mismatch")
+
+// ErrInvalidEndpoint is returned when node endpoint isn't a host with optional port.
+var ErrInvalidEndpoint = errors.New("invalid node endpoint")
+
+// minio error codes signalling that the bucket lives in a different region than requested
+var minioRegionMismatchCodes = map[string]bool{
+	"AuthorizationHeaderMalformed": true,
+	"InvalidRegion":                true,
+}
+
+// minio error codes signalling that node credentials are not valid anymore
+var minioAuthErrorCodes = map[string]bool{
+	"InvalidAccessKeyId":    true,
+	"SignatureDoesNotMatch": true,
+}
 
 type MinioConfig struct {
+	// Endpoint is the host of the node, with optional port. Scheme prefix "https://" or "http://" overrides Secure.
 	Endpoint   string
 	AccessKey  string
 	SecretKey  string
 	BucketName string
+	// Region is the region the bucket is expected to live in. When empty the minio client
+	// discovers the bucket region on its own.
+	Region string
+	// AdoptBucketRegion makes Init switch to the bucket's actual region when it differs
+	// from Region, instead of failing.
+	AdoptBucketRegion bool
+	// VerifyContentLength makes Get verify that the read object body has the size declared by the node.
+	VerifyContentLength bool
+	// StreamChecksums stores checksum of streamed uploads, known only once the content was uploaded, by copying
+	// the object onto itself with the checksum added to its metadata. The copy doubles write I/O of the node and
+	// updates the last modification time, so it's disabled by default and streamed uploads are stored without
+	// checksum, leaving their content unverified by Get.
+	StreamChecksums bool
+	// MetadataTimeout is the response header timeout of metadata operations (bucket checks, stats).
+	// Defaults to DefaultMetadataTimeout.
+	MetadataTimeout time.Duration
+	// DataTimeout is the response header timeout of object upload/download operations. After ingesting
+	// a large body node may take long to respond, so it's disabled by default (zero).
+	DataTimeout time.Duration
+	// OperationTimeout bounds the whole Put, PutStream or Get, including retries and transferring the body, so
+	// a slow node can't hang a request. GetStream and GetRange are bounded until the first byte of content is
+	// read, as the rest of a large content may take longer to transfer. Zero disables it.
+	OperationTimeout time.Duration
+	// DefaultContentType is stored for objects put without content type. Defaults to DefaultContentType.
+	DefaultContentType string
+	// WarmupConnections is the number of connections opened in advance by Init for metadata and data
+	// operations each, so first requests don't pay the connection setup cost. Zero disables warm-up.
+	WarmupConnections int
+	// MaxIdleConnsPerHost is the number of idle connections kept open to the node for reuse, for metadata and data
+	// operations each. Connections beyond it are closed once their request completes, so a node serving more
+	// concurrent requests than that keeps paying connection setup (and TLS handshakes). Defaults to
+	// DefaultMaxIdleConnsPerHost, raised to WarmupConnections if that's larger, so warmed up connections are kept.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost bounds connections open to the node, for metadata and data operations each. Requests beyond
+	// it wait for a connection to free up instead of opening more, protecting a hot node from connection storms
+	// at the cost of queueing. Zero doesn't limit connections.
+	MaxConnsPerHost int
+	// Retry configures retrying of object uploads and downloads failing with transient errors.
+	// Streamed uploads can't be replayed, so they're never retried.
+	Retry RetryConfig
+	// Secure connects to the node over TLS. Disabled by default, as local docker nodes serve plain HTTP.
+	Secure bool
+	// CACertPath is the path of PEM encoded CA certificates trusted for node TLS in addition to system ones,
+	// e.g. of a private CA. Empty trusts system CAs only.
+	CACertPath string
+	// Compression configures gzip compression of stored content. Disabled by default.
+	Compression CompressionConfig
+	// Dedup configures deduplication of stored content. Disabled by default.
+	Dedup DedupConfig
+	// Clock tells expired objects apart. Defaults to SystemClock.
+	Clock Clock
+	// ClockSkew is the tolerated difference between clocks of gateways, so an object expired by a gateway with
+	// clock ahead isn't treated as absent, nor swept, until ClockSkew after its expiry. Zero tolerates no skew.
+	ClockSkew time.Duration
+	// KeyPrefix is prepended to IDs of objects stored on the node and stripped from listed ones, so deployments
+	// sharing the nodes get separate namespaces, e.g. "app1/". Objects are placed on nodes by their IDs, so the
+	// prefix doesn't change placement. Empty stores objects under their IDs.
+	KeyPrefix string
+	// PublicEndpoint is the endpoint presigned URLs point at, for clients not able to reach Endpoint, e.g.
+	// a host port the node is published on. Prefix "https://" marks endpoint served over TLS. Defaults to Endpoint.
+	PublicEndpoint string
+	// Logger logs node events and failures. Defaults to slog.Default().
+	Logger *slog.Logger
 }
 
 type MinioStorage struct {
+	// client is used for metadata operations, dataClient for transferring object bodies
 	client     *minio.Client
-	endpoint   string
-	bucketName string
+	dataClient *minio.Client
+	// presignClient signs URLs for PublicEndpoint
+	presignClient *minio.Client
+	cfg           MinioConfig
+	endpoint      string
+	bucketName    string
+	clock         Clock
+	logger        *slog.Logger
+
+	// transports are HTTP transports of the clients, holding their connections to the node
+	transports []*http.Transport
+
+	// buckets holds buckets known to exist on the node, so writes check for their bucket only once
+	bucketsMu sync.Mutex
+	buckets   map[string]bool
 }
 
 func NewMinioStorage(cfg *MinioConfig) (Storage, error) {
-	log.Printf("NewMinioStorage: %v\n", cfg.Endpoint)
+	endpoint, secure, err := normalizeEndpoint(cfg.Endpoint, cfg.Secure)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create minio storage instance: %w", err)
+	}
+	normalized := *cfg
+	normalized.Endpoint, normalized.Secure = endpoint, secure
+	cfg = &normalized
 
-	// Set the timeout values in HTTP transport
-	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout: 5 * time.Second, // Connection timeout
-		}).DialContext,
-		TLSHandshakeTimeout:   5 * time.Second,
-		ResponseHeaderTimeout: 5 * time.Second,
-		IdleConnTimeout:       90 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
 	}
+	logger = logger.With("endpoint", cfg.Endpoint)
+	logger.Info("creating minio storage")
 
+	clock := cfg.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+
+	s := &MinioStorage{
+		cfg:        *cfg,
+		endpoint:   cfg.Endpoint,
+		bucketName: cfg.BucketName,
+		clock:      clock,
+		logger:     logger,
+		// the configured bucket is created by Init
+		buckets: map[string]bool{cfg.BucketName: true},
+	}
+	if err := s.connect(cfg.Region); err != nil {
+		return nil, fmt.Errorf("unable to create minio storage instance: %w", err)
+	}
+	return s, nil
+}
+
+// connect creates metadata and data clients bound to the given region.
+func (s *MinioStorage) connect(region string) error {
+	metadataTimeout := s.cfg.MetadataTimeout
+	if metadataTimeout == 0 {
+		metadataTimeout = DefaultMetadataTimeout
+	}
+
+	client, transport, err := newMinioClient(&s.cfg, region, metadataTimeout)
+	if err != nil {
+		return err
+	}
+	dataClient, dataTransport, err := newMinioClient(&s.cfg, region, s.cfg.DataTimeout)
+	if err != nil {
+		return err
+	}
+	transports := []*http.Transport{transport, dataTransport}
+
+	presignClient := client
+	if s.cfg.PublicEndpoint != "" {
+		publicCfg := s.cfg
+		if publicCfg.Endpoint, publicCfg.Secure, err = normalizeEndpoint(s.cfg.PublicEndpoint, false); err != nil {
+			return fmt.Errorf("public endpoint: %w", err)
+		}
+		// presigning looks up unknown bucket region using the endpoint, which may not be reachable from here
+		if region == "" {
+			region = DefaultRegion
+		}
+		var presignTransport *http.Transport
+		if presignClient, presignTransport, err = newMinioClient(&publicCfg, region, metadataTimeout); err != nil {
+			return err
+		}
+		transports = append(transports, presignTransport)
+	}
+
+	s.client = client
+	s.dataClient = dataClient
+	s.presignClient = presignClient
+	s.transports = transports
+	return nil
+}
+
+// CloseIdleConnections closes idle connections of the clients, e.g. once the storage is replaced. Connections
+// in use are closed once their requests complete.
+func (s *MinioStorage) CloseIdleConnections() {
+	for _, transport := range s.transports {
+		transport.CloseIdleConnections()
+	}
+}
+
+// normalizeEndpoint returns node endpoint as the minio client expects it, host with optional port, and whether
+// the node is served over TLS. Scheme prefix "https://" or "http://" overrides secure, trailing slash is dropped.
+// Anything else than host and port, e.g. a path, is rejected, so a malformed endpoint fails with a clear error.
+func normalizeEndpoint(endpoint string, secure bool) (string, bool, error) {
+	hostPort := endpoint
+	if rest, ok := strings.CutPrefix(hostPort, "https://"); ok {
+		hostPort, secure = rest, true
+	} else if rest, ok := strings.CutPrefix(hostPort, "http://"); ok {
+		hostPort, secure = rest, false
+	}
+	hostPort = strings.TrimSuffix(hostPort, "/")
+
+	u, err := url.Parse("//" + hostPort)
+	if err != nil {
+		return "", false, fmt.Errorf("%w %q: %v", ErrInvalidEndpoint, endpoint, err)
+	}
+	if u.Hostname() == "" {
+		return "", false, fmt.Errorf("%w %q: empty host", ErrInvalidEndpoint, endpoint)
+	}
+	if u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" || strings.Contains(hostPort, "://") {
+		return "", false, fmt.Errorf("%w %q: expected host with optional port", ErrInvalidEndpoint, endpoint)
+	}
+	if port := u.Port(); port != "" || strings.HasSuffix(hostPort, ":") {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", false, fmt.Errorf("%w %q: invalid port %q", ErrInvalidEndpoint, endpoint, port)
+		}
+	}
+	return hostPort, secure, nil
+}
+
+// newMinioClient creates minio client bound to the given region, along with its transport. Zero
+// responseHeaderTimeout disables the timeout.
+func newMinioClient(cfg *MinioConfig, region string, responseHeaderTimeout time.Duration) (*minio.Client, *http.Transport, error) {
+	transport, err := newNodeTransport(cfg, responseHeaderTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
 	client, err := minio.New(cfg.Endpoint, &minio.Options{
 		Creds:     credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
-		Secure:    false,
+		Secure:    cfg.Secure,
 		Transport: transport,
+		Region:    region,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("unable to create minio storage instance: %w", err)
+		return nil, nil, err
 	}
+	return client, transport, nil
+}
 
-	return &MinioStorage{
-		client:     client,
-		endpoint:   cfg.Endpoint,
-		bucketName: cfg.BucketName,
+// newNodeTransport creates HTTP transport of a node client. Zero responseHeaderTimeout disables the timeout.
+func newNodeTransport(cfg *MinioConfig, responseHeaderTimeout time.Duration) (*http.Transport, error) {
+	maxIdleConns := cfg.MaxIdleConnsPerHost
+	if maxIdleConns <= 0 {
+		maxIdleConns = DefaultMaxIdleConnsPerHost
+	}
+	// keep all warmed up connections in the idle pool
+	maxIdleConns = max(maxIdleConns, cfg.WarmupConnections)
+
+	tlsConfig, err := nodeTLSConfig(cfg.CACertPath)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second, // Connection timeout
+		}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		TLSClientConfig:       tlsConfig,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		// the transport talks to a single node, so the overall idle limit is the one of the node; the per host
+		// default of 2 would close most connections of a node serving concurrent requests, only to reopen them
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConns,
+		MaxConnsPerHost:       max(cfg.MaxConnsPerHost, 0),
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		// content of compressed objects is decompressed by storage, not transparently by the transport
+		DisableCompression: true,
 	}, nil
 }
 
+// nodeTLSConfig returns TLS configuration trusting CA certificates of caCertPath in addition to system ones,
+// or nil for the default configuration when caCertPath is empty.
+func nodeTLSConfig(caCertPath string) (*tls.Config, error) {
+	if caCertPath == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(caCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("read CA certificates: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no CA certificates found in %s", caCertPath)
+	}
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
+
 func (s *MinioStorage) Init(ctx context.Context) error {
 	exists, err := s.client.BucketExists(ctx, s.bucketName)
+	if actual, mismatch := bucketRegionMismatch(err); mismatch {
+		if err = s.adoptBucketRegion(actual); err != nil {
+			return fmt.Errorf("error init bucket (%s): %w", s.endpoint, err)
+		}
+		exists, err = s.client.BucketExists(ctx, s.bucketName)
+	}
 	if err != nil {
 		return fmt.Errorf("error init bucket (%s): unable to check bucket: %w", s.endpoint, err)
 	}
-	if exists {
+	if !exists {
+		if err = s.client.MakeBucket(ctx, s.bucketName, minio.MakeBucketOptions{Region: s.cfg.Region}); err != nil {
+			return fmt.Errorf("error init bucket (%s): unable to create bucket: %w", s.endpoint, err)
+		}
+		s.logger.InfoContext(ctx, "created bucket", "bucket", s.bucketName)
+	}
+
+	s.warmUp(ctx)
+	return nil
+}
+
+// bucket returns bucket of operations performed with ctx: the one carried by ctx, or the configured one.
+func (s *MinioStorage) bucket(ctx context.Context) string {
+	if bucket := BucketFromContext(ctx); bucket != "" {
+		return bucket
+	}
+	return s.bucketName
+}
+
+// objectKey returns key the object ID is stored under in the bucket of operations performed with ctx.
+func (s *MinioStorage) objectKey(ctx context.Context, id string) string {
+	return s.keyPrefix(s.bucket(ctx)) + id
+}
+
+// keyPrefix returns prefix of keys of objects in the bucket. Deduplicated content is addressed by its checksum,
+// so the blob bucket is shared by all namespaces and its keys aren't prefixed.
+func (s *MinioStorage) keyPrefix(bucket string) string {
+	if s.cfg.Dedup.Enabled && bucket == s.blobBucket() {
+		return ""
+	}
+	return s.cfg.KeyPrefix
+}
+
+// ensureBucket creates bucket of operations performed with ctx, unless it's known to exist.
+// Concurrent writes may both create the bucket, so a bucket created meanwhile isn't an error.
+func (s *MinioStorage) ensureBucket(ctx context.Context) error {
+	bucket := s.bucket(ctx)
+	s.bucketsMu.Lock()
+	known := s.buckets[bucket]
+	s.bucketsMu.Unlock()
+	if known {
 		return nil
 	}
 
-	if err = s.client.MakeBucket(ctx, s.bucketName, minio.MakeBucketOptions{}); err != nil {
-		return fmt.Errorf("error init bucket (%s): unable to create bucket: %w", s.endpoint, err)
+	exists, err := s.client.BucketExists(ctx, bucket)
+	if err != nil {
+		return fmt.Errorf("unable to check bucket %s: %w", bucket, err)
+	}
+	if !exists {
+		err = s.client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: s.cfg.Region})
+		if code := minio.ToErrorResponse(err).Code; code == "BucketAlreadyOwnedByYou" {
+			err = nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to create bucket %s: %w", bucket, err)
+		}
+		s.logger.InfoContext(ctx, "created bucket", "bucket", bucket)
 	}
 
-	log.Printf("MinioStorage(%s) Init completed: created bucket %s\n", s.endpoint, s.bucketName)
+	s.bucketsMu.Lock()
+	s.buckets[bucket] = true
+	s.bucketsMu.Unlock()
 	return nil
 }
 
-func (s *MinioStorage) Get(ctx context.Context, id string) (*Object, error) {
-	mObj, err := s.client.GetObject(ctx, s.bucketName, id, minio.GetObjectOptions{})
+// warmUp opens configured number of connections of both clients by concurrent bucket checks.
+// Connections are returned to the idle pool of client transports, where first requests reuse them.
+// Warm-up is best effort, failures are only logged.
+func (s *MinioStorage) warmUp(ctx context.Context) {
+	if s.cfg.WarmupConnections <= 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, client := range []*minio.Client{s.client, s.dataClient} {
+		for i := 0; i < s.cfg.WarmupConnections; i++ {
+			wg.Add(1)
+			go func(client *minio.Client) {
+				defer wg.Done()
+				if _, err := client.BucketExists(ctx, s.bucketName); err != nil {
+					s.logger.WarnContext(ctx, "connection warm-up failed", "error", err)
+				}
+			}(client)
+		}
+	}
+	wg.Wait()
+}
+
+func (s *MinioStorage) Get(ctx context.Context, id string) (object *Object, err error) {
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+	err = retry(ctx, s.logger, s.cfg.Retry, func() error {
+		object, err = s.get(ctx, id)
+		return err
+	})
+	return object, err
+}
+
+func (s *MinioStorage) get(ctx context.Context, id string) (*Object, error) {
+	mObj, err := s.dataClient.GetObject(ctx, s.bucket(ctx), s.objectKey(ctx, id), minio.GetObjectOptions{})
 	if err != nil {
 		return s.handleKeyDoesNotExistError(err, "error get object", id)
 	}
@@ -87,24 +459,87 @@ func (s *MinioStorage) Get(ctx context.Context, id string) (*Object, error) {
 	if err != nil {
 		return s.handleKeyDoesNotExistError(err, "error get object: unable to read stat", id)
 	}
+	if s.expired(info) {
+		return nil, nil
+	}
+	if blobReference(info) != "" {
+		return s.getReferenced(ctx, id, info)
+	}
 
 	body, err := io.ReadAll(mObj)
+	if s.cfg.VerifyContentLength && errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("error get object (%s | %s): %w: body truncated", s.endpoint, id, ErrContentLengthMismatch)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error get object (%s | %s): unable to read body: %w", s.endpoint, id, err)
 	}
+	if s.cfg.VerifyContentLength && info.Size >= 0 && int64(len(body)) != info.Size {
+		return nil, fmt.Errorf("error get object (%s | %s): %w: read %d bytes, expected %d", s.endpoint, id, ErrContentLengthMismatch, len(body), info.Size)
+	}
+	// checksum is of the original content, so compressed content is decompressed even if passed through
+	content := body
+	if compressed(info) {
+		if content, err = gunzipContent(body); err != nil {
+			return nil, fmt.Errorf("error get object (%s | %s): unable to decompress body: %w", s.endpoint, id, err)
+		}
+	}
+	// objects stored without checksum aren't verified
+	checksum := info.UserMetadata[checksumMetadataKey]
+	if checksum != "" {
+		if actual := contentChecksum(content); actual != checksum {
+			return nil, fmt.Errorf("error get object (%s | %s): %w: computed %s, stored %s", s.endpoint, id, ErrChecksumMismatch, actual, checksum)
+		}
+	}
 
 	object := Object{
-		ID:          id,
-		ContentType: info.ContentType,
-		Content:     body,
+		ID:           id,
+		ContentType:  info.ContentType,
+		Content:      content,
+		Checksum:     checksum,
+		Metadata:     objectMetadata(info),
+		LastModified: info.LastModified,
+		ETag:         objectETag(info),
+		ExpiresAt:    objectExpiry(info),
+	}
+	if compressed(info) && s.cfg.Compression.PassThrough {
+		object.Content = body
+		object.ContentEncoding = ContentEncodingGzip
 	}
 
 	return &object, nil
 }
 
 func (s *MinioStorage) Put(ctx context.Context, object *Object) error {
-	_, err := s.client.PutObject(ctx, s.bucketName, object.ID, bytes.NewReader(object.Content), int64(len(object.Content)), minio.PutObjectOptions{
-		ContentType: object.ContentType,
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+	if s.cfg.Dedup.Enabled {
+		return s.putDeduplicated(ctx, object)
+	}
+	return s.put(ctx, object)
+}
+
+// put stores object content as it is, compressed if configured.
+func (s *MinioStorage) put(ctx context.Context, object *Object) error {
+	if err := s.ensureBucket(ctx); err != nil {
+		return fmt.Errorf("error put object (%s | %s): %w", s.endpoint, object.ID, err)
+	}
+	opts := minio.PutObjectOptions{
+		ContentType:  s.contentType(object.ContentType),
+		UserMetadata: userMetadata(object.Metadata, contentChecksum(object.Content)),
+	}
+	setExpiry(opts.UserMetadata, object.ExpiresAt)
+	content := object.Content
+	if s.cfg.Compression.compressible(opts.ContentType) {
+		gzipped, err := gzipContent(object.Content)
+		if err != nil {
+			return fmt.Errorf("error put object (%s | %s): unable to compress content: %w", s.endpoint, object.ID, err)
+		}
+		content = gzipped
+		setCompressed(&opts, int64(len(object.Content)))
+	}
+	err := retry(ctx, s.logger, s.cfg.Retry, func() error {
+		_, err := s.dataClient.PutObject(ctx, s.bucket(ctx), s.objectKey(ctx, object.ID), bytes.NewReader(content), int64(len(content)), opts)
+		return err
 	})
 	if err != nil {
 		return fmt.Errorf("error put object (%s | %s): %w", s.endpoint, object.ID, err)
@@ -112,6 +547,406 @@ func (s *MinioStorage) Put(ctx context.Context, object *Object) error {
 	return nil
 }
 
+func (s *MinioStorage) PutStream(ctx context.Context, object *ObjectStream) error {
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+	if s.cfg.Dedup.Enabled {
+		return s.putStreamDeduplicated(ctx, object)
+	}
+	if s.cfg.StreamChecksums {
+		return s.putStreamWithChecksum(ctx, object)
+	}
+	_, _, err := s.putStream(ctx, object)
+	return err
+}
+
+// putStreamWithChecksum stores object content streamed as it is, along with its checksum. The checksum is known
+// only once the content was uploaded, while user metadata can be sent only before it, so it's stored by copying
+// the object onto itself with replaced metadata, unless the object was replaced meanwhile. Content is stored
+// already, so failing to store the checksum doesn't fail the upload, leaving the object without checksum, like
+// objects too large to be copied at once.
+func (s *MinioStorage) putStreamWithChecksum(ctx context.Context, object *ObjectStream) error {
+	content := &hashingReader{ReadCloser: object.Content, hash: sha256.New()}
+	stream := *object
+	stream.Content = content
+	opts, upload, err := s.putStream(ctx, &stream)
+	if err != nil {
+		return err
+	}
+	if content.read > maxCopyObjectSize {
+		s.logger.DebugContext(ctx, "object stored without checksum", "object_id", object.ID, "size", content.read)
+		return nil
+	}
+
+	// headers are sent as they are, as copy options of the client strip the user metadata prefix, letting
+	// metadata such as "Content-Type" override standard headers
+	headers := map[string]string{
+		"X-Amz-Metadata-Directive":               "REPLACE",
+		"X-Amz-Copy-Source-If-Match":             upload.ETag,
+		"Content-Type":                           opts.ContentType,
+		userMetadataPrefix + checksumMetadataKey: hex.EncodeToString(content.hash.Sum(nil)),
+	}
+	if opts.ContentEncoding != "" {
+		headers["Content-Encoding"] = opts.ContentEncoding
+	}
+	for key, value := range opts.UserMetadata {
+		if !strings.HasPrefix(key, userMetadataPrefix) {
+			key = userMetadataPrefix + key
+		}
+		headers[key] = value
+	}
+	key := s.objectKey(ctx, object.ID)
+	_, err = (&minio.Core{Client: s.dataClient}).CopyObject(ctx, s.bucket(ctx), key, s.bucket(ctx), key, headers,
+		minio.CopySrcOptions{}, minio.PutObjectOptions{})
+	// object replaced meanwhile is stored with checksum by its own write
+	if err != nil && minio.ToErrorResponse(err).Code != "PreconditionFailed" {
+		s.logger.WarnContext(ctx, "object stored without checksum", "object_id", object.ID, "error", err)
+	}
+	return nil
+}
+
+// putStream stores object content streamed as it is, compressed if configured and the size is known. It returns
+// options the object was stored with.
+func (s *MinioStorage) putStream(ctx context.Context, object *ObjectStream) (minio.PutObjectOptions, minio.UploadInfo, error) {
+	if err := s.ensureBucket(ctx); err != nil {
+		return minio.PutObjectOptions{}, minio.UploadInfo{}, fmt.Errorf("error put object stream (%s | %s): %w", s.endpoint, object.ID, err)
+	}
+	opts := minio.PutObjectOptions{
+		ContentType:  s.contentType(object.ContentType),
+		UserMetadata: userMetadata(object.Metadata, ""),
+	}
+	setExpiry(opts.UserMetadata, object.ExpiresAt)
+	var content io.Reader = object.Content
+	size := object.Size
+	// original size is stored upfront, so streams of unknown size are stored uncompressed
+	if size >= 0 && s.cfg.Compression.compressible(opts.ContentType) {
+		// compressed size isn't known, so the declared one is verified while compressing
+		gzipped := gzipStream(&lengthVerifyingReader{ReadCloser: object.Content, expected: size})
+		defer gzipped.Close()
+		content, size = gzipped, -1
+		setCompressed(&opts, object.Size)
+	}
+	upload, err := s.dataClient.PutObject(ctx, s.bucket(ctx), s.objectKey(ctx, object.ID), content, size, opts)
+	if err != nil {
+		return opts, upload, fmt.Errorf("error put object stream (%s | %s): %w", s.endpoint, object.ID, err)
+	}
+	return opts, upload, nil
+}
+
+func (s *MinioStorage) GetStream(ctx context.Context, id string) (object *ObjectStream, err error) {
+	ctx, release, stop := s.firstByteContext(ctx)
+	// only opening the stream is retried, failures while streaming content surface to the reader
+	err = retry(ctx, s.logger, s.cfg.Retry, func() error {
+		object, err = s.getStream(ctx, id, s.cfg.Compression.PassThrough)
+		return err
+	})
+	return streamWithinFirstByteTimeout(ctx, release, stop, object, err)
+}
+
+// getStream opens stream of object content. Compressed content is decompressed, unless passed through.
+func (s *MinioStorage) getStream(ctx context.Context, id string, passThrough bool) (*ObjectStream, error) {
+	mObj, err := s.dataClient.GetObject(ctx, s.bucket(ctx), s.objectKey(ctx, id), minio.GetObjectOptions{})
+	if err != nil {
+		return s.handleKeyDoesNotExistStreamError(err, "error get object stream", id)
+	}
+
+	// stat issues the request, so it detects missing key before any content is streamed
+	info, err := mObj.Stat()
+	if err != nil {
+		mObj.Close()
+		return s.handleKeyDoesNotExistStreamError(err, "error get object stream: unable to read stat", id)
+	}
+	if s.expired(info) {
+		mObj.Close()
+		return nil, nil
+	}
+	if blobReference(info) != "" {
+		mObj.Close()
+		return s.getReferencedStream(ctx, id, info, passThrough)
+	}
+
+	var content io.ReadCloser = mObj
+	if s.cfg.VerifyContentLength && info.Size >= 0 {
+		content = &lengthVerifyingReader{ReadCloser: content, expected: info.Size}
+	}
+	object := &ObjectStream{
+		ID:           id,
+		ContentType:  info.ContentType,
+		Size:         info.Size,
+		Metadata:     objectMetadata(info),
+		LastModified: info.LastModified,
+		ETag:         objectETag(info),
+		ExpiresAt:    objectExpiry(info),
+	}
+	// content passed through compressed isn't verified, as checksum is of the original content
+	if compressed(info) && passThrough {
+		object.Content = content
+		object.ContentEncoding = ContentEncodingGzip
+		return object, nil
+	}
+	if compressed(info) {
+		if object.Size, err = uncompressedSize(info); err != nil {
+			content.Close()
+			return nil, fmt.Errorf("error get object stream (%s | %s): %w", s.endpoint, id, err)
+		}
+		if content, err = newGunzipReader(content); err != nil {
+			return nil, fmt.Errorf("error get object stream (%s | %s): unable to decompress content: %w", s.endpoint, id, err)
+		}
+	}
+	if checksum := info.UserMetadata[checksumMetadataKey]; checksum != "" {
+		content = newChecksumVerifyingReader(content, checksum)
+	}
+	object.Content = content
+	return object, nil
+}
+
+func (s *MinioStorage) GetRange(ctx context.Context, id string, offset, length int64) (object *ObjectStream, err error) {
+	ctx, release, stop := s.firstByteContext(ctx)
+	// only opening the stream is retried, failures while streaming content surface to the reader
+	err = retry(ctx, s.logger, s.cfg.Retry, func() error {
+		object, err = s.getRange(ctx, id, offset, length)
+		return err
+	})
+	return streamWithinFirstByteTimeout(ctx, release, stop, object, err)
+}
+
+func (s *MinioStorage) getRange(ctx context.Context, id string, offset, length int64) (*ObjectStream, error) {
+	if offset < 0 || length <= 0 {
+		return nil, fmt.Errorf("error get object range (%s | %s): %w: offset %d, length %d", s.endpoint, id, ErrInvalidRange, offset, length)
+	}
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(offset, offset+length-1); err != nil {
+		return nil, fmt.Errorf("error get object range (%s | %s): %w: %v", s.endpoint, id, ErrInvalidRange, err)
+	}
+
+	// unlike minio.Object, core client issues a single ranged request, as stat of minio.Object drops the range
+	content, info, _, err := (&minio.Core{Client: s.dataClient}).GetObject(ctx, s.bucket(ctx), s.objectKey(ctx, id), opts)
+	if err != nil {
+		if invalidRange(err) {
+			// range may be beyond compressed or referenced content, yet within the original one
+			stat, statErr := s.client.StatObject(ctx, s.bucket(ctx), s.objectKey(ctx, id), minio.StatObjectOptions{})
+			if statErr == nil && s.expired(stat) {
+				return nil, nil
+			}
+			if statErr == nil && blobReference(stat) != "" {
+				return s.getReferencedRange(ctx, id, stat, offset, length)
+			}
+			if statErr == nil && compressed(stat) {
+				return s.getCompressedRange(ctx, id, offset, length)
+			}
+			return nil, fmt.Errorf("error get object range (%s | %s): %w: offset %d, length %d", s.endpoint, id, ErrInvalidRange, offset, length)
+		}
+		return s.handleKeyDoesNotExistStreamError(err, "error get object range", id)
+	}
+	if s.expired(info) {
+		content.Close()
+		return nil, nil
+	}
+	if blobReference(info) != "" {
+		content.Close()
+		return s.getReferencedRange(ctx, id, info, offset, length)
+	}
+	if compressed(info) {
+		content.Close()
+		return s.getCompressedRange(ctx, id, offset, length)
+	}
+
+	if s.cfg.VerifyContentLength && info.Size >= 0 {
+		content = &lengthVerifyingReader{ReadCloser: content, expected: info.Size}
+	}
+
+	return &ObjectStream{
+		ID:           id,
+		ContentType:  info.ContentType,
+		Size:         info.Size,
+		Content:      content,
+		Metadata:     objectMetadata(info),
+		LastModified: info.LastModified,
+		ETag:         objectETag(info),
+		ExpiresAt:    objectExpiry(info),
+	}, nil
+}
+
+// getCompressedRange returns range of compressed object's original content. Ranges of stored content don't map
+// to the original one, so the object is decompressed from the start, skipping content before the range.
+func (s *MinioStorage) getCompressedRange(ctx context.Context, id string, offset, length int64) (*ObjectStream, error) {
+	object, err := s.getStream(ctx, id, false)
+	if err != nil || object == nil {
+		return object, err
+	}
+	if offset >= object.Size {
+		object.Content.Close()
+		return nil, fmt.Errorf("error get object range (%s | %s): %w: offset %d, length %d", s.endpoint, id, ErrInvalidRange, offset, length)
+	}
+	if _, err := io.CopyN(io.Discard, object.Content, offset); err != nil {
+		object.Content.Close()
+		return nil, fmt.Errorf("error get object range (%s | %s): unable to skip compressed content: %w", s.endpoint, id, err)
+	}
+
+	object.Size = min(length, object.Size-offset)
+	object.Content = struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(object.Content, object.Size), object.Content}
+	return object, nil
+}
+
+func (s *MinioStorage) Stat(ctx context.Context, id string) (*ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket(ctx), s.objectKey(ctx, id), minio.StatObjectOptions{})
+	if err != nil {
+		if keyDoesNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error stat object (%s | %s): %w", s.endpoint, id, err)
+	}
+	if s.expired(info) {
+		return nil, nil
+	}
+	size, err := objectSize(info)
+	if err != nil {
+		return nil, fmt.Errorf("error stat object (%s | %s): %w", s.endpoint, id, err)
+	}
+
+	return &ObjectInfo{
+		ID:           id,
+		ContentType:  info.ContentType,
+		Size:         size,
+		LastModified: info.LastModified,
+		ETag:         objectETag(info),
+		Metadata:     objectMetadata(info),
+		ExpiresAt:    objectExpiry(info),
+	}, nil
+}
+
+func (s *MinioStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	// listing stops only when its context is done, so it's cancelled when returning early on error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keyPrefix := s.keyPrefix(s.bucket(ctx))
+	var ids []string
+	for info := range s.client.ListObjects(ctx, s.bucket(ctx), minio.ListObjectsOptions{Prefix: keyPrefix + prefix, Recursive: true}) {
+		if info.Err != nil {
+			if bucketDoesNotExist(info.Err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("error list objects (%s | %s): %w", s.endpoint, prefix, info.Err)
+		}
+		ids = append(ids, strings.TrimPrefix(info.Key, keyPrefix))
+	}
+	return ids, nil
+}
+
+func (s *MinioStorage) Delete(ctx context.Context, id string) error {
+	// minio doesn't report removal of non-existent key, so check existence first
+	if _, err := s.client.StatObject(ctx, s.bucket(ctx), s.objectKey(ctx, id), minio.StatObjectOptions{}); err != nil {
+		if keyDoesNotExist(err) {
+			return fmt.Errorf("error delete object (%s | %s): %w", s.endpoint, id, ErrObjectNotFound)
+		}
+		return fmt.Errorf("error delete object (%s | %s): unable to read stat: %w", s.endpoint, id, err)
+	}
+
+	if err := s.client.RemoveObject(ctx, s.bucket(ctx), s.objectKey(ctx, id), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("error delete object (%s | %s): %w", s.endpoint, id, err)
+	}
+	return nil
+}
+
+// Copy copies the object server-side, within the node.
+func (s *MinioStorage) Copy(ctx context.Context, srcID, dstID string) error {
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+	if err := s.ensureBucket(ctx); err != nil {
+		return fmt.Errorf("error copy object (%s | %s): %w", s.endpoint, srcID, err)
+	}
+	err := retry(ctx, s.logger, s.cfg.Retry, func() error {
+		_, err := s.client.CopyObject(ctx,
+			minio.CopyDestOptions{Bucket: s.bucket(ctx), Object: s.objectKey(ctx, dstID)},
+			minio.CopySrcOptions{Bucket: s.bucket(ctx), Object: s.objectKey(ctx, srcID)})
+		return err
+	})
+	if err != nil {
+		if keyDoesNotExist(err) {
+			return fmt.Errorf("error copy object (%s | %s): %w", s.endpoint, srcID, ErrObjectNotFound)
+		}
+		return fmt.Errorf("error copy object (%s | %s => %s): %w", s.endpoint, srcID, dstID, err)
+	}
+	return nil
+}
+
+// operationContext bounds ctx by the operation timeout, if configured. Streamed reads are bounded by
+// firstByteContext instead, as their content is transferred after they return.
+func (s *MinioStorage) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.cfg.OperationTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.cfg.OperationTimeout)
+}
+
+// firstByteContext bounds ctx of a streamed read by the operation timeout, if configured, until the first byte
+// of its content is read, so a stalled node can't hang the read while transferring large content may take longer.
+// It returns ctx cancelled with context.DeadlineExceeded cause once the timeout elapses, and function stopping it.
+func (s *MinioStorage) firstByteContext(ctx context.Context) (context.Context, context.CancelFunc, func() bool) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	release := func() { cancel(context.Canceled) }
+	if s.cfg.OperationTimeout <= 0 {
+		return ctx, release, func() bool { return false }
+	}
+	timer := time.AfterFunc(s.cfg.OperationTimeout, func() { cancel(context.DeadlineExceeded) })
+	return ctx, release, timer.Stop
+}
+
+// streamWithinFirstByteTimeout bounds content of the stream opened with ctx of firstByteContext, releasing ctx
+// once the stream is closed. Stream failed to open releases ctx right away.
+func streamWithinFirstByteTimeout(ctx context.Context, release context.CancelFunc, stop func() bool, object *ObjectStream, err error) (*ObjectStream, error) {
+	if err != nil || object == nil {
+		release()
+		return object, timedOut(ctx, err)
+	}
+	object.Content = &firstByteReader{ReadCloser: object.Content, ctx: ctx, stop: stop, release: release}
+	return object, nil
+}
+
+// contentType returns the given content type, or the configured default when it's empty. Minio versions differ
+// in what they store for empty content type, so it's always set explicitly to keep reads deterministic.
+func (s *MinioStorage) contentType(contentType string) string {
+	if contentType != "" {
+		return contentType
+	}
+	if s.cfg.DefaultContentType != "" {
+		return s.cfg.DefaultContentType
+	}
+	return DefaultContentType
+}
+
+// Ping checks that node serves requests and the bucket exists.
+func (s *MinioStorage) Ping(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucketName)
+	if err != nil {
+		return fmt.Errorf("error ping (%s): %w", s.endpoint, err)
+	}
+	if !exists {
+		return fmt.Errorf("error ping (%s): bucket %s doesn't exist", s.endpoint, s.bucketName)
+	}
+	return nil
+}
+
+// adoptBucketRegion switches the client to the bucket's actual region if allowed by configuration.
+func (s *MinioStorage) adoptBucketRegion(actual string) error {
+	s.logger.Warn("bucket region mismatch", "bucket", s.bucketName, "expected_region", s.cfg.Region, "actual_region", actual)
+	if !s.cfg.AdoptBucketRegion || actual == "" {
+		return fmt.Errorf("bucket %s exists in region %q but node is configured for region %q: "+
+			"set the configured region to %q or enable bucket region adoption", s.bucketName, actual, s.cfg.Region, actual)
+	}
+
+	if err := s.connect(actual); err != nil {
+		return fmt.Errorf("unable to recreate client for region %q: %w", actual, err)
+	}
+	s.cfg.Region = actual
+	s.logger.Info("adopted bucket region", "bucket", s.bucketName, "region", actual)
+	return nil
+}
+
 func (s *MinioStorage) handleKeyDoesNotExistError(err error, prefix, id string) (*Object, error) {
 	if keyDoesNotExist(err) {
 		return nil, nil
@@ -119,6 +954,94 @@ func (s *MinioStorage) handleKeyDoesNotExistError(err error, prefix, id string)
 	return nil, fmt.Errorf("%s (%s | %s): %w", prefix, s.endpoint, id, err)
 }
 
+// bucketRegionMismatch checks if error is caused by a bucket region mismatch and returns the actual bucket region.
+func bucketRegionMismatch(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+	errResp := minio.ToErrorResponse(err)
+	if !minioRegionMismatchCodes[errResp.Code] {
+		return "", false
+	}
+	return errResp.Region, true
+}
+
+// authenticationFailed checks if error is caused by invalid node credentials.
+func authenticationFailed(err error) bool {
+	var errResp minio.ErrorResponse
+	return errors.As(err, &errResp) && minioAuthErrorCodes[errResp.Code]
+}
+
+func (s *MinioStorage) handleKeyDoesNotExistStreamError(err error, prefix, id string) (*ObjectStream, error) {
+	if keyDoesNotExist(err) {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%s (%s | %s): %w", prefix, s.endpoint, id, err)
+}
+
+// keyDoesNotExist checks if node reported the object missing. Objects of buckets not created yet are missing too.
 func keyDoesNotExist(err error) bool {
-	return strings.Contains(err.Error(), MinioKeyNotExistErrString)
+	return strings.Contains(err.Error(), MinioKeyNotExistErrString) || bucketDoesNotExist(err)
+}
+
+// bucketDoesNotExist checks if node reported the bucket missing.
+func bucketDoesNotExist(err error) bool {
+	return minio.ToErrorResponse(err).Code == "NoSuchBucket"
+}
+
+// objectETag returns stored object checksum, which is the same on all replicas, falling back to the node's ETag.
+func objectETag(info minio.ObjectInfo) string {
+	if checksum := info.UserMetadata[checksumMetadataKey]; checksum != "" {
+		return checksum
+	}
+	return info.ETag
+}
+
+// userMetadata returns minio user metadata storing object metadata, and checksum unless empty. Object metadata
+// can't override internal keys, and its keys are sent prefixed, so they aren't mistaken for standard headers.
+func userMetadata(metadata map[string]string, checksum string) map[string]string {
+	stored := make(map[string]string, len(metadata)+1)
+	for key, value := range metadata {
+		if !internalMetadataKey(key) {
+			stored[userMetadataPrefix+key] = value
+		}
+	}
+	if checksum != "" {
+		stored[checksumMetadataKey] = checksum
+	}
+	return stored
+}
+
+// objectMetadata returns object metadata stored with the object by userMetadata, nil if there's none.
+func objectMetadata(info minio.ObjectInfo) map[string]string {
+	var metadata map[string]string
+	for key, value := range info.UserMetadata {
+		if internalMetadataKey(key) {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string, len(info.UserMetadata))
+		}
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// internalMetadataKey checks if user metadata key is used by storage itself rather than holding object metadata.
+func internalMetadataKey(key string) bool {
+	return strings.EqualFold(key, checksumMetadataKey) || strings.EqualFold(key, uncompressedSizeMetadataKey) ||
+		strings.EqualFold(key, blobMetadataKey) || strings.EqualFold(key, blobSizeMetadataKey) ||
+		strings.EqualFold(key, expiresAtMetadataKey)
+}
+
+// contentChecksum returns hex encoded SHA-256 of object content.
+func contentChecksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// invalidRange checks if node rejected requested range as not satisfiable.
+func invalidRange(err error) bool {
+	var errResp minio.ErrorResponse
+	return errors.As(err, &errResp) && (errResp.Code == "InvalidRange" || errResp.StatusCode == http.StatusRequestedRangeNotSatisfiable)
 }
@@ -0,0 +1,999 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketRegionMismatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedRegion string
+		expectedMatch  bool
+	}{
+		{name: "no error", err: nil},
+		{name: "unrelated error", err: errors.New("connection refused")},
+		{name: "access denied", err: minio.ErrorResponse{Code: "AccessDenied", Region: "eu-west-1"}},
+		{name: "invalid region", err: minio.ErrorResponse{Code: "InvalidRegion", Region: "eu-west-1"}, expectedRegion: "eu-west-1", expectedMatch: true},
+		{name: "malformed authorization", err: minio.ErrorResponse{Code: "AuthorizationHeaderMalformed"}, expectedMatch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			region, mismatch := bucketRegionMismatch(tt.err)
+			assert.Equal(t, tt.expectedMatch, mismatch)
+			assert.Equal(t, tt.expectedRegion, region)
+		})
+	}
+}
+
+func TestMinioStorage_InitRegionMismatch(t *testing.T) {
+	// fake minio node whose bucket lives in eu-west-1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/") {
+			w.Header().Set("x-minio-error-code", "InvalidRegion")
+			w.Header().Set("x-amz-bucket-region", "eu-west-1")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		adopt         bool
+		expectedError string
+	}{
+		{name: "fail with actionable message", adopt: false, expectedError: `bucket default exists in region "eu-west-1" but node is configured for region "us-east-1"`},
+		{name: "adopt actual region", adopt: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewMinioStorage(&MinioConfig{
+				Endpoint:          strings.TrimPrefix(server.URL, "http://"),
+				AccessKey:         "key",
+				SecretKey:         "secret",
+				BucketName:        "default",
+				Region:            "us-east-1",
+				AdoptBucketRegion: tt.adopt,
+			})
+			assert.NoError(t, err)
+
+			err = s.Init(context.TODO())
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "eu-west-1", s.(*MinioStorage).cfg.Region)
+		})
+	}
+}
+
+func TestMinioStorage_Buckets(t *testing.T) {
+	// fake minio node with the configured bucket only, creating buckets on request
+	var mu sync.Mutex
+	buckets := map[string]bool{"default": true}
+	var bucketChecks []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = io.Copy(io.Discard, r.Body)
+		bucket, object, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		switch {
+		case object == "" && r.Method == http.MethodHead:
+			bucketChecks = append(bucketChecks, bucket)
+			if !buckets[bucket] {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		case object == "" && r.Method == http.MethodPut:
+			buckets[bucket] = true
+		case !buckets[bucket]:
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>`)
+			return
+		case object == "" && r.Method == http.MethodGet:
+			// listing of an empty bucket
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = io.WriteString(w, `<ListBucketResult></ListBucketResult>`)
+			return
+		case r.Header.Get("X-Amz-Copy-Source") != "":
+			_, _ = io.WriteString(w, copyObjectResult)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	s, err := NewMinioStorage(&MinioConfig{
+		Endpoint:   strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:  "key",
+		SecretKey:  "secret",
+		BucketName: "default",
+		Region:     "us-east-1",
+	})
+	assert.NoError(t, err)
+	ctx := WithBucket(context.TODO(), "photos")
+
+	// objects of a bucket not created yet are missing
+	obj, err := s.Get(ctx, "object-1")
+	assert.NoError(t, err)
+	assert.Nil(t, obj)
+	info, err := s.Stat(ctx, "object-1")
+	assert.NoError(t, err)
+	assert.Nil(t, info)
+	ids, err := s.List(ctx, "")
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+	assert.ErrorIs(t, s.Delete(ctx, "object-1"), ErrObjectNotFound)
+
+	// bucket is created by the first write into it and checked only once
+	assert.NoError(t, s.Put(ctx, &Object{ID: "object-1", Content: []byte("data")}))
+	assert.NoError(t, s.PutStream(ctx, &ObjectStream{ID: "object-2", Size: 4, Content: io.NopCloser(strings.NewReader("data"))}))
+	mu.Lock()
+	assert.True(t, buckets["photos"])
+	assert.Equal(t, []string{"photos"}, bucketChecks)
+	mu.Unlock()
+
+	// configured bucket is used without bucket in context, and isn't checked
+	assert.NoError(t, s.Put(context.TODO(), &Object{ID: "object-1", Content: []byte("data")}))
+	mu.Lock()
+	assert.Equal(t, []string{"photos"}, bucketChecks)
+	mu.Unlock()
+}
+
+func TestMinioStorage_GetContentLengthMismatch(t *testing.T) {
+	// fake minio node declaring more bytes than it sends
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", "10")
+		w.Header().Set("Last-Modified", "Sun, 01 Oct 2023 12:00:00 GMT")
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("12345"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		verify        bool
+		expectedError error
+	}{
+		{name: "verification enabled", verify: true, expectedError: ErrContentLengthMismatch},
+		{name: "verification disabled", verify: false, expectedError: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewMinioStorage(&MinioConfig{
+				Endpoint:            strings.TrimPrefix(server.URL, "http://"),
+				AccessKey:           "key",
+				SecretKey:           "secret",
+				BucketName:          "default",
+				Region:              "us-east-1",
+				VerifyContentLength: tt.verify,
+			})
+			assert.NoError(t, err)
+
+			obj, err := s.Get(context.TODO(), "object")
+			assert.Nil(t, obj)
+			assert.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestMinioStorage_PutSlowResponse(t *testing.T) {
+	// fake minio node responding slowly after ingesting the uploaded body
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		dataTimeout time.Duration
+		expectedErr bool
+	}{
+		{name: "relaxed data timeout", dataTimeout: 0},
+		{name: "tight data timeout", dataTimeout: 50 * time.Millisecond, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewMinioStorage(&MinioConfig{
+				Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+				AccessKey:       "key",
+				SecretKey:       "secret",
+				BucketName:      "default",
+				Region:          "us-east-1",
+				MetadataTimeout: 50 * time.Millisecond,
+				DataTimeout:     tt.dataTimeout,
+			})
+			assert.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			err = s.Put(ctx, &Object{ID: "object", ContentType: "text/plain", Content: bytes.Repeat([]byte("x"), 1<<20)})
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMinioStorage_DefaultContentType(t *testing.T) {
+	// fake minio node storing objects with the content type they were uploaded with
+	contentTypes := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		switch r.Method {
+		case http.MethodPut:
+			_, _ = io.Copy(io.Discard, r.Body)
+			contentTypes[id] = r.Header.Get("Content-Type")
+		case http.MethodGet, http.MethodHead:
+			w.Header().Set("Content-Type", contentTypes[id])
+			w.Header().Set("Content-Length", "0")
+			w.Header().Set("Last-Modified", "Sun, 01 Oct 2023 12:00:00 GMT")
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name                string
+		defaultContentType  string
+		expectedContentType string
+	}{
+		{name: "built-in default", expectedContentType: DefaultContentType},
+		{name: "configured default", defaultContentType: "text/plain", expectedContentType: "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewMinioStorage(&MinioConfig{
+				Endpoint:           strings.TrimPrefix(server.URL, "http://"),
+				AccessKey:          "key",
+				SecretKey:          "secret",
+				BucketName:         "default",
+				Region:             "us-east-1",
+				DefaultContentType: tt.defaultContentType,
+			})
+			assert.NoError(t, err)
+
+			err = s.Put(context.TODO(), &Object{ID: "object", Content: []byte{}})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedContentType, contentTypes["object"])
+
+			obj, err := s.Get(context.TODO(), "object")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedContentType, obj.ContentType)
+		})
+	}
+}
+
+func TestMinioStorage_InitWarmUp(t *testing.T) {
+	// fake minio node counting opened connections, responding slowly so warm-up requests overlap
+	var mu sync.Mutex
+	connections := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Last-Modified", "Sun, 01 Oct 2023 12:00:00 GMT")
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			connections++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+	opened := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return connections
+	}
+
+	s, err := NewMinioStorage(&MinioConfig{
+		Endpoint:          strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:         "key",
+		SecretKey:         "secret",
+		BucketName:        "default",
+		Region:            "us-east-1",
+		WarmupConnections: 3,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Init(context.TODO()))
+	// warmed up connections of metadata and data clients
+	assert.Equal(t, 6, opened())
+
+	// concurrent requests reuse warmed up connections
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := s.Stat(context.TODO(), "object")
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			obj, err := s.GetStream(context.TODO(), "object")
+			assert.NoError(t, err)
+			obj.Content.Close()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 6, opened())
+}
+
+func TestMinioStorage_GetRange(t *testing.T) {
+	// fake minio node serving object content with Range support
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", `"etag"`)
+		http.ServeContent(w, r, "object", time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC), strings.NewReader("0123456789"))
+	}))
+	defer server.Close()
+
+	s, err := NewMinioStorage(&MinioConfig{
+		Endpoint:            strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:           "key",
+		SecretKey:           "secret",
+		BucketName:          "default",
+		Region:              "us-east-1",
+		VerifyContentLength: true,
+	})
+	assert.NoError(t, err)
+
+	obj, err := s.GetRange(context.TODO(), "object", 2, 4)
+	if assert.NoError(t, err) {
+		defer obj.Content.Close()
+		content, err := io.ReadAll(obj.Content)
+		assert.NoError(t, err)
+		assert.Equal(t, "2345", string(content))
+		assert.Equal(t, int64(4), obj.Size)
+		assert.Equal(t, "text/plain", obj.ContentType)
+	}
+
+	_, err = s.GetRange(context.TODO(), "object", 10, 5)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+
+	obj, err = s.GetRange(context.TODO(), "missing", 0, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, obj)
+}
+
+func TestMinioStorage_Checksum(t *testing.T) {
+	// fake minio node serving known object content with checksums stored on put, optionally corrupting the content.
+	// Uploaded body isn't kept, as it's signed in chunks.
+	var mu sync.Mutex
+	contents := map[string]string{"object": "test content", "legacy": "legacy content"}
+	checksums := map[string]string{}
+	corrupt, replaced, copyDenied := false, false, false
+	copies := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		if r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "" {
+			copies++
+			if copyDenied {
+				w.Header().Set("Content-Type", "application/xml")
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>Access Denied.</Message></Error>`)
+				return
+			}
+			// object replaced since the upload doesn't match its ETag anymore
+			if replaced || r.Header.Get("X-Amz-Copy-Source-If-Match") != "etag" {
+				w.Header().Set("Content-Type", "application/xml")
+				w.WriteHeader(http.StatusPreconditionFailed)
+				_, _ = io.WriteString(w, `<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`)
+				return
+			}
+			checksums[id] = r.Header.Get("X-Amz-Meta-Sha256")
+			_, _ = io.WriteString(w, copyObjectResult)
+			return
+		}
+		if r.Method == http.MethodPut {
+			_, _ = io.Copy(io.Discard, r.Body)
+			checksums[id] = r.Header.Get("X-Amz-Meta-Sha256")
+			w.Header().Set("ETag", `"etag"`)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		content := []byte(contents[id])
+		if corrupt {
+			content[0] ^= 0xff
+		}
+		if checksum := checksums[id]; checksum != "" {
+			w.Header().Set("X-Amz-Meta-Sha256", checksum)
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Header().Set("Last-Modified", "Sun, 01 Oct 2023 12:00:00 GMT")
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(content)
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewMinioStorage(&MinioConfig{
+		Endpoint:   strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:  "key",
+		SecretKey:  "secret",
+		BucketName: "default",
+		Region:     "us-east-1",
+	})
+	assert.NoError(t, err)
+
+	// checksum is stored on put and verified on get
+	err = s.Put(context.TODO(), &Object{ID: "object", ContentType: "text/plain", Content: []byte("test content")})
+	assert.NoError(t, err)
+	mu.Lock()
+	assert.Equal(t, contentChecksum([]byte("test content")), checksums["object"])
+	mu.Unlock()
+
+	obj, err := s.Get(context.TODO(), "object")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte("test content"), obj.Content)
+		assert.Equal(t, contentChecksum([]byte("test content")), obj.Checksum)
+		// stored checksum identifies content rather than node ETag
+		assert.Equal(t, contentChecksum([]byte("test content")), obj.ETag)
+		assert.Equal(t, time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC), obj.LastModified.UTC())
+	}
+
+	// streamed content is stored without checksum by default, so it isn't copied
+	err = s.PutStream(context.TODO(), &ObjectStream{ID: "unverified", Size: 12, Content: io.NopCloser(strings.NewReader("test content"))})
+	assert.NoError(t, err)
+	mu.Lock()
+	assert.Empty(t, checksums["unverified"])
+	assert.Zero(t, copies)
+	mu.Unlock()
+
+	// checksum of streamed content is stored once it's uploaded, unless the object was replaced meanwhile
+	streamChecksums, err := NewMinioStorage(&MinioConfig{
+		Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:       "key",
+		SecretKey:       "secret",
+		BucketName:      "default",
+		Region:          "us-east-1",
+		StreamChecksums: true,
+	})
+	assert.NoError(t, err)
+	err = streamChecksums.PutStream(context.TODO(), &ObjectStream{ID: "streamed", Size: 12, Content: io.NopCloser(strings.NewReader("test content"))})
+	assert.NoError(t, err)
+	mu.Lock()
+	assert.Equal(t, contentChecksum([]byte("test content")), checksums["streamed"])
+	replaced = true
+	mu.Unlock()
+	err = streamChecksums.PutStream(context.TODO(), &ObjectStream{ID: "replaced", Size: 12, Content: io.NopCloser(strings.NewReader("test content"))})
+	assert.NoError(t, err)
+	mu.Lock()
+	assert.Empty(t, checksums["replaced"])
+	// failing to store the checksum doesn't fail the upload, as the content is stored already
+	copyDenied = true
+	mu.Unlock()
+	err = streamChecksums.PutStream(context.TODO(), &ObjectStream{ID: "denied", Size: 12, Content: io.NopCloser(strings.NewReader("test content"))})
+	assert.NoError(t, err)
+	mu.Lock()
+	assert.Empty(t, checksums["denied"])
+	assert.Equal(t, 3, copies)
+	mu.Unlock()
+
+	// corrupted content is detected by get and at the end of stream
+	mu.Lock()
+	corrupt = true
+	mu.Unlock()
+	obj, err = s.Get(context.TODO(), "object")
+	assert.Nil(t, obj)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	stream, err := s.GetStream(context.TODO(), "object")
+	if assert.NoError(t, err) {
+		_, err = io.ReadAll(stream.Content)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+		stream.Content.Close()
+	}
+
+	// objects stored without checksum aren't verified
+	obj, err = s.Get(context.TODO(), "legacy")
+	if assert.NoError(t, err) {
+		assert.Empty(t, obj.Checksum)
+		assert.Equal(t, "etag", obj.ETag)
+	}
+}
+
+func TestMinioStorage_Metadata(t *testing.T) {
+	// fake minio node storing user metadata headers of uploaded and copied objects and serving them back
+	var mu sync.Mutex
+	headers := map[string]http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		if r.Method == http.MethodPut {
+			_, _ = io.Copy(io.Discard, r.Body)
+			stored := http.Header{}
+			for key, values := range r.Header {
+				if strings.HasPrefix(key, "X-Amz-Meta-") {
+					stored[key] = values
+				}
+			}
+			stored.Set("Content-Type", r.Header.Get("Content-Type"))
+			headers[id] = stored
+			if r.Header.Get("X-Amz-Copy-Source") != "" {
+				_, _ = io.WriteString(w, copyObjectResult)
+			}
+			return
+		}
+
+		for key, values := range headers[id] {
+			w.Header()[key] = values
+		}
+		w.Header().Set("Content-Length", "4")
+		w.Header().Set("Last-Modified", "Sun, 01 Oct 2023 12:00:00 GMT")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte("data"))
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewMinioStorage(&MinioConfig{
+		Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:       "key",
+		SecretKey:       "secret",
+		BucketName:      "default",
+		Region:          "us-east-1",
+		StreamChecksums: true,
+	})
+	assert.NoError(t, err)
+
+	// metadata can't pass for the checksum or standard headers
+	metadata := map[string]string{"Author": "jane", "Content-Type": "image/png", "Sha256": "forged"}
+	expected := map[string]string{"Author": "jane", "Content-Type": "image/png"}
+	err = s.Put(context.TODO(), &Object{ID: "object", ContentType: "text/plain", Content: []byte("data"), Metadata: metadata})
+	assert.NoError(t, err)
+	obj, err := s.Get(context.TODO(), "object")
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, obj.Metadata)
+		assert.Equal(t, "text/plain", obj.ContentType)
+		assert.Equal(t, contentChecksum([]byte("data")), obj.Checksum)
+	}
+
+	err = s.PutStream(context.TODO(), &ObjectStream{ID: "stream", ContentType: "text/plain", Size: 4, Content: io.NopCloser(strings.NewReader("data")), Metadata: metadata})
+	assert.NoError(t, err)
+	stream, err := s.GetStream(context.TODO(), "stream")
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, stream.Metadata)
+		assert.Equal(t, "text/plain", stream.ContentType)
+		stream.Content.Close()
+	}
+	info, err := s.Stat(context.TODO(), "stream")
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, info.Metadata)
+		// forged checksum isn't stored, so it doesn't identify content
+		assert.Equal(t, contentChecksum([]byte("data")), info.ETag)
+	}
+
+	// objects stored without metadata have none
+	err = s.PutStream(context.TODO(), &ObjectStream{ID: "plain", Size: 4, Content: io.NopCloser(strings.NewReader("data"))})
+	assert.NoError(t, err)
+	info, err = s.Stat(context.TODO(), "plain")
+	if assert.NoError(t, err) {
+		assert.Nil(t, info.Metadata)
+	}
+}
+
+func TestMinioStorage_Copy(t *testing.T) {
+	// fake minio node copying objects server-side, recording copy sources
+	var mu sync.Mutex
+	copied := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		source := r.Header.Get("X-Amz-Copy-Source")
+		if r.Method != http.MethodPut || source == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if strings.HasSuffix(source, "/missing") {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			return
+		}
+		copied[id] = source
+		_, _ = w.Write([]byte(`<CopyObjectResult><ETag>"etag"</ETag><LastModified>2023-10-01T12:00:00.000Z</LastModified></CopyObjectResult>`))
+	}))
+	defer server.Close()
+
+	s, err := NewMinioStorage(&MinioConfig{
+		Endpoint:   strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:  "key",
+		SecretKey:  "secret",
+		BucketName: "default",
+		Region:     "us-east-1",
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Copy(context.TODO(), "source", "copy"))
+	mu.Lock()
+	assert.Equal(t, "default/source", strings.TrimPrefix(copied["copy"], "/"))
+	mu.Unlock()
+
+	assert.ErrorIs(t, s.Copy(context.TODO(), "missing", "copy"), ErrObjectNotFound)
+}
+
+// writeCACert writes certificate of TLS test server to a PEM file, so it can be trusted as CA.
+func writeCACert(t *testing.T, server *httptest.Server) string {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, cert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMinioStorage_TLS(t *testing.T) {
+	// fake minio node served over TLS with a self-signed certificate, its bucket exists
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	caCertPath := writeCACert(t, server)
+
+	tests := []struct {
+		name          string
+		secure        bool
+		caCertPath    string
+		expectedError string
+	}{
+		{name: "trusted CA", secure: true, caCertPath: caCertPath},
+		{name: "untrusted certificate", secure: true, expectedError: "certificate"},
+		{name: "plaintext to TLS node", secure: false, caCertPath: caCertPath, expectedError: "unable to check bucket"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewMinioStorage(&MinioConfig{
+				Endpoint:   strings.TrimPrefix(server.URL, "https://"),
+				AccessKey:  "key",
+				SecretKey:  "secret",
+				BucketName: "default",
+				Region:     "us-east-1",
+				Secure:     tt.secure,
+				CACertPath: tt.caCertPath,
+			})
+			assert.NoError(t, err)
+
+			err = s.Init(context.TODO())
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	// CA file is checked when storage is created
+	_, err := NewMinioStorage(&MinioConfig{Endpoint: "localhost:9000", Secure: true, CACertPath: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.ErrorContains(t, err, "read CA certificates")
+}
+
+func TestMinioStorage_OperationContext(t *testing.T) {
+	// fake minio node never responding until the request is aborted or the test ends
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	newStorage := func(operationTimeout time.Duration) Storage {
+		s, err := NewMinioStorage(&MinioConfig{
+			Endpoint:         strings.TrimPrefix(server.URL, "http://"),
+			AccessKey:        "key",
+			SecretKey:        "secret",
+			BucketName:       "default",
+			Region:           "us-east-1",
+			OperationTimeout: operationTimeout,
+			Retry:            RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond},
+		})
+		assert.NoError(t, err)
+		return s
+	}
+	object := &Object{ID: "object", Content: []byte("test content")}
+
+	// context cancelled before the call
+	s := newStorage(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, s.Put(ctx, object), context.Canceled)
+	_, err := s.Get(ctx, "object")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// client gone mid-put
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	assert.ErrorIs(t, s.Put(ctx, object), context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// slow node
+	s = newStorage(50 * time.Millisecond)
+	start = time.Now()
+	assert.ErrorIs(t, s.Put(context.Background(), object), context.DeadlineExceeded)
+	_, err = s.Get(context.Background(), "object")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	err = s.PutStream(context.Background(), &ObjectStream{ID: "object", Size: 12, Content: io.NopCloser(strings.NewReader("test content"))})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = s.GetStream(context.Background(), "object")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = s.GetRange(context.Background(), "object", 0, 4)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestMinioStorage_FirstByteTimeout(t *testing.T) {
+	// fake minio node stalling after response headers, or streaming content slower than the operation timeout
+	const content = "test content"
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", "Sun, 01 Oct 2023 12:00:00 GMT")
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		if r.Header.Get("Range") != "" {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+		w.(http.Flusher).Flush()
+		if strings.HasSuffix(r.URL.Path, "/stalled") {
+			select {
+			case <-r.Context().Done():
+			case <-done:
+			}
+			return
+		}
+		_, _ = io.WriteString(w, content[:4])
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		_, _ = io.WriteString(w, content[4:])
+	}))
+	defer server.Close()
+	defer close(done)
+
+	s, err := NewMinioStorage(&MinioConfig{
+		Endpoint:         strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:        "key",
+		SecretKey:        "secret",
+		BucketName:       "default",
+		Region:           "us-east-1",
+		OperationTimeout: 50 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	// stalled node fails the read once the timeout elapses
+	for _, open := range []func() (*ObjectStream, error){
+		func() (*ObjectStream, error) { return s.GetStream(ctx, "stalled") },
+		func() (*ObjectStream, error) { return s.GetRange(ctx, "stalled", 0, int64(len(content))) },
+	} {
+		start := time.Now()
+		object, err := open()
+		if assert.NoError(t, err) && assert.NotNil(t, object) {
+			_, err = io.ReadAll(object.Content)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.NoError(t, object.Content.Close())
+		}
+		assert.Less(t, time.Since(start), time.Second)
+	}
+
+	// content arriving after the first byte isn't bounded by the timeout
+	for _, open := range []func() (*ObjectStream, error){
+		func() (*ObjectStream, error) { return s.GetStream(ctx, "slow") },
+		func() (*ObjectStream, error) { return s.GetRange(ctx, "slow", 0, int64(len(content))) },
+	} {
+		object, err := open()
+		if assert.NoError(t, err) && assert.NotNil(t, object) {
+			// reading more than the first chunk would wait for the rest
+			first := make([]byte, 4)
+			_, err = io.ReadFull(object.Content, first)
+			assert.NoError(t, err)
+			rest, err := io.ReadAll(object.Content)
+			assert.NoError(t, err)
+			assert.Equal(t, content, string(first)+string(rest))
+			assert.NoError(t, object.Content.Close())
+		}
+	}
+}
+
+func TestMinioStorage_KeyPrefix(t *testing.T) {
+	node := newBucketNode()
+	node.buckets["default"] = map[string][]byte{}
+	server := httptest.NewServer(node)
+	defer server.Close()
+	newStorage := func(keyPrefix string) Storage {
+		s, err := NewMinioStorage(&MinioConfig{
+			Endpoint:   strings.TrimPrefix(server.URL, "http://"),
+			AccessKey:  "key",
+			SecretKey:  "secret",
+			BucketName: "default",
+			Region:     "us-east-1",
+			KeyPrefix:  keyPrefix,
+		})
+		assert.NoError(t, err)
+		return s
+	}
+	app1, app2, unprefixed := newStorage("app1/"), newStorage("app2/"), newStorage("")
+	ctx := context.TODO()
+
+	// objects are stored under prefixed keys, transparently to the instance storing them
+	assert.NoError(t, app1.Put(ctx, &Object{ID: "report", Content: []byte("app1 report")}))
+	assert.NoError(t, app1.PutStream(ctx, &ObjectStream{ID: "logs/today", Size: 4, Content: io.NopCloser(strings.NewReader("logs"))}))
+	assert.NoError(t, app2.Put(ctx, &Object{ID: "report", Content: []byte("app2 report")}))
+	assert.ElementsMatch(t, []string{"app1/report", "app1/logs/today", "app2/report"}, node.keys("default"))
+
+	obj, err := app1.Get(ctx, "report")
+	if assert.NoError(t, err) && assert.NotNil(t, obj) {
+		assert.Equal(t, "report", obj.ID)
+		assert.Equal(t, []byte("app1 report"), obj.Content)
+	}
+	obj, err = app2.Get(ctx, "report")
+	if assert.NoError(t, err) && assert.NotNil(t, obj) {
+		assert.Equal(t, []byte("app2 report"), obj.Content)
+	}
+	ids, err := app1.List(ctx, "")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"report", "logs/today"}, ids)
+	ids, err = app1.List(ctx, "logs/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"logs/today"}, ids)
+
+	// objects of a namespace aren't visible to an unprefixed instance
+	obj, err = unprefixed.Get(ctx, "report")
+	assert.NoError(t, err)
+	assert.Nil(t, obj)
+	info, err := unprefixed.Stat(ctx, "logs/today")
+	assert.NoError(t, err)
+	assert.Nil(t, info)
+	assert.ErrorIs(t, unprefixed.Delete(ctx, "report"), ErrObjectNotFound)
+	ids, err = unprefixed.List(ctx, "")
+	assert.NoError(t, err)
+	assert.NotContains(t, ids, "report")
+
+	// copies and deletes stay within the namespace
+	assert.NoError(t, app1.Copy(ctx, "report", "archive"))
+	assert.NoError(t, app2.Delete(ctx, "report"))
+	assert.ElementsMatch(t, []string{"app1/report", "app1/logs/today", "app1/archive"}, node.keys("default"))
+}
+
+func TestNormalizeEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint         string
+		secure           bool
+		expectedEndpoint string
+		expectedSecure   bool
+		expectedErr      string
+	}{
+		{endpoint: "10.0.0.1:9000", expectedEndpoint: "10.0.0.1:9000"},
+		{endpoint: "minio.example.com", secure: true, expectedEndpoint: "minio.example.com", expectedSecure: true},
+		{endpoint: "[::1]:9000", expectedEndpoint: "[::1]:9000"},
+		{endpoint: "https://minio.example.com:9000", expectedEndpoint: "minio.example.com:9000", expectedSecure: true},
+		{endpoint: "http://10.0.0.1:9000/", secure: true, expectedEndpoint: "10.0.0.1:9000"},
+		{endpoint: "10.0.0.1:9000/", expectedEndpoint: "10.0.0.1:9000"},
+		{endpoint: "", expectedErr: "empty host"},
+		{endpoint: "https://", expectedErr: "empty host"},
+		{endpoint: ":9000", expectedErr: "empty host"},
+		{endpoint: "10.0.0.1:", expectedErr: `invalid port ""`},
+		{endpoint: "10.0.0.1:0", expectedErr: `invalid port "0"`},
+		{endpoint: "10.0.0.1:70000", expectedErr: `invalid port "70000"`},
+		{endpoint: "10.0.0.1:port", expectedErr: "invalid port"},
+		{endpoint: "10.0.0.1:9000/bucket", expectedErr: "expected host with optional port"},
+		{endpoint: "key:secret@10.0.0.1:9000", expectedErr: "expected host with optional port"},
+		{endpoint: "ftp://10.0.0.1:9000", expectedErr: "invalid node endpoint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			endpoint, secure, err := normalizeEndpoint(tt.endpoint, tt.secure)
+			if tt.expectedErr != "" {
+				assert.ErrorIs(t, err, ErrInvalidEndpoint)
+				assert.ErrorContains(t, err, tt.expectedErr)
+				assert.ErrorContains(t, err, strconv.Quote(tt.endpoint))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedEndpoint, endpoint)
+			assert.Equal(t, tt.expectedSecure, secure)
+		})
+	}
+}
+
+func TestNewMinioStorage_InvalidEndpoint(t *testing.T) {
+	_, err := NewMinioStorage(&MinioConfig{Endpoint: "10.0.0.1:9000/bucket", AccessKey: "key", SecretKey: "secret"})
+	assert.ErrorIs(t, err, ErrInvalidEndpoint)
+	_, err = NewMinioStorage(&MinioConfig{Endpoint: "10.0.0.1:9000", AccessKey: "key", SecretKey: "secret", PublicEndpoint: "https://"})
+	assert.ErrorIs(t, err, ErrInvalidEndpoint)
+
+	// scheme prefix is stripped and selects TLS
+	s, err := NewMinioStorage(&MinioConfig{Endpoint: "https://10.0.0.1:9000/", AccessKey: "key", SecretKey: "secret"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "10.0.0.1:9000", s.(*MinioStorage).endpoint)
+		assert.Equal(t, "https", s.(*MinioStorage).client.EndpointURL().Scheme)
+	}
+}
+
+func TestNewNodeTransport(t *testing.T) {
+	tests := []struct {
+		name                 string
+		cfg                  MinioConfig
+		expectedIdleConns    int
+		expectedConnsPerHost int
+	}{
+		{name: "defaults", expectedIdleConns: DefaultMaxIdleConnsPerHost},
+		{name: "configured", cfg: MinioConfig{MaxIdleConnsPerHost: 20, MaxConnsPerHost: 50}, expectedIdleConns: 20, expectedConnsPerHost: 50},
+		{name: "warmed up connections kept", cfg: MinioConfig{MaxIdleConnsPerHost: 4, WarmupConnections: 8}, expectedIdleConns: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := newNodeTransport(&tt.cfg, time.Second)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedIdleConns, transport.MaxIdleConns)
+			assert.Equal(t, tt.expectedIdleConns, transport.MaxIdleConnsPerHost)
+			assert.Equal(t, tt.expectedConnsPerHost, transport.MaxConnsPerHost)
+			assert.Equal(t, time.Second, transport.ResponseHeaderTimeout)
+			assert.True(t, transport.DisableCompression)
+		})
+	}
+}
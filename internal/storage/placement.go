@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"math"
+	"sort"
+)
+
+// PlacementReport summarizes how a set of object IDs is distributed across ring nodes.
+type PlacementReport struct {
+	// Counts holds number of IDs placed on each node, keyed by ring key.
+	Counts map[string]int
+	// Expected is the number of IDs each node of weight 1 would get with perfectly even placement.
+	// Weighted nodes are expected to get a multiple of it.
+	Expected float64
+	// MaxSkew is the largest relative deviation of a node's count from its expected count (0.5 = 50%).
+	MaxSkew float64
+	// Skewed lists ring keys of nodes deviating from their expected count by more than the threshold, sorted.
+	Skewed []string
+}
+
+// RingNode is a node on the hash ring, along with the number of ring partitions it owns.
+type RingNode struct {
+	Node       Node
+	Partitions int
+}
+
+// LocateNode returns the node owning object ID on the hash ring, which Put stores its primary replica on.
+func (s *DistributedStorage) LocateNode(id string) (Node, error) {
+	nodes, err := s.replicas(id)
+	if err != nil {
+		return Node{}, err
+	}
+	return nodes[0], nil
+}
+
+// RingNodes returns nodes on the hash ring, sorted by ring key, along with the number of partitions each owns.
+// Partitions owned by points of weighted nodes are summed up.
+func (s *DistributedStorage) RingNodes() ([]RingNode, error) {
+	if err := s.ready(); err != nil {
+		return nil, err
+	}
+	circle, ringConfig := s.ring()
+	nodes := circleNodes(circle)
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	partitions := make(map[string]int, len(nodes))
+	for partID := 0; partID < ringConfig.PartitionCount; partID++ {
+		partitions[ringKey(circle.GetPartitionOwner(partID).(ringMember).Node)]++
+	}
+
+	ringNodes := make([]RingNode, 0, len(nodes))
+	for _, node := range nodes {
+		ringNodes = append(ringNodes, RingNode{Node: node, Partitions: partitions[ringKey(node)]})
+	}
+	return ringNodes, nil
+}
+
+// AnalyzePlacement reports IDs placement on the hash ring, flagging nodes whose share deviates
+// from even placement by more than threshold (relative, e.g. 0.25 for 25%).
+// It's a diagnostic for detecting adversarial or pathological ID sets.
+func (s *DistributedStorage) AnalyzePlacement(ids []string, threshold float64) PlacementReport {
+	report := PlacementReport{Counts: make(map[string]int)}
+
+	circle, _ := s.ring()
+	nodes := circleNodes(circle)
+	if len(nodes) == 0 {
+		return report
+	}
+	weights := make(map[string]int, len(nodes))
+	totalWeight := 0
+	for _, node := range nodes {
+		report.Counts[ringKey(node)] = 0
+		weights[ringKey(node)] = node.weight()
+		totalWeight += node.weight()
+	}
+	for _, id := range ids {
+		report.Counts[ringKey(circle.LocateKey([]byte(id)).(ringMember).Node)]++
+	}
+
+	report.Expected = float64(len(ids)) / float64(totalWeight)
+	if report.Expected == 0 {
+		return report
+	}
+	for key, count := range report.Counts {
+		expected := report.Expected * float64(weights[key])
+		skew := math.Abs(float64(count)-expected) / expected
+		report.MaxSkew = math.Max(report.MaxSkew, skew)
+		if skew > threshold {
+			report.Skewed = append(report.Skewed, key)
+		}
+	}
+	sort.Strings(report.Skewed)
+	return report
+}
+
+// FindHashCollisions returns groups of distinct IDs sharing the same ring hash, keyed by the hash.
+// Colliding IDs are always placed on the same node; they don't overwrite each other as the
+// physical key is the ID itself, but they do affect placement distribution.
+func FindHashCollisions(ids []string) map[uint64][]string {
+	byHash := make(map[uint64][]string)
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		sum := hasher{}.Sum64([]byte(id))
+		byHash[sum] = append(byHash[sum], id)
+	}
+
+	collisions := make(map[uint64][]string)
+	for sum, group := range byHash {
+		if len(group) > 1 {
+			collisions[sum] = group
+		}
+	}
+	return collisions
+}
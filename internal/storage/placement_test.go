@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAnalyzePlacement(t *testing.T) {
+	mockStorage, nodes := setupMocksAndNodes()
+	ds := createDistributedStorage(mockStorage, nodes)
+
+	// ids all placed on the same node are flagged as skewed
+	target := ringKey(ds.locate("object-1"))
+	var pathological []string
+	for i := 0; len(pathological) < 300; i++ {
+		id := fmt.Sprintf("key%d", i)
+		if ringKey(ds.locate(id)) == target {
+			pathological = append(pathological, id)
+		}
+	}
+
+	report := ds.AnalyzePlacement(pathological, 0.5)
+	assert.Equal(t, 300, report.Counts[target])
+	assert.Equal(t, float64(100), report.Expected)
+	assert.Equal(t, float64(2), report.MaxSkew)
+	assert.Len(t, report.Skewed, 3)
+	assert.Len(t, report.Counts, 3)
+
+	// empty id set is not skewed
+	report = ds.AnalyzePlacement(nil, 0.5)
+	assert.Empty(t, report.Skewed)
+	assert.Zero(t, report.MaxSkew)
+}
+
+func TestFindHashCollisions(t *testing.T) {
+	var ids []string
+	for i := 0; i < 10000; i++ {
+		ids = append(ids, fmt.Sprintf("object%d", i))
+	}
+	// duplicates of the same ID are not collisions
+	ids = append(ids, "object1", "object1")
+
+	assert.Empty(t, FindHashCollisions(ids))
+}
+
+func TestDistributedStorage_LocateNode(t *testing.T) {
+	ds, storages := createReplicatedStorage(1)
+	for _, storage := range storages {
+		storage.On("Put", mock.Anything, mock.Anything).Return(nil)
+	}
+
+	// located node is the one objects are stored on
+	for i := 0; i < 20; i++ {
+		object := &Object{ID: fmt.Sprintf("object-%d", i), Content: []byte("data")}
+		node, err := ds.LocateNode(object.ID)
+		assert.NoError(t, err)
+		assert.NoError(t, ds.Put(context.TODO(), object))
+		for key, storage := range storages {
+			if key == ringKey(node) {
+				storage.AssertCalled(t, "Put", mock.Anything, object)
+			} else {
+				storage.AssertNotCalled(t, "Put", mock.Anything, object)
+			}
+		}
+	}
+
+	// every partition is owned by one of the nodes
+	ringNodes, err := ds.RingNodes()
+	assert.NoError(t, err)
+	assert.Len(t, ringNodes, 3)
+	partitions := 0
+	for i, ringNode := range ringNodes {
+		assert.Equal(t, ds.RingMembers()[i], ringKey(ringNode.Node))
+		assert.Positive(t, ringNode.Partitions)
+		partitions += ringNode.Partitions
+	}
+	assert.Equal(t, ringPartitionCount, partitions)
+
+	// storage not initialized has no ring
+	_, err = new(DistributedStorage).LocateNode("object-1")
+	assert.ErrorIs(t, err, ErrNotReady)
+	_, err = new(DistributedStorage).RingNodes()
+	assert.ErrorIs(t, err, ErrNotReady)
+}
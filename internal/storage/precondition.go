@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrPreconditionFailed is returned by conditional writes whose precondition doesn't hold for the stored object.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// Precondition decides whether a write may replace the stored object, given its metadata, or nil if the object
+// doesn't exist.
+type Precondition func(current *ObjectInfo) bool
+
+type preconditionKey struct{}
+
+// WithPrecondition returns context making writes performed with it conditional: the object is written only if
+// the precondition holds for the stored object, failing with ErrPreconditionFailed otherwise.
+func WithPrecondition(ctx context.Context, precondition Precondition) context.Context {
+	return context.WithValue(ctx, preconditionKey{}, precondition)
+}
+
+// PreconditionFromContext returns precondition carried by ctx, or nil if writes are unconditional.
+func PreconditionFromContext(ctx context.Context) Precondition {
+	precondition, _ := ctx.Value(preconditionKey{}).(Precondition)
+	return precondition
+}
+
+// CheckPrecondition checks precondition carried by ctx, if any, against the stored object, or nil if it doesn't
+// exist, failing with ErrPreconditionFailed if it doesn't hold.
+func CheckPrecondition(ctx context.Context, current *ObjectInfo) error {
+	if precondition := PreconditionFromContext(ctx); precondition != nil && !precondition(current) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// objectLocks are mutexes of individual objects, created while held.
+type objectLocks struct {
+	mu    sync.Mutex
+	locks map[string]*objectLock
+}
+
+type objectLock struct {
+	sync.Mutex
+	holders int
+}
+
+// lock locks the object with given ID, returning function unlocking it.
+func (l *objectLocks) lock(id string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*objectLock)
+	}
+	lock := l.locks[id]
+	if lock == nil {
+		lock = &objectLock{}
+		l.locks[id] = lock
+	}
+	lock.holders++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mu.Lock()
+		if lock.holders--; lock.holders == 0 {
+			delete(l.locks, id)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// checkPrecondition checks precondition of a conditional write of object ID against the stored object, returning
+// function to be called once the write completes on all replicas, including the ones written after the write was
+// acknowledged. Conditional writes of the same object are serialized, so none of them replaces an object changed
+// after its precondition was checked, nor races with replica writes of the previous one. Unconditional writes and writes through
+// other gateways aren't, so the check guards against lost updates of cooperating clients only.
+func (s *DistributedStorage) checkPrecondition(ctx context.Context, id string) (func(), error) {
+	if PreconditionFromContext(ctx) == nil {
+		return func() {}, nil
+	}
+	unlock := s.conditionalWrites.lock(id)
+	current, err := s.Stat(ctx, id)
+	if err != nil {
+		unlock()
+		return nil, fmt.Errorf("failed to check precondition: %w", err)
+	}
+	if err := CheckPrecondition(ctx, current); err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
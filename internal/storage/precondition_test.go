@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ifAbsent(current *ObjectInfo) bool {
+	return current == nil
+}
+
+// createMemoryStorage creates initialized storage of a single node keeping objects in memory
+func createMemoryStorage(t *testing.T) (*DistributedStorage, *memoryStorage) {
+	nodes, err := ParseStaticNodes("key:secret@10.0.0.1:9000")
+	assert.NoError(t, err)
+	ds := NewDistributedStorage(NewStaticDiscoverer(nodes), &DistributedConfig{}).(*DistributedStorage)
+	node := &memoryStorage{objects: map[string]*Object{}}
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) { return node, nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	assert.NoError(t, ds.Init(ctx))
+	return ds, node
+}
+
+func TestDistributedStorage_Precondition(t *testing.T) {
+	ds, node := createMemoryStorage(t)
+	ctx := WithPrecondition(context.Background(), ifAbsent)
+
+	// object is created only if absent
+	assert.NoError(t, ds.Put(ctx, &Object{ID: "1", Content: []byte("first")}))
+	assert.ErrorIs(t, ds.Put(ctx, &Object{ID: "1", Content: []byte("second")}), ErrPreconditionFailed)
+	err := ds.PutStream(ctx, &ObjectStream{ID: "1", Size: 5, Content: io.NopCloser(strings.NewReader("third"))})
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+	assert.Equal(t, "first", string(node.objects["1"].Content))
+
+	// precondition is checked against the stored object
+	ctx = WithPrecondition(context.Background(), func(current *ObjectInfo) bool {
+		return current != nil && current.Size == 5
+	})
+	err = ds.PutStream(ctx, &ObjectStream{ID: "1", Size: 6, Content: io.NopCloser(strings.NewReader("second"))})
+	assert.NoError(t, err)
+	assert.Equal(t, "second", string(node.objects["1"].Content))
+	assert.ErrorIs(t, ds.Put(ctx, &Object{ID: "1", Content: []byte("third")}), ErrPreconditionFailed)
+	assert.ErrorIs(t, ds.Put(ctx, &Object{ID: "2", Content: []byte("other")}), ErrPreconditionFailed)
+	assert.NotContains(t, node.objects, "2")
+}
+
+func TestDistributedStorage_ConcurrentConditionalWrites(t *testing.T) {
+	ds, node := createMemoryStorage(t)
+	ctx := WithPrecondition(context.Background(), ifAbsent)
+
+	// only one of concurrent writes creating the object succeeds
+	var created atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := ds.Put(ctx, &Object{ID: "1", Content: []byte("data")})
+			if err == nil {
+				created.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, ErrPreconditionFailed)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), created.Load())
+	assert.Contains(t, node.objects, "1")
+	assert.Empty(t, ds.conditionalWrites.locks)
+}
+
+func TestDistributedStorage_ConditionalWriteNotBuffered(t *testing.T) {
+	ds, node := createBufferedStorage(t, WriteBufferConfig{Size: 10})
+	close(node.release)
+
+	ctx, buffered := TrackBufferedWrites(WithPrecondition(context.Background(), ifAbsent))
+	assert.NoError(t, ds.Put(ctx, &Object{ID: "1", Content: []byte("data")}))
+	assert.False(t, buffered.Load())
+	assert.Contains(t, node.objects, "1")
+}
+
+func TestDistributedStorage_ConditionalWriteAwaitsReplicas(t *testing.T) {
+	ds, slow := createSlowReplicaStorage(t)
+
+	// write is acknowledged by the fast replica, while the slow one is still being written
+	conditional := WithPrecondition(context.Background(), ifAbsent)
+	assert.NoError(t, ds.Put(conditional, &Object{ID: "1", Content: []byte("first")}))
+	assert.Equal(t, "1", <-slow.started)
+
+	// next conditional write of the object waits until all replicas are written
+	done := make(chan error)
+	go func() { done <- ds.Put(conditional, &Object{ID: "1", Content: []byte("second")}) }()
+	select {
+	case err := <-done:
+		t.Fatalf("conditional write completed while replica write was running: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(slow.release)
+	assert.ErrorIs(t, <-done, ErrPreconditionFailed)
+	ds.pendingWrites.Wait()
+	assert.Equal(t, "first", string(slow.objects["1"].Content))
+	assert.Empty(t, ds.conditionalWrites.locks)
+}
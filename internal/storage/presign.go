@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// DefaultRegion is the region presigned URLs are signed for when the node region isn't configured.
+	DefaultRegion = "us-east-1"
+	// MaxPresignExpiry is the longest validity of presigned URLs accepted by S3 compatible storages.
+	MaxPresignExpiry = 7 * 24 * time.Hour
+)
+
+// PresignOperation is the operation a presigned URL allows.
+type PresignOperation string
+
+const (
+	// PresignGet allows downloading the object with GET.
+	PresignGet PresignOperation = "get"
+	// PresignPut allows uploading the object with PUT.
+	PresignPut PresignOperation = "put"
+)
+
+// ParsePresignOperation parses presigned URL operation name, case insensitively.
+func ParsePresignOperation(name string) (PresignOperation, error) {
+	switch op := PresignOperation(strings.ToLower(name)); op {
+	case PresignGet, PresignPut:
+		return op, nil
+	default:
+		return "", fmt.Errorf("unknown presign operation %q, expected get or put", name)
+	}
+}
+
+// presigner is implemented by node storages able to presign URLs of their objects.
+type presigner interface {
+	PresignedURL(ctx context.Context, id string, op PresignOperation, expiry time.Duration) (*url.URL, error)
+}
+
+// PresignedURL returns URL letting its holder perform the operation on the object directly on the node,
+// until expiry elapses. The URL points at PublicEndpoint, if configured. Objects uploaded with the URL
+// bypass compression, checksums and deduplication.
+func (s *MinioStorage) PresignedURL(ctx context.Context, id string, op PresignOperation, expiry time.Duration) (*url.URL, error) {
+	if expiry <= 0 || expiry > MaxPresignExpiry {
+		return nil, fmt.Errorf("error presign object (%s | %s): expiry must be between 1s and %s", s.endpoint, id, MaxPresignExpiry)
+	}
+	switch op {
+	case PresignGet:
+		bucket, key, err := s.presignedObject(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("error presign object (%s | %s): %w", s.endpoint, id, err)
+		}
+		u, err := s.presignClient.PresignedGetObject(ctx, bucket, key, expiry, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error presign object (%s | %s): %w", s.endpoint, id, err)
+		}
+		return u, nil
+	case PresignPut:
+		// upload goes straight to the node, so its bucket must exist already
+		if err := s.ensureBucket(ctx); err != nil {
+			return nil, fmt.Errorf("error presign object (%s | %s): %w", s.endpoint, id, err)
+		}
+		u, err := s.presignClient.PresignedPutObject(ctx, s.bucket(ctx), s.objectKey(ctx, id), expiry)
+		if err != nil {
+			return nil, fmt.Errorf("error presign object (%s | %s): %w", s.endpoint, id, err)
+		}
+		return u, nil
+	default:
+		return nil, fmt.Errorf("error presign object (%s | %s): unknown operation %q", s.endpoint, id, op)
+	}
+}
+
+// presignedObject returns bucket and key of the object content downloads are presigned for: the object itself,
+// or deduplicated content it refers to.
+func (s *MinioStorage) presignedObject(ctx context.Context, id string) (string, string, error) {
+	key := s.objectKey(ctx, id)
+	if !s.cfg.Dedup.Enabled {
+		return s.bucket(ctx), key, nil
+	}
+	info, err := s.client.StatObject(ctx, s.bucket(ctx), key, minio.StatObjectOptions{})
+	if err != nil {
+		if keyDoesNotExist(err) {
+			return s.bucket(ctx), key, nil
+		}
+		return "", "", err
+	}
+	if blobReference(info) == "" {
+		return s.bucket(ctx), key, nil
+	}
+	return s.blobBucket(), blobReference(info), nil
+}
+
+// PresignedURL returns URL letting its holder perform the operation on the object directly on the primary
+// replica node of the object. Objects uploaded with the URL are stored on the primary node only.
+func (s *DistributedStorage) PresignedURL(ctx context.Context, id string, op PresignOperation, expiry time.Duration) (*url.URL, error) {
+	nodes, err := s.replicas(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign object: %w", err)
+	}
+	node := nodes[0]
+	s.logger.DebugContext(ctx, "object located", "operation", "presign", "object_id", id, "node", ringKey(node))
+
+	var u *url.URL
+	start := time.Now()
+	err = s.onNode(ctx, node, func(storage Storage) (err error) {
+		p, ok := storage.(presigner)
+		if !ok {
+			return errors.New("storage node doesn't presign URLs")
+		}
+		u, err = p.PresignedURL(ctx, id, op, expiry)
+		return err
+	})
+	if err != nil {
+		s.nodeFailed(ctx, "presign", id, node, time.Since(start), err)
+		return nil, fmt.Errorf("failed to presign object using node (%s): %w", ringKey(node), err)
+	}
+	return u, nil
+}
+
+// ParsePublicEndpoints parses comma separated list of "endpoint=publicEndpoint" pairs, mapping node endpoints
+// to endpoints presigned URLs point at.
+func ParsePublicEndpoints(value string) (map[string]string, error) {
+	endpoints := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		endpoint, public, ok := strings.Cut(entry, "=")
+		if !ok || endpoint == "" || public == "" {
+			return nil, fmt.Errorf("invalid public endpoint %q: expected endpoint=publicEndpoint", entry)
+		}
+		endpoints[endpoint] = public
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePresignOperation(t *testing.T) {
+	for name, expected := range map[string]PresignOperation{"get": PresignGet, "PUT": PresignPut} {
+		op, err := ParsePresignOperation(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, op, name)
+	}
+	for _, name := range []string{"", "delete"} {
+		_, err := ParsePresignOperation(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestParsePublicEndpoints(t *testing.T) {
+	endpoints, err := ParsePublicEndpoints("172.18.0.2:9000=localhost:9001, 172.18.0.3:9000=https://minio-2.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"172.18.0.2:9000": "localhost:9001", "172.18.0.3:9000": "https://minio-2.example.com"}, endpoints)
+
+	for _, value := range []string{"172.18.0.2:9000", "=localhost:9001", "172.18.0.2:9000="} {
+		_, err := ParsePublicEndpoints(value)
+		assert.Error(t, err, value)
+	}
+}
+
+// newPresigningStorage creates node storage presigning URLs offline, as its region is known
+func newPresigningStorage(t *testing.T, endpoint, publicEndpoint string) Storage {
+	s, err := NewMinioStorage(&MinioConfig{
+		Endpoint:       endpoint,
+		AccessKey:      "key",
+		SecretKey:      "secret",
+		BucketName:     "default",
+		Region:         "us-east-1",
+		PublicEndpoint: publicEndpoint,
+	})
+	assert.NoError(t, err)
+	return s
+}
+
+func TestMinioStorage_PresignedURL(t *testing.T) {
+	s := newPresigningStorage(t, "10.0.0.1:9000", "").(*MinioStorage)
+
+	u, err := s.PresignedURL(context.TODO(), "object-1", PresignGet, 5*time.Minute)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "http", u.Scheme)
+		assert.Equal(t, "10.0.0.1:9000", u.Host)
+		assert.Equal(t, "/default/object-1", u.Path)
+		assert.Equal(t, "300", u.Query().Get("X-Amz-Expires"))
+		assert.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+	}
+	u, err = s.PresignedURL(WithBucket(context.TODO(), "default"), "object-1", PresignPut, time.Hour)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "/default/object-1", u.Path)
+		assert.Equal(t, "3600", u.Query().Get("X-Amz-Expires"))
+	}
+
+	// URLs point at public endpoint, signed for it
+	public := newPresigningStorage(t, "10.0.0.1:9000", "https://minio-1.example.com").(*MinioStorage)
+	publicURL, err := public.PresignedURL(context.TODO(), "object-1", PresignGet, 5*time.Minute)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "https", publicURL.Scheme)
+		assert.Equal(t, "minio-1.example.com", publicURL.Host)
+	}
+
+	for _, expiry := range []time.Duration{0, MaxPresignExpiry + time.Second} {
+		_, err = s.PresignedURL(context.TODO(), "object-1", PresignGet, expiry)
+		assert.Error(t, err, expiry)
+	}
+}
+
+func TestDistributedStorage_PresignedURL(t *testing.T) {
+	_, nodes := setupMocksAndNodes()
+	ds := createDistributedStorage(nil, nodes)
+	for key, node := range nodes {
+		ds.availableStorages[key] = newPresigningStorage(t, node.Endpoint+":9000", "")
+	}
+
+	// URL points at the primary node of each object
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprintf("object-%d", i)
+		u, err := ds.PresignedURL(context.TODO(), id, PresignGet, time.Minute)
+		if assert.NoError(t, err, id) {
+			assert.Equal(t, ds.locate(id).Endpoint+":9000", u.Host, id)
+			assert.Equal(t, "/default/"+url.PathEscape(id), u.EscapedPath(), id)
+		}
+	}
+
+	// storage not presigning URLs fails
+	ds.availableStorages[ringKey(ds.locate("object-1"))] = new(MockStorage)
+	_, err := ds.PresignedURL(context.TODO(), "object-1", PresignGet, time.Minute)
+	assert.Error(t, err)
+}
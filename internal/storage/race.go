@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ReadStrategy is how Get and GetStream read object from its replica nodes.
+type ReadStrategy string
+
+const (
+	// ReadSequential reads replicas one by one, failing over to the next replica when a node errors.
+	ReadSequential ReadStrategy = "sequential"
+	// ReadRacing reads all replicas concurrently and returns the first one found, trading load for latency.
+	ReadRacing ReadStrategy = "racing"
+)
+
+// ParseReadStrategy parses read strategy name, case insensitively. Empty name is ReadSequential.
+func ParseReadStrategy(name string) (ReadStrategy, error) {
+	switch strategy := ReadStrategy(strings.ToLower(name)); strategy {
+	case "":
+		return ReadSequential, nil
+	case ReadSequential, ReadRacing:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown read strategy %q, expected sequential or racing", name)
+	}
+}
+
+// race reads object from all replica nodes concurrently, returning the first replica found with its node index.
+// Reads of the other nodes are cancelled and waited for, so none outlives the call, and replicas they found
+// anyway are released. The winning read isn't cancelled, as its result may still be consumed (e.g. streamed).
+// As with sequential reads, object is reported absent only if no node errored.
+func race[T any](ctx context.Context, s *DistributedStorage, id string, nodes []Node, read func(ctx context.Context, storage Storage) (T, error), found func(T) bool, release func(T)) (T, int, error) {
+	type raceResult struct {
+		index int
+		value T
+		err   error
+	}
+	results := make(chan raceResult, len(nodes))
+	cancels := make([]context.CancelFunc, len(nodes))
+	var g errgroup.Group
+	for i, node := range nodes {
+		i, node := i, node
+		nodeCtx, cancel := context.WithCancel(ctx)
+		cancels[i] = cancel
+		g.Go(func() error {
+			var value T
+			start := time.Now()
+			err := s.onNode(nodeCtx, node, func(storage Storage) (err error) {
+				value, err = read(nodeCtx, storage)
+				return err
+			})
+			// reads cancelled as the race was won aren't node failures
+			if err != nil && (ctx.Err() != nil || nodeCtx.Err() == nil) {
+				s.nodeFailed(ctx, "get", id, node, time.Since(start), err)
+				err = fmt.Errorf("failed to get data using node (%s): %w", ringKey(node), err)
+			}
+			results <- raceResult{index: i, value: value, err: err}
+			return nil
+		})
+	}
+
+	winner := -1
+	var value T
+	var errs []error
+	for received := 0; received < len(nodes) && winner < 0; received++ {
+		result := <-results
+		if result.err != nil {
+			errs = append(errs, result.err)
+			continue
+		}
+		if found(result.value) {
+			winner, value = result.index, result.value
+		}
+	}
+	for i, cancel := range cancels {
+		if i != winner {
+			cancel()
+		}
+	}
+	_ = g.Wait()
+	close(results)
+	for result := range results {
+		if result.err == nil && found(result.value) {
+			release(result.value)
+		}
+	}
+	if winner < 0 {
+		return value, winner, errors.Join(errs...)
+	}
+	return value, winner, nil
+}
+
+// getRacing gets the object from all replica nodes concurrently, returning the first replica found.
+func (s *DistributedStorage) getRacing(ctx context.Context, id string, nodes []Node) (*Object, error) {
+	object, _, err := race(ctx, s, id, nodes,
+		func(ctx context.Context, storage Storage) (*Object, error) { return storage.Get(ctx, id) },
+		func(object *Object) bool { return object != nil },
+		func(*Object) {})
+	return object, err
+}
+
+// getStreamRacing opens object stream on all replica nodes concurrently, returning the first replica found
+// with its node index. Streams opened by the other nodes are closed.
+func (s *DistributedStorage) getStreamRacing(ctx context.Context, id string, nodes []Node) (*ObjectStream, int, error) {
+	return race(ctx, s, id, nodes,
+		func(ctx context.Context, storage Storage) (*ObjectStream, error) { return storage.GetStream(ctx, id) },
+		func(object *ObjectStream) bool { return object != nil },
+		func(object *ObjectStream) { object.Content.Close() })
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// latencyStorage responds to reads after a delay, unless the request is cancelled first
+type latencyStorage struct {
+	MockStorage
+	delay     time.Duration
+	object    *Object
+	err       error
+	cancelled atomic.Bool
+}
+
+func (ls *latencyStorage) Get(ctx context.Context, id string) (*Object, error) {
+	select {
+	case <-time.After(ls.delay):
+		return ls.object, ls.err
+	case <-ctx.Done():
+		ls.cancelled.Store(true)
+		return nil, ctx.Err()
+	}
+}
+
+func (ls *latencyStorage) GetStream(ctx context.Context, id string) (*ObjectStream, error) {
+	object, err := ls.Get(ctx, id)
+	if object == nil || err != nil {
+		return nil, err
+	}
+	return &ObjectStream{ID: id, Size: int64(len(object.Content)), Content: &contextReader{ctx: ctx, r: bytes.NewReader(object.Content)}}, nil
+}
+
+// contextReader fails reads once its context is done, as do streams of minio responses
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+func (cr *contextReader) Close() error {
+	return nil
+}
+
+func TestParseReadStrategy(t *testing.T) {
+	for name, expected := range map[string]ReadStrategy{"": ReadSequential, "sequential": ReadSequential, "Racing": ReadRacing} {
+		strategy, err := ParseReadStrategy(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, strategy, name)
+	}
+
+	_, err := ParseReadStrategy("fastest")
+	assert.Error(t, err)
+}
+
+func TestDistributedStorage_GetRacing(t *testing.T) {
+	object := func(content string) *Object { return &Object{ID: "object-1", Content: []byte(content)} }
+	nodeDown := errors.New("node down")
+
+	tests := []struct {
+		name            string
+		replicas        []*latencyStorage
+		expectedContent string
+		expectedErr     bool
+	}{
+		{
+			name: "fastest replica wins",
+			replicas: []*latencyStorage{
+				{delay: 10 * time.Second, object: object("slowest")},
+				{delay: time.Millisecond, object: object("fastest")},
+				{delay: 5 * time.Second, object: object("slow")},
+			},
+			expectedContent: "fastest",
+		},
+		{
+			name: "missing replica doesn't win",
+			replicas: []*latencyStorage{
+				{delay: time.Millisecond},
+				{delay: 20 * time.Millisecond, object: object("found")},
+				{delay: 10 * time.Second, object: object("slowest")},
+			},
+			expectedContent: "found",
+		},
+		{
+			name: "failing replica doesn't win",
+			replicas: []*latencyStorage{
+				{delay: time.Millisecond, err: nodeDown},
+				{delay: 20 * time.Millisecond, object: object("found")},
+				{delay: 10 * time.Second, object: object("slowest")},
+			},
+			expectedContent: "found",
+		},
+		{
+			name:     "missing on all replicas",
+			replicas: []*latencyStorage{{delay: time.Millisecond}, {delay: 2 * time.Millisecond}, {delay: 3 * time.Millisecond}},
+		},
+		{
+			name: "missing on replicas that responded",
+			replicas: []*latencyStorage{
+				{delay: time.Millisecond},
+				{delay: 2 * time.Millisecond, err: nodeDown},
+				{delay: 3 * time.Millisecond},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, _ := createReplicatedStorage(3)
+			ds.readStrategy = ReadRacing
+			for i, node := range mustReplicas(t, ds, "object-1") {
+				ds.availableStorages[ringKey(node)] = tt.replicas[i]
+			}
+
+			start := time.Now()
+			obj, err := ds.Get(context.TODO(), "object-1")
+			// slowest replicas are cancelled rather than waited for
+			assert.Less(t, time.Since(start), time.Second)
+			if tt.expectedErr {
+				assert.ErrorIs(t, err, nodeDown)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.expectedContent == "" {
+				assert.Nil(t, obj)
+				return
+			}
+			if assert.NotNil(t, obj) {
+				assert.Equal(t, tt.expectedContent, string(obj.Content))
+			}
+			for _, replica := range tt.replicas {
+				if replica.delay >= time.Second {
+					assert.True(t, replica.cancelled.Load())
+				}
+			}
+		})
+	}
+}
+
+func TestDistributedStorage_GetStreamRacing(t *testing.T) {
+	ds, _ := createReplicatedStorage(3)
+	ds.readStrategy = ReadRacing
+	replicas := []*latencyStorage{
+		{delay: 10 * time.Second, object: &Object{ID: "object-1", Content: []byte("slowest")}},
+		{delay: time.Millisecond, object: &Object{ID: "object-1", Content: []byte("fastest")}},
+		{delay: 5 * time.Second, object: &Object{ID: "object-1", Content: []byte("slow")}},
+	}
+	for i, node := range mustReplicas(t, ds, "object-1") {
+		ds.availableStorages[ringKey(node)] = replicas[i]
+	}
+
+	start := time.Now()
+	stream, err := ds.GetStream(context.TODO(), "object-1")
+	assert.Less(t, time.Since(start), time.Second)
+	if !assert.NoError(t, err) || !assert.NotNil(t, stream) {
+		return
+	}
+	defer stream.Content.Close()
+	// reads of losing replicas are cancelled, while the winning stream is still read
+	assert.True(t, replicas[0].cancelled.Load())
+	assert.True(t, replicas[2].cancelled.Load())
+	content, err := io.ReadAll(stream.Content)
+	assert.NoError(t, err)
+	assert.Equal(t, "fastest", string(content))
+}
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/buraksezer/consistent"
+)
+
+// DefaultRebalanceRate is the default number of objects moved per second while rebalancing.
+const DefaultRebalanceRate = 20
+
+// RebalanceConfig configures moving existing objects to their new replica nodes when the ring changes.
+type RebalanceConfig struct {
+	// Enabled starts rebalancing in the background whenever rediscovered nodes change the ring. Objects are
+	// listed on every node; objects placed elsewhere are copied to their replica nodes missing them, and deleted
+	// from the node once all replicas hold them. Only objects of the configured bucket are rebalanced.
+	Enabled bool
+	// Rate is the number of objects moved per second, so rebalancing doesn't starve regular operations of node
+	// I/O. Defaults to DefaultRebalanceRate.
+	Rate int
+}
+
+// startRebalance moves objects placed by the previous ring to their replica nodes on the current ring, in the background.
+// Until it completes, reads fall back to replica nodes of previous rings. Rebalancing already running is cancelled,
+// as the new one lists all objects again.
+func (s *DistributedStorage) startRebalance(ctx context.Context, previous *consistent.Consistent) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.previousCircles = append(s.previousCircles, previous)
+	if s.cancelRebalance != nil {
+		s.cancelRebalance()
+	}
+	s.cancelRebalance = cancel
+	s.mu.Unlock()
+
+	s.rebalances.Add(1)
+	go func() {
+		defer s.rebalances.Done()
+		defer cancel()
+		start := time.Now()
+		moved, err := s.rebalance(ctx)
+		if err != nil {
+			// previous rings are kept, so objects left behind stay readable until the next rebalance moves them
+			s.logger.WarnContext(ctx, "rebalance incomplete", "moved", moved, "duration", time.Since(start), "error", err)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// rebalance cancelled meanwhile doesn't cover the latest ring change
+		if ctx.Err() == nil {
+			s.previousCircles = nil
+			s.cancelRebalance = nil
+		}
+		s.logger.InfoContext(ctx, "rebalance completed", "moved", moved, "duration", time.Since(start))
+	}()
+}
+
+// rebalance lists objects on every ring node and moves the ones the node doesn't hold a replica of anymore,
+// at most rate objects per second. It returns the number of moved objects, failing if any object wasn't moved.
+func (s *DistributedStorage) rebalance(ctx context.Context) (int, error) {
+	rate := s.rebalanceConfig.Rate
+	if rate <= 0 {
+		rate = DefaultRebalanceRate
+	}
+	limiter := time.NewTicker(time.Second / time.Duration(rate))
+	defer limiter.Stop()
+
+	circle, _ := s.ring()
+	moved := 0
+	var errs []error
+	for _, node := range circleNodes(circle) {
+		var ids []string
+		err := s.onNode(ctx, node, func(storage Storage) (err error) {
+			ids, err = storage.List(ctx, "")
+			return err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list data using node (%s): %w", ringKey(node), err))
+			continue
+		}
+
+		for _, id := range ids {
+			replicas, err := s.replicas(id)
+			if err != nil {
+				return moved, err
+			}
+			if containsNode(replicas, node) {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return moved, ctx.Err()
+			case <-limiter.C:
+			}
+			start := time.Now()
+			if err := s.moveObject(ctx, id, node, replicas); err != nil {
+				s.nodeFailed(ctx, "rebalance", id, node, time.Since(start), err)
+				errs = append(errs, fmt.Errorf("failed to move object %s from node (%s): %w", id, ringKey(node), err))
+				continue
+			}
+			moved++
+		}
+	}
+	return moved, errors.Join(errs...)
+}
+
+// moveObject copies object from the node to its replica nodes missing it, deleting it from the node once
+// all replicas hold it. Replicas already holding the object aren't overwritten, as they may hold a newer version.
+func (s *DistributedStorage) moveObject(ctx context.Context, id string, node Node, replicas []Node) error {
+	// deleting the object from one of its replicas could lose its only copy
+	if containsNode(replicas, node) {
+		return fmt.Errorf("node (%s) holds a replica of the object", ringKey(node))
+	}
+	for _, replica := range replicas {
+		var info *ObjectInfo
+		err := s.onNode(ctx, replica, func(storage Storage) (err error) {
+			info, err = storage.Stat(ctx, id)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to stat data using node (%s): %w", ringKey(replica), err)
+		}
+		if info != nil {
+			continue
+		}
+
+		var object *ObjectStream
+		err = s.onNode(ctx, node, func(storage Storage) (err error) {
+			object, err = storage.GetStream(ctx, id)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get data: %w", err)
+		}
+		if object == nil {
+			// deleted meanwhile
+			return nil
+		}
+		err = decompressStream(object)
+		if err == nil {
+			err = s.putStreamOnNode(ctx, replica, object)
+		}
+		object.Content.Close()
+		if err != nil {
+			return fmt.Errorf("failed to put data using node (%s): %w", ringKey(replica), err)
+		}
+	}
+
+	err := s.onNode(ctx, node, func(storage Storage) error { return storage.Delete(ctx, id) })
+	if err != nil && !errors.Is(err, ErrObjectNotFound) {
+		return fmt.Errorf("failed to delete data: %w", err)
+	}
+	s.logger.DebugContext(ctx, "object moved", "operation", "rebalance", "object_id", id, "node", ringKey(node), "nodes", ringKeys(replicas))
+	return nil
+}
+
+// previousReplicas returns nodes that held replicas of object ID on rings preceding rebalancing still running,
+// apart from the given ones. Reads fall back to them, as the object may not have been moved yet.
+func (s *DistributedStorage) previousReplicas(id string, nodes []Node) []Node {
+	s.mu.RLock()
+	circles := s.previousCircles
+	s.mu.RUnlock()
+
+	var previous []Node
+	for _, circle := range circles {
+		replicas, err := s.circleReplicas(circle, id)
+		if err != nil {
+			continue
+		}
+		for _, node := range replicas {
+			if !containsNode(nodes, node) && !containsNode(previous, node) {
+				previous = append(previous, node)
+			}
+		}
+	}
+	return previous
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStorage keeps objects in memory. Listing waits until listed is closed, if set.
+type memoryStorage struct {
+	MockStorage
+	mu      sync.Mutex
+	objects map[string]*Object
+	listed  chan struct{}
+}
+
+func (ms *memoryStorage) Init(ctx context.Context) error {
+	return nil
+}
+
+func (ms *memoryStorage) Put(ctx context.Context, object *Object) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.objects[object.ID] = object
+	return nil
+}
+
+func (ms *memoryStorage) PutStream(ctx context.Context, object *ObjectStream) error {
+	content, err := io.ReadAll(object.Content)
+	if err != nil {
+		return err
+	}
+	return ms.Put(ctx, &Object{ID: object.ID, ContentType: object.ContentType, Content: content, Metadata: object.Metadata})
+}
+
+func (ms *memoryStorage) Get(ctx context.Context, id string) (*Object, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.objects[id], nil
+}
+
+func (ms *memoryStorage) GetStream(ctx context.Context, id string) (*ObjectStream, error) {
+	object, _ := ms.Get(ctx, id)
+	if object == nil {
+		return nil, nil
+	}
+	return &ObjectStream{ID: id, ContentType: object.ContentType, Size: int64(len(object.Content)), Content: io.NopCloser(bytes.NewReader(object.Content)), Metadata: object.Metadata}, nil
+}
+
+func (ms *memoryStorage) Stat(ctx context.Context, id string) (*ObjectInfo, error) {
+	object, _ := ms.Get(ctx, id)
+	if object == nil {
+		return nil, nil
+	}
+	return &ObjectInfo{ID: id, ContentType: object.ContentType, Size: int64(len(object.Content))}, nil
+}
+
+func (ms *memoryStorage) Delete(ctx context.Context, id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.objects[id] == nil {
+		return ErrObjectNotFound
+	}
+	delete(ms.objects, id)
+	return nil
+}
+
+func (ms *memoryStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	if ms.listed != nil {
+		<-ms.listed
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var ids []string
+	for id := range ms.objects {
+		if strings.HasPrefix(id, prefix) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func TestDistributedStorage_Rebalance(t *testing.T) {
+	nodes, err := ParseStaticNodes("key:secret@10.0.0.1:9000,key:secret@10.0.0.2:9000,key:secret@10.0.0.3:9000")
+	assert.NoError(t, err)
+	discoverer := NewStaticDiscoverer(nodes[:2])
+	ds := NewDistributedStorage(discoverer, &DistributedConfig{Rebalance: RebalanceConfig{Enabled: true, Rate: 1000}}).(*DistributedStorage)
+
+	// rebalancing waits for the test to check reads during migration
+	listed := make(chan struct{})
+	var mu sync.Mutex
+	storages := make(map[string]*memoryStorage)
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		storages[cfg.Endpoint] = &memoryStorage{objects: map[string]*Object{}, listed: listed}
+		return storages[cfg.Endpoint], nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, ds.Init(ctx))
+	ids := make([]string, 50)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("object-%d", i)
+		assert.NoError(t, ds.Put(ctx, &Object{ID: ids[i], Content: []byte(ids[i])}))
+	}
+	ds.pendingWrites.Wait()
+
+	assertReadable := func() {
+		for _, id := range ids {
+			obj, err := ds.Get(ctx, id)
+			if assert.NoError(t, err, id) && assert.NotNil(t, obj, id) {
+				assert.Equal(t, id, string(obj.Content))
+			}
+			info, err := ds.Stat(ctx, id)
+			assert.NoError(t, err, id)
+			assert.NotNil(t, info, id)
+		}
+	}
+
+	// third node joins, owning objects still stored on the first two
+	discoverer.nodes = nodes
+	ds.rediscoverNodes(ctx)
+	assert.Len(t, ds.RingMembers(), 3)
+	var moved []string
+	for _, id := range ids {
+		if ds.locate(id).Endpoint == "10.0.0.3:9000" {
+			moved = append(moved, id)
+		}
+	}
+	assert.NotEmpty(t, moved)
+	assert.Empty(t, storages["10.0.0.3:9000"].objects)
+	assertReadable()
+
+	close(listed)
+	ds.rebalances.Wait()
+	for _, id := range ids {
+		owner := ds.locate(id).Endpoint
+		for endpoint, storage := range storages {
+			_, ok := storage.objects[id]
+			assert.Equal(t, endpoint == owner, ok, "%s on %s", id, endpoint)
+		}
+	}
+	assert.Len(t, storages["10.0.0.3:9000"].objects, len(moved))
+	assert.Empty(t, ds.previousReplicas(ids[0], nil))
+	assertReadable()
+}
+
+// rotatingDiscoverer discovers the static nodes, resolving rotated credentials
+type rotatingDiscoverer struct {
+	*StaticDiscoverer
+}
+
+func (rd *rotatingDiscoverer) Credentials(ctx context.Context, node Node) (string, string, error) {
+	return "new-key", "new-secret", nil
+}
+
+// rejectingStorage is memoryStorage rejecting the first listing, as if its credentials were rotated
+type rejectingStorage struct {
+	*memoryStorage
+	rejected bool
+}
+
+func (rs *rejectingStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	if !rs.rejected {
+		rs.rejected = true
+		return nil, minio.ErrorResponse{Code: "InvalidAccessKeyId"}
+	}
+	return rs.memoryStorage.List(ctx, prefix)
+}
+
+func TestDistributedStorage_RebalanceRotatedCredentials(t *testing.T) {
+	nodes, err := ParseStaticNodes("key:secret@10.0.0.1:9000,key:secret@10.0.0.2:9000,key:secret@10.0.0.3:9000")
+	assert.NoError(t, err)
+	discoverer := &rotatingDiscoverer{StaticDiscoverer: NewStaticDiscoverer(nodes[:2])}
+	ds := NewDistributedStorage(discoverer, &DistributedConfig{Rebalance: RebalanceConfig{Enabled: true, Rate: 1000}}).(*DistributedStorage)
+
+	// node storages recreated with rotated credentials hold the same objects
+	var mu sync.Mutex
+	storages := make(map[string]*memoryStorage)
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if storages[cfg.Endpoint] == nil {
+			storages[cfg.Endpoint] = &memoryStorage{objects: map[string]*Object{}}
+		}
+		if cfg.AccessKey == "new-key" {
+			return storages[cfg.Endpoint], nil
+		}
+		return &rejectingStorage{memoryStorage: storages[cfg.Endpoint]}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, ds.Init(ctx))
+	ids := make([]string, 50)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("object-%d", i)
+		assert.NoError(t, ds.Put(ctx, &Object{ID: ids[i], Content: []byte(ids[i])}))
+	}
+	ds.pendingWrites.Wait()
+
+	// third node joins; rebalancing listing the first ones refreshes their credentials
+	discoverer.nodes = nodes
+	ds.rediscoverNodes(ctx)
+	ds.rebalances.Wait()
+	assert.Equal(t, "new-key", ds.locate(ids[0]).AccessKey)
+
+	// objects staying on their nodes aren't deleted, as the nodes still hold their only replica
+	for _, id := range ids {
+		owner := ds.locate(id).Endpoint
+		for endpoint, storage := range storages {
+			_, ok := storage.objects[id]
+			assert.Equal(t, endpoint == owner, ok, "%s on %s", id, endpoint)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// RetryConfig configures retrying of node operations failing with transient errors.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled before every next one.
+	BaseDelay time.Duration
+}
+
+// retry runs op until it succeeds, fails with non-retryable error or attempts are exhausted.
+// It stops waiting for the next attempt when ctx is done, returning the last error.
+func retry(ctx context.Context, logger *slog.Logger, cfg RetryConfig, op func() error) error {
+	delay := cfg.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= cfg.MaxAttempts || !retryable(err) {
+			return err
+		}
+		logger.WarnContext(ctx, "retrying node operation", "delay", delay, "attempt", attempt+1, "max_attempts", cfg.MaxAttempts, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// retryable checks if error is transient: a network error or a node server error.
+// Client errors, including missing keys, and cancelled or expired contexts aren't retried.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var errResp minio.ErrorResponse
+	if errors.As(err, &errResp) {
+		return errResp.StatusCode >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "connection reset", err: &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, expected: true},
+		{name: "truncated body", err: fmt.Errorf("unable to read body: %w", io.ErrUnexpectedEOF), expected: true},
+		{name: "server error", err: minio.ErrorResponse{Code: "InternalError", StatusCode: 500}, expected: true},
+		{name: "unavailable", err: minio.ErrorResponse{Code: "SlowDown", StatusCode: 503}, expected: true},
+		{name: "missing key", err: minio.ErrorResponse{Code: "NoSuchKey", StatusCode: 404}},
+		{name: "access denied", err: minio.ErrorResponse{Code: "AccessDenied", StatusCode: 403}},
+		{name: "deadline exceeded", err: fmt.Errorf("get: %w", context.DeadlineExceeded)},
+		{name: "other error", err: errors.New("object is empty")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, retryable(tt.err))
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	transient := minio.ErrorResponse{Code: "InternalError", StatusCode: 500}
+
+	tests := []struct {
+		name             string
+		cfg              RetryConfig
+		errs             []error
+		expectedErr      error
+		expectedAttempts int
+	}{
+		{name: "succeeds after transient failures", cfg: RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond}, errs: []error{transient, transient, nil}, expectedAttempts: 3},
+		{name: "attempts exhausted", cfg: RetryConfig{MaxAttempts: 2, BaseDelay: time.Millisecond}, errs: []error{transient, transient, nil}, expectedErr: transient, expectedAttempts: 2},
+		{name: "client error not retried", cfg: RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond}, errs: []error{minio.ErrorResponse{Code: "AccessDenied", StatusCode: 403}}, expectedErr: minio.ErrorResponse{Code: "AccessDenied", StatusCode: 403}, expectedAttempts: 1},
+		{name: "retries disabled", cfg: RetryConfig{}, errs: []error{transient, nil}, expectedErr: transient, expectedAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := retry(context.TODO(), slog.Default(), tt.cfg, func() error {
+				attempts++
+				return tt.errs[attempts-1]
+			})
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedAttempts, attempts)
+		})
+	}
+}
+
+func TestRetry_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	start := time.Now()
+	err := retry(ctx, slog.Default(), RetryConfig{MaxAttempts: 5, BaseDelay: time.Second}, func() error {
+		attempts++
+		return minio.ErrorResponse{Code: "InternalError", StatusCode: 500}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
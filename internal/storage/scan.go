@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// DefaultScanConcurrency is the default number of nodes stat at the same time while scanning for missing object.
+	DefaultScanConcurrency = 4
+	// DefaultScanTimeout is the default time scanning for missing object may take.
+	DefaultScanTimeout = 5 * time.Second
+)
+
+// ScanConfig configures looking for objects missing on their replica nodes on the remaining ring nodes.
+type ScanConfig struct {
+	// Enabled makes Get and GetStream stat the object on all other ring nodes when none of its replica nodes
+	// holds it, before reporting it absent. Objects end up there when written with a different ring, e.g. by
+	// a gateway instance that discovered other nodes. Every miss costs a request to each node, so it's disabled
+	// by default.
+	Enabled bool
+	// Concurrency is the number of nodes stat at the same time. Defaults to DefaultScanConcurrency.
+	Concurrency int
+	// Timeout bounds the scan. Object not found in time is reported absent. Defaults to DefaultScanTimeout.
+	Timeout time.Duration
+	// Relocate moves objects found on other nodes to their replica nodes in the background, so later reads find
+	// them without scanning. Streamed objects are moved once their stream is closed.
+	Relocate bool
+}
+
+// scanGet gets object missing on its replica nodes from another ring node holding it, if scanning is enabled.
+func (s *DistributedStorage) scanGet(ctx context.Context, id string) (*Object, error) {
+	node, ok := s.scan(ctx, id)
+	if !ok {
+		return nil, nil
+	}
+	var object *Object
+	start := time.Now()
+	err := s.onNode(ctx, node, func(storage Storage) (err error) {
+		object, err = storage.Get(ctx, id)
+		return err
+	})
+	if err != nil {
+		s.nodeFailed(ctx, "get", id, node, time.Since(start), err)
+		return nil, nil
+	}
+	if object != nil {
+		s.relocate(ctx, id, node)
+	}
+	return object, nil
+}
+
+// scanGetStream streams object missing on its replica nodes from another ring node holding it, if scanning is enabled.
+func (s *DistributedStorage) scanGetStream(ctx context.Context, id string) (*ObjectStream, error) {
+	node, ok := s.scan(ctx, id)
+	if !ok {
+		return nil, nil
+	}
+	var object *ObjectStream
+	start := time.Now()
+	err := s.onNode(ctx, node, func(storage Storage) (err error) {
+		object, err = storage.GetStream(ctx, id)
+		return err
+	})
+	if err != nil {
+		s.nodeFailed(ctx, "get", id, node, time.Since(start), err)
+		return nil, nil
+	}
+	if object != nil && s.scanConfig.Relocate {
+		// moving deletes the object from the node, so it waits until the node is done streaming it
+		object.Content = &relocatingReader{ReadCloser: object.Content, relocate: func() { s.relocate(ctx, id, node) }}
+	}
+	return object, nil
+}
+
+// scan stats object ID on ring nodes other than its current and previous replica nodes, at most
+// scanConfig.Concurrency at a time, returning the first node found holding it. Nodes failing the stat are
+// logged and skipped, as replica nodes already reported the object absent.
+func (s *DistributedStorage) scan(ctx context.Context, id string) (Node, bool) {
+	if !s.scanConfig.Enabled {
+		return Node{}, false
+	}
+	replicas, err := s.replicas(id)
+	if err != nil {
+		return Node{}, false
+	}
+	skipped := append(replicas, s.previousReplicas(id, replicas)...)
+	circle, _ := s.ring()
+	var nodes []Node
+	for _, node := range circleNodes(circle) {
+		if !containsNode(skipped, node) {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		return Node{}, false
+	}
+
+	concurrency := s.scanConfig.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultScanConcurrency
+	}
+	timeout := s.scanConfig.Timeout
+	if timeout <= 0 {
+		timeout = DefaultScanTimeout
+	}
+	scanCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var found *Node
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for _, node := range nodes {
+		node := node
+		g.Go(func() error {
+			if scanCtx.Err() != nil {
+				return nil
+			}
+			var info *ObjectInfo
+			start := time.Now()
+			err := s.onNode(scanCtx, node, func(storage Storage) (err error) {
+				info, err = storage.Stat(scanCtx, id)
+				return err
+			})
+			// stats cancelled as the object was found aren't node failures
+			if err != nil && scanCtx.Err() == nil {
+				s.nodeFailed(ctx, "scan", id, node, time.Since(start), err)
+			}
+			if err != nil || info == nil {
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if found == nil {
+				found = &node
+				cancel()
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	if found == nil {
+		if ctx.Err() == nil && scanCtx.Err() != nil {
+			s.logger.WarnContext(ctx, "object scan timed out", "operation", "scan", "object_id", id, "timeout", timeout)
+		}
+		return Node{}, false
+	}
+	s.logger.InfoContext(ctx, "object found outside its replica nodes", "operation", "scan", "object_id", id, "node", ringKey(*found), "nodes", ringKeys(replicas))
+	return *found, true
+}
+
+// relocate moves object found by scan on the node to its replica nodes in the background, if enabled.
+func (s *DistributedStorage) relocate(ctx context.Context, id string, node Node) {
+	if !s.scanConfig.Relocate {
+		return
+	}
+	replicas, err := s.replicas(id)
+	if err != nil {
+		return
+	}
+	// relocation outlives the read it was triggered by
+	ctx = context.WithoutCancel(ctx)
+	s.pendingWrites.Add(1)
+	go func() {
+		defer s.pendingWrites.Done()
+		start := time.Now()
+		if err := s.moveObject(ctx, id, node, replicas); err != nil {
+			s.nodeFailed(ctx, "relocate", id, node, time.Since(start), err)
+		}
+	}()
+}
+
+// relocatingReader relocates the streamed object once closed.
+type relocatingReader struct {
+	io.ReadCloser
+	relocate func()
+	once     sync.Once
+}
+
+func (rr *relocatingReader) Close() error {
+	err := rr.ReadCloser.Close()
+	rr.once.Do(rr.relocate)
+	return err
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistributedStorage_ScanOnMiss(t *testing.T) {
+	ctx := context.Background()
+	_, nodes := setupMocksAndNodes()
+	ds := createDistributedStorage(new(MockStorage), nodes)
+	storages := make(map[string]*memoryStorage)
+	for key := range ds.availableStorages {
+		storages[key] = &memoryStorage{objects: map[string]*Object{}}
+		ds.availableStorages[key] = storages[key]
+	}
+	// object written to a node other than its owner, e.g. by a gateway with a different ring
+	place := func(id string) (owner, other *memoryStorage) {
+		owner = storages[ringKey(ds.locate(id))]
+		for key, storage := range storages {
+			if key != ringKey(ds.locate(id)) {
+				other = storage
+				break
+			}
+		}
+		assert.NoError(t, other.Put(ctx, &Object{ID: id, ContentType: "text/plain", Content: []byte(id)}))
+		return owner, other
+	}
+
+	// absent without scanning
+	owner, other := place("misplaced")
+	object, err := ds.Get(ctx, "misplaced")
+	assert.NoError(t, err)
+	assert.Nil(t, object)
+
+	ds.scanConfig = ScanConfig{Enabled: true, Concurrency: 1}
+	object, err = ds.Get(ctx, "misplaced")
+	if assert.NoError(t, err) && assert.NotNil(t, object) {
+		assert.Equal(t, "misplaced", string(object.Content))
+	}
+	// not relocated unless enabled
+	assert.Nil(t, owner.objects["misplaced"])
+
+	// object missing on all nodes is absent
+	object, err = ds.Get(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, object)
+	stream, err := ds.GetStream(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, stream)
+
+	// found object is moved to its owner
+	ds.scanConfig.Relocate = true
+	object, err = ds.Get(ctx, "misplaced")
+	assert.NoError(t, err)
+	assert.NotNil(t, object)
+	ds.pendingWrites.Wait()
+	if assert.NotNil(t, owner.objects["misplaced"]) {
+		assert.Equal(t, "misplaced", string(owner.objects["misplaced"].Content))
+	}
+	assert.Nil(t, other.objects["misplaced"])
+
+	// streamed object is moved once its stream is closed
+	owner, other = place("streamed")
+	stream, err = ds.GetStream(ctx, "streamed")
+	if assert.NoError(t, err) && assert.NotNil(t, stream) {
+		content, err := io.ReadAll(stream.Content)
+		assert.NoError(t, err)
+		assert.Equal(t, "streamed", string(content))
+		ds.pendingWrites.Wait()
+		assert.Nil(t, owner.objects["streamed"])
+		assert.NoError(t, stream.Content.Close())
+	}
+	ds.pendingWrites.Wait()
+	assert.NotNil(t, owner.objects["streamed"])
+	assert.Nil(t, other.objects["streamed"])
+}
@@ -2,16 +2,25 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	dockercli "github.com/docker/docker/client"
-	"log"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/buraksezer/consistent"
+	"github.com/cavke/go-distributed-object-storage/internal/metrics"
 	"github.com/cespare/xxhash"
-	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/filters"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
@@ -19,12 +28,75 @@ const (
 	MinioAccessKeyEnv    = "MINIO_ACCESS_KEY"
 	MinioSecretKeyEnv    = "MINIO_SECRET_KEY"
 	MinioApiPort         = 9000
+	// MinioHealthPath is the minio liveness endpoint, responding OK once minio serves requests.
+	MinioHealthPath = "/minio/health/live"
+	// DefaultReadinessInterval is the default delay between node readiness probes.
+	DefaultReadinessInterval = 500 * time.Millisecond
+	// DefaultNodeInitConcurrency is the default number of storage nodes initialized at the same time.
+	DefaultNodeInitConcurrency = 8
+	// ringPartitionCount is the number of partitions object IDs are hashed to. It's independent of node count,
+	// so adding or removing a node relocates only the partitions it gains or loses.
+	ringPartitionCount = 271
+	// ringVirtualNodes is the number of points each node has on the ring, spreading partitions evenly.
+	ringVirtualNodes = 100
+	// ringLoad bounds the number of partitions a node owns to this multiple of the average.
+	ringLoad = 1.25
+	// nodeEventsRetryDelay is the delay before resubscribing to docker events after the subscription failed.
+	nodeEventsRetryDelay = 5 * time.Second
 )
 
 type Object struct {
 	ID          string
 	ContentType string
 	Content     []byte
+	// Checksum is hex encoded SHA-256 of Content stored with the object by Put and verified by Get.
+	// It's empty for objects stored without checksum, e.g. by PutStream.
+	Checksum string
+	// Metadata is user metadata stored with the object, keyed by canonical header key (e.g. "Author").
+	Metadata map[string]string
+	// ContentEncoding is set by Get to ContentEncodingGzip when Content is returned still compressed.
+	ContentEncoding string
+	// LastModified and ETag are set by Get.
+	LastModified time.Time
+	ETag         string
+	// ExpiresAt is when the object expires, after which it's treated as absent and deleted by the expiry
+	// sweeper. Zero never expires.
+	ExpiresAt time.Time
+}
+
+// ObjectStream is an object with content streamed rather than held in memory.
+// For PutStream, Content is read until EOF and closing it is caller's responsibility.
+// For GetStream, caller must close Content.
+type ObjectStream struct {
+	ID          string
+	ContentType string
+	// Size of content in bytes, -1 if unknown
+	Size    int64
+	Content io.ReadCloser
+	// Metadata is user metadata stored with the object, keyed by canonical header key (e.g. "Author").
+	Metadata map[string]string
+	// ContentEncoding is set by GetStream to ContentEncodingGzip when Content is streamed still compressed.
+	ContentEncoding string
+	// LastModified and ETag are set by GetStream and GetRange.
+	LastModified time.Time
+	ETag         string
+	// ExpiresAt is when the object expires, after which it's treated as absent and deleted by the expiry
+	// sweeper. Zero never expires.
+	ExpiresAt time.Time
+}
+
+// ObjectInfo describes stored object without its content.
+type ObjectInfo struct {
+	ID           string
+	ContentType  string
+	Size         int64
+	LastModified time.Time
+	// ETag identifies object content: its checksum if stored, otherwise the node's ETag.
+	ETag string
+	// Metadata is user metadata stored with the object, keyed by canonical header key (e.g. "Author").
+	Metadata map[string]string
+	// ExpiresAt is when the object expires. Zero never expires.
+	ExpiresAt time.Time
 }
 
 type Node struct {
@@ -33,12 +105,50 @@ type Node struct {
 	Endpoint  string
 	AccessKey string
 	SecretKey string
+	// Secure makes the gateway connect to the node over TLS, even if not enabled by MinioConfig.Secure.
+	Secure bool
+	// Weight is the node's share of objects relative to other nodes, e.g. by its capacity. Zero is weight 1.
+	Weight int
 }
 
+// ErrObjectNotFound is returned when operation requires an existing object, but it doesn't exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ErrInvalidRange is returned when requested range of object content lies outside of the content.
+var ErrInvalidRange = errors.New("invalid range")
+
+// ErrNotReady is returned by operations of distributed storage not initialized yet, or already shut down.
+var ErrNotReady = errors.New("storage not ready")
+
+// ErrNoNodeAvailable is returned when the hash ring has no storage node to place objects on, e.g. as no nodes
+// were discovered or all of them failed initialization.
+var ErrNoNodeAvailable = errors.New("no storage nodes available")
+
+// ErrNodeUnreachable is returned by operations on a node that's on the hash ring, but whose storage isn't
+// available anymore, e.g. as the node left the cluster meanwhile.
+var ErrNodeUnreachable = errors.New("storage node not available")
+
 type Storage interface {
 	Init(ctx context.Context) error
 	Put(ctx context.Context, object *Object) error
 	Get(ctx context.Context, id string) (*Object, error)
+	Delete(ctx context.Context, id string) error
+	// PutStream stores object streaming its content, without buffering it whole in memory.
+	PutStream(ctx context.Context, object *ObjectStream) error
+	// GetStream returns object with streamed content, or nil if it doesn't exist.
+	GetStream(ctx context.Context, id string) (*ObjectStream, error)
+	// GetRange returns object streaming length bytes of its content starting at offset, or nil if it doesn't exist.
+	// Size of the returned object is the size of the range.
+	GetRange(ctx context.Context, id string, offset, length int64) (*ObjectStream, error)
+	// Stat returns object metadata without its content, or nil if it doesn't exist.
+	Stat(ctx context.Context, id string) (*ObjectInfo, error)
+	// Ping checks that storage serves requests.
+	Ping(ctx context.Context) error
+	// List returns sorted IDs of stored objects starting with prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Copy copies object content and metadata to another object ID, without transferring it through the caller.
+	// It fails with ErrObjectNotFound if the source object doesn't exist.
+	Copy(ctx context.Context, srcID, dstID string) error
 }
 
 func (n Node) String() string {
@@ -49,63 +159,306 @@ func (n Node) Debug() string {
 	return fmt.Sprintf("%s#%s#%s#%s#%s", n.ID, n.Name, n.Endpoint, n.AccessKey, maskSecret(n.SecretKey))
 }
 
+// LogValue logs node as Debug does, so its secret key is masked by any log handler.
+func (n Node) LogValue() slog.Value {
+	return slog.StringValue(n.Debug())
+}
+
+// ringKey returns the key identifying node both on the hash ring and in the available storages map.
+// It's intentionally decoupled from Node.String(), which is for display only, as any change of
+// the ring key changes object placement.
+func ringKey(n Node) string {
+	return n.ID + "#" + n.Name
+}
+
+// ringMember is a point of a storage node on the hash ring. A node is added to the ring as one member per unit
+// of its weight; the first is keyed by the node ring key, so placement on unweighted nodes doesn't depend on weights.
+type ringMember struct {
+	Node
+	point int
+}
+
+func (m ringMember) String() string {
+	if m.point == 0 {
+		return ringKey(m.Node)
+	}
+	return fmt.Sprintf("%s#%d", ringKey(m.Node), m.point+1)
+}
+
+// weight returns node weight, at least 1.
+func (n Node) weight() int {
+	return max(n.Weight, 1)
+}
+
 type hasher struct{}
 
 func (h hasher) Sum64(data []byte) uint64 {
 	return xxhash.Sum64(data)
 }
 
+// DistributedConfig holds DistributedStorage settings.
+type DistributedConfig struct {
+	// Node is the template used to create the storage of every discovered node.
+	// Endpoint and credentials are filled in from node discovery.
+	Node MinioConfig
+	// ReplicationFactor is the number of nodes each object is stored on. Defaults to 1.
+	ReplicationFactor int
+	// WriteConsistency is the number of replicas a write must succeed on before it's acknowledged. Remaining
+	// replicas keep being written in the background. Defaults to ConsistencyOne.
+	WriteConsistency ConsistencyLevel
+	// ReadConsistency is the number of replicas asked for object metadata before a read, so the most recently
+	// modified replica is read. Defaults to ConsistencyOne, reading the first replica having the object.
+	ReadConsistency ConsistencyLevel
+	// ReadStrategy is how Get and GetStream read replicas with ConsistencyOne reads. Higher levels read the latest
+	// replica first, so replicas are read sequentially. Defaults to ReadSequential.
+	ReadStrategy ReadStrategy
+	// ExpectedRingFingerprint is the ring fingerprint all gateway instances sharing the cluster should agree on.
+	// A warning is logged when the discovered ring differs. Empty disables the check.
+	ExpectedRingFingerprint string
+	// NodeReadinessTimeout is how long health endpoint of a discovered node is polled before the node is added
+	// to the ring. Nodes not ready in time are left out until the next rediscovery. Zero disables the probe.
+	NodeReadinessTimeout time.Duration
+	// NodeReadinessInterval is the delay between node readiness probes. Defaults to DefaultReadinessInterval.
+	NodeReadinessInterval time.Duration
+	// NodeInitConcurrency is the number of storage nodes initialized at the same time. Defaults to
+	// DefaultNodeInitConcurrency.
+	NodeInitConcurrency int
+	// AbortOnNodeInitFailure makes Init fail when any storage node fails initialization. By default such nodes
+	// are left out of the ring with a warning, so the storage starts degraded, and are retried on rediscovery.
+	AbortOnNodeInitFailure bool
+	// NodeChangeWindow is how long node changes reported by the discoverer are collected before nodes are
+	// rediscovered, so a burst of changes (e.g. scaling up) updates the ring once. Zero rediscovers on every change.
+	NodeChangeWindow time.Duration
+	// ResumeStreams makes object streams failing midway continue from another replica, from the offset already
+	// streamed. Replicas must hold identical content, otherwise the resumed stream mixes different versions.
+	ResumeStreams bool
+	// PublicEndpoints maps node endpoints to endpoints presigned URLs of their objects point at, for nodes
+	// clients can't reach at the endpoint the gateway uses. Nodes not listed are presigned their own endpoint.
+	PublicEndpoints map[string]string
+	// Rebalance configures moving existing objects to their new replica nodes when rediscovered nodes change
+	// the ring. Disabled by default, in which case objects stay where they were written.
+	Rebalance RebalanceConfig
+	// WriteBuffer configures buffering writes in memory, so Put and PutStream return once the object is queued
+	// rather than stored. Disabled by default.
+	WriteBuffer WriteBufferConfig
+	// ScanOnMiss configures looking for objects missing on their replica nodes on the remaining ring nodes.
+	// Disabled by default, in which case such objects are reported absent.
+	ScanOnMiss ScanConfig
+	// ExpirySweepInterval is how often expired objects are deleted from the nodes, until the Init context
+	// is cancelled. Zero disables the sweeper, leaving expired objects on the nodes, yet absent for reads.
+	ExpirySweepInterval time.Duration
+	// Breaker configures per-node circuit breakers, failing operations on nodes that keep failing right away
+	// so replicas are tried without waiting for the node. Breakers are disabled unless Breaker.Threshold is set.
+	Breaker BreakerConfig
+	// Metrics records failed node operations. Nil disables recording.
+	Metrics *metrics.Metrics
+	// Logger logs node events and failures. It's also used by node storages unless Node has its own logger.
+	// Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
 type DistributedStorage struct {
-	client            *dockercli.Client
-	bucketName        string
+	discoverer        NodeDiscoverer
+	nodeConfig        MinioConfig
+	newStorage        func(cfg *MinioConfig) (Storage, error)
+	expectedRingPrint string
+	replicationFactor int
+	writeConsistency  ConsistencyLevel
+	readConsistency   ConsistencyLevel
+	readStrategy      ReadStrategy
+	readinessTimeout  time.Duration
+	readinessInterval time.Duration
+	initConcurrency   int
+	abortOnInitFail   bool
+	nodeChangeWindow  time.Duration
+	resumeStreams     bool
+	publicEndpoints   map[string]string
+	rebalanceConfig   RebalanceConfig
+	scanConfig        ScanConfig
+	buffer            *writeBuffer
+	sweepInterval     time.Duration
+	breakerConfig     BreakerConfig
+	metrics           *metrics.Metrics
+	logger            *slog.Logger
+	// pendingWrites tracks replica writes still running after the write was acknowledged
+	pendingWrites sync.WaitGroup
+	// rebalances tracks running rebalancing
+	rebalances sync.WaitGroup
+	// rediscovery and sweeper track background workers started by Init, stopped by their cancel functions
+	rediscovery     sync.WaitGroup
+	stopRediscovery context.CancelFunc
+	sweeper         sync.WaitGroup
+	stopSweeper     context.CancelFunc
+	// conditionalWrites serializes conditional writes of the same object
+	conditionalWrites objectLocks
+	// credentialRefreshes deduplicates concurrent credential refreshes of a node, keyed by its ring key
+	credentialRefreshes singleflight.Group
+	// reloadMu serializes rebuilding the hash ring from discovered nodes
+	reloadMu sync.Mutex
+	// mu guards the hash ring and available storages, which change as nodes come and go
+	mu                sync.RWMutex
 	circle            *consistent.Consistent
+	ringConfig        consistent.Config
 	availableStorages map[string]Storage
+	// previousCircles are rings objects may still be placed by, until rebalancing completes
+	previousCircles []*consistent.Consistent
+	cancelRebalance context.CancelFunc
+	// stopped is set once the Init context is cancelled, as the storage is shutting down
+	stopped atomic.Bool
 }
 
-func NewDistributedStorage(cli *dockercli.Client, bucketName string) Storage {
+// NewDistributedStorage creates storage distributing objects across nodes found by the discoverer.
+func NewDistributedStorage(discoverer NodeDiscoverer, cfg *DistributedConfig) Storage {
+	readinessInterval := cfg.NodeReadinessInterval
+	if readinessInterval <= 0 {
+		readinessInterval = DefaultReadinessInterval
+	}
+	initConcurrency := cfg.NodeInitConcurrency
+	if initConcurrency <= 0 {
+		initConcurrency = DefaultNodeInitConcurrency
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	nodeConfig := cfg.Node
+	if nodeConfig.Logger == nil {
+		nodeConfig.Logger = logger
+	}
+	var buffer *writeBuffer
+	if cfg.WriteBuffer.Size > 0 {
+		buffer = newWriteBuffer(cfg.WriteBuffer)
+	}
 	return &DistributedStorage{
-		client:     cli,
-		bucketName: bucketName,
+		discoverer:        discoverer,
+		nodeConfig:        nodeConfig,
+		expectedRingPrint: cfg.ExpectedRingFingerprint,
+		replicationFactor: cfg.ReplicationFactor,
+		writeConsistency:  cfg.WriteConsistency,
+		readConsistency:   cfg.ReadConsistency,
+		readStrategy:      cfg.ReadStrategy,
+		readinessTimeout:  cfg.NodeReadinessTimeout,
+		readinessInterval: readinessInterval,
+		initConcurrency:   initConcurrency,
+		abortOnInitFail:   cfg.AbortOnNodeInitFailure,
+		nodeChangeWindow:  cfg.NodeChangeWindow,
+		resumeStreams:     cfg.ResumeStreams,
+		publicEndpoints:   cfg.PublicEndpoints,
+		rebalanceConfig:   cfg.Rebalance,
+		scanConfig:        cfg.ScanOnMiss,
+		buffer:            buffer,
+		sweepInterval:     cfg.ExpirySweepInterval,
+		breakerConfig:     cfg.Breaker,
+		metrics:           cfg.Metrics,
+		logger:            logger,
 	}
 }
 
+// Init discovers and initializes storage nodes. If the discoverer watches nodes, e.g. docker events,
+// nodes are rediscovered whenever they come or go, until ctx is cancelled or Stop is called. Operations fail
+// with ErrNotReady until Init succeeds, so requests may be served while it runs, and again once the storage
+// is stopped. Storage stopped before Init completes doesn't start background workers.
 func (s *DistributedStorage) Init(ctx context.Context) error {
-	nodes, err := s.getAvailableStorageNodes(ctx)
+	nodes, err := s.discoverer.Discover(ctx)
 	if err != nil {
 		return fmt.Errorf("retrieve storage nodes: %w", err)
 	}
-
-	if err := s.initStorages(ctx, nodes); err != nil {
+	nodes, storages, err := s.initStorages(ctx, s.readyNodes(ctx, nodes), s.abortOnInitFail)
+	if err != nil {
 		return err
 	}
 
-	s.initHashCircle(nodes)
-	log.Println("DistributedStorage initialized successfully")
+	s.setNodes(nodes, storages)
+	s.checkRingFingerprint()
+	context.AfterFunc(ctx, func() { s.stopped.Store(true) })
+	s.mu.Lock()
+	// storage stopped while initializing, e.g. shut down before nodes were ready, doesn't start workers
+	if !s.stopped.Load() {
+		s.startWorkers(ctx)
+	}
+	s.mu.Unlock()
+	s.startWriteBuffer(ctx)
+	s.logger.InfoContext(ctx, "distributed storage initialized", "nodes", s.RingMembers())
 	return nil
 }
 
-// initStorages initializes all storage nodes.
-func (s *DistributedStorage) initStorages(ctx context.Context, nodes []Node) error {
-	s.availableStorages = make(map[string]Storage, len(nodes))
+// startWorkers starts background workers rediscovering nodes and sweeping expired objects, stopped by Stop.
+// It must be called with mu held, so Stop doesn't miss workers started meanwhile.
+func (s *DistributedStorage) startWorkers(ctx context.Context) {
+	if watcher, ok := s.discoverer.(nodeWatcher); ok {
+		rediscoveryCtx, stop := context.WithCancel(ctx)
+		s.stopRediscovery = stop
+		s.rediscovery.Add(1)
+		go func() {
+			defer s.rediscovery.Done()
+			watcher.Watch(rediscoveryCtx, s.coalesceRediscovery(rediscoveryCtx))
+		}()
+	}
+	if s.sweepInterval > 0 {
+		sweepCtx, stop := context.WithCancel(ctx)
+		s.stopSweeper = stop
+		s.sweeper.Add(1)
+		go func() {
+			defer s.sweeper.Done()
+			s.sweepExpired(sweepCtx, s.sweepInterval)
+		}()
+	}
+}
+
+// initStorages initializes storage nodes concurrently, at most initConcurrency at a time, so an unreachable node
+// doesn't hold up the others. Nodes failing initialization are left out with a warning, unless abort is set,
+// in which case the first failure cancels the remaining initializations and is returned.
+// Initialized nodes are returned in the given order, along with their storages.
+func (s *DistributedStorage) initStorages(ctx context.Context, nodes []Node, abort bool) ([]Node, map[string]Storage, error) {
+	var mu sync.Mutex
+	storages := make(map[string]Storage, len(nodes))
 
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.initConcurrency)
 	for _, node := range nodes {
-		storage, err := s.initStorageNode(ctx, node)
-		if err != nil {
-			return err
+		node := node
+		g.Go(func() error {
+			storage, err := s.initStorageNode(gctx, node)
+			if err != nil {
+				if abort {
+					return err
+				}
+				s.logger.WarnContext(ctx, "leaving node out of the ring", "node", node, "error", err)
+				return nil
+			}
+			mu.Lock()
+			storages[ringKey(node)] = storage
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	initialized := make([]Node, 0, len(storages))
+	for _, node := range nodes {
+		if _, ok := storages[ringKey(node)]; ok {
+			initialized = append(initialized, node)
 		}
-		s.availableStorages[node.String()] = storage
 	}
-	return nil
+	return initialized, storages, nil
 }
 
 // initStorageNode initializes a single storage node.
 func (s *DistributedStorage) initStorageNode(ctx context.Context, node Node) (Storage, error) {
-	storage, err := NewMinioStorage(&MinioConfig{
-		Endpoint:   node.Endpoint,
-		AccessKey:  node.AccessKey,
-		SecretKey:  node.SecretKey,
-		BucketName: s.bucketName,
-	})
+	cfg := s.nodeConfig
+	cfg.Endpoint = node.Endpoint
+	cfg.AccessKey = node.AccessKey
+	cfg.SecretKey = node.SecretKey
+	cfg.Secure = cfg.Secure || node.Secure
+	cfg.PublicEndpoint = s.publicEndpoints[node.Endpoint]
+
+	newStorage := s.newStorage
+	if newStorage == nil {
+		newStorage = NewMinioStorage
+	}
+	storage, err := newStorage(&cfg)
 	if err != nil {
 		return nil, fmt.Errorf("create Minio storage for node %s: %w", node.Debug(), err)
 	}
@@ -113,19 +466,106 @@ func (s *DistributedStorage) initStorageNode(ctx context.Context, node Node) (St
 	if err := storage.Init(ctx); err != nil {
 		return nil, fmt.Errorf("initialize storage for node %s: %w", node.Debug(), err)
 	}
+	if s.breakerConfig.Threshold > 0 {
+		storage = newBreakerStorage(storage, s.breakerConfig, SystemClock)
+	}
 	return storage, nil
 }
 
-// initHashCircle initializes the hash circle for node distribution.
-func (s *DistributedStorage) initHashCircle(nodes []Node) {
-	s.circle = consistent.New(nil, consistent.Config{
+// setNodes replaces the hash ring and available storages with the given nodes.
+// The ring is built from scratch, so it's the same as the one built by a freshly started gateway.
+func (s *DistributedStorage) setNodes(nodes []Node, storages map[string]Storage) {
+	circle, ringConfig := newHashCircle(nodes)
+
+	s.mu.Lock()
+	s.circle = circle
+	s.ringConfig = ringConfig
+	s.availableStorages = storages
+	s.mu.Unlock()
+}
+
+// newHashCircle creates the hash circle for node distribution. Nodes get points on the circle by their weight,
+// so heavier nodes own proportionally more partitions.
+func newHashCircle(nodes []Node) (*consistent.Consistent, consistent.Config) {
+	ringConfig := consistent.Config{
 		Hasher:            hasher{},
-		PartitionCount:    len(nodes),
-		ReplicationFactor: 0,
-		Load:              1.25,
-	})
+		PartitionCount:    ringPartitionCount,
+		ReplicationFactor: ringVirtualNodes,
+		Load:              ringLoad,
+	}
+	circle := consistent.New(nil, ringConfig)
 	for _, node := range nodes {
-		s.circle.Add(node)
+		for point := 0; point < node.weight(); point++ {
+			circle.Add(ringMember{Node: node, point: point})
+		}
+	}
+	return circle, ringConfig
+}
+
+// ring returns the current hash ring and its configuration.
+func (s *DistributedStorage) ring() (*consistent.Consistent, consistent.Config) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.circle, s.ringConfig
+}
+
+// RingFingerprint returns deterministic fingerprint of the hash ring (its members, including points of weighted
+// nodes, and parameters).
+// Gateway instances sharing a cluster place objects identically only if their fingerprints match.
+func (s *DistributedStorage) RingFingerprint() string {
+	circle, ringConfig := s.ring()
+
+	h := sha256.New()
+	fmt.Fprintf(h, "partitions=%d;replication=%d;load=%g;", ringConfig.PartitionCount, ringConfig.ReplicationFactor, ringConfig.Load)
+	for _, key := range ringMembers(circle) {
+		fmt.Fprintf(h, "%s;", key)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RingMembers returns sorted ring keys of the nodes on the hash ring.
+func (s *DistributedStorage) RingMembers() []string {
+	circle, _ := s.ring()
+	return ringKeys(circleNodes(circle))
+}
+
+// circleNodes returns nodes on the hash circle, sorted by ring key. Weighted nodes are returned once.
+func circleNodes(circle *consistent.Consistent) []Node {
+	// ring isn't built until Init
+	if circle == nil {
+		return nil
+	}
+	var nodes []Node
+	for _, member := range circle.GetMembers() {
+		if member := member.(ringMember); member.point == 0 {
+			nodes = append(nodes, member.Node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return ringKey(nodes[i]) < ringKey(nodes[j]) })
+	return nodes
+}
+
+// ringMembers returns sorted keys of all members of the hash circle, including points of weighted nodes.
+func ringMembers(circle *consistent.Consistent) []string {
+	if circle == nil {
+		return nil
+	}
+	var keys []string
+	for _, member := range circle.GetMembers() {
+		keys = append(keys, member.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkRingFingerprint warns when ring differs from the one expected by configuration.
+func (s *DistributedStorage) checkRingFingerprint() {
+	if s.expectedRingPrint == "" {
+		return
+	}
+	if actual := s.RingFingerprint(); actual != s.expectedRingPrint {
+		s.logger.Warn("ring fingerprint differs from expected, placement diverges from other gateway instances",
+			"fingerprint", actual, "expected_fingerprint", s.expectedRingPrint, "nodes", s.RingMembers())
 	}
 }
 
@@ -133,111 +573,1135 @@ func (s *DistributedStorage) Put(ctx context.Context, object *Object) error {
 	if object == nil || object.ID == "" {
 		return errors.New("object is empty")
 	}
-	// locate key on hash ring
-	key := s.circle.LocateKey([]byte(object.ID)).String()
-	log.Printf("DistributedStorage.Put: %s | %s\n", key, object.ID)
+	if buffered, err := s.bufferPut(ctx, object); buffered || err != nil {
+		return err
+	}
+	if err := s.awaitBuffered(ctx, object.ID); err != nil {
+		return fmt.Errorf("failed to push data: %w", err)
+	}
+	unlock, err := s.checkPrecondition(ctx, object.ID)
+	if err != nil {
+		return err
+	}
+	// replicas written after the write was acknowledged are written under the lock too, so the next conditional
+	// write checks its precondition only once all replicas hold this object
+	return s.put(ctx, object, unlock)
+}
 
-	// resolve storage
-	storage, ok := s.availableStorages[key]
-	if !ok {
-		return fmt.Errorf("failed to push data: storage node not available (%s)", key)
+// put stores object to its replica nodes, calling replicated once all replica writes completed, including the ones
+// still running after the write was acknowledged.
+func (s *DistributedStorage) put(ctx context.Context, object *Object, replicated func()) error {
+	// locate replica nodes on hash ring
+	nodes, err := s.replicas(object.ID)
+	if err != nil {
+		replicated()
+		return fmt.Errorf("failed to push data: %w", err)
 	}
+	s.logger.DebugContext(ctx, "object located", "operation", "put", "object_id", object.ID, "nodes", ringKeys(nodes))
+
+	// store object to all replica nodes concurrently, acknowledging the write once the consistency level is reached.
+	// Acknowledged write must reach remaining replicas too, so they're written regardless of ctx cancellation.
+	required := consistency(ctx, s.writeConsistency).required(len(nodes))
+	writeCtx := context.WithoutCancel(ctx)
+	results := make(chan error, len(nodes))
+	s.pendingWrites.Add(1)
+	go func() {
+		defer s.pendingWrites.Done()
+		defer replicated()
+		var wg sync.WaitGroup
+		var written atomic.Int32
+		for _, node := range nodes {
+			wg.Add(1)
+			go func(node Node) {
+				defer wg.Done()
+				start := time.Now()
+				err := s.onNode(writeCtx, node, func(storage Storage) error { return storage.Put(writeCtx, object) })
+				if err != nil {
+					s.nodeFailed(writeCtx, "put", object.ID, node, time.Since(start), err)
+					err = fmt.Errorf("failed to put data using node (%s): %w", ringKey(node), err)
+				} else {
+					written.Add(1)
+				}
+				results <- err
+			}(node)
+		}
+		wg.Wait()
+		if replicas := int(written.Load()); replicas < len(nodes) {
+			s.logger.WarnContext(ctx, "object under-replicated", "operation", "put", "object_id", object.ID, "replicas", replicas, "expected_replicas", len(nodes))
+		}
+	}()
+
+	return s.awaitConsistency(ctx, results, len(nodes), required)
+}
 
-	// store object to node
-	if err := storage.Put(ctx, object); err != nil {
-		return fmt.Errorf("failed to put data using node (%s): %w", key, err)
+// awaitConsistency waits for results of operation on the given number of replicas until the required number
+// succeeds, or so many fail that it can't. It stops waiting when ctx is done.
+func (s *DistributedStorage) awaitConsistency(ctx context.Context, results <-chan error, replicas, required int) error {
+	var errs []error
+	for succeeded := 0; succeeded < required; {
+		select {
+		case err := <-results:
+			if err == nil {
+				succeeded++
+				continue
+			}
+			errs = append(errs, err)
+			if len(errs) > replicas-required {
+				return fmt.Errorf("%w: %d of %d replicas required: %w", ErrConsistencyNotReached, required, replicas, errors.Join(errs...))
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 	return nil
 }
 
 func (s *DistributedStorage) Get(ctx context.Context, id string) (*Object, error) {
-	// locate key on hash ring
-	key := s.circle.LocateKey([]byte(id)).String()
-	log.Printf("DistributedStorage.Get: %s | %s\n", key, id)
+	if err := s.awaitBuffered(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+	// locate replica nodes on hash ring
+	nodes, err := s.replicas(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+	s.logger.DebugContext(ctx, "object located", "operation", "get", "object_id", id, "nodes", ringKeys(nodes))
+	previous := s.previousReplicas(id, nodes)
+	if s.readStrategy == ReadRacing && len(nodes) > 1 && consistency(ctx, s.readConsistency).required(len(nodes)) <= 1 {
+		object, err := s.getRacing(ctx, id, append(nodes, previous...))
+		if object == nil && err == nil {
+			return s.scanGet(ctx, id)
+		}
+		return object, err
+	}
+	if nodes, err = s.latestReplicas(ctx, id, nodes); err != nil {
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+	nodes = append(nodes, previous...)
 
-	// resolve storage
-	storage, ok := s.availableStorages[key]
+	// retrieve object from the first replica node having it, failing over to the next one when a node errors.
+	// Object is reported absent only if no node errored, as an errored node may hold it.
+	var errs []error
+	for _, node := range nodes {
+		var object *Object
+		start := time.Now()
+		err := s.onNode(ctx, node, func(storage Storage) (err error) {
+			object, err = storage.Get(ctx, id)
+			return err
+		})
+		if err != nil {
+			s.nodeFailed(ctx, "get", id, node, time.Since(start), err)
+			errs = append(errs, fmt.Errorf("failed to get data using node (%s): %w", ringKey(node), err))
+			continue
+		}
+		if object != nil {
+			return object, nil
+		}
+	}
+	if len(errs) == 0 {
+		return s.scanGet(ctx, id)
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (s *DistributedStorage) PutStream(ctx context.Context, object *ObjectStream) error {
+	if object == nil || object.ID == "" {
+		return errors.New("object is empty")
+	}
+	if written, err := s.bufferPutStream(ctx, object); written || err != nil {
+		return err
+	}
+	if err := s.awaitBuffered(ctx, object.ID); err != nil {
+		return fmt.Errorf("failed to push data: %w", err)
+	}
+	unlock, err := s.checkPrecondition(ctx, object.ID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// locate replica nodes on hash ring
+	nodes, err := s.replicas(object.ID)
+	if err != nil {
+		return fmt.Errorf("failed to push data: %w", err)
+	}
+	s.logger.DebugContext(ctx, "object located", "operation", "put_stream", "object_id", object.ID, "nodes", ringKeys(nodes))
+
+	if len(nodes) == 1 {
+		start := time.Now()
+		if err := s.putStreamOnNode(ctx, nodes[0], object); err != nil {
+			s.nodeFailed(ctx, "put", object.ID, nodes[0], time.Since(start), err)
+			return fmt.Errorf("failed to put data using node (%s): %w", ringKey(nodes[0]), err)
+		}
+		return nil
+	}
+	return s.putStreamReplicated(ctx, nodes, object)
+}
+
+// putStreamReplicated streams object content to all replica nodes at once through pipes, succeeding if
+// the write consistency level was reached. Content is read once, as fast as the slowest replica consumes it,
+// so unlike Put the write isn't acknowledged before all replicas complete.
+func (s *DistributedStorage) putStreamReplicated(ctx context.Context, nodes []Node, object *ObjectStream) error {
+	start := time.Now()
+	pipes := make([]*io.PipeWriter, len(nodes))
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		pr, pw := io.Pipe()
+		pipes[i] = pw
+		wg.Add(1)
+		go func(i int, node Node) {
+			defer wg.Done()
+			replica := *object
+			replica.Content = pr
+			errs[i] = s.putStreamOnNode(ctx, node, &replica)
+			// unblock writer in case node stopped reading early
+			pr.CloseWithError(fmt.Errorf("replica node (%s) stopped reading", ringKey(node)))
+		}(i, node)
+	}
+
+	source := &sourceReader{r: object.Content}
+	_, _ = io.Copy(newFanOutWriter(pipes), source)
+	for _, pw := range pipes {
+		if source.err != nil {
+			pw.CloseWithError(source.err)
+		} else {
+			pw.Close()
+		}
+	}
+	wg.Wait()
+
+	if source.err != nil {
+		return fmt.Errorf("failed to read object content: %w", source.err)
+	}
+
+	var failed []error
+	for i, err := range errs {
+		if err != nil {
+			s.nodeFailed(ctx, "put", object.ID, nodes[i], time.Since(start), err)
+			failed = append(failed, fmt.Errorf("failed to put data using node (%s): %w", ringKey(nodes[i]), err))
+		}
+	}
+	written := len(nodes) - len(failed)
+	if required := consistency(ctx, s.writeConsistency).required(len(nodes)); written < required {
+		return fmt.Errorf("%w: %d of %d replicas required: %w", ErrConsistencyNotReached, required, len(nodes), errors.Join(failed...))
+	}
+	if written < len(nodes) {
+		s.logger.WarnContext(ctx, "object under-replicated", "operation", "put", "object_id", object.ID, "replicas", written, "expected_replicas", len(nodes))
+	}
+	return nil
+}
+
+// putStreamOnNode streams object to the node. Consumed stream can't be replayed, so unlike onNode,
+// the operation isn't retried after refreshing rejected credentials.
+func (s *DistributedStorage) putStreamOnNode(ctx context.Context, node Node, object *ObjectStream) error {
+	key := ringKey(node)
+	storage, ok := s.storage(key)
 	if !ok {
-		return nil, fmt.Errorf("failed to get data: storage node not available (%s)", key)
+		return fmt.Errorf("%w (%s)", ErrNodeUnreachable, key)
+	}
+
+	err := storage.PutStream(ctx, object)
+	if authenticationFailed(err) {
+		if _, refreshErr := s.refreshNodeCredentials(ctx, node); refreshErr != nil {
+			s.logger.WarnContext(ctx, "cannot refresh node credentials", "node", key, "error", refreshErr)
+		}
 	}
+	return err
+}
 
-	// retrieve object from node
-	object, err := storage.Get(ctx, id)
+func (s *DistributedStorage) GetStream(ctx context.Context, id string) (*ObjectStream, error) {
+	if err := s.awaitBuffered(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+	// locate replica nodes on hash ring
+	nodes, err := s.replicas(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get data using node (%s): %w", key, err)
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+	s.logger.DebugContext(ctx, "object located", "operation", "get_stream", "object_id", id, "nodes", ringKeys(nodes))
+	previous := s.previousReplicas(id, nodes)
+	if s.readStrategy == ReadRacing && len(nodes) > 1 && consistency(ctx, s.readConsistency).required(len(nodes)) <= 1 {
+		nodes = append(nodes, previous...)
+		object, i, err := s.getStreamRacing(ctx, id, nodes)
+		if object != nil {
+			s.resumeStream(ctx, id, object, nodes[i], append(append([]Node{}, nodes[:i]...), nodes[i+1:]...))
+		}
+		if object == nil && err == nil {
+			return s.scanGetStream(ctx, id)
+		}
+		return object, err
+	}
+	if nodes, err = s.latestReplicas(ctx, id, nodes); err != nil {
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+	nodes = append(nodes, previous...)
+
+	// stream object from the first replica node having it
+	var lastErr error
+	for i, node := range nodes {
+		var object *ObjectStream
+		start := time.Now()
+		err := s.onNode(ctx, node, func(storage Storage) (err error) {
+			object, err = storage.GetStream(ctx, id)
+			return err
+		})
+		if err != nil {
+			s.nodeFailed(ctx, "get", id, node, time.Since(start), err)
+			lastErr = fmt.Errorf("failed to get data using node (%s): %w", ringKey(node), err)
+			continue
+		}
+		if object != nil {
+			s.resumeStream(ctx, id, object, node, nodes[i+1:])
+			return object, nil
+		}
 	}
-	return object, nil
+	if lastErr == nil {
+		return s.scanGetStream(ctx, id)
+	}
+	return nil, lastErr
 }
 
-// getAvailableStorageNodes returns map od Nodes that correspond to minio docker containers in running status
-func (s *DistributedStorage) getAvailableStorageNodes(ctx context.Context) ([]Node, error) {
-	containers, err := s.client.ContainerList(ctx, types.ContainerListOptions{
-		Filters: filters.NewArgs(filters.KeyValuePair{
-			Key: "status", Value: "running",
-		}),
-	})
+// resumeStream makes object stream opened on the node continue from the other replicas when it fails midway,
+// if enabled. Compressed content can't be resumed, as ranges are served decompressed.
+func (s *DistributedStorage) resumeStream(ctx context.Context, id string, object *ObjectStream, node Node, replicas []Node) {
+	if s.resumeStreams && object.Size >= 0 && object.ContentEncoding == "" && len(replicas) > 0 {
+		object.Content = &resumingReader{ReadCloser: object.Content, resume: s.streamResumer(ctx, id, object.Size, object.ETag, node, replicas)}
+	}
+}
+
+// streamResumer returns function resuming stream of object with given size and ETag, which failed on the node,
+// from the next replica node serving the rest of the content of the same version.
+func (s *DistributedStorage) streamResumer(ctx context.Context, id string, size int64, etag string, node Node, replicas []Node) func(offset int64) (io.ReadCloser, error) {
+	return func(offset int64) (io.ReadCloser, error) {
+		s.metrics.NodeError(ringKey(node), "get")
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if offset >= size {
+			return nil, errors.New("stream failed after all content was read")
+		}
+
+		for len(replicas) > 0 {
+			node, replicas = replicas[0], replicas[1:]
+			var object *ObjectStream
+			start := time.Now()
+			err := s.onNode(ctx, node, func(storage Storage) (err error) {
+				object, err = storage.GetRange(ctx, id, offset, size-offset)
+				return err
+			})
+			if err != nil {
+				s.nodeFailed(ctx, "get", id, node, time.Since(start), err)
+				continue
+			}
+			if object == nil {
+				continue
+			}
+			if object.Size != size-offset || object.ETag != etag {
+				// replica holds another version, its content can't continue the stream
+				object.Content.Close()
+				s.logger.WarnContext(ctx, "replica differs, not resuming stream from it", "operation", "get", "object_id", id, "node", ringKey(node))
+				continue
+			}
+			s.logger.InfoContext(ctx, "stream resumed", "operation", "get", "object_id", id, "node", ringKey(node), "offset", offset)
+			return object.Content, nil
+		}
+		return nil, errors.New("no replica to resume from")
+	}
+}
+
+func (s *DistributedStorage) GetRange(ctx context.Context, id string, offset, length int64) (*ObjectStream, error) {
+	if err := s.awaitBuffered(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+	// locate replica nodes on hash ring
+	nodes, err := s.replicas(id)
 	if err != nil {
-		return nil, fmt.Errorf("unable to list containers: %w", err)
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+	s.logger.DebugContext(ctx, "object located", "operation", "get_range", "object_id", id, "nodes", ringKeys(nodes), "offset", offset, "length", length)
+	previous := s.previousReplicas(id, nodes)
+	if nodes, err = s.latestReplicas(ctx, id, nodes); err != nil {
+		return nil, fmt.Errorf("failed to get data: %w", err)
 	}
-	// log.Printf("getAvailableStorageNodes: %v\n", containers)
+	nodes = append(nodes, previous...)
 
-	var storageNodes []Node
-	for _, container := range containers {
-		if container.ID == "" || container.NetworkSettings == nil {
+	// stream object range from the first replica node having it
+	var lastErr error
+	for _, node := range nodes {
+		var object *ObjectStream
+		start := time.Now()
+		err := s.onNode(ctx, node, func(storage Storage) (err error) {
+			object, err = storage.GetRange(ctx, id, offset, length)
+			return err
+		})
+		if errors.Is(err, ErrInvalidRange) {
+			// replicas hold the same content, so the range is invalid on all of them
+			return nil, fmt.Errorf("failed to get data using node (%s): %w", ringKey(node), err)
+		}
+		if err != nil {
+			s.nodeFailed(ctx, "get", id, node, time.Since(start), err)
+			lastErr = fmt.Errorf("failed to get data using node (%s): %w", ringKey(node), err)
 			continue
 		}
-		node := Node{ID: container.ID}
+		if object != nil {
+			return object, nil
+		}
+	}
+	return nil, lastErr
+}
 
-		// parse name
-		if len(container.Names) > 0 {
-			node.Name = container.Names[0]
+func (s *DistributedStorage) Stat(ctx context.Context, id string) (*ObjectInfo, error) {
+	if err := s.awaitBuffered(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to stat data: %w", err)
+	}
+	// locate replica nodes on hash ring
+	nodes, err := s.replicas(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat data: %w", err)
+	}
+	s.logger.DebugContext(ctx, "object located", "operation", "stat", "object_id", id, "nodes", ringKeys(nodes))
+	previous := s.previousReplicas(id, nodes)
+	if required := consistency(ctx, s.readConsistency).required(len(nodes)); required > 1 {
+		_, info, err := s.statReplicas(ctx, id, nodes, required)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat data: %w", err)
 		}
-		// check if it's relevant storage node
-		if !strings.Contains(node.Name, ContainerNamePattern) {
+		if info != nil || len(previous) == 0 {
+			return info, nil
+		}
+		nodes = nil
+	}
+	nodes = append(nodes, previous...)
+
+	// retrieve object info from the first replica node having it
+	var lastErr error
+	for _, node := range nodes {
+		var info *ObjectInfo
+		start := time.Now()
+		err := s.onNode(ctx, node, func(storage Storage) (err error) {
+			info, err = storage.Stat(ctx, id)
+			return err
+		})
+		if err != nil {
+			s.nodeFailed(ctx, "stat", id, node, time.Since(start), err)
+			lastErr = fmt.Errorf("failed to stat data using node (%s): %w", ringKey(node), err)
 			continue
 		}
+		if info != nil {
+			return info, nil
+		}
+	}
+	return nil, lastErr
+}
 
-		// log.Printf("getAvailableStorageNodes: node: %v\n", node)
+// latestReplicas orders replica nodes of the object for reading it at the read consistency level. Above
+// ConsistencyOne, nodes holding the latest version come first, see statReplicas. Otherwise nodes keep ring order.
+func (s *DistributedStorage) latestReplicas(ctx context.Context, id string, nodes []Node) ([]Node, error) {
+	required := consistency(ctx, s.readConsistency).required(len(nodes))
+	if required <= 1 {
+		return nodes, nil
+	}
+	nodes, _, err := s.statReplicas(ctx, id, nodes, required)
+	return nodes, err
+}
 
-		// resolve IPAddress of storage node
-		var addr string
-		if container.NetworkSettings != nil && container.NetworkSettings.Networks != nil {
-			for _, n := range container.NetworkSettings.Networks {
-				if n.IPAddress != "" {
-					addr = n.IPAddress
-					break
-				}
+// statReplicas stats the object on replica nodes concurrently until the required number of them responds,
+// failing with ErrConsistencyNotReached if too many fail. Responding nodes holding the object are ordered first,
+// latest modified first, followed by the rest in ring order. It also returns info of the latest version,
+// or nil if no responding node holds the object.
+func (s *DistributedStorage) statReplicas(ctx context.Context, id string, nodes []Node, required int) ([]Node, *ObjectInfo, error) {
+	type statResult struct {
+		index    int
+		info     *ObjectInfo
+		err      error
+		duration time.Duration
+	}
+	// nodes not responding by the time enough did are abandoned
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan statResult, len(nodes))
+	for i, node := range nodes {
+		go func(i int, node Node) {
+			var info *ObjectInfo
+			start := time.Now()
+			err := s.onNode(ctx, node, func(storage Storage) (err error) {
+				info, err = storage.Stat(ctx, id)
+				return err
+			})
+			results <- statResult{index: i, info: info, err: err, duration: time.Since(start)}
+		}(i, node)
+	}
+
+	infos := make([]*ObjectInfo, len(nodes))
+	var errs []error
+	for responded := 0; responded < required; {
+		select {
+		case result := <-results:
+			if result.err == nil {
+				infos[result.index] = result.info
+				responded++
+				continue
+			}
+			node := nodes[result.index]
+			s.nodeFailed(ctx, "stat", id, node, result.duration, result.err)
+			errs = append(errs, fmt.Errorf("failed to stat data using node (%s): %w", ringKey(node), result.err))
+			if len(errs) > len(nodes)-required {
+				return nil, nil, fmt.Errorf("%w: %d of %d replicas required: %w", ErrConsistencyNotReached, required, len(nodes), errors.Join(errs...))
 			}
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	order := make([]int, len(nodes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		infoA, infoB := infos[order[a]], infos[order[b]]
+		if infoA == nil || infoB == nil {
+			return infoA != nil && infoB == nil
+		}
+		return infoA.LastModified.After(infoB.LastModified)
+	})
+	ordered := make([]Node, 0, len(nodes))
+	for _, i := range order {
+		ordered = append(ordered, nodes[i])
+	}
+	return ordered, infos[order[0]], nil
+}
+
+func (s *DistributedStorage) Delete(ctx context.Context, id string) error {
+	if err := s.awaitBuffered(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete data: %w", err)
+	}
+	// locate replica nodes on hash ring
+	nodes, err := s.replicas(id)
+	if err != nil {
+		return fmt.Errorf("failed to delete data: %w", err)
+	}
+	s.logger.DebugContext(ctx, "object located", "operation", "delete", "object_id", id, "nodes", ringKeys(nodes))
+	// object not moved yet is deleted from its previous replicas too, so rebalancing doesn't bring it back
+	nodes = append(nodes, s.previousReplicas(id, nodes)...)
+
+	// delete object from all replica nodes
+	var lastErr error
+	deleted := 0
+	for _, node := range nodes {
+		start := time.Now()
+		err := s.onNode(ctx, node, func(storage Storage) error { return storage.Delete(ctx, id) })
+		if errors.Is(err, ErrObjectNotFound) {
+			continue
 		}
-		if addr == "" {
-			log.Fatalf("unable to resolve ip address of storage node: %v", node)
+		if err != nil {
+			s.nodeFailed(ctx, "delete", id, node, time.Since(start), err)
+			lastErr = fmt.Errorf("failed to delete data using node (%s): %w", ringKey(node), err)
 			continue
 		}
-		node.Endpoint = fmt.Sprintf("%s:%d", addr, MinioApiPort)
+		deleted++
+	}
+	if lastErr != nil {
+		return lastErr
+	}
+	if deleted == 0 {
+		return fmt.Errorf("failed to delete data (%s): %w", id, ErrObjectNotFound)
+	}
+	return nil
+}
 
-		// resolve access credentials
-		inspectData, err := s.client.ContainerInspect(ctx, container.ID)
+// Copy copies the object to every replica node of the destination ID. Nodes holding a source replica copy it
+// server-side, other nodes are streamed the source object read from its replicas. Copy succeeds once
+// the write consistency level is reached.
+func (s *DistributedStorage) Copy(ctx context.Context, srcID, dstID string) error {
+	for _, id := range []string{srcID, dstID} {
+		if err := s.awaitBuffered(ctx, id); err != nil {
+			return fmt.Errorf("failed to copy data: %w", err)
+		}
+	}
+	// locate replica nodes of both objects on hash ring
+	srcNodes, err := s.replicas(srcID)
+	if err != nil {
+		return fmt.Errorf("failed to copy data: %w", err)
+	}
+	dstNodes, err := s.replicas(dstID)
+	if err != nil {
+		return fmt.Errorf("failed to copy data: %w", err)
+	}
+	s.logger.DebugContext(ctx, "object located", "operation", "copy", "object_id", srcID, "nodes", ringKeys(srcNodes), "destination_id", dstID, "destination_nodes", ringKeys(dstNodes))
+
+	sourceKeys := make(map[string]bool, len(srcNodes))
+	for _, node := range srcNodes {
+		sourceKeys[ringKey(node)] = true
+	}
+	var errs []error
+	for _, node := range dstNodes {
+		start := time.Now()
+		err := ErrObjectNotFound
+		if sourceKeys[ringKey(node)] {
+			err = s.onNode(ctx, node, func(storage Storage) error { return storage.Copy(ctx, srcID, dstID) })
+		}
+		// node may miss its source replica, e.g. after a failed write, so it's streamed one from other replicas
+		if errors.Is(err, ErrObjectNotFound) {
+			err = s.copyToNode(ctx, srcID, dstID, node)
+		}
+		if errors.Is(err, ErrObjectNotFound) {
+			return fmt.Errorf("failed to copy data: %w", err)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("unable to inspect container %s: %w", container.ID, err)
+			s.nodeFailed(ctx, "copy", dstID, node, time.Since(start), err)
+			errs = append(errs, fmt.Errorf("failed to copy data using node (%s): %w", ringKey(node), err))
 		}
+	}
 
-		containerEnv := make(map[string]string)
-		for _, env := range inspectData.Config.Env {
-			splitted := strings.Split(env, "=")
-			if len(splitted) > 1 {
-				containerEnv[splitted[0]] = splitted[1]
+	copied := len(dstNodes) - len(errs)
+	if required := consistency(ctx, s.writeConsistency).required(len(dstNodes)); copied < required {
+		return fmt.Errorf("%w: %d of %d replicas required: %w", ErrConsistencyNotReached, required, len(dstNodes), errors.Join(errs...))
+	}
+	if copied < len(dstNodes) {
+		s.logger.WarnContext(ctx, "object under-replicated", "operation", "copy", "object_id", dstID, "replicas", copied, "expected_replicas", len(dstNodes))
+	}
+	return nil
+}
+
+// copyToNode streams source object, read from its replicas, to the node as the destination object.
+func (s *DistributedStorage) copyToNode(ctx context.Context, srcID, dstID string, node Node) error {
+	object, err := s.GetStream(ctx, srcID)
+	if err != nil {
+		return err
+	}
+	if object == nil {
+		return fmt.Errorf("%w: %s", ErrObjectNotFound, srcID)
+	}
+	defer object.Content.Close()
+
+	object.ID = dstID
+	if err := decompressStream(object); err != nil {
+		return err
+	}
+	return s.putStreamOnNode(ctx, node, object)
+}
+
+// Ping checks that all available storage nodes serve requests.
+func (s *DistributedStorage) Ping(ctx context.Context) error {
+	var errs []error
+	for key, err := range s.PingNodes(ctx) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PingNodes pings all available storage nodes concurrently and returns their ping errors keyed by ring key.
+func (s *DistributedStorage) PingNodes(ctx context.Context) map[string]error {
+	s.mu.RLock()
+	storages := make(map[string]Storage, len(s.availableStorages))
+	for key, storage := range s.availableStorages {
+		storages[key] = storage
+	}
+	s.mu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]error, len(storages))
+	for key, storage := range storages {
+		wg.Add(1)
+		go func(key string, storage Storage) {
+			defer wg.Done()
+			err := storage.Ping(ctx)
+			mu.Lock()
+			results[key] = err
+			mu.Unlock()
+		}(key, storage)
+	}
+	wg.Wait()
+	return results
+}
+
+// BreakerStates returns circuit breaker states of available storage nodes keyed by ring key.
+// It's empty if breakers are disabled.
+func (s *DistributedStorage) BreakerStates() map[string]BreakerState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	states := make(map[string]BreakerState)
+	for key, storage := range s.availableStorages {
+		if breaker, ok := storage.(*breakerStorage); ok {
+			states[key] = breaker.State()
+		}
+	}
+	return states
+}
+
+// List lists objects on all available storage nodes concurrently, as an object can be placed on any of them,
+// merging their IDs. Replicas are listed once. Listing fails if any node fails, as its objects would be missing.
+func (s *DistributedStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := s.ready(); err != nil {
+		return nil, fmt.Errorf("failed to list data: %w", err)
+	}
+	s.mu.RLock()
+	storages := make(map[string]Storage, len(s.availableStorages))
+	for key, storage := range s.availableStorages {
+		storages[key] = storage
+	}
+	s.mu.RUnlock()
+
+	var mu sync.Mutex
+	unique := make(map[string]struct{})
+	g, gctx := errgroup.WithContext(ctx)
+	for key, storage := range storages {
+		key, storage := key, storage
+		g.Go(func() error {
+			start := time.Now()
+			ids, err := storage.List(gctx, prefix)
+			if err != nil {
+				s.logger.WarnContext(ctx, "node operation failed", "operation", "list", "prefix", prefix, "node", key, "duration", time.Since(start), "error", err)
+				s.metrics.NodeError(key, "list")
+				return fmt.Errorf("failed to list data using node (%s): %w", key, err)
+			}
+			mu.Lock()
+			for _, id := range ids {
+				unique[id] = struct{}{}
 			}
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(unique))
+	for id := range unique {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Placement describes a node object is placed on.
+type Placement struct {
+	Node      Node
+	Primary   bool
+	Available bool
+}
+
+// Locate returns nodes object ID is placed on according to the current ring, the primary first.
+// It doesn't contact the nodes; a node is available if it has an initialized storage.
+func (s *DistributedStorage) Locate(id string) ([]Placement, error) {
+	nodes, err := s.replicas(id)
+	if err != nil {
+		return nil, err
+	}
+
+	placements := make([]Placement, 0, len(nodes))
+	for i, node := range nodes {
+		_, available := s.storage(ringKey(node))
+		placements = append(placements, Placement{Node: node, Primary: i == 0, Available: available})
+	}
+	return placements, nil
+}
+
+// replicas returns nodes holding replicas of object ID, starting with its owner on the hash ring.
+func (s *DistributedStorage) replicas(id string) ([]Node, error) {
+	if err := s.ready(); err != nil {
+		return nil, err
+	}
+	circle, _ := s.ring()
+	return s.circleReplicas(circle, id)
+}
+
+// ready checks that the storage is initialized and not shut down, so operations have a ring to locate objects on.
+func (s *DistributedStorage) ready() error {
+	if circle, _ := s.ring(); circle == nil || s.stopped.Load() {
+		return ErrNotReady
+	}
+	return nil
+}
+
+// circleReplicas returns nodes holding replicas of object ID on the hash circle, starting with its owner.
+func (s *DistributedStorage) circleReplicas(circle *consistent.Consistent, id string) ([]Node, error) {
+	members := len(circle.GetMembers())
+	if members == 0 {
+		return nil, ErrNoNodeAvailable
+	}
+	count := min(s.replicationFactor, len(circleNodes(circle)))
+	if count <= 1 {
+		return []Node{circle.LocateKey([]byte(id)).(ringMember).Node}, nil
+	}
+
+	// members closest to the owner may be points of the same weighted node, so all members are walked
+	// until enough distinct nodes are found
+	closest, err := circle.GetClosestN([]byte(id), members)
+	if err != nil {
+		return nil, fmt.Errorf("unable to locate %d replicas: %w", count, err)
+	}
+	nodes := make([]Node, 0, count)
+	for _, member := range closest {
+		if node := member.(ringMember).Node; !containsNode(nodes, node) {
+			nodes = append(nodes, node)
+		}
+		if len(nodes) == count {
+			break
+		}
+	}
+	return nodes, nil
+}
+
+// onNode runs operation using storage of the node. If node rejects credentials, they're refreshed
+// and operation retried.
+func (s *DistributedStorage) onNode(ctx context.Context, node Node, op func(storage Storage) error) error {
+	key := ringKey(node)
+	storage, ok := s.storage(key)
+	if !ok {
+		return fmt.Errorf("%w (%s)", ErrNodeUnreachable, key)
+	}
+
+	err := op(storage)
+	if authenticationFailed(err) {
+		if storage, err = s.refreshNodeCredentials(ctx, node); err == nil {
+			err = op(storage)
 		}
+	}
+	return err
+}
 
-		node.AccessKey = containerEnv[MinioAccessKeyEnv]
-		node.SecretKey = containerEnv[MinioSecretKeyEnv]
+// nodeFailed logs and records failed operation on object ID using the node.
+func (s *DistributedStorage) nodeFailed(ctx context.Context, operation, id string, node Node, duration time.Duration, err error) {
+	s.logger.WarnContext(ctx, "node operation failed", "operation", operation, "object_id", id, "node", ringKey(node), "duration", duration, "error", err)
+	s.metrics.NodeError(ringKey(node), operation)
+}
+
+// containsNode reports whether nodes contain the node, compared by ring key, as credentials of the same node
+// differ between rings built before and after they were refreshed.
+func containsNode(nodes []Node, node Node) bool {
+	return slices.ContainsFunc(nodes, func(n Node) bool { return ringKey(n) == ringKey(node) })
+}
 
-		// add storage node
-		storageNodes = append(storageNodes, node)
-		log.Printf("getAvailableStorageNodes: node added %s", node.Debug())
+// ringKeys returns ring keys of nodes, which identify them in logs without exposing their credentials.
+func ringKeys(nodes []Node) []string {
+	keys := make([]string, len(nodes))
+	for i, node := range nodes {
+		keys[i] = ringKey(node)
 	}
+	return keys
+}
+
+// locate returns the node owning object ID on the hash ring.
+func (s *DistributedStorage) locate(id string) Node {
+	circle, _ := s.ring()
+	return circle.LocateKey([]byte(id)).(ringMember).Node
+}
+
+// storage returns storage of the node with given key.
+func (s *DistributedStorage) storage(key string) (Storage, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	storage, ok := s.availableStorages[key]
+	return storage, ok
+}
 
-	return storageNodes, nil
+// idleConnectionCloser is implemented by node storages holding idle connections to the node.
+type idleConnectionCloser interface {
+	CloseIdleConnections()
+}
+
+// refreshNodeCredentials resolves rotated node credentials using the discoverer and replaces node storage.
+// It's triggered by authentication failures, so credential rotation doesn't wait for a full rediscovery.
+// Concurrent failures of the node share a single refresh and its result.
+func (s *DistributedStorage) refreshNodeCredentials(ctx context.Context, node Node) (Storage, error) {
+	storage, err, _ := s.credentialRefreshes.Do(ringKey(node), func() (any, error) {
+		return s.reauthenticate(ctx, node)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return storage.(Storage), nil
+}
+
+// reauthenticate replaces node storage by one using credentials resolved by the discoverer. The node on the hash
+// ring gets the rotated credentials, and idle connections of the replaced storage are closed.
+func (s *DistributedStorage) reauthenticate(ctx context.Context, node Node) (storage Storage, err error) {
+	s.logger.WarnContext(ctx, "node authentication failed, refreshing credentials", "node", node)
+	key := ringKey(node)
+	defer func() {
+		result := "success"
+		if err != nil {
+			result = "failure"
+		}
+		s.metrics.NodeReauth(key, result)
+	}()
+
+	resolver, ok := s.discoverer.(credentialResolver)
+	if !ok {
+		return nil, fmt.Errorf("refresh credentials for node %s: node discovery doesn't resolve credentials", node.Debug())
+	}
+	accessKey, secretKey, err := resolver.Credentials(ctx, node)
+	if err != nil {
+		return nil, fmt.Errorf("refresh credentials for node %s: %w", node.Debug(), err)
+	}
+	if accessKey == node.AccessKey && secretKey == node.SecretKey {
+		return nil, fmt.Errorf("refresh credentials for node %s: credentials unchanged", node.Debug())
+	}
+	node.AccessKey = accessKey
+	node.SecretKey = secretKey
+
+	storage, err = s.initStorageNode(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	// node left the ring meanwhile, its storage is used by this operation only
+	previous, available := s.availableStorages[key]
+	if available {
+		s.availableStorages[key] = storage
+		nodes := circleNodes(s.circle)
+		for i := range nodes {
+			if ringKey(nodes[i]) == key {
+				nodes[i] = node
+			}
+		}
+		s.circle, s.ringConfig = newHashCircle(nodes)
+	}
+	s.mu.Unlock()
+	if closer, ok := previous.(idleConnectionCloser); ok {
+		closer.CloseIdleConnections()
+	}
+
+	s.logger.InfoContext(ctx, "node re-authenticated with rotated credentials", "node", node)
+	return storage, nil
+}
+
+// coalesceRediscovery returns node change handler rediscovering nodes once the change window after the first
+// change elapses, so all changes within the window result in a single ring update. Changes during rediscovery
+// trigger another one. The returned handler doesn't block; rediscovery stops when ctx is cancelled.
+func (s *DistributedStorage) coalesceRediscovery(ctx context.Context) func(ctx context.Context) {
+	if s.nodeChangeWindow <= 0 {
+		return s.rediscoverNodes
+	}
+
+	changes := make(chan struct{}, 1)
+	s.rediscovery.Add(1)
+	go func() {
+		defer s.rediscovery.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changes:
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.nodeChangeWindow):
+			}
+			// changes within the window are covered by this rediscovery
+			select {
+			case <-changes:
+			default:
+			}
+			s.rediscoverNodes(ctx)
+		}
+	}()
+
+	return func(context.Context) {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Stop stops background workers started by Init and waits until they return, or ctx is done: node rediscovery
+// first, so it doesn't start another rebalancing, then expiry sweeping and rebalancing. Finally it waits for
+// replica writes still running after their write was acknowledged. Operations fail with ErrNotReady once it's
+// called, so buffered writes must be flushed by FlushWrites before.
+func (s *DistributedStorage) Stop(ctx context.Context) error {
+	s.stopped.Store(true)
+	s.mu.Lock()
+	stopRediscovery, stopSweeper := s.stopRediscovery, s.stopSweeper
+	s.mu.Unlock()
+
+	if stopRediscovery != nil {
+		stopRediscovery()
+	}
+	if err := waitGroup(ctx, &s.rediscovery); err != nil {
+		return fmt.Errorf("failed to stop node rediscovery: %w", err)
+	}
+	if stopSweeper != nil {
+		stopSweeper()
+	}
+	if err := waitGroup(ctx, &s.sweeper); err != nil {
+		return fmt.Errorf("failed to stop expiry sweeping: %w", err)
+	}
+	s.mu.Lock()
+	if s.cancelRebalance != nil {
+		s.cancelRebalance()
+	}
+	s.mu.Unlock()
+	if err := waitGroup(ctx, &s.rebalances); err != nil {
+		return fmt.Errorf("failed to stop rebalancing: %w", err)
+	}
+	// acknowledged writes must reach their remaining replicas, or they would be lost on exit
+	if err := waitGroup(ctx, &s.pendingWrites); err != nil {
+		return fmt.Errorf("failed to complete replica writes: %w", err)
+	}
+	return nil
+}
+
+// waitGroup waits until the wait group counter is zero, or ctx is done.
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reload rediscovers storage nodes and rebuilds the hash ring, e.g. when the node topology changed without
+// the discoverer reporting it. Operations in flight complete using storages they already resolved. If discovery
+// fails, current nodes are kept and the error is returned. It fails with ErrNotReady before Init and after shutdown.
+func (s *DistributedStorage) Reload(ctx context.Context) error {
+	if err := s.ready(); err != nil {
+		return err
+	}
+	if err := s.reloadNodes(ctx); err != nil {
+		return fmt.Errorf("failed to reload nodes: %w", err)
+	}
+	return nil
+}
+
+// rediscoverNodes rebuilds the hash ring from currently discovered nodes, keeping current nodes if discovery fails.
+func (s *DistributedStorage) rediscoverNodes(ctx context.Context) {
+	if err := s.reloadNodes(ctx); err != nil {
+		s.logger.ErrorContext(ctx, "node rediscovery failed, keeping current nodes", "error", err)
+	}
+}
+
+// reloadNodes rebuilds the hash ring from currently discovered nodes. Storages of known nodes
+// are kept, new nodes are initialized and nodes failing initialization are left out of the ring.
+// Reloads are serialized, so a reload based on older discovery can't overwrite a newer one.
+func (s *DistributedStorage) reloadNodes(ctx context.Context) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	discovered, err := s.discoverer.Discover(ctx)
+	if err != nil {
+		return err
+	}
+
+	nodes := make([]Node, 0, len(discovered))
+	storages := make(map[string]Storage, len(discovered))
+	var added []Node
+	for _, node := range discovered {
+		if storage, ok := s.storage(ringKey(node)); ok {
+			nodes = append(nodes, node)
+			storages[ringKey(node)] = storage
+			continue
+		}
+		added = append(added, node)
+	}
+	added, addedStorages, _ := s.initStorages(ctx, s.readyNodes(ctx, added), false)
+	nodes = append(nodes, added...)
+	for key, storage := range addedStorages {
+		storages[key] = storage
+	}
+
+	previous, _ := s.ring()
+	s.setNodes(nodes, storages)
+	s.logger.InfoContext(ctx, "storage nodes rediscovered", "nodes", s.RingMembers())
+	s.checkRingFingerprint()
+	if current, _ := s.ring(); s.rebalanceConfig.Enabled && !slices.Equal(ringMembers(previous), ringMembers(current)) {
+		s.startRebalance(ctx, previous)
+	}
+	return nil
+}
+
+// readyNodes returns nodes whose minio serves requests, probing all nodes concurrently. Docker reports
+// a container running before minio within it is ready, so routing to it right away would fail.
+func (s *DistributedStorage) readyNodes(ctx context.Context, nodes []Node) []Node {
+	if s.readinessTimeout <= 0 {
+		return nodes
+	}
+
+	ready := make([]bool, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node Node) {
+			defer wg.Done()
+			if err := s.waitNodeReady(ctx, node); err != nil {
+				s.logger.WarnContext(ctx, "leaving node out of the ring", "node", node, "error", err)
+				return
+			}
+			ready[i] = true
+		}(i, node)
+	}
+	wg.Wait()
+
+	readyNodes := make([]Node, 0, len(nodes))
+	for i, node := range nodes {
+		if ready[i] {
+			readyNodes = append(readyNodes, node)
+		}
+	}
+	return readyNodes
+}
+
+// waitNodeReady polls node health endpoint until it responds OK or readiness timeout elapses.
+func (s *DistributedStorage) waitNodeReady(ctx context.Context, node Node) error {
+	ctx, cancel := context.WithTimeout(ctx, s.readinessTimeout)
+	defer cancel()
+
+	tlsConfig, err := nodeTLSConfig(s.nodeConfig.CACertPath)
+	if err != nil {
+		return fmt.Errorf("node %s not ready: %w", node, err)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	defer client.CloseIdleConnections()
+
+	scheme := "http"
+	if s.nodeConfig.Secure || node.Secure {
+		scheme = "https"
+	}
+	url := fmt.Sprintf("%s://%s%s", scheme, node.Endpoint, MinioHealthPath)
+	for {
+		err := probeNode(ctx, client, url)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("node %s not ready within %s: %w", node, s.readinessTimeout, err)
+		case <-time.After(s.readinessInterval):
+		}
+	}
+}
+
+// probeNode checks if node health endpoint responds OK.
+func probeNode(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health endpoint responded %s", resp.Status)
+	}
+	return nil
 }
 
 func maskSecret(secret string) string {
@@ -1,14 +1,77 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"github.com/buraksezer/consistent"
+	"github.com/cavke/go-distributed-object-storage/internal/logging"
+	"github.com/cavke/go-distributed-object-storage/internal/metrics"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/network"
+	"github.com/minio/minio-go/v7"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/iotest"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+// fakeDockerClient serves predefined containers and forwards events sent by test
+type fakeDockerClient struct {
+	mu         sync.Mutex
+	containers []types.Container
+	env        map[string][]string
+	events     chan events.Message
+}
+
+func (f *fakeDockerClient) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.containers, nil
+}
+
+func (f *fakeDockerClient) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	env, ok := f.env[containerID]
+	if !ok {
+		return types.ContainerJSON{}, fmt.Errorf("no such container: %s", containerID)
+	}
+	return types.ContainerJSON{Config: &container.Config{Env: env}}, nil
+}
+
+func (f *fakeDockerClient) Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error) {
+	return f.events, make(chan error)
+}
+
+func (f *fakeDockerClient) setContainers(containers ...types.Container) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.containers = containers
+}
+
+// nodeContainer creates running storage node container
+func nodeContainer(id, name, ip string) types.Container {
+	return types.Container{
+		ID:    id,
+		Names: []string{"/" + name},
+		NetworkSettings: &types.SummaryNetworkSettings{Networks: map[string]*network.EndpointSettings{
+			"default": {IPAddress: ip},
+		}},
+	}
+}
+
 // Mocking the Storage behavior
 type MockStorage struct {
 	mock.Mock
@@ -29,6 +92,59 @@ func (m *MockStorage) Get(ctx context.Context, id string) (*Object, error) {
 	return args.Get(0).(*Object), args.Error(1)
 }
 
+func (m *MockStorage) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockStorage) PutStream(ctx context.Context, object *ObjectStream) error {
+	content, err := io.ReadAll(object.Content)
+	if err != nil {
+		return err
+	}
+	args := m.Called(ctx, object.ID, content)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetStream(ctx context.Context, id string) (*ObjectStream, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*ObjectStream), args.Error(1)
+}
+
+func (m *MockStorage) GetRange(ctx context.Context, id string, offset, length int64) (*ObjectStream, error) {
+	args := m.Called(ctx, id, offset, length)
+	return args.Get(0).(*ObjectStream), args.Error(1)
+}
+
+func (m *MockStorage) Stat(ctx context.Context, id string) (*ObjectInfo, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*ObjectInfo), args.Error(1)
+}
+
+func (m *MockStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	args := m.Called(ctx, prefix)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockStorage) Copy(ctx context.Context, srcID, dstID string) error {
+	args := m.Called(ctx, srcID, dstID)
+	return args.Error(0)
+}
+
+func (m *MockStorage) Ping(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+// brokenStreamStorage fails stream uploads without reading any content
+type brokenStreamStorage struct {
+	MockStorage
+}
+
+func (b *brokenStreamStorage) PutStream(ctx context.Context, object *ObjectStream) error {
+	return errors.New("node down")
+}
+
 func TestDistributedStorage_Get(t *testing.T) {
 	// Setup mockStorage and nodes
 	mockStorage, nodes := setupMocksAndNodes()
@@ -68,6 +184,1373 @@ func TestDistributedStorage_Get(t *testing.T) {
 	}
 }
 
+func TestDistributedStorage_Delete(t *testing.T) {
+	mockStorage, nodes := setupMocksAndNodes()
+	mockStorage.On("Delete", mock.Anything, "object-1").Return(nil)
+	mockStorage.On("Delete", mock.Anything, "missing").Return(fmt.Errorf("node error: %w", ErrObjectNotFound))
+
+	ds := createDistributedStorage(mockStorage, nodes)
+
+	assert.NoError(t, ds.Delete(context.TODO(), "object-1"))
+	assert.ErrorIs(t, ds.Delete(context.TODO(), "missing"), ErrObjectNotFound)
+}
+
+func TestDistributedStorage_PutReplicated(t *testing.T) {
+	object := &Object{ID: "object-1", Content: []byte("data1")}
+
+	tests := []struct {
+		name        string
+		consistency ConsistencyLevel
+		failing     int
+		expectedErr bool
+	}{
+		{name: "all replicas written", failing: 0},
+		{name: "partial failure", failing: 2},
+		{name: "all replicas failed", failing: 3, expectedErr: true},
+		{name: "quorum, minority failed", consistency: ConsistencyQuorum, failing: 1},
+		{name: "quorum, majority failed", consistency: ConsistencyQuorum, failing: 2, expectedErr: true},
+		{name: "all, all replicas written", consistency: ConsistencyAll, failing: 0},
+		{name: "all, one replica failed", consistency: ConsistencyAll, failing: 1, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, storages := createReplicatedStorage(3)
+			ds.writeConsistency = tt.consistency
+			for i, node := range mustReplicas(t, ds, object.ID) {
+				var err error
+				if i < tt.failing {
+					err = fmt.Errorf("node down")
+				}
+				storages[ringKey(node)].On("Put", mock.Anything, object).Return(err)
+			}
+
+			err := ds.Put(context.TODO(), object)
+			if tt.expectedErr {
+				assert.ErrorIs(t, err, ErrConsistencyNotReached)
+			} else {
+				assert.NoError(t, err)
+			}
+			// replicas are written even after the write was acknowledged or failed
+			ds.pendingWrites.Wait()
+			for _, storage := range storages {
+				storage.AssertCalled(t, "Put", mock.Anything, object)
+			}
+		})
+	}
+}
+
+func TestDistributedStorage_Bucket(t *testing.T) {
+	object := &Object{ID: "object-1", Content: []byte("data1")}
+	inBucket := mock.MatchedBy(func(ctx context.Context) bool { return BucketFromContext(ctx) == "photos" })
+
+	// bucket of the request reaches every replica
+	ds, storages := createReplicatedStorage(2)
+	for _, node := range mustReplicas(t, ds, object.ID) {
+		storages[ringKey(node)].On("Put", inBucket, object).Return(nil)
+		storages[ringKey(node)].On("Get", inBucket, object.ID).Return(object, nil)
+	}
+
+	ctx := WithBucket(context.TODO(), "photos")
+	assert.NoError(t, ds.Put(ctx, object))
+	ds.pendingWrites.Wait()
+	obj, err := ds.Get(ctx, object.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, object, obj)
+	for _, node := range mustReplicas(t, ds, object.ID) {
+		storages[ringKey(node)].AssertCalled(t, "Put", inBucket, object)
+	}
+}
+
+func TestDistributedStorage_PutAcknowledged(t *testing.T) {
+	object := &Object{ID: "object-1", Content: []byte("data1")}
+
+	tests := []struct {
+		name        string
+		consistency ConsistencyLevel
+		override    ConsistencyLevel
+		slow        int
+	}{
+		{name: "one", consistency: ConsistencyOne, slow: 2},
+		{name: "quorum", consistency: ConsistencyQuorum, slow: 1},
+		{name: "overridden by context", consistency: ConsistencyAll, override: ConsistencyQuorum, slow: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, storages := createReplicatedStorage(3)
+			ds.writeConsistency = tt.consistency
+			release := make(chan time.Time)
+			var slow []*MockStorage
+			for i, node := range mustReplicas(t, ds, object.ID) {
+				call := storages[ringKey(node)].On("Put", mock.Anything, object).Return(nil)
+				if i >= 3-tt.slow {
+					call.WaitUntil(release)
+					slow = append(slow, storages[ringKey(node)])
+				}
+			}
+
+			// write is acknowledged while slow replicas are still being written, even after ctx is cancelled
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.override != "" {
+				ctx = WithConsistency(ctx, tt.override)
+			}
+			acknowledged := make(chan error, 1)
+			go func() { acknowledged <- ds.Put(ctx, object) }()
+			select {
+			case err := <-acknowledged:
+				assert.NoError(t, err)
+			case <-time.After(time.Second):
+				t.Fatal("write not acknowledged before slow replicas were written")
+			}
+			cancel()
+			close(release)
+			ds.pendingWrites.Wait()
+			for _, storage := range slow {
+				storage.AssertCalled(t, "Put", mock.Anything, object)
+			}
+		})
+	}
+}
+
+func TestDistributedStorage_ReadLatest(t *testing.T) {
+	older, latest := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		consistency ConsistencyLevel
+		// stats of replicas in ring order, nil info for a missing object
+		stats           []*ObjectInfo
+		statErrs        []error
+		expectedContent string
+		expectedErr     error
+	}{
+		{
+			name:            "one reads first replica",
+			consistency:     ConsistencyOne,
+			expectedContent: "node 0",
+		},
+		{
+			name:            "all reads latest replica",
+			consistency:     ConsistencyAll,
+			stats:           []*ObjectInfo{{LastModified: older}, nil, {LastModified: latest}},
+			expectedContent: "node 2",
+		},
+		{
+			name:            "quorum with one replica failing",
+			consistency:     ConsistencyQuorum,
+			stats:           []*ObjectInfo{nil, {LastModified: latest}, nil},
+			statErrs:        []error{errors.New("node down"), nil, nil},
+			expectedContent: "node 1",
+		},
+		{
+			name:        "quorum with two replicas failing",
+			consistency: ConsistencyQuorum,
+			stats:       []*ObjectInfo{nil, {LastModified: latest}, nil},
+			statErrs:    []error{errors.New("node down"), nil, errors.New("node down")},
+			expectedErr: ErrConsistencyNotReached,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, storages := createReplicatedStorage(3)
+			ds.readConsistency = tt.consistency
+			for i, node := range mustReplicas(t, ds, "object-1") {
+				storage := storages[ringKey(node)]
+				if tt.stats != nil {
+					var err error
+					if tt.statErrs != nil {
+						err = tt.statErrs[i]
+					}
+					storage.On("Stat", mock.Anything, "object-1").Return(tt.stats[i], err)
+				}
+				storage.On("Get", mock.Anything, "object-1").Return(&Object{ID: "object-1", Content: []byte(fmt.Sprintf("node %d", i))}, nil).Maybe()
+			}
+
+			object, err := ds.Get(context.TODO(), "object-1")
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedContent, string(object.Content))
+
+			if tt.stats == nil {
+				return
+			}
+			info, err := ds.Stat(context.TODO(), "object-1")
+			assert.NoError(t, err)
+			assert.Equal(t, latest, info.LastModified)
+		})
+	}
+}
+
+func TestDistributedStorage_NodeErrorMetrics(t *testing.T) {
+	ds, storages := createReplicatedStorage(2)
+	ds.metrics = metrics.New()
+	nodes := mustReplicas(t, ds, "object-1")
+
+	object := &Object{ID: "object-1", Content: []byte("data1")}
+	storages[ringKey(nodes[0])].On("Put", mock.Anything, object).Return(errors.New("node down"))
+	storages[ringKey(nodes[1])].On("Put", mock.Anything, object).Return(nil)
+
+	assert.NoError(t, ds.Put(context.TODO(), object))
+	ds.pendingWrites.Wait()
+
+	expected := fmt.Sprintf(`
+# HELP storage_node_errors_total Total number of failed storage node operations by node and operation.
+# TYPE storage_node_errors_total counter
+storage_node_errors_total{node="%s",operation="put"} 1
+`, ringKey(nodes[0]))
+	assert.NoError(t, testutil.GatherAndCompare(ds.metrics.Registry(), strings.NewReader(expected), "storage_node_errors_total"))
+}
+
+func TestDistributedStorage_GetReplicated(t *testing.T) {
+	ds, storages := createReplicatedStorage(2)
+	nodes := mustReplicas(t, ds, "object-1")
+	assert.Len(t, nodes, 2)
+
+	// owner misses the object, replica has it
+	storages[ringKey(nodes[0])].On("Get", mock.Anything, "object-1").Return((*Object)(nil), nil)
+	storages[ringKey(nodes[1])].On("Get", mock.Anything, "object-1").Return(&Object{ID: "object-1", Content: []byte("data1")}, nil)
+
+	obj, err := ds.Get(context.TODO(), "object-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &Object{ID: "object-1", Content: []byte("data1")}, obj)
+}
+
+func TestDistributedStorage_GetFailover(t *testing.T) {
+	unreachable := errors.New("dial tcp: connection refused")
+	found := &Object{ID: "object-1", Content: []byte("data1")}
+
+	tests := []struct {
+		name         string
+		results      []*Object
+		errs         []error
+		expected     *Object
+		expectedErrs []error
+		unknownOwner bool
+	}{
+		{
+			name:     "primary unreachable, replica has it",
+			results:  []*Object{nil, found, nil},
+			errs:     []error{unreachable, nil, nil},
+			expected: found,
+		},
+		{
+			name:         "primary not in ring storages, replica has it",
+			results:      []*Object{nil, found, nil},
+			errs:         []error{nil, nil, nil},
+			unknownOwner: true,
+			expected:     found,
+		},
+		{
+			name:    "absent everywhere",
+			results: []*Object{nil, nil, nil},
+			errs:    []error{nil, nil, nil},
+		},
+		{
+			name:         "all nodes errored",
+			results:      []*Object{nil, nil, nil},
+			errs:         []error{unreachable, ErrContentLengthMismatch, unreachable},
+			expectedErrs: []error{unreachable, ErrContentLengthMismatch},
+		},
+		{
+			name:         "absent on reachable nodes, primary errored",
+			results:      []*Object{nil, nil, nil},
+			errs:         []error{unreachable, nil, nil},
+			expectedErrs: []error{unreachable},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, storages := createReplicatedStorage(3)
+			nodes := mustReplicas(t, ds, "object-1")
+			for i, node := range nodes {
+				storages[ringKey(node)].On("Get", mock.Anything, "object-1").Return(tt.results[i], tt.errs[i])
+			}
+			if tt.unknownOwner {
+				delete(ds.availableStorages, ringKey(nodes[0]))
+			}
+
+			obj, err := ds.Get(context.TODO(), "object-1")
+			assert.Equal(t, tt.expected, obj)
+			if tt.expectedErrs == nil {
+				assert.NoError(t, err)
+				return
+			}
+			for _, expectedErr := range tt.expectedErrs {
+				assert.ErrorIs(t, err, expectedErr)
+			}
+		})
+	}
+}
+
+func TestDistributedStorage_Replicas(t *testing.T) {
+	ds, _ := createReplicatedStorage(5)
+
+	// replication factor is bounded by number of nodes, owner comes first
+	nodes := mustReplicas(t, ds, "object-1")
+	assert.Len(t, nodes, 3)
+	assert.Equal(t, ds.locate("object-1"), nodes[0])
+
+	// replicas are distinct nodes
+	keys := map[string]bool{}
+	for _, node := range nodes {
+		keys[ringKey(node)] = true
+	}
+	assert.Len(t, keys, 3)
+}
+
+func TestDistributedStorage_PutStreamReplicated(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 64<<10)
+
+	tests := []struct {
+		name        string
+		consistency ConsistencyLevel
+		broken      int
+		expectedErr bool
+	}{
+		{name: "all replicas written", broken: 0},
+		{name: "replica failing early", broken: 1},
+		{name: "all replicas failed", broken: 3, expectedErr: true},
+		{name: "quorum with one replica failing", consistency: ConsistencyQuorum, broken: 1},
+		{name: "quorum with two replicas failing", consistency: ConsistencyQuorum, broken: 2, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, storages := createReplicatedStorage(3)
+			ds.writeConsistency = tt.consistency
+			for i, node := range mustReplicas(t, ds, "object-1") {
+				if i < tt.broken {
+					ds.availableStorages[ringKey(node)] = &brokenStreamStorage{}
+					continue
+				}
+				storages[ringKey(node)].On("PutStream", mock.Anything, "object-1", content).Return(nil)
+			}
+
+			err := ds.PutStream(context.TODO(), &ObjectStream{
+				ID:      "object-1",
+				Size:    int64(len(content)),
+				Content: io.NopCloser(bytes.NewReader(content)),
+			})
+			if tt.expectedErr {
+				assert.ErrorIs(t, err, ErrConsistencyNotReached)
+				return
+			}
+			assert.NoError(t, err)
+			for _, storage := range storages {
+				if len(storage.ExpectedCalls) > 0 {
+					storage.AssertCalled(t, "PutStream", mock.Anything, "object-1", content)
+				}
+			}
+		})
+	}
+}
+
+func TestDistributedStorage_PutStreamSourceError(t *testing.T) {
+	ds, storages := createReplicatedStorage(2)
+	for _, storage := range storages {
+		storage.On("PutStream", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	}
+
+	err := ds.PutStream(context.TODO(), &ObjectStream{
+		ID:      "object-1",
+		Size:    -1,
+		Content: io.NopCloser(io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errors.New("client gone")))),
+	})
+	assert.ErrorContains(t, err, "client gone")
+}
+
+func TestDistributedStorage_GetStreamReplicated(t *testing.T) {
+	ds, storages := createReplicatedStorage(2)
+	nodes := mustReplicas(t, ds, "object-1")
+
+	stream := &ObjectStream{ID: "object-1", Size: 5, Content: io.NopCloser(strings.NewReader("data1"))}
+	storages[ringKey(nodes[0])].On("GetStream", mock.Anything, "object-1").Return((*ObjectStream)(nil), errors.New("node down"))
+	storages[ringKey(nodes[1])].On("GetStream", mock.Anything, "object-1").Return(stream, nil)
+
+	obj, err := ds.GetStream(context.TODO(), "object-1")
+	assert.NoError(t, err)
+	assert.Equal(t, stream, obj)
+}
+
+func TestDistributedStorage_StatReplicated(t *testing.T) {
+	ds, storages := createReplicatedStorage(2)
+	nodes := mustReplicas(t, ds, "object-1")
+
+	info := &ObjectInfo{ID: "object-1", ContentType: "text/plain", Size: 5}
+	storages[ringKey(nodes[0])].On("Stat", mock.Anything, "object-1").Return((*ObjectInfo)(nil), nil)
+	storages[ringKey(nodes[1])].On("Stat", mock.Anything, "object-1").Return(info, nil)
+	for _, storage := range storages {
+		storage.On("Stat", mock.Anything, "missing").Return((*ObjectInfo)(nil), nil)
+	}
+
+	obj, err := ds.Stat(context.TODO(), "object-1")
+	assert.NoError(t, err)
+	assert.Equal(t, info, obj)
+
+	// missing on all replicas
+	obj, err = ds.Stat(context.TODO(), "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, obj)
+}
+
+func TestDistributedStorage_GetRangeReplicated(t *testing.T) {
+	ds, storages := createReplicatedStorage(2)
+	nodes := mustReplicas(t, ds, "object-1")
+
+	object := &ObjectStream{ID: "object-1", ContentType: "text/plain", Size: 2, Content: io.NopCloser(strings.NewReader("23"))}
+	storages[ringKey(nodes[0])].On("GetRange", mock.Anything, "object-1", int64(2), int64(2)).Return((*ObjectStream)(nil), errors.New("node down"))
+	storages[ringKey(nodes[1])].On("GetRange", mock.Anything, "object-1", int64(2), int64(2)).Return(object, nil)
+	storages[ringKey(nodes[0])].On("GetRange", mock.Anything, "object-1", int64(20), int64(2)).Return((*ObjectStream)(nil), ErrInvalidRange)
+
+	// falls back to replica when node fails
+	obj, err := ds.GetRange(context.TODO(), "object-1", 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, object, obj)
+
+	// invalid range isn't retried on other replicas
+	obj, err = ds.GetRange(context.TODO(), "object-1", 20, 2)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+	assert.Nil(t, obj)
+	storages[ringKey(nodes[1])].AssertNotCalled(t, "GetRange", mock.Anything, "object-1", int64(20), int64(2))
+}
+
+func TestDistributedStorage_GetStreamResume(t *testing.T) {
+	// stream breaking after the first 4 bytes
+	brokenStream := func() *ObjectStream {
+		content := io.MultiReader(strings.NewReader("0123"), iotest.ErrReader(errors.New("connection reset")))
+		return &ObjectStream{ID: "object-1", ContentType: "text/plain", Size: 10, ETag: "etag", Content: io.NopCloser(content)}
+	}
+
+	tests := []struct {
+		name          string
+		resume        bool
+		expectedBody  string
+		expectedError string
+	}{
+		{name: "resumed from replica", resume: true, expectedBody: "0123456789"},
+		{name: "resumption disabled", resume: false, expectedBody: "0123", expectedError: "connection reset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, storages := createReplicatedStorage(2)
+			ds.resumeStreams = tt.resume
+			nodes := mustReplicas(t, ds, "object-1")
+			storages[ringKey(nodes[0])].On("GetStream", mock.Anything, "object-1").Return(brokenStream(), nil)
+			storages[ringKey(nodes[1])].On("GetRange", mock.Anything, "object-1", int64(4), int64(6)).Return(
+				&ObjectStream{ID: "object-1", ContentType: "text/plain", Size: 6, ETag: "etag", Content: io.NopCloser(strings.NewReader("456789"))}, nil)
+
+			obj, err := ds.GetStream(context.TODO(), "object-1")
+			assert.NoError(t, err)
+			content, err := io.ReadAll(obj.Content)
+			assert.Equal(t, tt.expectedBody, string(content))
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDistributedStorage_GetStreamResume_ReplicaDiffers(t *testing.T) {
+	tests := []struct {
+		name    string
+		replica *ObjectStream
+	}{
+		{name: "different size", replica: &ObjectStream{ID: "object-1", Size: 3, ETag: "etag", Content: io.NopCloser(strings.NewReader("xyz"))}},
+		{name: "different etag", replica: &ObjectStream{ID: "object-1", Size: 6, ETag: "other", Content: io.NopCloser(strings.NewReader("abcdef"))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, storages := createReplicatedStorage(2)
+			ds.resumeStreams = true
+			nodes := mustReplicas(t, ds, "object-1")
+			content := io.MultiReader(strings.NewReader("0123"), iotest.ErrReader(errors.New("connection reset")))
+			storages[ringKey(nodes[0])].On("GetStream", mock.Anything, "object-1").Return(
+				&ObjectStream{ID: "object-1", Size: 10, ETag: "etag", Content: io.NopCloser(content)}, nil)
+			// replica holds a different version
+			storages[ringKey(nodes[1])].On("GetRange", mock.Anything, "object-1", int64(4), int64(6)).Return(tt.replica, nil)
+
+			obj, err := ds.GetStream(context.TODO(), "object-1")
+			assert.NoError(t, err)
+			read, err := io.ReadAll(obj.Content)
+			assert.ErrorContains(t, err, "connection reset")
+			assert.Equal(t, "0123", string(read))
+		})
+	}
+}
+
+func TestDistributedStorage_Ping(t *testing.T) {
+	ds, storages := createReplicatedStorage(1)
+	storages["node1#1"].On("Ping", mock.Anything).Return(nil)
+	storages["node2#2"].On("Ping", mock.Anything).Return(errors.New("connection refused"))
+	storages["node3#3"].On("Ping", mock.Anything).Return(nil)
+
+	assert.Equal(t, map[string]error{
+		"node1#1": nil,
+		"node2#2": errors.New("connection refused"),
+		"node3#3": nil,
+	}, ds.PingNodes(context.TODO()))
+	assert.EqualError(t, ds.Ping(context.TODO()), "node node2#2: connection refused")
+}
+
+func TestDistributedStorage_List(t *testing.T) {
+	ds, storages := createReplicatedStorage(2)
+	// replicas of object-1 and object-2 are listed by two nodes each
+	storages["node1#1"].On("List", mock.Anything, "object-").Return([]string{"object-1", "object-3"}, nil)
+	storages["node2#2"].On("List", mock.Anything, "object-").Return([]string{"object-1", "object-2"}, nil)
+	storages["node3#3"].On("List", mock.Anything, "object-").Return([]string{"object-2"}, nil)
+
+	ids, err := ds.List(context.TODO(), "object-")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"object-1", "object-2", "object-3"}, ids)
+
+	// failing node fails the listing, as its objects would be missing
+	storages["node1#1"].On("List", mock.Anything, "other").Return([]string{}, nil)
+	storages["node2#2"].On("List", mock.Anything, "other").Return(([]string)(nil), errors.New("node down"))
+	storages["node3#3"].On("List", mock.Anything, "other").Return([]string{"other-1"}, nil)
+
+	ids, err = ds.List(context.TODO(), "other")
+	assert.ErrorContains(t, err, "node down")
+	assert.Nil(t, ids)
+}
+
+func TestDistributedStorage_Locate(t *testing.T) {
+	ds, _ := createReplicatedStorage(2)
+	nodes := mustReplicas(t, ds, "object-1")
+	delete(ds.availableStorages, ringKey(nodes[1]))
+
+	placements, err := ds.Locate("object-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []Placement{
+		{Node: nodes[0], Primary: true, Available: true},
+		{Node: nodes[1], Primary: false, Available: false},
+	}, placements)
+}
+
+// closingStorage records closing its idle connections
+type closingStorage struct {
+	*MockStorage
+	closed bool
+}
+
+func (cs *closingStorage) CloseIdleConnections() {
+	cs.closed = true
+}
+
+func TestDistributedStorage_RefreshNodeCredentials(t *testing.T) {
+	staleStorage := &closingStorage{MockStorage: new(MockStorage)}
+	staleStorage.On("Get", mock.Anything, "object-1").Return((*Object)(nil), minio.ErrorResponse{Code: "InvalidAccessKeyId"})
+
+	freshStorage := new(MockStorage)
+	freshStorage.On("Init", mock.Anything).Return(nil)
+	freshStorage.On("Get", mock.Anything, "object-1").Return(&Object{ID: "object-1", Content: []byte("data1")}, nil)
+	freshStorage.On("Get", mock.Anything, "object-2").Return((*Object)(nil), minio.ErrorResponse{Code: "InvalidAccessKeyId"})
+
+	node := Node{ID: "node1", Name: "1", Endpoint: "1.1.1.1", AccessKey: "old-key", SecretKey: "old-secret"}
+	var createdWith *MinioConfig
+	var logs bytes.Buffer
+	ds := &DistributedStorage{
+		logger:  slog.New(logging.NewHandler(slog.NewJSONHandler(&logs, nil))),
+		metrics: metrics.New(),
+		discoverer: NewDockerDiscoverer(&fakeDockerClient{env: map[string][]string{
+			"node1": {MinioAccessKeyEnv + "=new-key", MinioSecretKeyEnv + "=new-secret"},
+		}}),
+		newStorage: func(cfg *MinioConfig) (Storage, error) {
+			createdWith = cfg
+			return freshStorage, nil
+		},
+		circle: consistent.New(nil, consistent.Config{
+			Hasher:            hasher{},
+			PartitionCount:    1,
+			ReplicationFactor: 0,
+			Load:              1.25,
+		}),
+		availableStorages: map[string]Storage{ringKey(node): staleStorage},
+	}
+	ds.circle.Add(ringMember{Node: node})
+
+	obj, err := ds.Get(logging.WithRequestID(context.TODO(), "request-1"), "object-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &Object{ID: "object-1", Content: []byte("data1")}, obj)
+	assert.Equal(t, "new-key", createdWith.AccessKey)
+	assert.Equal(t, "new-secret", createdWith.SecretKey)
+	assert.Equal(t, freshStorage, ds.availableStorages[ringKey(node)])
+	assert.True(t, staleStorage.closed)
+	// the ring node holds the rotated credentials
+	assert.Equal(t, "new-key", ds.locate("object-1").AccessKey)
+	assert.Equal(t, "new-secret", ds.locate("object-1").SecretKey)
+
+	// credentials rejected again aren't refreshed, as they didn't rotate since
+	_, err = ds.Get(context.TODO(), "object-2")
+	assert.Error(t, err)
+	assert.Equal(t, freshStorage, ds.availableStorages[ringKey(node)])
+	expected := fmt.Sprintf(`
+# HELP node_reauth_total Total number of storage node re-authentications with rotated credentials by node and result.
+# TYPE node_reauth_total counter
+node_reauth_total{node="%[1]s",result="failure"} 1
+node_reauth_total{node="%[1]s",result="success"} 1
+`, ringKey(node))
+	assert.NoError(t, testutil.GatherAndCompare(ds.metrics.Registry(), strings.NewReader(expected), "node_reauth_total"))
+
+	// refresh is logged with the request, but never with node secrets
+	assert.Contains(t, logs.String(), `"request_id":"request-1"`)
+	assert.Contains(t, logs.String(), "re-authenticated")
+	assert.NotContains(t, logs.String(), "old-secret")
+	assert.NotContains(t, logs.String(), "new-secret")
+}
+
+func TestDistributedStorage_ConcurrentCredentialRefresh(t *testing.T) {
+	const requests = 10
+	// all requests fail on the stale credentials before any of them refreshes
+	var failed sync.WaitGroup
+	failed.Add(requests)
+	staleStorage := new(MockStorage)
+	staleStorage.On("Get", mock.Anything, "object-1").Return((*Object)(nil), minio.ErrorResponse{Code: "InvalidAccessKeyId"}).
+		Run(func(mock.Arguments) {
+			failed.Done()
+			failed.Wait()
+		})
+
+	freshStorage := new(MockStorage)
+	freshStorage.On("Init", mock.Anything).Return(nil)
+	freshStorage.On("Get", mock.Anything, "object-1").Return(&Object{ID: "object-1", Content: []byte("data1")}, nil)
+
+	node := Node{ID: "node1", Name: "1", Endpoint: "1.1.1.1", AccessKey: "old-key", SecretKey: "old-secret"}
+	var created atomic.Int32
+	ds := &DistributedStorage{
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics: metrics.New(),
+		discoverer: NewDockerDiscoverer(&fakeDockerClient{env: map[string][]string{
+			"node1": {MinioAccessKeyEnv + "=new-key", MinioSecretKeyEnv + "=new-secret"},
+		}}),
+		newStorage: func(cfg *MinioConfig) (Storage, error) {
+			created.Add(1)
+			// refresh in flight while the remaining requests fail
+			time.Sleep(100 * time.Millisecond)
+			return freshStorage, nil
+		},
+		circle: consistent.New(nil, consistent.Config{
+			Hasher:            hasher{},
+			PartitionCount:    1,
+			ReplicationFactor: 0,
+			Load:              1.25,
+		}),
+		availableStorages: map[string]Storage{ringKey(node): staleStorage},
+	}
+	ds.circle.Add(ringMember{Node: node})
+
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			obj, err := ds.Get(context.TODO(), "object-1")
+			assert.NoError(t, err)
+			assert.Equal(t, &Object{ID: "object-1", Content: []byte("data1")}, obj)
+		}()
+	}
+	wg.Wait()
+
+	// requests share a single refresh
+	assert.Equal(t, int32(1), created.Load())
+	freshStorage.AssertNumberOfCalls(t, "Init", 1)
+	expected := fmt.Sprintf(`
+# HELP node_reauth_total Total number of storage node re-authentications with rotated credentials by node and result.
+# TYPE node_reauth_total counter
+node_reauth_total{node="%s",result="success"} 1
+`, ringKey(node))
+	assert.NoError(t, testutil.GatherAndCompare(ds.metrics.Registry(), strings.NewReader(expected), "node_reauth_total"))
+}
+
+func TestDistributedStorage_ReadyNodes(t *testing.T) {
+	// node becoming ready after a few probes
+	var mu sync.Mutex
+	probes := 0
+	starting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, MinioHealthPath, r.URL.Path)
+		mu.Lock()
+		defer mu.Unlock()
+		if probes++; probes < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer starting.Close()
+	// node running, but never ready
+	stuck := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer stuck.Close()
+
+	nodes := []Node{
+		{ID: "node1", Name: "1", Endpoint: strings.TrimPrefix(starting.URL, "http://")},
+		{ID: "node2", Name: "2", Endpoint: strings.TrimPrefix(stuck.URL, "http://")},
+	}
+
+	ds := NewDistributedStorage(NewStaticDiscoverer(nil), &DistributedConfig{
+		NodeReadinessTimeout:  200 * time.Millisecond,
+		NodeReadinessInterval: 10 * time.Millisecond,
+	}).(*DistributedStorage)
+	assert.Equal(t, nodes[:1], ds.readyNodes(context.TODO(), nodes))
+
+	// probe disabled
+	ds = NewDistributedStorage(NewStaticDiscoverer(nil), &DistributedConfig{}).(*DistributedStorage)
+	assert.Equal(t, nodes, ds.readyNodes(context.TODO(), nodes))
+}
+
+func TestDistributedStorage_SecureNodes(t *testing.T) {
+	// node served over TLS with certificate of a private CA
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	caCertPath := writeCACert(t, server)
+
+	// TLS enabled for the node only
+	node := Node{ID: "node1", Name: "1", Endpoint: strings.TrimPrefix(server.URL, "https://"), Secure: true}
+	ds := NewDistributedStorage(NewStaticDiscoverer([]Node{node}), &DistributedConfig{
+		Node:                  MinioConfig{BucketName: "default", CACertPath: caCertPath},
+		NodeReadinessTimeout:  time.Second,
+		NodeReadinessInterval: 10 * time.Millisecond,
+	}).(*DistributedStorage)
+	var createdWith *MinioConfig
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) {
+		createdWith = cfg
+		storage := new(MockStorage)
+		storage.On("Init", mock.Anything).Return(nil)
+		return storage, nil
+	}
+
+	assert.NoError(t, ds.Init(context.TODO()))
+	// readiness is probed over TLS, so the node is used
+	assert.Equal(t, []string{ringKey(node)}, ds.RingMembers())
+	if assert.NotNil(t, createdWith) {
+		assert.True(t, createdWith.Secure)
+		assert.Equal(t, caCertPath, createdWith.CACertPath)
+		assert.Equal(t, "default", createdWith.BucketName)
+	}
+
+	// plaintext probes of the TLS node fail, so it's never ready
+	node.Secure = false
+	ds = NewDistributedStorage(NewStaticDiscoverer(nil), &DistributedConfig{
+		Node:                  MinioConfig{CACertPath: caCertPath},
+		NodeReadinessTimeout:  50 * time.Millisecond,
+		NodeReadinessInterval: 10 * time.Millisecond,
+	}).(*DistributedStorage)
+	assert.Empty(t, ds.readyNodes(context.TODO(), []Node{node}))
+}
+
+func TestDistributedStorage_InitConcurrently(t *testing.T) {
+	nodes, err := ParseStaticNodes("key:secret@10.0.0.1:9000,key:secret@10.0.0.2:9000,key:secret@10.0.0.3:9000,key:secret@10.0.0.4:9000")
+	assert.NoError(t, err)
+	// each node takes a while to initialize, one of them much longer, and one fails
+	const delay = 100 * time.Millisecond
+	delays := map[string]time.Duration{"10.0.0.1:9000": delay, "10.0.0.2:9000": 3 * delay, "10.0.0.3:9000": delay, "10.0.0.4:9000": delay}
+	unreachable := errors.New("dial tcp 10.0.0.4:9000: i/o timeout")
+
+	tests := []struct {
+		name          string
+		abort         bool
+		expectedNodes []string
+		expectedErr   error
+	}{
+		{
+			name:          "failing node skipped",
+			expectedNodes: []string{"10.0.0.1:9000#static", "10.0.0.2:9000#static", "10.0.0.3:9000#static"},
+		},
+		{
+			name:        "failing node aborts",
+			abort:       true,
+			expectedErr: unreachable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := NewDistributedStorage(NewStaticDiscoverer(nodes), &DistributedConfig{AbortOnNodeInitFailure: tt.abort}).(*DistributedStorage)
+			ds.newStorage = func(cfg *MinioConfig) (Storage, error) {
+				var initErr error
+				if cfg.Endpoint == "10.0.0.4:9000" {
+					initErr = unreachable
+				}
+				storage := new(MockStorage)
+				storage.On("Init", mock.Anything).Return(initErr).Run(func(args mock.Arguments) {
+					select {
+					case <-time.After(delays[cfg.Endpoint]):
+					case <-args.Get(0).(context.Context).Done():
+					}
+				})
+				return storage, nil
+			}
+
+			start := time.Now()
+			err := ds.Init(context.TODO())
+			// bounded by the slowest node rather than the sum of all
+			assert.Less(t, time.Since(start), 5*delay)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedNodes, ds.RingMembers())
+		})
+	}
+}
+
+func TestDistributedStorage_NotReady(t *testing.T) {
+	nodes, err := ParseStaticNodes("key:secret@10.0.0.1:9000")
+	assert.NoError(t, err)
+	ds := NewDistributedStorage(NewStaticDiscoverer(nodes), &DistributedConfig{}).(*DistributedStorage)
+	node := new(MockStorage)
+	node.On("Init", mock.Anything).Return(nil)
+	node.On("Get", mock.Anything, "object").Return(&Object{ID: "object"}, nil)
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) { return node, nil }
+
+	// operations fail until Init, instead of using the missing ring
+	assert.Empty(t, ds.RingMembers())
+	assert.NotEmpty(t, ds.RingFingerprint())
+	_, err = ds.Get(context.TODO(), "object")
+	assert.ErrorIs(t, err, ErrNotReady)
+	assert.ErrorIs(t, ds.Put(context.TODO(), &Object{ID: "object"}), ErrNotReady)
+	_, err = ds.List(context.TODO(), "")
+	assert.ErrorIs(t, err, ErrNotReady)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.NoError(t, ds.Init(ctx))
+	obj, err := ds.Get(context.TODO(), "object")
+	assert.NoError(t, err)
+	assert.NotNil(t, obj)
+
+	// and again once the storage is shut down
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, err := ds.Get(context.TODO(), "object")
+		return errors.Is(err, ErrNotReady)
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestDistributedStorage_NodeNotAvailable(t *testing.T) {
+	_, nodes := setupMocksAndNodes()
+	ds := createDistributedStorage(new(MockStorage), nodes)
+	ctx := context.TODO()
+
+	// node owning the object left, while the ring still places objects on it
+	delete(ds.availableStorages, ringKey(ds.locate("object")))
+	_, err := ds.Get(ctx, "object")
+	assert.ErrorIs(t, err, ErrNodeUnreachable)
+	assert.ErrorIs(t, ds.Put(ctx, &Object{ID: "object"}), ErrNodeUnreachable)
+
+	// ring without nodes has nowhere to place objects
+	ds.circle, _ = newHashCircle(nil)
+	_, err = ds.Get(ctx, "object")
+	assert.ErrorIs(t, err, ErrNoNodeAvailable)
+	assert.ErrorIs(t, ds.Put(ctx, &Object{ID: "object"}), ErrNoNodeAvailable)
+}
+
+func TestDistributedStorage_WatchNodes(t *testing.T) {
+	node1 := nodeContainer("node1", ContainerNamePattern+"1", "10.0.0.1")
+	node2 := nodeContainer("node2", ContainerNamePattern+"2", "10.0.0.2")
+	client := &fakeDockerClient{
+		containers: []types.Container{node1},
+		env:        map[string][]string{"node1": {}, "node2": {}},
+		events:     make(chan events.Message),
+	}
+	ds := NewDistributedStorage(NewDockerDiscoverer(client), &DistributedConfig{}).(*DistributedStorage)
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) {
+		storage := new(MockStorage)
+		storage.On("Init", mock.Anything).Return(nil)
+		return storage, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, ds.Init(ctx))
+	assert.Equal(t, []string{"node1#/" + ContainerNamePattern + "1"}, ds.RingMembers())
+
+	// requests keep being served while the ring changes
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			_, _ = ds.Locate("object-1")
+			_ = ds.RingFingerprint()
+		}
+	}()
+
+	// node started
+	client.setContainers(node1, node2)
+	client.events <- events.Message{Action: "start", Actor: events.Actor{ID: "node2", Attributes: map[string]string{"name": ContainerNamePattern + "2"}}}
+	assert.Eventually(t, func() bool { return len(ds.RingMembers()) == 2 }, time.Second, 10*time.Millisecond)
+	_, available := ds.storage("node2#/" + ContainerNamePattern + "2")
+	assert.True(t, available)
+
+	// node died
+	client.setContainers(node2)
+	client.events <- events.Message{Action: "die", Actor: events.Actor{ID: "node1", Attributes: map[string]string{"name": ContainerNamePattern + "1"}}}
+	assert.Eventually(t, func() bool { return len(ds.RingMembers()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"node2#/" + ContainerNamePattern + "2"}, ds.RingMembers())
+	_, available = ds.storage("node1#/" + ContainerNamePattern + "1")
+	assert.False(t, available)
+
+	// watcher stops with init context
+	cancel()
+	<-done
+	select {
+	case client.events <- events.Message{Action: "start", Actor: events.Actor{Attributes: map[string]string{"name": ContainerNamePattern + "1"}}}:
+		t.Fatal("watcher still running after context cancellation")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// stopRecorder records background workers of the storage in the order they stopped
+type stopRecorder struct {
+	mu      sync.Mutex
+	started map[string]bool
+	stopped []string
+	running sync.WaitGroup
+}
+
+// run marks the worker running until ctx is done, then records it stopped
+func (r *stopRecorder) run(ctx context.Context, worker string) {
+	r.mu.Lock()
+	if !r.started[worker] {
+		r.started[worker] = true
+		r.running.Done()
+	}
+	r.mu.Unlock()
+
+	<-ctx.Done()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.stopped) == 0 || r.stopped[len(r.stopped)-1] != worker {
+		r.stopped = append(r.stopped, worker)
+	}
+}
+
+// watchingDiscoverer discovers the static nodes, watching them until ctx is done
+type watchingDiscoverer struct {
+	*StaticDiscoverer
+	recorder *stopRecorder
+}
+
+func (wd *watchingDiscoverer) Watch(ctx context.Context, rediscover func(ctx context.Context)) {
+	wd.recorder.run(ctx, "rediscovery")
+}
+
+// workerStorage blocks sweeping and listing objects until ctx is done
+type workerStorage struct {
+	*memoryStorage
+	recorder *stopRecorder
+}
+
+func (ws *workerStorage) SweepExpired(ctx context.Context) (int, error) {
+	ws.recorder.run(ctx, "sweeper")
+	return 0, ctx.Err()
+}
+
+func (ws *workerStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	ws.recorder.run(ctx, "rebalance")
+	return nil, ctx.Err()
+}
+
+func TestDistributedStorage_Stop(t *testing.T) {
+	nodes, err := ParseStaticNodes("key:secret@10.0.0.1:9000,key:secret@10.0.0.2:9000")
+	assert.NoError(t, err)
+	recorder := &stopRecorder{started: map[string]bool{}}
+	discoverer := &watchingDiscoverer{StaticDiscoverer: NewStaticDiscoverer(nodes[:1]), recorder: recorder}
+	ds := NewDistributedStorage(discoverer, &DistributedConfig{
+		Rebalance:           RebalanceConfig{Enabled: true},
+		ExpirySweepInterval: time.Millisecond,
+	}).(*DistributedStorage)
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) {
+		return &workerStorage{memoryStorage: &memoryStorage{objects: map[string]*Object{}}, recorder: recorder}, nil
+	}
+
+	// rediscovery, sweeper and rebalancing of the joined node are all running
+	recorder.running.Add(3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, ds.Init(ctx))
+	discoverer.nodes = nodes
+	ds.rediscoverNodes(ctx)
+	recorder.running.Wait()
+
+	assert.NoError(t, ds.Stop(context.Background()))
+	assert.Equal(t, []string{"rediscovery", "sweeper", "rebalance"}, recorder.stopped)
+	_, err = ds.Get(ctx, "object")
+	assert.ErrorIs(t, err, ErrNotReady)
+}
+
+// createSlowReplicaStorage creates initialized storage of two nodes replicating objects, acknowledging writes
+// once the first replica is written, while the second one writes only once released
+func createSlowReplicaStorage(t *testing.T) (*DistributedStorage, *blockingStorage) {
+	nodes, err := ParseStaticNodes("key:secret@10.0.0.1:9000,key:secret@10.0.0.2:9000")
+	assert.NoError(t, err)
+	ds := NewDistributedStorage(NewStaticDiscoverer(nodes), &DistributedConfig{
+		ReplicationFactor: 2,
+		WriteConsistency:  ConsistencyOne,
+	}).(*DistributedStorage)
+	fast := &memoryStorage{objects: map[string]*Object{}}
+	slow := &blockingStorage{memoryStorage: &memoryStorage{objects: map[string]*Object{}}, started: make(chan string, 10), release: make(chan struct{})}
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) {
+		if cfg.Endpoint == "10.0.0.1:9000" {
+			return fast, nil
+		}
+		return slow, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	assert.NoError(t, ds.Init(ctx))
+	return ds, slow
+}
+
+func TestDistributedStorage_StopAwaitsReplicaWrites(t *testing.T) {
+	ds, slow := createSlowReplicaStorage(t)
+	assert.NoError(t, ds.Put(context.Background(), &Object{ID: "1", Content: []byte("data")}))
+	assert.Equal(t, "1", <-slow.started)
+
+	// shutdown timing out reports the write still running
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, ds.Stop(ctx), context.DeadlineExceeded)
+
+	// acknowledged write reaches the slow replica before the storage stops
+	stopped := make(chan error)
+	go func() { stopped <- ds.Stop(context.Background()) }()
+	select {
+	case err := <-stopped:
+		t.Fatalf("storage stopped while replica write was running: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(slow.release)
+	assert.NoError(t, <-stopped)
+	assert.Contains(t, slow.objects, "1")
+}
+
+// pausedDiscoverer discovers the static nodes once resumed, watching them until ctx is done
+type pausedDiscoverer struct {
+	*StaticDiscoverer
+	resume chan struct{}
+}
+
+func (pd *pausedDiscoverer) Discover(ctx context.Context) ([]Node, error) {
+	<-pd.resume
+	return pd.StaticDiscoverer.Discover(ctx)
+}
+
+func (pd *pausedDiscoverer) Watch(ctx context.Context, rediscover func(ctx context.Context)) {
+	<-ctx.Done()
+}
+
+func TestDistributedStorage_StopWhileInitializing(t *testing.T) {
+	nodes, err := ParseStaticNodes("key:secret@10.0.0.1:9000")
+	assert.NoError(t, err)
+	discoverer := &pausedDiscoverer{StaticDiscoverer: NewStaticDiscoverer(nodes), resume: make(chan struct{})}
+	ds := NewDistributedStorage(discoverer, &DistributedConfig{ExpirySweepInterval: time.Millisecond}).(*DistributedStorage)
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) {
+		return &memoryStorage{objects: map[string]*Object{}}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	initialized := make(chan error)
+	go func() { initialized <- ds.Init(ctx) }()
+
+	// storage shut down before nodes were discovered doesn't start workers nor serve operations
+	assert.NoError(t, ds.Stop(context.Background()))
+	close(discoverer.resume)
+	assert.NoError(t, <-initialized)
+	assert.Nil(t, ds.stopRediscovery)
+	assert.Nil(t, ds.stopSweeper)
+	_, err = ds.Get(ctx, "object")
+	assert.ErrorIs(t, err, ErrNotReady)
+}
+
+// countingDiscoverer counts discoveries of the wrapped docker discoverer
+type countingDiscoverer struct {
+	*DockerDiscoverer
+	mu          sync.Mutex
+	discoveries int
+}
+
+func (cd *countingDiscoverer) Discover(ctx context.Context) ([]Node, error) {
+	cd.mu.Lock()
+	cd.discoveries++
+	cd.mu.Unlock()
+	return cd.DockerDiscoverer.Discover(ctx)
+}
+
+func (cd *countingDiscoverer) count() int {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	return cd.discoveries
+}
+
+// failingDiscoverer fails discovery with err if set, otherwise discovers the static nodes
+type failingDiscoverer struct {
+	*StaticDiscoverer
+	err error
+}
+
+func (fd *failingDiscoverer) Discover(ctx context.Context) ([]Node, error) {
+	if fd.err != nil {
+		return nil, fd.err
+	}
+	return fd.StaticDiscoverer.Discover(ctx)
+}
+
+func TestDistributedStorage_Reload(t *testing.T) {
+	nodes, err := ParseStaticNodes("key:secret@10.0.0.1:9000,key:secret@10.0.0.2:9000,key:secret@10.0.0.3:9000")
+	assert.NoError(t, err)
+	discoverer := &failingDiscoverer{StaticDiscoverer: NewStaticDiscoverer(nodes[:2])}
+	ds := NewDistributedStorage(discoverer, &DistributedConfig{}).(*DistributedStorage)
+	var mu sync.Mutex
+	initialized := map[string]int{}
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		initialized[cfg.Endpoint]++
+		storage := new(MockStorage)
+		storage.On("Init", mock.Anything).Return(nil)
+		return storage, nil
+	}
+	assert.ErrorIs(t, ds.Reload(context.TODO()), ErrNotReady)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, ds.Init(ctx))
+	assert.Equal(t, []string{"10.0.0.1:9000#static", "10.0.0.2:9000#static"}, ds.RingMembers())
+
+	// node set is swapped, keeping storage of the node still present
+	discoverer.nodes = nodes[1:]
+	assert.NoError(t, ds.Reload(ctx))
+	assert.Equal(t, []string{"10.0.0.2:9000#static", "10.0.0.3:9000#static"}, ds.RingMembers())
+	_, ok := ds.storage(ringKey(nodes[0]))
+	assert.False(t, ok)
+	assert.Equal(t, map[string]int{"10.0.0.1:9000": 1, "10.0.0.2:9000": 1, "10.0.0.3:9000": 1}, initialized)
+
+	// failed discovery keeps current nodes
+	discoverer.err = errors.New("discovery failed")
+	assert.ErrorContains(t, ds.Reload(ctx), "discovery failed")
+	assert.Equal(t, []string{"10.0.0.2:9000#static", "10.0.0.3:9000#static"}, ds.RingMembers())
+
+	cancel()
+	assert.Eventually(t, func() bool { return errors.Is(ds.Reload(context.TODO()), ErrNotReady) }, time.Second, 5*time.Millisecond)
+}
+
+func TestDistributedStorage_NodeChangeWindow(t *testing.T) {
+	containers := []types.Container{nodeContainer("node1", ContainerNamePattern+"1", "10.0.0.1")}
+	client := &fakeDockerClient{
+		containers: containers,
+		env:        map[string][]string{"node1": {}, "node2": {}, "node3": {}, "node4": {}},
+		events:     make(chan events.Message),
+	}
+	discoverer := &countingDiscoverer{DockerDiscoverer: NewDockerDiscoverer(client)}
+	ds := NewDistributedStorage(discoverer, &DistributedConfig{NodeChangeWindow: 100 * time.Millisecond}).(*DistributedStorage)
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) {
+		storage := new(MockStorage)
+		storage.On("Init", mock.Anything).Return(nil)
+		return storage, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, ds.Init(ctx))
+	assert.Equal(t, 1, discoverer.count())
+
+	// record object owners on every ring update
+	var mu sync.Mutex
+	owners := map[string][]string{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			mu.Lock()
+			for i := 0; i < 100; i++ {
+				id := fmt.Sprintf("object-%d", i)
+				owner := ringKey(ds.locate(id))
+				if history := owners[id]; len(history) == 0 || history[len(history)-1] != owner {
+					owners[id] = append(history, owner)
+				}
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	// burst of nodes started in quick succession
+	for i := 2; i <= 4; i++ {
+		id := fmt.Sprintf("node%d", i)
+		name := fmt.Sprintf("%s%d", ContainerNamePattern, i)
+		containers = append(containers, nodeContainer(id, name, fmt.Sprintf("10.0.0.%d", i)))
+		client.setContainers(containers...)
+		client.events <- events.Message{Action: "start", Actor: events.Actor{ID: id, Attributes: map[string]string{"name": name}}}
+	}
+
+	assert.Eventually(t, func() bool { return len(ds.RingMembers()) == 4 }, time.Second, 10*time.Millisecond)
+	// let the recorder observe the final ring
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	// burst is coalesced into a single rediscovery, so objects move at most once
+	assert.Equal(t, 2, discoverer.count())
+	mu.Lock()
+	defer mu.Unlock()
+	for id, history := range owners {
+		assert.LessOrEqual(t, len(history), 2, "object %s moved more than once: %v", id, history)
+	}
+}
+
+func TestDistributedStorage_ConcurrentReinit(t *testing.T) {
+	client := &fakeDockerClient{
+		containers: []types.Container{
+			nodeContainer("node1", ContainerNamePattern+"1", "10.0.0.1"),
+			nodeContainer("node2", ContainerNamePattern+"2", "10.0.0.2"),
+			nodeContainer("node3", ContainerNamePattern+"3", "10.0.0.3"),
+		},
+		env: map[string][]string{"node1": {}, "node2": {}, "node3": {}},
+	}
+	ds := NewDistributedStorage(NewDockerDiscoverer(client), &DistributedConfig{ReplicationFactor: 2}).(*DistributedStorage)
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) {
+		storage := new(MockStorage)
+		storage.On("Init", mock.Anything).Return(nil)
+		storage.On("Get", mock.Anything, "object-1").Return(&Object{ID: "object-1", Content: []byte("data1")}, nil)
+		return storage, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, ds.Init(ctx))
+
+	// hammer Get while storages and hash ring are reinitialized
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				obj, err := ds.Get(ctx, "object-1")
+				if assert.NoError(t, err) {
+					assert.Equal(t, "object-1", obj.ID)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, ds.Init(ctx))
+	}
+	wg.Wait()
+}
+
+func TestRingKey(t *testing.T) {
+	mockStorage, nodes := setupMocksAndNodes()
+	ds := createDistributedStorage(mockStorage, nodes)
+
+	// ring members resolve to keys of available storages
+	for _, id := range []string{"object-1", "object-2", "543b8e0ef09346689eb33adbbbee452a"} {
+		node := ds.locate(id)
+		assert.Contains(t, ds.availableStorages, ringKey(node))
+		assert.Equal(t, ringKey(node), ds.circle.LocateKey([]byte(id)).String())
+	}
+
+	// ring key must stay stable, as changing it changes object placement
+	node := Node{ID: "node1", Name: "1", Endpoint: "1.1.1.1", AccessKey: "key", SecretKey: "secret"}
+	assert.Equal(t, "node1#1", ringKey(node))
+	assert.Equal(t, ringKey(node), ringMember{Node: node}.String())
+}
+
+func TestDistributedStorage_RingFingerprint(t *testing.T) {
+	nodes := []Node{
+		{ID: "node1", Name: "1", Endpoint: "1.1.1.1"},
+		{ID: "node2", Name: "2", Endpoint: "2.2.2.2"},
+		{ID: "node3", Name: "3", Endpoint: "3.3.3.3"},
+	}
+	fingerprint := func(nodes ...Node) string {
+		ds := &DistributedStorage{}
+		ds.setNodes(nodes, nil)
+		return ds.RingFingerprint()
+	}
+
+	// discovery order doesn't matter
+	assert.Equal(t, fingerprint(nodes[0], nodes[1], nodes[2]), fingerprint(nodes[2], nodes[0], nodes[1]))
+	// credentials and endpoints don't affect placement
+	moved := nodes[0]
+	moved.Endpoint = "4.4.4.4"
+	assert.Equal(t, fingerprint(nodes[0], nodes[1]), fingerprint(moved, nodes[1]))
+	// different node set diverges
+	assert.NotEqual(t, fingerprint(nodes...), fingerprint(nodes[0], nodes[1]))
+}
+
+func TestHashCircle_Distribution(t *testing.T) {
+	var nodes []Node
+	for i := 1; i <= 6; i++ {
+		nodes = append(nodes, Node{ID: fmt.Sprintf("%064x", i*104729), Name: fmt.Sprintf("/%s%d", ContainerNamePattern, i)})
+	}
+	circle5, _ := newHashCircle(nodes[:5])
+	circle6, _ := newHashCircle(nodes)
+
+	const keys = 10000
+	counts := make(map[string]int)
+	relocated := 0
+	for i := 0; i < keys; i++ {
+		key := []byte(fmt.Sprintf("object-%d", i))
+		owner := circle5.LocateKey(key).String()
+		counts[owner]++
+		if circle6.LocateKey(key).String() != owner {
+			relocated++
+		}
+	}
+
+	// each node gets its share of keys within 30%
+	assert.Len(t, counts, 5)
+	for node, count := range counts {
+		assert.InDelta(t, keys/5, count, keys/5*0.3, "node %s", node)
+	}
+	// adding a node relocates roughly the share of the new node (1/6), not the whole keyspace
+	assert.InDelta(t, keys/6, relocated, keys/6*0.3)
+}
+
+func TestHashCircle_WeightedDistribution(t *testing.T) {
+	nodes := []Node{
+		{ID: "node1", Name: "1"},
+		{ID: "node2", Name: "2"},
+		{ID: "node3", Name: "3", Weight: 2},
+	}
+	ds := &DistributedStorage{replicationFactor: 2}
+	ds.setNodes(nodes, nil)
+	assert.Equal(t, []string{"node1#1", "node2#2", "node3#3"}, ds.RingMembers())
+
+	const keys = 10000
+	counts := make(map[string]int)
+	for i := 0; i < keys; i++ {
+		id := fmt.Sprintf("object-%d", i)
+		counts[ringKey(ds.locate(id))]++
+		// points of a weighted node don't make it hold several replicas
+		replicas := mustReplicas(t, ds, id)
+		assert.Len(t, replicas, 2)
+		assert.NotEqual(t, ringKey(replicas[0]), ringKey(replicas[1]))
+	}
+
+	// node of weight 2 gets twice the keys of a node of weight 1
+	assert.InDelta(t, keys/2, counts["node3#3"], keys/2*0.15)
+	for _, key := range []string{"node1#1", "node2#2"} {
+		assert.InDelta(t, 2, float64(counts["node3#3"])/float64(counts[key]), 0.5, key)
+	}
+	report := ds.AnalyzePlacement(nil, 0.5)
+	assert.Len(t, report.Counts, 3)
+}
+
 func setupMocksAndNodes() (*MockStorage, map[string]Node) {
 	mockStorage := new(MockStorage)
 	mockStorage.On("Get", mock.Anything, "object-1").Return(&Object{ID: "object-1", Content: []byte("data1")}, nil)
@@ -83,20 +1566,78 @@ func setupMocksAndNodes() (*MockStorage, map[string]Node) {
 	return mockStorage, nodes
 }
 
+func mustReplicas(t *testing.T, ds *DistributedStorage, id string) []Node {
+	nodes, err := ds.replicas(id)
+	assert.NoError(t, err)
+	return nodes
+}
+
+// createReplicatedStorage creates DistributedStorage with three nodes, each backed by its own mock
+func createReplicatedStorage(replicationFactor int) (*DistributedStorage, map[string]*MockStorage) {
+	_, nodes := setupMocksAndNodes()
+	storages := make(map[string]*MockStorage, len(nodes))
+	ds := createDistributedStorage(new(MockStorage), nodes)
+	ds.replicationFactor = replicationFactor
+	for key := range ds.availableStorages {
+		storages[key] = new(MockStorage)
+		ds.availableStorages[key] = storages[key]
+	}
+	return ds, storages
+}
+
 func createDistributedStorage(mockStorage *MockStorage, nodes map[string]Node) *DistributedStorage {
-	ds := &DistributedStorage{
-		circle: consistent.New(nil, consistent.Config{
-			Hasher:            hasher{},
-			PartitionCount:    len(nodes),
-			ReplicationFactor: 0,
-			Load:              1.25,
-		}),
+	ringNodes := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		ringNodes = append(ringNodes, node)
+	}
+	circle, ringConfig := newHashCircle(ringNodes)
+
+	return &DistributedStorage{
+		logger:            slog.Default(),
+		circle:            circle,
+		ringConfig:        ringConfig,
 		availableStorages: map[string]Storage{"node1#1": mockStorage, "node2#2": mockStorage, "node3#3": mockStorage},
 	}
+}
 
-	for _, node := range nodes {
-		ds.circle.Add(node)
+func TestDistributedStorage_Copy(t *testing.T) {
+	// object IDs located on the same node as the source, and on another node
+	ds, storages := createReplicatedStorage(1)
+	srcNode := ds.locate("source")
+	var sameNodeID, otherNodeID string
+	for i := 0; sameNodeID == "" || otherNodeID == ""; i++ {
+		id := fmt.Sprintf("copy-%d", i)
+		if ds.locate(id) == srcNode {
+			sameNodeID = id
+		} else {
+			otherNodeID = id
+		}
 	}
+	source := storages[ringKey(srcNode)]
+	otherNode := storages[ringKey(ds.locate(otherNodeID))]
+	content := []byte("data")
+
+	// same node copies server-side
+	source.On("Copy", mock.Anything, "source", sameNodeID).Return(nil).Once()
+	assert.NoError(t, ds.Copy(context.TODO(), "source", sameNodeID))
+	source.AssertExpectations(t)
+
+	// other node is streamed the source object
+	source.On("GetStream", mock.Anything, "source").Return(&ObjectStream{
+		ID:          "source",
+		ContentType: "text/plain",
+		Size:        int64(len(content)),
+		Content:     io.NopCloser(bytes.NewReader(content)),
+	}, nil).Once()
+	otherNode.On("PutStream", mock.Anything, otherNodeID, content).Return(nil).Once()
+	assert.NoError(t, ds.Copy(context.TODO(), "source", otherNodeID))
+	source.AssertNotCalled(t, "Copy", mock.Anything, "source", otherNodeID)
+	otherNode.AssertExpectations(t)
 
-	return ds
+	// missing source isn't found by either path
+	missing := storages[ringKey(ds.locate("missing"))]
+	missing.On("Copy", mock.Anything, "missing", mock.Anything).Return(ErrObjectNotFound).Maybe()
+	missing.On("GetStream", mock.Anything, "missing").Return((*ObjectStream)(nil), nil)
+	assert.ErrorIs(t, ds.Copy(context.TODO(), "missing", sameNodeID), ErrObjectNotFound)
+	assert.ErrorIs(t, ds.Copy(context.TODO(), "missing", otherNodeID), ErrObjectNotFound)
 }
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// fanOutWriter writes to all pipes, dropping the ones that fail. It fails only when all pipes failed,
+// so a single slow or broken replica doesn't abort the upload to the others.
+type fanOutWriter struct {
+	pipes []*io.PipeWriter
+	errs  []error
+}
+
+func newFanOutWriter(pipes []*io.PipeWriter) *fanOutWriter {
+	return &fanOutWriter{pipes: pipes, errs: make([]error, len(pipes))}
+}
+
+func (w *fanOutWriter) Write(p []byte) (int, error) {
+	var lastErr error
+	active := 0
+	for i, pipe := range w.pipes {
+		if w.errs[i] != nil {
+			lastErr = w.errs[i]
+			continue
+		}
+		if _, err := pipe.Write(p); err != nil {
+			w.errs[i] = err
+			lastErr = err
+			continue
+		}
+		active++
+	}
+	if active == 0 {
+		return 0, fmt.Errorf("all replica writers failed: %w", lastErr)
+	}
+	return len(p), nil
+}
+
+// sourceReader records error of the underlying reader, so it can be told apart from errors of writers.
+type sourceReader struct {
+	r   io.Reader
+	err error
+}
+
+func (sr *sourceReader) Read(p []byte) (int, error) {
+	n, err := sr.r.Read(p)
+	if err != nil && err != io.EOF {
+		sr.err = err
+	}
+	return n, err
+}
+
+// lengthVerifyingReader fails with ErrContentLengthMismatch when stream ends before or after the declared size.
+type lengthVerifyingReader struct {
+	io.ReadCloser
+	expected int64
+	read     int64
+}
+
+func (lr *lengthVerifyingReader) Read(p []byte) (int, error) {
+	n, err := lr.ReadCloser.Read(p)
+	lr.read += int64(n)
+	switch {
+	case err == io.ErrUnexpectedEOF:
+		return n, fmt.Errorf("%w: body truncated", ErrContentLengthMismatch)
+	case err == io.EOF && lr.read != lr.expected:
+		return n, fmt.Errorf("%w: read %d bytes, expected %d", ErrContentLengthMismatch, lr.read, lr.expected)
+	case err == nil && lr.read > lr.expected:
+		return n, fmt.Errorf("%w: read more than %d bytes", ErrContentLengthMismatch, lr.expected)
+	}
+	return n, err
+}
+
+// firstByteReader stops the first byte timeout of a streamed read once content is read, failing with
+// context.DeadlineExceeded if it elapsed before. Closing the reader releases context of the read.
+type firstByteReader struct {
+	io.ReadCloser
+	ctx     context.Context
+	stop    func() bool
+	release context.CancelFunc
+}
+
+func (fr *firstByteReader) Read(p []byte) (int, error) {
+	n, err := fr.ReadCloser.Read(p)
+	if n > 0 || err == io.EOF {
+		fr.stop()
+	}
+	return n, timedOut(fr.ctx, err)
+}
+
+func (fr *firstByteReader) Close() error {
+	fr.stop()
+	err := fr.ReadCloser.Close()
+	fr.release()
+	return err
+}
+
+// timedOut wraps err of an operation aborted by ctx of firstByteContext with context.DeadlineExceeded, if the
+// timeout elapsed, as ctx itself reports only being cancelled.
+func timedOut(ctx context.Context, err error) error {
+	if err == nil || err == io.EOF || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if cause := context.Cause(ctx); errors.Is(cause, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", err, cause)
+	}
+	return err
+}
+
+// checksumVerifyingReader fails with ErrChecksumMismatch when SHA-256 of the whole stream differs from
+// the expected one. Mismatch can be detected only at the end, after the content was passed on.
+type checksumVerifyingReader struct {
+	io.ReadCloser
+	expected string
+	hash     hash.Hash
+}
+
+func newChecksumVerifyingReader(r io.ReadCloser, expected string) *checksumVerifyingReader {
+	return &checksumVerifyingReader{ReadCloser: r, expected: expected, hash: sha256.New()}
+}
+
+func (cr *checksumVerifyingReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	cr.hash.Write(p[:n])
+	if err == io.EOF {
+		if actual := hex.EncodeToString(cr.hash.Sum(nil)); actual != cr.expected {
+			return n, fmt.Errorf("%w: computed %s, stored %s", ErrChecksumMismatch, actual, cr.expected)
+		}
+	}
+	return n, err
+}
+
+// resumingReader continues a stream failing midway from the offset already read, using stream opened by resume.
+// The resumed stream must continue the same content, so it's only valid for identical replicas of an object.
+type resumingReader struct {
+	io.ReadCloser
+	read int64
+	// resume opens stream of the remaining content starting at offset, or fails if it can't be resumed
+	resume func(offset int64) (io.ReadCloser, error)
+}
+
+func (rr *resumingReader) Read(p []byte) (int, error) {
+	for {
+		n, err := rr.ReadCloser.Read(p)
+		rr.read += int64(n)
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+
+		resumed, resumeErr := rr.resume(rr.read)
+		if resumeErr != nil {
+			return n, fmt.Errorf("%w (resume failed: %v)", err, resumeErr)
+		}
+		rr.ReadCloser.Close()
+		rr.ReadCloser = resumed
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/minio/minio-go/v7"
+	"golang.org/x/sync/errgroup"
+)
+
+// NodeUsage is the number of objects stored on a node and their total size in bytes, as stored
+// (e.g. compressed).
+type NodeUsage struct {
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// usageReporter is implemented by node storages able to report their usage.
+type usageReporter interface {
+	Usage(ctx context.Context) (NodeUsage, error)
+}
+
+// Usage lists objects of all buckets on the node, summing their sizes. Only objects of the configured namespace
+// are counted, along with all deduplicated content. Listing is linear in the number of objects, so callers
+// should cache the result.
+func (s *MinioStorage) Usage(ctx context.Context) (NodeUsage, error) {
+	// listing stops only when its context is done, so it's cancelled when returning early on error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	buckets, err := s.client.ListBuckets(ctx)
+	if err != nil {
+		return NodeUsage{}, fmt.Errorf("error list buckets (%s): %w", s.endpoint, err)
+	}
+	var usage NodeUsage
+	for _, bucket := range buckets {
+		for info := range s.client.ListObjects(ctx, bucket.Name, minio.ListObjectsOptions{Prefix: s.keyPrefix(bucket.Name), Recursive: true}) {
+			if info.Err != nil {
+				return NodeUsage{}, fmt.Errorf("error list objects (%s | %s): %w", s.endpoint, bucket.Name, info.Err)
+			}
+			usage.Objects++
+			usage.Bytes += info.Size
+		}
+	}
+	return usage, nil
+}
+
+// Usage returns usage summed across all available storage nodes. Replicas are counted on every node
+// holding them.
+func (s *DistributedStorage) Usage(ctx context.Context) (NodeUsage, error) {
+	nodes, err := s.NodesUsage(ctx)
+	if err != nil {
+		return NodeUsage{}, err
+	}
+	var total NodeUsage
+	for _, usage := range nodes {
+		total.Objects += usage.Objects
+		total.Bytes += usage.Bytes
+	}
+	return total, nil
+}
+
+// NodesUsage returns usage of all available storage nodes keyed by ring key, querying them concurrently.
+// It fails if any node fails, as the cluster usage would be understated.
+func (s *DistributedStorage) NodesUsage(ctx context.Context) (map[string]NodeUsage, error) {
+	s.mu.RLock()
+	storages := make(map[string]Storage, len(s.availableStorages))
+	for key, storage := range s.availableStorages {
+		storages[key] = storage
+	}
+	s.mu.RUnlock()
+
+	var mu sync.Mutex
+	nodes := make(map[string]NodeUsage, len(storages))
+	g, gctx := errgroup.WithContext(ctx)
+	for key, storage := range storages {
+		key, storage := key, storage
+		g.Go(func() error {
+			ur, ok := storage.(usageReporter)
+			if !ok {
+				return fmt.Errorf("storage node (%s) doesn't report usage", key)
+			}
+			usage, err := ur.Usage(gctx)
+			if err != nil {
+				s.logger.WarnContext(ctx, "node operation failed", "operation", "usage", "node", key, "error", err)
+				s.metrics.NodeError(key, "usage")
+				return fmt.Errorf("failed to get usage using node (%s): %w", key, err)
+			}
+			mu.Lock()
+			nodes[key] = usage
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// usageStorage is MockStorage reporting fixed usage
+type usageStorage struct {
+	MockStorage
+	usage NodeUsage
+	err   error
+}
+
+func (us *usageStorage) Usage(ctx context.Context) (NodeUsage, error) {
+	return us.usage, us.err
+}
+
+func TestDistributedStorage_Usage(t *testing.T) {
+	ds := createDistributedStorage(new(MockStorage), nil)
+	ds.availableStorages = map[string]Storage{
+		"node1#1": &usageStorage{usage: NodeUsage{Objects: 3, Bytes: 300}},
+		"node2#2": &usageStorage{usage: NodeUsage{Objects: 2, Bytes: 1024}},
+	}
+
+	nodes, err := ds.NodesUsage(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]NodeUsage{"node1#1": {Objects: 3, Bytes: 300}, "node2#2": {Objects: 2, Bytes: 1024}}, nodes)
+	total, err := ds.Usage(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, NodeUsage{Objects: 5, Bytes: 1324}, total)
+
+	// usage of a failing node is unknown, so the total would be understated
+	ds.availableStorages["node3#3"] = &usageStorage{err: errors.New("node down")}
+	_, err = ds.Usage(context.TODO())
+	assert.ErrorContains(t, err, "node3#3")
+}
+
+func TestMinioStorage_Usage(t *testing.T) {
+	// fake minio node holding objects in two buckets
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		switch strings.Trim(r.URL.Path, "/") {
+		case "":
+			_, _ = w.Write([]byte(`<ListAllMyBucketsResult><Buckets>` +
+				`<Bucket><Name>default</Name><CreationDate>2023-10-01T12:00:00.000Z</CreationDate></Bucket>` +
+				`<Bucket><Name>photos</Name><CreationDate>2023-10-01T12:00:00.000Z</CreationDate></Bucket>` +
+				`</Buckets></ListAllMyBucketsResult>`))
+		case "default":
+			_, _ = w.Write([]byte(`<ListBucketResult><Name>default</Name><KeyCount>2</KeyCount><IsTruncated>false</IsTruncated>` +
+				`<Contents><Key>a</Key><Size>100</Size></Contents><Contents><Key>b</Key><Size>20</Size></Contents></ListBucketResult>`))
+		case "photos":
+			_, _ = w.Write([]byte(`<ListBucketResult><Name>photos</Name><KeyCount>1</KeyCount><IsTruncated>false</IsTruncated>` +
+				`<Contents><Key>a</Key><Size>4096</Size></Contents></ListBucketResult>`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewMinioStorage(&MinioConfig{
+		Endpoint:   strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:  "key",
+		SecretKey:  "secret",
+		BucketName: "default",
+		Region:     "us-east-1",
+	})
+	assert.NoError(t, err)
+
+	usage, err := s.(usageReporter).Usage(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, NodeUsage{Objects: 3, Bytes: 4216}, usage)
+}
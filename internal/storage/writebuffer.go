@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultWriteBufferWorkers is the default number of buffered objects flushed to the nodes at the same time.
+	DefaultWriteBufferWorkers = 8
+	// DefaultWriteBufferMaxObjectSize is the default size of the largest object content buffered, in bytes.
+	DefaultWriteBufferMaxObjectSize = 1 << 20
+)
+
+// ErrWriteBufferFull is returned by writes that can't be buffered, as the write buffer is full.
+var ErrWriteBufferFull = errors.New("write buffer full")
+
+// WriteBufferConfig configures buffering writes in memory, acknowledging them before they reach the nodes.
+type WriteBufferConfig struct {
+	// Size is the number of objects buffered at most. Zero disables buffering, so writes are acknowledged
+	// once stored on the nodes.
+	Size int
+	// Workers is the number of buffered objects flushed to the nodes at the same time. Writes of the same object
+	// are flushed by the same worker, in order. Defaults to DefaultWriteBufferWorkers.
+	Workers int
+	// MaxObjectSize is the largest object content buffered, in bytes. Larger objects and streams of unknown size
+	// are written directly. Defaults to DefaultWriteBufferMaxObjectSize.
+	MaxObjectSize int64
+	// BlockWhenFull makes writes wait for room in the full buffer. By default they fail with ErrWriteBufferFull.
+	BlockWhenFull bool
+}
+
+type bufferedKey struct{}
+
+// TrackBufferedWrites returns context reporting writes performed with it were buffered, by setting the returned flag,
+// so they can be acknowledged as accepted rather than stored.
+func TrackBufferedWrites(ctx context.Context) (context.Context, *atomic.Bool) {
+	buffered := new(atomic.Bool)
+	return context.WithValue(ctx, bufferedKey{}, buffered), buffered
+}
+
+// MarkBuffered reports write performed with ctx was buffered rather than stored, if ctx tracks buffered writes.
+func MarkBuffered(ctx context.Context) {
+	if buffered, ok := ctx.Value(bufferedKey{}).(*atomic.Bool); ok {
+		buffered.Store(true)
+	}
+}
+
+// bufferedWrite is an object waiting in the write buffer. flushed is closed once it was written to the nodes.
+type bufferedWrite struct {
+	object  *Object
+	flushed chan struct{}
+}
+
+// writeBuffer queues objects to be written to the nodes by background workers. Objects are assigned to worker
+// queues by ID, so writes of the same object reach the nodes in order.
+type writeBuffer struct {
+	maxObjectSize int64
+	blockWhenFull bool
+	queues        []chan *bufferedWrite
+	workers       sync.WaitGroup
+
+	// closeMu guards sending to queues against closing them
+	closeMu sync.RWMutex
+	closed  bool
+
+	// mu guards the latest buffered write of each object
+	mu      sync.Mutex
+	pending map[string]*bufferedWrite
+}
+
+func newWriteBuffer(cfg WriteBufferConfig) *writeBuffer {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = DefaultWriteBufferWorkers
+	}
+	workers = min(workers, cfg.Size)
+	maxObjectSize := cfg.MaxObjectSize
+	if maxObjectSize <= 0 {
+		maxObjectSize = DefaultWriteBufferMaxObjectSize
+	}
+	b := &writeBuffer{
+		maxObjectSize: maxObjectSize,
+		blockWhenFull: cfg.BlockWhenFull,
+		queues:        make([]chan *bufferedWrite, workers),
+		pending:       make(map[string]*bufferedWrite),
+	}
+	// the size is split among the queues, rounded up
+	for i := range b.queues {
+		b.queues[i] = make(chan *bufferedWrite, (cfg.Size+workers-1)/workers)
+	}
+	return b
+}
+
+// start starts workers writing buffered objects using flush, until the buffer is closed.
+func (b *writeBuffer) start(flush func(object *Object)) {
+	for _, queue := range b.queues {
+		b.workers.Add(1)
+		go func(queue chan *bufferedWrite) {
+			defer b.workers.Done()
+			for write := range queue {
+				flush(write.object)
+				b.mu.Lock()
+				if b.pending[write.object.ID] == write {
+					delete(b.pending, write.object.ID)
+				}
+				close(write.flushed)
+				b.mu.Unlock()
+			}
+		}(queue)
+	}
+}
+
+// enqueue buffers the object, returning false if the buffer is closed. It fails with ErrWriteBufferFull
+// when the buffer is full, unless blocking until there's room or ctx is done.
+func (b *writeBuffer) enqueue(ctx context.Context, object *Object) (bool, error) {
+	b.closeMu.RLock()
+	defer b.closeMu.RUnlock()
+	if b.closed {
+		return false, nil
+	}
+
+	write := &bufferedWrite{object: object, flushed: make(chan struct{})}
+	b.mu.Lock()
+	previous := b.pending[object.ID]
+	b.pending[object.ID] = write
+	b.mu.Unlock()
+
+	queue := b.queues[b.queueIndex(object.ID)]
+	var err error
+	if b.blockWhenFull {
+		select {
+		case queue <- write:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	} else {
+		select {
+		case queue <- write:
+		default:
+			err = ErrWriteBufferFull
+		}
+	}
+	if err != nil {
+		// operations keep waiting for the previous write, unless it was flushed meanwhile
+		b.mu.Lock()
+		if b.pending[object.ID] == write {
+			delete(b.pending, object.ID)
+			if previous != nil && !isClosed(previous.flushed) {
+				b.pending[object.ID] = previous
+			}
+		}
+		b.mu.Unlock()
+		return false, err
+	}
+	return true, nil
+}
+
+// wait waits until buffered writes of the object with given ID are flushed. Writes of the object are flushed
+// in order, so waiting for the latest one is enough.
+func (b *writeBuffer) wait(ctx context.Context, id string) error {
+	b.mu.Lock()
+	write := b.pending[id]
+	b.mu.Unlock()
+	if write == nil {
+		return nil
+	}
+	select {
+	case <-write.flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close stops buffering and waits until all buffered objects are flushed, or ctx is done.
+func (b *writeBuffer) close(ctx context.Context) error {
+	b.closeMu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, queue := range b.queues {
+			close(queue)
+		}
+	}
+	b.closeMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *writeBuffer) queueIndex(id string) int {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() % uint32(len(b.queues)))
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// bufferPut buffers the object if the write buffer is enabled and the object isn't too large, returning
+// whether it was buffered. Conditional writes aren't buffered, as they're checked against the stored object.
+func (s *DistributedStorage) bufferPut(ctx context.Context, object *Object) (bool, error) {
+	if s.buffer == nil || PreconditionFromContext(ctx) != nil || int64(len(object.Content)) > s.buffer.maxObjectSize {
+		return false, nil
+	}
+	if err := s.ready(); err != nil {
+		return false, err
+	}
+	buffered, err := s.buffer.enqueue(ctx, object)
+	if buffered {
+		MarkBuffered(ctx)
+		s.logger.DebugContext(ctx, "object buffered", "operation", "put", "object_id", object.ID)
+	}
+	return buffered, err
+}
+
+// bufferPutStream buffers the streamed object if the write buffer is enabled and its declared size isn't too
+// large, reading its content into memory. It returns whether the object was written, buffered or, once
+// the buffer is closed, directly, as its content was consumed.
+func (s *DistributedStorage) bufferPutStream(ctx context.Context, object *ObjectStream) (bool, error) {
+	if s.buffer == nil || PreconditionFromContext(ctx) != nil || object.Size < 0 || object.Size > s.buffer.maxObjectSize {
+		return false, nil
+	}
+	content, err := io.ReadAll(io.LimitReader(object.Content, object.Size+1))
+	if err != nil {
+		return true, fmt.Errorf("failed to read data: %w", err)
+	}
+	if int64(len(content)) != object.Size {
+		return true, fmt.Errorf("%w: read %d bytes, expected %d", ErrContentLengthMismatch, len(content), object.Size)
+	}
+	buffered := &Object{
+		ID:          object.ID,
+		ContentType: object.ContentType,
+		Content:     content,
+		Metadata:    object.Metadata,
+		ExpiresAt:   object.ExpiresAt,
+	}
+	if ok, err := s.bufferPut(ctx, buffered); ok || err != nil {
+		return true, err
+	}
+	return true, s.put(ctx, buffered, func() {})
+}
+
+// awaitBuffered waits until buffered writes of object ID reach the nodes, so operations on the object
+// don't race with them.
+func (s *DistributedStorage) awaitBuffered(ctx context.Context, id string) error {
+	if s.buffer == nil {
+		return nil
+	}
+	return s.buffer.wait(ctx, id)
+}
+
+// startWriteBuffer starts flushing buffered objects to the nodes. Flushing outlives ctx, so objects buffered
+// before shutdown are flushed by FlushWrites.
+func (s *DistributedStorage) startWriteBuffer(ctx context.Context) {
+	if s.buffer == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	s.buffer.start(func(object *Object) {
+		start := time.Now()
+		if err := s.put(ctx, object, func() {}); err != nil {
+			s.logger.ErrorContext(ctx, "cannot flush buffered object", "operation", "put", "object_id", object.ID, "duration", time.Since(start), "error", err)
+		}
+	})
+}
+
+// FlushWrites stops buffering writes and waits until buffered objects are written to the nodes, or ctx is done.
+// Later writes are written directly. It must be called before cancelling the Init context, as the storage
+// stops serving operations then.
+func (s *DistributedStorage) FlushWrites(ctx context.Context) error {
+	if s.buffer == nil {
+		return nil
+	}
+	return s.buffer.close(ctx)
+}
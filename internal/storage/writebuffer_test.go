@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingStorage is memoryStorage whose writes wait until released, signalling each one started
+type blockingStorage struct {
+	*memoryStorage
+	started chan string
+	release chan struct{}
+}
+
+func (bs *blockingStorage) Put(ctx context.Context, object *Object) error {
+	bs.started <- object.ID
+	<-bs.release
+	return bs.memoryStorage.Put(ctx, object)
+}
+
+// createBufferedStorage creates initialized storage of a single node, buffering writes
+func createBufferedStorage(t *testing.T, cfg WriteBufferConfig) (*DistributedStorage, *blockingStorage) {
+	nodes, err := ParseStaticNodes("key:secret@10.0.0.1:9000")
+	assert.NoError(t, err)
+	ds := NewDistributedStorage(NewStaticDiscoverer(nodes), &DistributedConfig{WriteBuffer: cfg}).(*DistributedStorage)
+	node := &blockingStorage{
+		memoryStorage: &memoryStorage{objects: map[string]*Object{}},
+		started:       make(chan string, 100),
+		release:       make(chan struct{}),
+	}
+	ds.newStorage = func(cfg *MinioConfig) (Storage, error) { return node, nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		assert.NoError(t, ds.FlushWrites(ctx))
+		cancel()
+	})
+	assert.NoError(t, ds.Init(ctx))
+	return ds, node
+}
+
+func TestDistributedStorage_WriteBuffer(t *testing.T) {
+	ds, node := createBufferedStorage(t, WriteBufferConfig{Size: 10, Workers: 1, MaxObjectSize: 8})
+
+	// write is acknowledged before it reaches the node
+	ctx, buffered := TrackBufferedWrites(context.Background())
+	assert.NoError(t, ds.Put(ctx, &Object{ID: "1", Content: []byte("first")}))
+	assert.True(t, buffered.Load())
+	assert.NoError(t, ds.PutStream(ctx, &ObjectStream{ID: "2", Size: 6, Content: io.NopCloser(strings.NewReader("second"))}))
+	assert.Equal(t, "1", <-node.started)
+
+	// reads wait until the object is flushed
+	read := make(chan *Object)
+	go func() {
+		object, err := ds.Get(context.Background(), "1")
+		assert.NoError(t, err)
+		read <- object
+	}()
+	select {
+	case <-read:
+		t.Fatal("object read before flushed")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(node.release)
+	if object := <-read; assert.NotNil(t, object) {
+		assert.Equal(t, "first", string(object.Content))
+	}
+	object, err := ds.Get(context.Background(), "2")
+	if assert.NoError(t, err) && assert.NotNil(t, object) {
+		assert.Equal(t, "second", string(object.Content))
+	}
+
+	// objects larger than buffered ones are written directly
+	ctx, buffered = TrackBufferedWrites(context.Background())
+	assert.NoError(t, ds.Put(ctx, &Object{ID: "3", Content: []byte("too large")}))
+	assert.False(t, buffered.Load())
+	assert.NoError(t, ds.PutStream(ctx, &ObjectStream{ID: "4", Size: -1, Content: io.NopCloser(strings.NewReader("unknown"))}))
+	assert.False(t, buffered.Load())
+
+	// declared size of buffered streams is verified
+	err = ds.PutStream(ctx, &ObjectStream{ID: "5", Size: 4, Content: io.NopCloser(strings.NewReader("short, actually"))})
+	assert.ErrorIs(t, err, ErrContentLengthMismatch)
+}
+
+func TestDistributedStorage_WriteBufferFull(t *testing.T) {
+	ds, node := createBufferedStorage(t, WriteBufferConfig{Size: 1, Workers: 1})
+	defer close(node.release)
+	ctx := context.Background()
+
+	// first write is being flushed, second one fills the buffer
+	assert.NoError(t, ds.Put(ctx, &Object{ID: "1", Content: []byte("1")}))
+	<-node.started
+	assert.NoError(t, ds.Put(ctx, &Object{ID: "2", Content: []byte("2")}))
+	assert.ErrorIs(t, ds.Put(ctx, &Object{ID: "3", Content: []byte("3")}), ErrWriteBufferFull)
+
+	// rejected write leaves nothing to wait for
+	assert.NoError(t, ds.awaitBuffered(ctx, "3"))
+
+	// blocking writes wait for room until cancelled
+	ds.buffer.blockWhenFull = true
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, ds.Put(timeoutCtx, &Object{ID: "3", Content: []byte("3")}), context.DeadlineExceeded)
+}
+
+func TestDistributedStorage_FlushWrites(t *testing.T) {
+	ds, node := createBufferedStorage(t, WriteBufferConfig{Size: 20, Workers: 4})
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, ds.Put(ctx, &Object{ID: fmt.Sprintf("object-%d", i), Content: []byte("data")}))
+	}
+
+	// shutdown waits until buffered objects are stored
+	flushed := make(chan error)
+	go func() { flushed <- ds.FlushWrites(ctx) }()
+	select {
+	case <-flushed:
+		t.Fatal("flush completed before objects were stored")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(node.release)
+	assert.NoError(t, <-flushed)
+	ds.pendingWrites.Wait()
+	assert.Len(t, node.objects, 10)
+
+	// writes after flush are written directly
+	ctx, buffered := TrackBufferedWrites(ctx)
+	assert.NoError(t, ds.Put(ctx, &Object{ID: "late", Content: []byte("data")}))
+	assert.False(t, buffered.Load())
+	ds.pendingWrites.Wait()
+	assert.Len(t, node.objects, 11)
+}
+
+func TestFlushWrites_Timeout(t *testing.T) {
+	ds, node := createBufferedStorage(t, WriteBufferConfig{Size: 1})
+	defer close(node.release)
+	assert.NoError(t, ds.Put(context.Background(), &Object{ID: "1", Content: []byte("1")}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, ds.FlushWrites(ctx), context.DeadlineExceeded)
+}
@@ -1,8 +1,10 @@
 package itests
 
 import (
+	"bytes"
 	"context"
 	"github.com/cavke/go-distributed-object-storage/internal/storage"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,10 +38,12 @@ func TestMinioIntegration(t *testing.T) {
 
 	// Test Put
 	content := []byte("Hello, Minio!")
+	metadata := map[string]string{"Author": "jane", "Reviewed-By": "john"}
 	object := storage.Object{
 		ID:          testObjectID,
 		ContentType: testContentType,
 		Content:     content,
+		Metadata:    metadata,
 	}
 	err = mStorage.Put(ctx, &object)
 	assert.Nil(t, err)
@@ -50,4 +54,37 @@ func TestMinioIntegration(t *testing.T) {
 	assert.Equal(t, testObjectID, object1.ID)
 	assert.Equal(t, testContentType, object1.ContentType)
 	assert.Equal(t, content, object1.Content)
+	assert.Equal(t, metadata, object1.Metadata)
+
+	// Test PutStream
+	streamContent := []byte("Hello, streaming Minio!")
+	err = mStorage.PutStream(ctx, &storage.ObjectStream{
+		ID:          testObjectID,
+		ContentType: testContentType,
+		Size:        int64(len(streamContent)),
+		Content:     io.NopCloser(bytes.NewReader(streamContent)),
+		Metadata:    map[string]string{"Author": "john"},
+	})
+	assert.Nil(t, err)
+
+	// Test GetStream
+	stream, err := mStorage.GetStream(ctx, testObjectID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(streamContent)), stream.Size)
+	assert.Equal(t, map[string]string{"Author": "john"}, stream.Metadata)
+	body, err := io.ReadAll(stream.Content)
+	assert.Nil(t, err)
+	assert.Nil(t, stream.Content.Close())
+	assert.Equal(t, streamContent, body)
+
+	// Test Delete
+	err = mStorage.Delete(ctx, testObjectID)
+	assert.Nil(t, err)
+
+	object2, err := mStorage.Get(ctx, testObjectID)
+	assert.Nil(t, err)
+	assert.Nil(t, object2)
+
+	err = mStorage.Delete(ctx, testObjectID)
+	assert.ErrorIs(t, err, storage.ErrObjectNotFound)
 }